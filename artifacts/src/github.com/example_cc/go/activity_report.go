@@ -0,0 +1,311 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// Review states of a suspicious activity report.
+const (
+	reportStatusOpen     = "open"
+	reportStatusApproved = "approved"
+	reportStatusRejected = "rejected"
+)
+
+type SuspiciousActivityReport struct {
+	ObjectType     string `json:"docType"`
+	ReportID       string `json:"reportID"`
+	ReportedMarble string `json:"reportedMarble"`
+	ReportedOwner  string `json:"reportedOwner"`
+	Category       string `json:"category"`
+	Evidence       string `json:"evidence"`
+	ReporterMSPID  string `json:"reporterMSPID"`
+	Status         string `json:"status"`
+}
+
+func getActivityReport(stub shim.ChaincodeStubInterface, reportID string) (*SuspiciousActivityReport, error) {
+	reportAsBytes, err := stub.GetPrivateData("collectionMarbleSARs", reportID)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get report: %s", err.Error())
+	} else if reportAsBytes == nil {
+		return nil, fmt.Errorf("Report does not exist: %s", reportID)
+	}
+
+	report := &SuspiciousActivityReport{}
+	err = json.Unmarshal(reportAsBytes, report)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to decode JSON of: %s", string(reportAsBytes))
+	}
+	return report, nil
+}
+
+func putActivityReport(stub shim.ChaincodeStubInterface, report *SuspiciousActivityReport) error {
+	reportAsBytes, err := json.Marshal(report)
+	if err != nil {
+		return err
+	}
+	return stub.PutPrivateData("collectionMarbleSARs", report.ReportID, reportAsBytes)
+}
+
+// adjustSuspicionCount changes the approved report count of an owner MSP and keeps the
+// governance blacklist in step with the suspicion threshold. It only ever lifts a
+// blacklisting it made itself, never one made by the admin.
+func adjustSuspicionCount(stub shim.ChaincodeStubInterface, ownerMSPID string, delta int) error {
+	gov, err := getGovernance(stub)
+	if err != nil {
+		return err
+	}
+	if gov.OwnerSuspicionCount == nil {
+		gov.OwnerSuspicionCount = map[string]int{}
+	}
+
+	count := gov.OwnerSuspicionCount[ownerMSPID] + delta
+	if count <= 0 {
+		delete(gov.OwnerSuspicionCount, ownerMSPID)
+	} else {
+		gov.OwnerSuspicionCount[ownerMSPID] = count
+	}
+
+	// the admin may have lifted the blacklisting since
+	if !gov.isBlacklisted(ownerMSPID) {
+		gov.SuspicionBlacklisted = removeString(gov.SuspicionBlacklisted, ownerMSPID)
+	}
+
+	if count > gov.SuspicionThreshold {
+		if !gov.isBlacklisted(ownerMSPID) {
+			gov.addToBlacklist(ownerMSPID)
+			gov.SuspicionBlacklisted = append(gov.SuspicionBlacklisted, ownerMSPID)
+		}
+	} else if containsString(gov.SuspicionBlacklisted, ownerMSPID) {
+		gov.removeFromBlacklist(ownerMSPID)
+		gov.SuspicionBlacklisted = removeString(gov.SuspicionBlacklisted, ownerMSPID)
+	}
+	return putGovernance(stub, gov)
+}
+
+// =================================================================================
+// fileActivityReport - report suspicious activity around a marble and its owner
+// =================================================================================
+func (t *SimpleChaincode) fileActivityReport(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	fmt.Println("- start file activity report")
+
+	type activityReportTransientInput struct {
+		ReportedMarble string `json:"reportedMarble"`
+		ReportedOwner  string `json:"reportedOwner"`
+		Category       string `json:"category"`
+		Evidence       string `json:"evidence"`
+	}
+
+	if len(args) != 0 {
+		return shim.Error("Incorrect number of arguments. Private report data must be passed in transient map.")
+	}
+
+	var reportInput activityReportTransientInput
+	err := getTransientInput(stub, "activity_report", &reportInput)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	if len(reportInput.ReportedMarble) == 0 {
+		return shim.Error("reportedMarble field must be a non-empty string")
+	}
+	if len(reportInput.ReportedOwner) == 0 {
+		return shim.Error("reportedOwner field must be a non-empty string")
+	}
+	if len(reportInput.Category) == 0 {
+		return shim.Error("category field must be a non-empty string")
+	}
+
+	m, err := getMarble(stub, reportInput.ReportedMarble)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if m.Owner != reportInput.ReportedOwner {
+		return shim.Error(fmt.Sprintf("%s is not the owner of marble %s", reportInput.ReportedOwner, m.Name))
+	}
+
+	reporterMSPID, err := getCallerMSPID(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	report := &SuspiciousActivityReport{
+		ObjectType:     "suspiciousActivityReport",
+		ReportID:       stub.GetTxID(),
+		ReportedMarble: reportInput.ReportedMarble,
+		ReportedOwner:  reportInput.ReportedOwner,
+		Category:       reportInput.Category,
+		Evidence:       reportInput.Evidence,
+		ReporterMSPID:  reporterMSPID,
+		Status:         reportStatusOpen,
+	}
+	err = putActivityReport(stub, report)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	fmt.Println("- end file activity report")
+	return shim.Success([]byte(report.ReportID))
+}
+
+// =================================================================================
+// reviewReport - admin finding on an open report, either "approved" or "rejected".
+// An approved report counts against the reported owner.
+// =================================================================================
+func (t *SimpleChaincode) reviewReport(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+
+	//     0            1
+	// "reportID", "approved"
+	if len(args) != 2 {
+		return shim.Error("Incorrect number of arguments. Expecting 2")
+	}
+	reportID, finding := args[0], args[1]
+	if finding != reportStatusApproved && finding != reportStatusRejected {
+		return shim.Error("finding must be \"approved\" or \"rejected\"")
+	}
+
+	err := requireAdmin(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	report, err := getActivityReport(stub, reportID)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if report.Status != reportStatusOpen {
+		return shim.Error("Report has already been reviewed: " + reportID)
+	}
+
+	report.Status = finding
+	err = putActivityReport(stub, report)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	if finding == reportStatusApproved {
+		err = adjustSuspicionCount(stub, report.ReportedOwner, 1)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+	}
+	return shim.Success(nil)
+}
+
+// =================================================================================
+// dismissReport - admin removal of a report. Dismissing an approved report takes it
+// back off the reported owner's suspicion count.
+// =================================================================================
+func (t *SimpleChaincode) dismissReport(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+
+	//     0
+	// "reportID"
+	if len(args) != 1 {
+		return shim.Error("Incorrect number of arguments. Expecting 1")
+	}
+
+	err := requireAdmin(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	report, err := getActivityReport(stub, args[0])
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	err = stub.DelPrivateData("collectionMarbleSARs", report.ReportID)
+	if err != nil {
+		return shim.Error("Failed to delete state:" + err.Error())
+	}
+
+	if report.Status == reportStatusApproved {
+		err = adjustSuspicionCount(stub, report.ReportedOwner, -1)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+	}
+	return shim.Success(nil)
+}
+
+// queryActivityReports scans collectionMarbleSARs and returns the reports accepted by
+// match as a JSON array.
+func queryActivityReports(stub shim.ChaincodeStubInterface, match func(*SuspiciousActivityReport) bool) ([]byte, error) {
+	resultsIterator, err := stub.GetPrivateDataByRange("collectionMarbleSARs", "", "")
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	reports := []SuspiciousActivityReport{}
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var report SuspiciousActivityReport
+		err = json.Unmarshal(queryResponse.Value, &report)
+		if err != nil {
+			return nil, err
+		}
+		if match(&report) {
+			reports = append(reports, report)
+		}
+	}
+	return json.Marshal(reports)
+}
+
+// ===========================================================================
+// getReportsByOwner - list the reports filed against an owner MSP
+// ===========================================================================
+func (t *SimpleChaincode) getReportsByOwner(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	if len(args) != 1 {
+		return shim.Error("Incorrect number of arguments. Expecting owner MSP ID to query")
+	}
+
+	reportsAsBytes, err := queryActivityReports(stub, func(r *SuspiciousActivityReport) bool {
+		return r.ReportedOwner == args[0]
+	})
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	return shim.Success(reportsAsBytes)
+}
+
+// ===========================================================================
+// getOpenReports - list the reports waiting for review
+// ===========================================================================
+func (t *SimpleChaincode) getOpenReports(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	if len(args) != 0 {
+		return shim.Error("Incorrect number of arguments. Expecting 0")
+	}
+
+	reportsAsBytes, err := queryActivityReports(stub, func(r *SuspiciousActivityReport) bool {
+		return r.Status == reportStatusOpen
+	})
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	return shim.Success(reportsAsBytes)
+}
+
+// ===========================================================================
+// getReportsByMarble - list the reports filed about a marble
+// ===========================================================================
+func (t *SimpleChaincode) getReportsByMarble(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	if len(args) != 1 {
+		return shim.Error("Incorrect number of arguments. Expecting name of the marble to query")
+	}
+
+	reportsAsBytes, err := queryActivityReports(stub, func(r *SuspiciousActivityReport) bool {
+		return r.ReportedMarble == args[0]
+	})
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	return shim.Success(reportsAsBytes)
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func report(marbleName, owner string) map[string]interface{} {
+	return map[string]interface{}{"activity_report": map[string]interface{}{"reportedMarble": marbleName, "reportedOwner": owner, "category": "wash trading"}}
+}
+
+func (s *testStub) readTestGovernance() *governance {
+	gov := &governance{}
+	err := json.Unmarshal(s.State[governanceKey], gov)
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	return gov
+}
+
+func TestReportedOwnerMustOwnTheMarble(t *testing.T) {
+	s := newTestStub(t)
+	s.createMarble("marble1", "blue", 35, "Org2MSP", 99)
+
+	s.mustFail("Org3MSP is not the owner of marble marble1", "fileActivityReport", report("marble1", "Org3MSP"))
+	s.mustInvoke("fileActivityReport", report("marble1", "Org2MSP"))
+}
+
+func TestDismissalOnlyLiftsSuspicionBlacklisting(t *testing.T) {
+	s := newTestStub(t)
+	s.mustInvoke("reinitialize", map[string]interface{}{"governance": map[string]interface{}{"suspicionThreshold": 1}})
+	// a blacklisting that did not come from activity reports
+	gov := s.readTestGovernance()
+	gov.addToBlacklist("Org3MSP")
+	s.State[governanceKey], _ = json.Marshal(gov)
+	s.createMarble("marble2", "blue", 35, "Org2MSP", 99)
+	s.createMarble("marble3", "red", 35, "Org3MSP", 99)
+
+	var org2Reports, org3Reports []string
+	for i := 0; i < 2; i++ {
+		org2Reports = append(org2Reports, string(s.mustInvoke("fileActivityReport", report("marble2", "Org2MSP"))))
+		org3Reports = append(org3Reports, string(s.mustInvoke("fileActivityReport", report("marble3", "Org3MSP"))))
+	}
+	for _, reportID := range append(org2Reports, org3Reports...) {
+		s.mustInvoke("reviewReport", nil, reportID, "approved")
+	}
+	if gov := s.readTestGovernance(); !gov.isBlacklisted("Org2MSP") || !gov.isBlacklisted("Org3MSP") {
+		t.Fatalf("expected Org2MSP and Org3MSP to be blacklisted, got %v", gov.Blacklist)
+	}
+
+	s.mustInvoke("dismissReport", nil, org2Reports[0])
+	s.mustInvoke("dismissReport", nil, org3Reports[0])
+	gov = s.readTestGovernance()
+	if gov.isBlacklisted("Org2MSP") {
+		t.Fatal("expected the dismissal to lift the blacklisting of Org2MSP")
+	}
+	if !gov.isBlacklisted("Org3MSP") {
+		t.Fatal("expected the other blacklisting of Org3MSP to stay")
+	}
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// AppraisalRecord is one valuation of a marble, kept with its private details.
+type AppraisalRecord struct {
+	Value          int    `json:"value"`
+	AppraiserMSPID string `json:"appraiserMSPID"`
+	TxID           string `json:"txID"`
+	AppraisedAt    string `json:"appraisedAt"`
+}
+
+func putMarblePrivateDetails(stub shim.ChaincodeStubInterface, details *marblePrivateDetails) error {
+	detailsAsBytes, err := json.Marshal(details)
+	if err != nil {
+		return err
+	}
+	return stub.PutPrivateData("collectionMarblePrivateDetails", details.Name, detailsAsBytes)
+}
+
+// ===========================================================================================
+// batchAppraise appends an appraisal to each marble in the batch. Every entry is validated
+// before anything is written, so a bad entry leaves all marbles untouched. Marbles not in
+// the batch are not changed.
+// ===========================================================================================
+func (t *SimpleChaincode) batchAppraise(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	fmt.Println("- start batch appraise")
+
+	type appraisalTransientInput struct {
+		Name  string `json:"name"`
+		Value int    `json:"value"`
+	}
+
+	type batchAppraisalSummary struct {
+		Appraised           int     `json:"appraised"`
+		TotalPortfolioValue int     `json:"totalPortfolioValue"`
+		AvgValue            float64 `json:"avgValue"`
+	}
+
+	if len(args) != 0 {
+		return shim.Error("Incorrect number of arguments. Appraisals must be passed in transient map.")
+	}
+
+	var appraisalInputs []appraisalTransientInput
+	err := getTransientInput(stub, "appraisals", &appraisalInputs)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if len(appraisalInputs) == 0 {
+		return shim.Error("appraisals must be a non-empty JSON array")
+	}
+
+	// ==== Validate the whole batch before writing ====
+	detailsByName := map[string]*marblePrivateDetails{}
+	for i, input := range appraisalInputs {
+		if len(input.Name) == 0 {
+			return shim.Error(fmt.Sprintf("appraisal %d: name field must be a non-empty string", i))
+		}
+		if input.Value <= 0 {
+			return shim.Error(fmt.Sprintf("appraisal %d: value field must be a positive integer", i))
+		}
+		if _, ok := detailsByName[input.Name]; ok {
+			continue
+		}
+		details, err := getMarblePrivateDetails(stub, input.Name)
+		if err != nil {
+			return shim.Error(fmt.Sprintf("appraisal %d: %s", i, err.Error()))
+		}
+		detailsByName[input.Name] = details
+	}
+
+	appraiserMSPID, err := getCallerMSPID(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	txTime, err := getTxTime(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	summary := batchAppraisalSummary{}
+	for _, input := range appraisalInputs {
+		details := detailsByName[input.Name]
+		details.Appraisals = append(details.Appraisals, AppraisalRecord{
+			Value:          input.Value,
+			AppraiserMSPID: appraiserMSPID,
+			TxID:           stub.GetTxID(),
+			AppraisedAt:    txTime.Format(time.RFC3339),
+		})
+		summary.Appraised++
+		summary.TotalPortfolioValue += input.Value
+	}
+	for _, details := range detailsByName {
+		err = putMarblePrivateDetails(stub, details)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+	}
+	summary.AvgValue = float64(summary.TotalPortfolioValue) / float64(summary.Appraised)
+
+	summaryAsBytes, err := json.Marshal(summary)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	fmt.Println("- end batch appraise")
+	return shim.Success(summaryAsBytes)
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// notArchivedSelector matches marbles that are not archived, including marbles written
+// before the isArchived field existed.
+const notArchivedSelector = `{"$or":[{"isArchived":false},{"isArchived":{"$exists":false}}]}`
+
+// checkNotArchived fails if the marble has been archived.
+func checkNotArchived(m *marble) error {
+	if m.IsArchived {
+		return fmt.Errorf("marble %s is archived", m.Name)
+	}
+	return nil
+}
+
+// parseIncludeArchived reads the optional includeArchived argument at index i of args,
+// false when it is absent.
+func parseIncludeArchived(args []string, i int) (bool, error) {
+	if len(args) <= i {
+		return false, nil
+	}
+	includeArchived, err := strconv.ParseBool(args[i])
+	if err != nil {
+		return false, fmt.Errorf("includeArchived must be true or false")
+	}
+	return includeArchived, nil
+}
+
+// excludeArchived narrows the selector of a rich query string to marbles that are not
+// archived.
+func excludeArchived(queryString string) (string, error) {
+	var query map[string]interface{}
+	err := json.Unmarshal([]byte(queryString), &query)
+	if err != nil {
+		return "", fmt.Errorf("Failed to decode JSON of: %s", queryString)
+	}
+	var notArchived interface{}
+	err = json.Unmarshal([]byte(notArchivedSelector), &notArchived)
+	if err != nil {
+		return "", err
+	}
+	if selector, ok := query["selector"]; ok {
+		query["selector"] = map[string]interface{}{"$and": []interface{}{selector, notArchived}}
+	} else {
+		query["selector"] = notArchived
+	}
+	queryAsBytes, err := json.Marshal(query)
+	if err != nil {
+		return "", err
+	}
+	return string(queryAsBytes), nil
+}
+
+// marbleArchiveTransientInput names the marble to archive or restore.
+type marbleArchiveTransientInput struct {
+	Name string `json:"name"`
+}
+
+// setArchived archives or restores the named marble for the owner or the admin
+// organization. Archiving withdraws the marble from sale.
+func setArchived(stub shim.ChaincodeStubInterface, archiveInput *marbleArchiveTransientInput, archived bool) error {
+	if len(archiveInput.Name) == 0 {
+		return fmt.Errorf("name field must be a non-empty string")
+	}
+
+	m, err := getMarble(stub, archiveInput.Name)
+	if err != nil {
+		return err
+	}
+	err = requireOwner(stub, m)
+	if err != nil {
+		err = requireAdmin(stub)
+		if err != nil {
+			return err
+		}
+	}
+	if archived {
+		err = checkNotArchived(m)
+		if err != nil {
+			return err
+		}
+	} else if !m.IsArchived {
+		return fmt.Errorf("marble %s is not archived", m.Name)
+	}
+	if archived && m.IsForSale {
+		err = removeListingIndex(stub, m)
+		if err != nil {
+			return err
+		}
+		m.IsForSale = false
+		m.AskingPrice = 0
+	}
+	m.IsArchived = archived
+	return putMarble(stub, m)
+}
+
+// ===========================================================================
+// archiveMarble - retire a marble. The record is kept for audit, but the
+// marble leaves the default queries and can no longer be sold or transferred.
+// ===========================================================================
+func (t *SimpleChaincode) archiveMarble(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	fmt.Println("- start archive marble")
+
+	if len(args) != 0 {
+		return shim.Error("Incorrect number of arguments. Private marble name must be passed in transient map.")
+	}
+
+	var archiveInput marbleArchiveTransientInput
+	err := getTransientInput(stub, "marble_archive", &archiveInput)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = setArchived(stub, &archiveInput, true)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	fmt.Println("- end archive marble")
+	return shim.Success(nil)
+}
+
+// ===========================================================================
+// restoreMarble - bring an archived marble back into use
+// ===========================================================================
+func (t *SimpleChaincode) restoreMarble(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	fmt.Println("- start restore marble")
+
+	if len(args) != 0 {
+		return shim.Error("Incorrect number of arguments. Private marble name must be passed in transient map.")
+	}
+
+	var archiveInput marbleArchiveTransientInput
+	err := getTransientInput(stub, "marble_restore", &archiveInput)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = setArchived(stub, &archiveInput, false)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	fmt.Println("- end restore marble")
+	return shim.Success(nil)
+}
@@ -0,0 +1,281 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// auctionLock is the LockedBy value of a marble under auction.
+const auctionLock = "auction"
+
+// Auction is an open bid auction on a marble, accepting bids up to EndTime. It is kept
+// in collectionMarbleAuctions under the marble name; a closed auction stays there until
+// the marble is auctioned again.
+type Auction struct {
+	ObjectType string `json:"docType"`
+	MarbleName string `json:"marbleName"`
+	Seller     string `json:"seller"`
+	StartPrice int    `json:"startPrice"`
+	HighBid    int    `json:"highBid"`
+	HighBidder string `json:"highBidder"`
+	EndTime    int64  `json:"endTime"`
+	Closed     bool   `json:"closed"`
+}
+
+// getAuction returns the auction of a marble, or nil if the marble was never auctioned.
+func getAuction(stub shim.ChaincodeStubInterface, marbleName string) (*Auction, error) {
+	auctionAsBytes, err := stub.GetPrivateData("collectionMarbleAuctions", marbleName)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get auction: %s", err.Error())
+	} else if auctionAsBytes == nil {
+		return nil, nil
+	}
+
+	auction := &Auction{}
+	err = json.Unmarshal(auctionAsBytes, auction)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to decode JSON of: %s", string(auctionAsBytes))
+	}
+	return auction, nil
+}
+
+// getOpenAuction returns the auction of a marble, failing unless it is open.
+func getOpenAuction(stub shim.ChaincodeStubInterface, marbleName string) (*Auction, error) {
+	auction, err := getAuction(stub, marbleName)
+	if err != nil {
+		return nil, err
+	}
+	if auction == nil || auction.Closed {
+		return nil, fmt.Errorf("Marble is not under auction: %s", marbleName)
+	}
+	return auction, nil
+}
+
+func putAuction(stub shim.ChaincodeStubInterface, auction *Auction) error {
+	auctionAsBytes, err := json.Marshal(auction)
+	if err != nil {
+		return err
+	}
+	return stub.PutPrivateData("collectionMarbleAuctions", auction.MarbleName, auctionAsBytes)
+}
+
+// ===========================================================================
+// startAuction - put an owned marble up for auction for a number of seconds
+// ===========================================================================
+func (t *SimpleChaincode) startAuction(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	fmt.Println("- start start auction")
+
+	type auctionTransientInput struct {
+		MarbleName      string `json:"marbleName"`
+		StartPrice      int    `json:"startPrice"`
+		DurationSeconds int64  `json:"durationSeconds"`
+	}
+
+	if len(args) != 0 {
+		return shim.Error("Incorrect number of arguments. Private marble data must be passed in transient map.")
+	}
+
+	var auctionInput auctionTransientInput
+	err := getTransientInput(stub, "auction", &auctionInput)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if len(auctionInput.MarbleName) == 0 {
+		return shim.Error("marbleName field must be a non-empty string")
+	}
+	if auctionInput.StartPrice <= 0 {
+		return shim.Error("startPrice field must be a positive integer")
+	}
+	if auctionInput.DurationSeconds <= 0 {
+		return shim.Error("durationSeconds field must be a positive integer")
+	}
+
+	m, err := getMarble(stub, auctionInput.MarbleName)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = requireOwner(stub, m)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	// a marble under auction is locked, so this also rules out a second open auction
+	err = checkNotLocked(m)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = checkNotArchived(m)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	currentTime, err := getTxUnixTime(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	auction := &Auction{
+		ObjectType: "auction",
+		MarbleName: m.Name,
+		Seller:     m.Owner,
+		StartPrice: auctionInput.StartPrice,
+		EndTime:    currentTime + auctionInput.DurationSeconds,
+	}
+	err = putAuction(stub, auction)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	// an auctioned marble is no longer offered at its asking price
+	if m.IsForSale {
+		err = removeListingIndex(stub, m)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		m.IsForSale = false
+		m.AskingPrice = 0
+	}
+	m.LockedBy = auctionLock
+	err = putMarble(stub, m)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	fmt.Println("- end start auction")
+	return shim.Success(nil)
+}
+
+// ===========================================================================
+// placeBid - open bid on an auction. A bid must reach the start price and
+// beat the high bid so far. The caller's organization is the bidder.
+// ===========================================================================
+func (t *SimpleChaincode) placeBid(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+
+	type auctionBidTransientInput struct {
+		MarbleName string `json:"marbleName"`
+		Amount     int    `json:"amount"`
+	}
+
+	if len(args) != 0 {
+		return shim.Error("Incorrect number of arguments. Private marble data must be passed in transient map.")
+	}
+
+	var bidInput auctionBidTransientInput
+	err := getTransientInput(stub, "auction_bid", &bidInput)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if len(bidInput.MarbleName) == 0 {
+		return shim.Error("marbleName field must be a non-empty string")
+	}
+	if bidInput.Amount <= 0 {
+		return shim.Error("amount field must be a positive integer")
+	}
+
+	auction, err := getOpenAuction(stub, bidInput.MarbleName)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	currentTime, err := getTxUnixTime(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if currentTime > auction.EndTime {
+		return shim.Error("Bidding has ended on the auction of " + auction.MarbleName)
+	}
+	if bidInput.Amount < auction.StartPrice {
+		return shim.Error(fmt.Sprintf("bid %d is below the start price %d", bidInput.Amount, auction.StartPrice))
+	}
+	if bidInput.Amount <= auction.HighBid {
+		return shim.Error(fmt.Sprintf("bid %d does not beat the high bid %d", bidInput.Amount, auction.HighBid))
+	}
+
+	isSeller, err := callerIs(stub, auction.Seller)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if isSeller {
+		return shim.Error("The seller cannot bid on its own auction")
+	}
+	bidderMSPID, err := getCallerMSPID(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	auction.HighBid = bidInput.Amount
+	auction.HighBidder = bidderMSPID
+	err = putAuction(stub, auction)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	return shim.Success(nil)
+}
+
+// ===========================================================================
+// closeAuction - after the end time, sell the marble to the highest bidder
+// at the winning bid, which becomes its private price. The sale goes through
+// every gate of a transfer. Without a bid the marble stays with the seller.
+// Anyone may call it, so the seller cannot hold the auction open.
+// ===========================================================================
+func (t *SimpleChaincode) closeAuction(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	fmt.Println("- start close auction")
+
+	//   0
+	// "marble1"
+	if len(args) != 1 {
+		return shim.Error("Incorrect number of arguments. Expecting name of the marble")
+	}
+
+	auction, err := getOpenAuction(stub, args[0])
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	currentTime, err := getTxUnixTime(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if currentTime <= auction.EndTime {
+		return shim.Error(fmt.Sprintf("The auction of %s cannot be closed before %d", auction.MarbleName, auction.EndTime))
+	}
+
+	m, err := getMarble(stub, auction.MarbleName)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	gov, err := getGovernance(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if len(auction.HighBidder) != 0 {
+		// priced while still locked, so the seller's auto-list policy leaves the
+		// marble alone and the tax receipt records the winning bid
+		err = setMarblePrice(stub, m, int64(auction.HighBid))
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		m.LockedBy = ""
+		err = transferMarbleTo(stub, gov, m, auction.HighBidder)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+	} else {
+		m.LockedBy = ""
+		err = putMarble(stub, m)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+	}
+
+	auction.Closed = true
+	err = putAuction(stub, auction)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	auctionAsBytes, err := json.Marshal(auction)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	fmt.Println("- end close auction")
+	return shim.Success(auctionAsBytes)
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func startAuctionOf(name string, startPrice int, durationSeconds int64) map[string]interface{} {
+	return map[string]interface{}{"auction": map[string]interface{}{
+		"marbleName": name, "startPrice": startPrice, "durationSeconds": durationSeconds,
+	}}
+}
+
+func bidOn(name string, amount int) map[string]interface{} {
+	return map[string]interface{}{"auction_bid": map[string]interface{}{"marbleName": name, "amount": amount}}
+}
+
+func TestSellerCannotBidOnItsOwnAuction(t *testing.T) {
+	s := newTestStub(t)
+	s.setCaller("Org1MSP", "alice")
+	s.createMarble("marble1", "blue", 35, "alice", 99)
+	s.mustInvoke("startAuction", startAuctionOf("marble1", 100, 5))
+
+	s.mustFail("The seller cannot bid on its own auction", "placeBid", bidOn("marble1", 150))
+	s.setCaller("Org1MSP", "bob")
+	s.mustInvoke("placeBid", bidOn("marble1", 150))
+}
+
+func TestClosingAnAuctionGoesThroughTheTransferGates(t *testing.T) {
+	s := newTestStub(t)
+	s.createMarble("marble1", "blue", 35, "Org1MSP", 99)
+	s.createMarble("marble2", "red", 35, "Org2MSP", 99)
+	s.mustInvoke("startAuction", startAuctionOf("marble1", 100, 5))
+	s.setCaller("Org2MSP", "user1")
+	s.mustInvoke("placeBid", bidOn("marble1", 150))
+	s.Now += 10
+
+	s.setCaller("Org1MSP", "admin")
+	s.mustInvoke("setOwnerMaxMarbles", nil, "1")
+	s.mustFail("owner Org2MSP already holds 1 marbles and may hold at most 1", "closeAuction", nil, "marble1")
+	if owner := s.readTestMarble("marble1").Owner; owner != "Org1MSP" {
+		t.Fatalf("expected the failed close to leave marble1 with Org1MSP, got %s", owner)
+	}
+
+	s.mustInvoke("setOwnerMaxMarbles", nil, "2")
+	s.mustInvoke("closeAuction", nil, "marble1")
+	m := s.readTestMarble("marble1")
+	if m.Owner != "Org2MSP" || len(m.LockedBy) != 0 {
+		t.Fatalf("expected Org2MSP to own the unlocked marble1, got %+v", m)
+	}
+	if len(s.PvtState["collectionMarbleTaxReceipts"]) != 1 {
+		t.Fatalf("expected one tax receipt for the sale, got %d", len(s.PvtState["collectionMarbleTaxReceipts"]))
+	}
+	for _, receiptAsBytes := range s.PvtState["collectionMarbleTaxReceipts"] {
+		var receipt TaxReceipt
+		err := json.Unmarshal(receiptAsBytes, &receipt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if receipt.MarbleName != "marble1" || receipt.Buyer != "Org2MSP" || receipt.Seller != "Org1MSP" {
+			t.Fatalf("unexpected tax receipt %+v", receipt)
+		}
+	}
+}
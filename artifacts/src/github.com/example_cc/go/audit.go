@@ -0,0 +1,290 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// Results of a physical audit.
+const (
+	auditResultPass = "pass"
+	auditResultFail = "fail"
+)
+
+// AuditSchedule is an in-person inspection of a marble by AuditorMSPID, due at
+// ScheduledTime. CompletedAt is the Unix time the result was recorded at, zero while the
+// audit is pending. It is kept in collectionMarbleAudits under the ID of the
+// transaction that scheduled it.
+type AuditSchedule struct {
+	ObjectType    string `json:"docType"`
+	AuditID       string `json:"auditID"`
+	MarbleName    string `json:"marbleName"`
+	AuditorMSPID  string `json:"auditorMSPID"`
+	ScheduledTime int64  `json:"scheduledTime"`
+	CompletedAt   int64  `json:"completedAt"`
+	AuditResult   string `json:"auditResult,omitempty"`
+	Notes         string `json:"notes,omitempty"`
+}
+
+func getAudit(stub shim.ChaincodeStubInterface, auditID string) (*AuditSchedule, error) {
+	auditAsBytes, err := stub.GetPrivateData("collectionMarbleAudits", auditID)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get audit: %s", err.Error())
+	} else if auditAsBytes == nil {
+		return nil, fmt.Errorf("Audit does not exist: %s", auditID)
+	}
+
+	audit := &AuditSchedule{}
+	err = json.Unmarshal(auditAsBytes, audit)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to decode JSON of: %s", string(auditAsBytes))
+	}
+	return audit, nil
+}
+
+func putAudit(stub shim.ChaincodeStubInterface, audit *AuditSchedule) error {
+	auditAsBytes, err := json.Marshal(audit)
+	if err != nil {
+		return err
+	}
+	return stub.PutPrivateData("collectionMarbleAudits", audit.AuditID, auditAsBytes)
+}
+
+// queryAudits scans collectionMarbleAudits for the audits accepted by match.
+func queryAudits(stub shim.ChaincodeStubInterface, match func(*AuditSchedule) bool) ([]AuditSchedule, error) {
+	resultsIterator, err := stub.GetPrivateDataByRange("collectionMarbleAudits", "", "")
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	audits := []AuditSchedule{}
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+		var audit AuditSchedule
+		err = json.Unmarshal(queryResponse.Value, &audit)
+		if err != nil {
+			return nil, err
+		}
+		if match(&audit) {
+			audits = append(audits, audit)
+		}
+	}
+	return audits, nil
+}
+
+// checkAuditPassed fails if the marble's latest audit failed.
+func checkAuditPassed(m *marble) error {
+	if m.FailedLastAudit {
+		return fmt.Errorf("marble %s failed its last audit", m.Name)
+	}
+	return nil
+}
+
+// ===========================================================================
+// scheduleAudit - schedule an in-person inspection of a marble. Callable by
+// the owner or the admin organization.
+// ===========================================================================
+func (t *SimpleChaincode) scheduleAudit(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	fmt.Println("- start schedule audit")
+
+	type auditTransientInput struct {
+		MarbleName    string `json:"marbleName"`
+		AuditorMSPID  string `json:"auditorMSPID"`
+		ScheduledTime int64  `json:"scheduledTime"`
+	}
+
+	if len(args) != 0 {
+		return shim.Error("Incorrect number of arguments. Private marble data must be passed in transient map.")
+	}
+
+	var auditInput auditTransientInput
+	err := getTransientInput(stub, "audit_schedule", &auditInput)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if len(auditInput.MarbleName) == 0 {
+		return shim.Error("marbleName field must be a non-empty string")
+	}
+	if len(auditInput.AuditorMSPID) == 0 {
+		return shim.Error("auditorMSPID field must be a non-empty string")
+	}
+
+	m, err := getMarble(stub, auditInput.MarbleName)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = requireOwner(stub, m)
+	if err != nil {
+		err = requireAdmin(stub)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+	}
+	currentTime, err := getTxUnixTime(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if auditInput.ScheduledTime <= currentTime {
+		return shim.Error("scheduledTime field must be in the future")
+	}
+
+	audit := &AuditSchedule{
+		ObjectType:    "auditSchedule",
+		AuditID:       stub.GetTxID(),
+		MarbleName:    m.Name,
+		AuditorMSPID:  auditInput.AuditorMSPID,
+		ScheduledTime: auditInput.ScheduledTime,
+	}
+	err = putAudit(stub, audit)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	fmt.Println("- end schedule audit")
+	return shim.Success([]byte(audit.AuditID))
+}
+
+// ===========================================================================
+// recordAuditResult - the auditor's result of an inspection. A failed audit
+// withdraws the marble from sale until a later audit passes.
+// ===========================================================================
+func (t *SimpleChaincode) recordAuditResult(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+
+	//     0         1        2
+	// "auditID", "pass", "notes"
+	if len(args) != 3 {
+		return shim.Error("Incorrect number of arguments. Expecting 3")
+	}
+	result := args[1]
+	if result != auditResultPass && result != auditResultFail {
+		return shim.Error("result must be " + auditResultPass + " or " + auditResultFail)
+	}
+
+	audit, err := getAudit(stub, args[0])
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if audit.CompletedAt != 0 {
+		return shim.Error("audit " + audit.AuditID + " is already completed")
+	}
+	callerMSPID, err := getCallerMSPID(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if callerMSPID != audit.AuditorMSPID {
+		return shim.Error("caller " + callerMSPID + " is not the auditor of audit " + audit.AuditID)
+	}
+	currentTime, err := getTxUnixTime(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	m, err := getMarble(stub, audit.MarbleName)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	m.FailedLastAudit = result == auditResultFail
+	reputationDelta := auditReputationDelta
+	if m.FailedLastAudit {
+		reputationDelta = -auditReputationDelta
+	}
+	err = updateOwnerReputation(stub, m.Owner, reputationDelta)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if m.FailedLastAudit && m.IsForSale {
+		err = removeListingIndex(stub, m)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		m.IsForSale = false
+		m.AskingPrice = 0
+	}
+	err = putMarble(stub, m)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	audit.CompletedAt = currentTime
+	audit.AuditResult = result
+	audit.Notes = args[2]
+	err = putAudit(stub, audit)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	return shim.Success(nil)
+}
+
+// ===========================================================================
+// getUpcomingAudits - list the pending audits that are not yet due
+// ===========================================================================
+func (t *SimpleChaincode) getUpcomingAudits(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	if len(args) != 0 {
+		return shim.Error("Incorrect number of arguments. Expecting 0")
+	}
+
+	currentTime, err := getTxUnixTime(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	audits, err := queryAudits(stub, func(a *AuditSchedule) bool {
+		return a.ScheduledTime > currentTime && a.CompletedAt == 0
+	})
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	auditsAsBytes, err := json.Marshal(audits)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	return shim.Success(auditsAsBytes)
+}
+
+// ===========================================================================
+// getAuditHistory - list the completed audits of a marble
+// ===========================================================================
+func (t *SimpleChaincode) getAuditHistory(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+
+	//   0
+	// "marble1"
+	if len(args) != 1 {
+		return shim.Error("Incorrect number of arguments. Expecting name of the marble to query")
+	}
+
+	audits, err := queryAudits(stub, func(a *AuditSchedule) bool {
+		return a.MarbleName == args[0] && a.CompletedAt != 0
+	})
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	auditsAsBytes, err := json.Marshal(audits)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	return shim.Success(auditsAsBytes)
+}
+
+// =========================================================================================
+// getFailedAuditMarbles queries for marbles whose last audit failed.
+// Only available on state databases that support rich query (e.g. CouchDB)
+// =========================================================================================
+func (t *SimpleChaincode) getFailedAuditMarbles(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	if len(args) != 0 {
+		return shim.Error("Incorrect number of arguments. Expecting 0")
+	}
+
+	queryString := "{\"selector\":{\"docType\":\"marble\",\"failedLastAudit\":true}}"
+
+	queryResults, err := getQueryResultForQueryString(stub, queryString)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	return shim.Success(queryResults)
+}
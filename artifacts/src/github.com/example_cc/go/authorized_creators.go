@@ -0,0 +1,107 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// authorizedCreatorsKey holds, in public state, the JSON array of MSP IDs allowed to
+// create marbles. Until it is set, any organization may create marbles.
+const authorizedCreatorsKey = "authorizedCreators"
+
+// getAuthorizedCreatorList returns the MSP IDs allowed to create marbles, or nil if
+// the list has never been set.
+func getAuthorizedCreatorList(stub shim.ChaincodeStubInterface) ([]string, error) {
+	creatorsAsBytes, err := stub.GetState(authorizedCreatorsKey)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get authorized creators: %s", err.Error())
+	} else if creatorsAsBytes == nil {
+		return nil, nil
+	}
+
+	var creators []string
+	err = json.Unmarshal(creatorsAsBytes, &creators)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to decode JSON of: %s", string(creatorsAsBytes))
+	}
+	return creators, nil
+}
+
+// checkAuthorizedCreator fails if the authorized creators list is set and does not
+// include mspID.
+func checkAuthorizedCreator(stub shim.ChaincodeStubInterface, mspID string) error {
+	creators, err := getAuthorizedCreatorList(stub)
+	if err != nil {
+		return err
+	}
+	if creators != nil && !containsString(creators, mspID) {
+		return fmt.Errorf("organization %s is not authorized to create marbles", mspID)
+	}
+	return nil
+}
+
+// ===========================================================================
+// setAuthorizedCreators - admin setting of the organizations allowed to
+// create marbles
+// ===========================================================================
+func (t *SimpleChaincode) setAuthorizedCreators(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	fmt.Println("- start set authorized creators")
+
+	if len(args) != 0 {
+		return shim.Error("Incorrect number of arguments. Authorized creators must be passed in transient map.")
+	}
+
+	err := requireAdmin(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	var creators []string
+	err = getTransientInput(stub, "authorized_creators", &creators)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	for _, creator := range creators {
+		if len(creator) == 0 {
+			return shim.Error("authorized creators must be non-empty MSP IDs")
+		}
+	}
+	if creators == nil {
+		creators = []string{}
+	}
+
+	creatorsAsBytes, err := json.Marshal(creators)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = stub.PutState(authorizedCreatorsKey, creatorsAsBytes)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	fmt.Println("- end set authorized creators")
+	return shim.Success(nil)
+}
+
+// ===========================================================================
+// getAuthorizedCreators - the organizations allowed to create marbles, null
+// when every organization is
+// ===========================================================================
+func (t *SimpleChaincode) getAuthorizedCreators(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	if len(args) != 0 {
+		return shim.Error("Incorrect number of arguments. Expecting 0")
+	}
+
+	creators, err := getAuthorizedCreatorList(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	creatorsAsBytes, err := json.Marshal(creators)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	return shim.Success(creatorsAsBytes)
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// AutoListPolicy lists a marble for sale whenever its price is updated, asking the new
+// price plus AutoListMarkup (0.1 is 10%). It is kept in collectionMarbleAutoList under
+// the marble name.
+type AutoListPolicy struct {
+	ObjectType      string  `json:"docType"`
+	MarbleName      string  `json:"marbleName"`
+	AutoListEnabled bool    `json:"autoListEnabled"`
+	AutoListMarkup  float64 `json:"autoListMarkup"`
+}
+
+type autoListedEvent struct {
+	MarbleName  string `json:"marbleName"`
+	Price       int64  `json:"price"`
+	AskingPrice int64  `json:"askingPrice"`
+}
+
+func getAutoListPolicy(stub shim.ChaincodeStubInterface, marbleName string) (*AutoListPolicy, error) {
+	policyAsBytes, err := stub.GetPrivateData("collectionMarbleAutoList", marbleName)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get auto-list policy: %s", err.Error())
+	} else if policyAsBytes == nil {
+		return nil, nil
+	}
+
+	policy := &AutoListPolicy{}
+	err = json.Unmarshal(policyAsBytes, policy)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to decode JSON of: %s", string(policyAsBytes))
+	}
+	return policy, nil
+}
+
+// clearAutoListPolicy deletes a marble's auto-list policy. The policy is its owner's, so
+// it is cleared when the marble changes hands and a new owner starts without one.
+func clearAutoListPolicy(stub shim.ChaincodeStubInterface, marbleName string) error {
+	err := stub.DelPrivateData("collectionMarbleAutoList", marbleName)
+	if err != nil {
+		return fmt.Errorf("Failed to delete state: %s", err.Error())
+	}
+	return nil
+}
+
+// autoListOnPriceUpdate applies the marble's auto-list policy after its price changed to
+// newPrice. A marble that is already listed keeps its listing, and a marble that may not
+// be listed, e.g. because it is locked or uncertified or the markup takes its asking
+// price above maxMarblePrice, is left off the market without failing the price update.
+func autoListOnPriceUpdate(stub shim.ChaincodeStubInterface, m *marble, newPrice int64) error {
+	policy, err := getAutoListPolicy(stub, m.Name)
+	if err != nil {
+		return err
+	}
+	if policy == nil || !policy.AutoListEnabled || m.IsForSale {
+		return nil
+	}
+	if checkListable(stub, m) != nil {
+		return nil
+	}
+
+	markedUp := float64(newPrice) * (1 + policy.AutoListMarkup)
+	if markedUp < 1 || markedUp > float64(maxMarblePrice) {
+		return nil
+	}
+	askingPrice := int64(markedUp)
+	err = listMarble(stub, m, askingPrice)
+	if err != nil {
+		return err
+	}
+	return emitEvent(stub, "AUTO_LISTED", autoListedEvent{
+		MarbleName:  m.Name,
+		Price:       newPrice,
+		AskingPrice: askingPrice,
+	})
+}
+
+// ===========================================================================
+// setAutoListPolicy - owner setting of a marble's auto-list policy
+// ===========================================================================
+func (t *SimpleChaincode) setAutoListPolicy(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	fmt.Println("- start set auto-list policy")
+
+	if len(args) != 0 {
+		return shim.Error("Incorrect number of arguments. Private marble data must be passed in transient map.")
+	}
+
+	var policy AutoListPolicy
+	err := getTransientInput(stub, "auto_list_policy", &policy)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if len(policy.MarbleName) == 0 {
+		return shim.Error("marbleName field must be a non-empty string")
+	}
+	if policy.AutoListMarkup < 0 {
+		return shim.Error("autoListMarkup field must not be negative")
+	}
+
+	m, err := getMarble(stub, policy.MarbleName)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = requireOwner(stub, m)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	policy.ObjectType = "autoListPolicy"
+	policyAsBytes, err := json.Marshal(&policy)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = stub.PutPrivateData("collectionMarbleAutoList", m.Name, policyAsBytes)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	fmt.Println("- end set auto-list policy")
+	return shim.Success(nil)
+}
+
+// ===========================================================================
+// disableAutoList - clear a marble's auto-list policy. An active listing is
+// left in place.
+// ===========================================================================
+func (t *SimpleChaincode) disableAutoList(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+
+	//   0
+	// "marble1"
+	if len(args) != 1 {
+		return shim.Error("Incorrect number of arguments. Expecting name of the marble")
+	}
+
+	m, err := getMarble(stub, args[0])
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = requireOwner(stub, m)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	err = clearAutoListPolicy(stub, m.Name)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	return shim.Success(nil)
+}
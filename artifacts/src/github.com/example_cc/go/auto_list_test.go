@@ -0,0 +1,54 @@
+package main
+
+import "testing"
+
+func autoListPolicy(name string, markup float64) map[string]interface{} {
+	return map[string]interface{}{"auto_list_policy": map[string]interface{}{
+		"marbleName": name, "autoListEnabled": true, "autoListMarkup": markup,
+	}}
+}
+
+func priceTo(name string, price int64) map[string]interface{} {
+	return map[string]interface{}{"marble_price": map[string]interface{}{"name": name, "price": price}}
+}
+
+func TestDisablingAutoListKeepsTheLiveListing(t *testing.T) {
+	s := newTestStub(t)
+	s.createMarble("marble1", "blue", 35, "Org1MSP", 99)
+	s.certify("marble1")
+	s.mustInvoke("setAutoListPolicy", autoListPolicy("marble1", 0.1))
+	s.mustInvoke("updateMarblePrice", priceTo("marble1", 100))
+	if m := s.readTestMarble("marble1"); !m.IsForSale || m.AskingPrice != 110 {
+		t.Fatalf("expected the price update to list marble1 at 110, got %+v", m)
+	}
+
+	s.mustInvoke("disableAutoList", nil, "marble1")
+	if s.PvtState["collectionMarbleAutoList"]["marble1"] != nil {
+		t.Fatal("expected the auto-list policy to be deleted")
+	}
+	if m := s.readTestMarble("marble1"); !m.IsForSale || m.AskingPrice != 110 {
+		t.Fatalf("expected marble1 to stay listed at 110, got %+v", m)
+	}
+}
+
+func TestAuctionedMarbleIsNotAutoListedForItsNewOwner(t *testing.T) {
+	s := newTestStub(t)
+	s.createMarble("marble1", "blue", 35, "Org1MSP", 99)
+	s.certify("marble1")
+	s.mustInvoke("setAutoListPolicy", autoListPolicy("marble1", 0.5))
+	s.mustInvoke("startAuction", map[string]interface{}{"auction": map[string]interface{}{
+		"marbleName": "marble1", "startPrice": 100, "durationSeconds": 5,
+	}})
+	s.setCaller("Org2MSP", "user1")
+	s.mustInvoke("placeBid", map[string]interface{}{"auction_bid": map[string]interface{}{"marbleName": "marble1", "amount": 150}})
+	s.Now += 10
+	s.mustInvoke("closeAuction", nil, "marble1")
+
+	m := s.readTestMarble("marble1")
+	if m.Owner != "Org2MSP" || m.IsForSale {
+		t.Fatalf("expected Org2MSP to own marble1 without it being listed, got %+v", m)
+	}
+	if s.PvtState["collectionMarbleAutoList"]["marble1"] != nil {
+		t.Fatal("expected the previous owner's auto-list policy to be cleared by the transfer")
+	}
+}
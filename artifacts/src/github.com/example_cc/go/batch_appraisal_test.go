@@ -0,0 +1,76 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+// preferredMaxBytes is the default BatchSize.PreferredMaxBytes of a Fabric orderer. A
+// transaction larger than it is cut into a block of its own.
+const preferredMaxBytes = 512 * 1024
+
+// writeSizeTestStub adds up the size of the private data a transaction writes.
+type writeSizeTestStub struct {
+	*testStub
+	written int
+}
+
+func (s *writeSizeTestStub) PutPrivateData(collection, key string, value []byte) error {
+	s.written += len(collection) + len(key) + len(value)
+	return s.testStub.PutPrivateData(collection, key, value)
+}
+
+func TestBatchOfFiftyAppraisals(t *testing.T) {
+	s := newTestStub(t)
+	appraisals := []map[string]interface{}{}
+	for i := 0; i < 50; i++ {
+		name := fmt.Sprintf("marble%02d", i)
+		s.createMarble(name, "blue", 35, "Org1MSP", 99)
+		appraisals = append(appraisals, map[string]interface{}{"name": name, "value": 100 + i})
+	}
+	s.createMarble("unappraised", "red", 35, "Org1MSP", 99)
+	untouched := string(s.PvtState["collectionMarblePrivateDetails"]["unappraised"])
+
+	s.setCaller("Org2MSP", "appraiser")
+	sized := &writeSizeTestStub{testStub: s}
+	s.startTx(map[string]interface{}{"appraisals": appraisals}, []string{"batchAppraise"})
+	response := s.cc.Invoke(sized)
+	s.MockTransactionEnd(s.TxID)
+	if response.Status != 200 {
+		t.Fatalf("batchAppraise failed: %s", response.Message)
+	}
+	if sized.written > preferredMaxBytes {
+		t.Fatalf("expected the batch to write at most %d bytes, wrote %d", preferredMaxBytes, sized.written)
+	}
+
+	var summary struct {
+		Appraised           int     `json:"appraised"`
+		TotalPortfolioValue int     `json:"totalPortfolioValue"`
+		AvgValue            float64 `json:"avgValue"`
+	}
+	err := json.Unmarshal(response.Payload, &summary)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 100 + 101 + ... + 149
+	if summary.Appraised != 50 || summary.TotalPortfolioValue != 6225 || summary.AvgValue != 124.5 {
+		t.Fatalf("unexpected summary %+v", summary)
+	}
+
+	for i := 0; i < 50; i++ {
+		var details marblePrivateDetails
+		err = json.Unmarshal(s.PvtState["collectionMarblePrivateDetails"][fmt.Sprintf("marble%02d", i)], &details)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := []AppraisalRecord{{Value: 100 + i, AppraiserMSPID: "Org2MSP", TxID: details.Appraisals[0].TxID, AppraisedAt: details.Appraisals[0].AppraisedAt}}
+		if !reflect.DeepEqual(details.Appraisals, want) {
+			t.Fatalf("marble%02d: unexpected appraisals %+v", i, details.Appraisals)
+		}
+	}
+	if got := string(s.PvtState["collectionMarblePrivateDetails"]["unappraised"]); got != untouched {
+		t.Fatalf("expected the marble outside the batch to be unchanged, got %s", got)
+	}
+}
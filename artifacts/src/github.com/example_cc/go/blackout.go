@@ -0,0 +1,160 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// BlackoutPeriod blocks marble transfers from StartTime to EndTime inclusive, both in
+// Unix seconds.
+type BlackoutPeriod struct {
+	StartTime int64  `json:"startTime"`
+	EndTime   int64  `json:"endTime"`
+	Reason    string `json:"reason"`
+}
+
+// isBlackoutActive returns the blackout period covering unixTime, or nil.
+func (gov *governance) isBlackoutActive(unixTime int64) *BlackoutPeriod {
+	for i := range gov.BlackoutPeriods {
+		if gov.BlackoutPeriods[i].StartTime <= unixTime && unixTime <= gov.BlackoutPeriods[i].EndTime {
+			return &gov.BlackoutPeriods[i]
+		}
+	}
+	return nil
+}
+
+// ===========================================================================
+// addBlackoutPeriod - admin scheduling of a transfer blackout
+// ===========================================================================
+func (t *SimpleChaincode) addBlackoutPeriod(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	fmt.Println("- start add blackout period")
+
+	if len(args) != 0 {
+		return shim.Error("Incorrect number of arguments. Blackout data must be passed in transient map.")
+	}
+
+	err := requireAdmin(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	var period BlackoutPeriod
+	err = getTransientInput(stub, "blackout_period", &period)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if period.StartTime <= 0 {
+		return shim.Error("startTime field must be a positive integer")
+	}
+	if period.EndTime < period.StartTime {
+		return shim.Error("endTime field must not be before startTime")
+	}
+	if len(period.Reason) == 0 {
+		return shim.Error("reason field must be a non-empty string")
+	}
+
+	gov, err := getGovernance(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	gov.BlackoutPeriods = append(gov.BlackoutPeriods, period)
+	err = putGovernance(stub, gov)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	fmt.Println("- end add blackout period")
+	return shim.Success(nil)
+}
+
+// ===========================================================================
+// removeBlackoutPeriod - admin removal of a blackout by its index
+// ===========================================================================
+func (t *SimpleChaincode) removeBlackoutPeriod(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+
+	//  0
+	// "1"
+	if len(args) != 1 {
+		return shim.Error("Incorrect number of arguments. Expecting index of the blackout period")
+	}
+	index, err := strconv.Atoi(args[0])
+	if err != nil {
+		return shim.Error("index must be an integer")
+	}
+
+	err = requireAdmin(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	gov, err := getGovernance(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if index < 0 || index >= len(gov.BlackoutPeriods) {
+		return shim.Error(fmt.Sprintf("No blackout period at index %d", index))
+	}
+	gov.BlackoutPeriods = append(gov.BlackoutPeriods[:index], gov.BlackoutPeriods[index+1:]...)
+	err = putGovernance(stub, gov)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	return shim.Success(nil)
+}
+
+// ===========================================================================
+// getActiveBlackout - the blackout period in force, or null
+// ===========================================================================
+func (t *SimpleChaincode) getActiveBlackout(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	if len(args) != 0 {
+		return shim.Error("Incorrect number of arguments. Expecting 0")
+	}
+
+	gov, err := getGovernance(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	currentTime, err := getTxUnixTime(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	activeAsBytes, err := json.Marshal(gov.isBlackoutActive(currentTime))
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	return shim.Success(activeAsBytes)
+}
+
+// ===========================================================================
+// getBlackoutHistory - the blackout periods that have ended
+// ===========================================================================
+func (t *SimpleChaincode) getBlackoutHistory(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	if len(args) != 0 {
+		return shim.Error("Incorrect number of arguments. Expecting 0")
+	}
+
+	gov, err := getGovernance(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	currentTime, err := getTxUnixTime(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	past := []BlackoutPeriod{}
+	for _, period := range gov.BlackoutPeriods {
+		if period.EndTime < currentTime {
+			past = append(past, period)
+		}
+	}
+	pastAsBytes, err := json.Marshal(past)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	return shim.Success(pastAsBytes)
+}
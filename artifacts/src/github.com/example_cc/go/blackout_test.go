@@ -0,0 +1,68 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+)
+
+func TestTransfersDuringBlackoutAreRejected(t *testing.T) {
+	s := newTestStub(t)
+	for i := 1; i <= 5; i++ {
+		s.createMarble(fmt.Sprintf("marble%d", i), "blue", 35, "Org1MSP", 99)
+	}
+	start, end := s.Now+100, s.Now+200
+	s.mustInvoke("addBlackoutPeriod", map[string]interface{}{"blackout_period": map[string]interface{}{"startTime": start, "endTime": end, "reason": "annual audit"}})
+
+	// the second before the blackout
+	s.Now = start - 1
+	s.mustInvoke("transferMarble", transferTo("marble1", "Org2MSP"))
+
+	// transactions submitted together during the blackout all carry the same time
+	for i := 2; i <= 4; i++ {
+		s.Now = start + 50
+		s.mustFail("transfers are blocked: annual audit", "transferMarble", transferTo(fmt.Sprintf("marble%d", i), "Org2MSP"))
+	}
+	s.Now = end
+	s.mustFail("transfers are blocked: annual audit", "transferMarble", transferTo("marble5", "Org2MSP"))
+	var active BlackoutPeriod
+	s.Now = start
+	err := json.Unmarshal(s.mustInvoke("getActiveBlackout", nil), &active)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if active.Reason != "annual audit" {
+		t.Fatalf("expected the audit blackout to be active, got %+v", active)
+	}
+
+	// the second after the blackout
+	s.Now = end + 1
+	for i := 2; i <= 5; i++ {
+		s.mustInvoke("transferMarble", transferTo(fmt.Sprintf("marble%d", i), "Org2MSP"))
+	}
+	if payload := string(s.mustInvoke("getActiveBlackout", nil)); payload != "null" {
+		t.Fatalf("expected no active blackout, got %s", payload)
+	}
+	var history []BlackoutPeriod
+	err = json.Unmarshal(s.mustInvoke("getBlackoutHistory", nil), &history)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 1 || history[0].StartTime != start || history[0].EndTime != end {
+		t.Fatalf("expected the audit blackout in the history, got %+v", history)
+	}
+}
+
+func TestBlackoutPeriodsAreAdminOnly(t *testing.T) {
+	s := newTestStub(t)
+	period := map[string]interface{}{"blackout_period": map[string]interface{}{"startTime": s.Now, "endTime": s.Now + 10, "reason": "maintenance"}}
+	s.setCaller("Org2MSP", "user2")
+	s.mustFail("is not the admin organization", "addBlackoutPeriod", period)
+	s.setCaller("Org1MSP", "admin")
+	s.mustInvoke("addBlackoutPeriod", period)
+	s.setCaller("Org2MSP", "user2")
+	s.mustFail("is not the admin organization", "removeBlackoutPeriod", nil, "0")
+	s.setCaller("Org1MSP", "admin")
+	s.mustInvoke("removeBlackoutPeriod", nil, "0")
+	s.mustFail("No blackout period at index 0", "removeBlackoutPeriod", nil, "0")
+}
@@ -0,0 +1,279 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// bridgeLock is the LockedBy value of a marble represented on another network.
+const bridgeLock = "bridge"
+
+// States of a bridge record.
+const (
+	bridgeStatePending   = "pending"
+	bridgeStateConfirmed = "confirmed"
+	bridgeStateReturned  = "returned"
+)
+
+// BridgeRecord tracks the representation of a marble on an external network. It is
+// kept in collectionMarbleBridges under the marble name.
+type BridgeRecord struct {
+	ObjectType      string `json:"docType"`
+	MarbleName      string `json:"marbleName"`
+	ExternalNetwork string `json:"externalNetwork"`
+	ExternalAddress string `json:"externalAddress"`
+	BridgedAt       string `json:"bridgedAt"`
+	BridgeState     string `json:"bridgeState"`
+}
+
+type bridgeEvent struct {
+	MarbleName      string `json:"marbleName"`
+	ExternalNetwork string `json:"externalNetwork"`
+	ExternalAddress string `json:"externalAddress"`
+	TxID            string `json:"txID"`
+}
+
+func getBridgeRecord(stub shim.ChaincodeStubInterface, marbleName string) (*BridgeRecord, error) {
+	recordAsBytes, err := stub.GetPrivateData("collectionMarbleBridges", marbleName)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get bridge record: %s", err.Error())
+	} else if recordAsBytes == nil {
+		return nil, fmt.Errorf("Marble has never been bridged: %s", marbleName)
+	}
+
+	record := &BridgeRecord{}
+	err = json.Unmarshal(recordAsBytes, record)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to decode JSON of: %s", string(recordAsBytes))
+	}
+	return record, nil
+}
+
+func putBridgeRecord(stub shim.ChaincodeStubInterface, record *BridgeRecord) error {
+	recordAsBytes, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	return stub.PutPrivateData("collectionMarbleBridges", record.MarbleName, recordAsBytes)
+}
+
+// ===========================================================================
+// initiateBridge - lock an owned marble while it is represented on an
+// external network
+// ===========================================================================
+func (t *SimpleChaincode) initiateBridge(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	fmt.Println("- start initiate bridge")
+
+	type bridgeTransientInput struct {
+		MarbleName      string `json:"marbleName"`
+		ExternalNetwork string `json:"externalNetwork"`
+		ExternalAddress string `json:"externalAddress"`
+	}
+
+	if len(args) != 0 {
+		return shim.Error("Incorrect number of arguments. Private marble data must be passed in transient map.")
+	}
+
+	var bridgeInput bridgeTransientInput
+	err := getTransientInput(stub, "bridge", &bridgeInput)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if len(bridgeInput.MarbleName) == 0 {
+		return shim.Error("marbleName field must be a non-empty string")
+	}
+	if len(bridgeInput.ExternalNetwork) == 0 {
+		return shim.Error("externalNetwork field must be a non-empty string")
+	}
+	if len(bridgeInput.ExternalAddress) == 0 {
+		return shim.Error("externalAddress field must be a non-empty string")
+	}
+
+	m, err := getMarble(stub, bridgeInput.MarbleName)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = requireOwner(stub, m)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = checkNotLocked(m)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if m.IsForSale {
+		return shim.Error("Marble is listed for sale: " + m.Name)
+	}
+
+	txTime, err := getTxTime(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	m.LockedBy = bridgeLock
+	err = putMarble(stub, m)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = putBridgeRecord(stub, &BridgeRecord{
+		ObjectType:      "bridgeRecord",
+		MarbleName:      m.Name,
+		ExternalNetwork: bridgeInput.ExternalNetwork,
+		ExternalAddress: bridgeInput.ExternalAddress,
+		BridgedAt:       txTime.Format(time.RFC3339),
+		BridgeState:     bridgeStatePending,
+	})
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	err = emitEvent(stub, "BRIDGE_LOCK", bridgeEvent{
+		MarbleName:      m.Name,
+		ExternalNetwork: bridgeInput.ExternalNetwork,
+		ExternalAddress: bridgeInput.ExternalAddress,
+		TxID:            stub.GetTxID(),
+	})
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	fmt.Println("- end initiate bridge")
+	return shim.Success(nil)
+}
+
+// ===========================================================================
+// confirmBridgeReceipt - admin record that the external network created the
+// marble's representation
+// ===========================================================================
+func (t *SimpleChaincode) confirmBridgeReceipt(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	fmt.Println("- start confirm bridge receipt")
+
+	type bridgeReceiptTransientInput struct {
+		MarbleName string `json:"marbleName"`
+	}
+
+	if len(args) != 0 {
+		return shim.Error("Incorrect number of arguments. Private marble data must be passed in transient map.")
+	}
+
+	var receiptInput bridgeReceiptTransientInput
+	err := getTransientInput(stub, "bridge_receipt", &receiptInput)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if len(receiptInput.MarbleName) == 0 {
+		return shim.Error("marbleName field must be a non-empty string")
+	}
+
+	err = requireAdmin(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	record, err := getBridgeRecord(stub, receiptInput.MarbleName)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if record.BridgeState != bridgeStatePending {
+		return shim.Error("Bridge is not awaiting confirmation, it is " + record.BridgeState + ": " + record.MarbleName)
+	}
+	record.BridgeState = bridgeStateConfirmed
+	err = putBridgeRecord(stub, record)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	fmt.Println("- end confirm bridge receipt")
+	return shim.Success(nil)
+}
+
+// ===========================================================================
+// bridgeBack - admin release of a bridged marble once its external
+// representation is gone
+// ===========================================================================
+func (t *SimpleChaincode) bridgeBack(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	fmt.Println("- start bridge back")
+
+	type bridgeBackTransientInput struct {
+		MarbleName string `json:"marbleName"`
+	}
+
+	if len(args) != 0 {
+		return shim.Error("Incorrect number of arguments. Private marble data must be passed in transient map.")
+	}
+
+	var backInput bridgeBackTransientInput
+	err := getTransientInput(stub, "bridge_back", &backInput)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if len(backInput.MarbleName) == 0 {
+		return shim.Error("marbleName field must be a non-empty string")
+	}
+
+	err = requireAdmin(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	record, err := getBridgeRecord(stub, backInput.MarbleName)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if record.BridgeState == bridgeStateReturned {
+		return shim.Error("Marble is not bridged: " + record.MarbleName)
+	}
+	record.BridgeState = bridgeStateReturned
+	err = putBridgeRecord(stub, record)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	m, err := getMarble(stub, record.MarbleName)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	m.LockedBy = ""
+	err = putMarble(stub, m)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	err = emitEvent(stub, "BRIDGE_UNLOCK", bridgeEvent{
+		MarbleName:      record.MarbleName,
+		ExternalNetwork: record.ExternalNetwork,
+		ExternalAddress: record.ExternalAddress,
+		TxID:            stub.GetTxID(),
+	})
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	fmt.Println("- end bridge back")
+	return shim.Success(nil)
+}
+
+// ===========================================================================
+// getBridgeStatus - read a marble's bridge record
+// ===========================================================================
+func (t *SimpleChaincode) getBridgeStatus(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+
+	//   0
+	// "marble1"
+	if len(args) != 1 {
+		return shim.Error("Incorrect number of arguments. Expecting name of the marble to query")
+	}
+
+	record, err := getBridgeRecord(stub, args[0])
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	recordAsBytes, err := json.Marshal(record)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	return shim.Success(recordAsBytes)
+}
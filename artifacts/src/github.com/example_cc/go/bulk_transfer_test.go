@@ -0,0 +1,66 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func bulkTransfer(fromOwner, toOwner string) map[string]interface{} {
+	return map[string]interface{}{"marble_bulk_owner": map[string]interface{}{"fromOwner": fromOwner, "toOwner": toOwner}}
+}
+
+func TestBulkTransferChecksTheWholeBatchAgainstTheOwnerLimit(t *testing.T) {
+	s := newTestStub(t)
+	s.createMarble("marble1", "blue", 35, "Org1MSP", 99)
+	s.createMarble("marble2", "red", 35, "Org1MSP", 120)
+	s.createMarble("marble3", "green", 35, "Org1MSP", 150)
+	s.createMarble("marble4", "blue", 35, "Org2MSP", 99)
+
+	s.mustInvoke("setOwnerMaxMarbles", nil, "3")
+	s.mustFail("owner Org2MSP already holds 1 marbles and may hold at most 3", "bulkTransferMarbles", bulkTransfer("Org1MSP", "Org2MSP"))
+	for _, name := range []string{"marble1", "marble2", "marble3"} {
+		if owner := s.readTestMarble(name).Owner; owner != "Org1MSP" {
+			t.Fatalf("expected the rejected batch to leave %s with Org1MSP, got %s", name, owner)
+		}
+	}
+
+	s.mustInvoke("setOwnerMaxMarbles", nil, "4")
+	s.mustInvoke("bulkTransferMarbles", bulkTransfer("Org1MSP", "Org2MSP"))
+	receipts := map[string]TaxReceipt{}
+	for _, receiptAsBytes := range s.PvtState["collectionMarbleTaxReceipts"] {
+		var receipt TaxReceipt
+		err := json.Unmarshal(receiptAsBytes, &receipt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		receipts[receipt.MarbleName] = receipt
+	}
+	if len(receipts) != 3 {
+		t.Fatalf("expected a tax receipt for each of the 3 marbles, got %+v", receipts)
+	}
+	for _, name := range []string{"marble1", "marble2", "marble3"} {
+		if owner := s.readTestMarble(name).Owner; owner != "Org2MSP" {
+			t.Fatalf("expected %s to belong to Org2MSP, got %s", name, owner)
+		}
+		if receipt := receipts[name]; receipt.Buyer != "Org2MSP" || receipt.Seller != "Org1MSP" {
+			t.Fatalf("unexpected tax receipt for %s: %+v", name, receipt)
+		}
+	}
+}
+
+func TestBulkTransferRunsTheExternalValidator(t *testing.T) {
+	s, validator := newValidatedTestStub(t)
+	s.createMarble("blue1", "blue", 35, "Org1MSP", 99)
+	s.createMarble("red1", "red", 35, "Org1MSP", 99)
+	s.mustInvoke("setExternalValidator", nil, "validator_cc", "mychannel")
+
+	s.mustFail("external validator rejected: blue marbles are not allowed", "bulkTransferMarbles", bulkTransfer("Org1MSP", "Org2MSP"))
+	for _, name := range []string{"blue1", "red1"} {
+		if owner := s.readTestMarble(name).Owner; owner != "Org1MSP" {
+			t.Fatalf("expected the rejected batch to leave %s with Org1MSP, got %s", name, owner)
+		}
+	}
+	if validator.last.Name != "blue1" || validator.last.Owner != "Org2MSP" {
+		t.Fatalf("expected the validator to be asked about blue1 with its new owner, got %+v", validator.last)
+	}
+}
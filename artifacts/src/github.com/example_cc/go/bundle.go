@@ -0,0 +1,293 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// bundlePrefix prefixes bundles in collectionMarbles. Marble names cannot contain "~",
+// so the keys never collide with a marble.
+const bundlePrefix = "bundle~"
+
+// Bundle groups marbles of one owner so they can be listed and transferred as one lot.
+// The marbles stay marbles of their own; disbanding the bundle leaves them untouched.
+type Bundle struct {
+	ObjectType  string   `json:"docType"`
+	BundleID    string   `json:"bundleID"`
+	MarbleNames []string `json:"marbleNames"`
+	Owner       string   `json:"owner"`
+	ListPrice   int      `json:"listPrice"`
+}
+
+func getBundle(stub shim.ChaincodeStubInterface, bundleID string) (*Bundle, error) {
+	bundleAsBytes, err := stub.GetPrivateData("collectionMarbles", bundlePrefix+bundleID)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get bundle: %s", err.Error())
+	} else if bundleAsBytes == nil {
+		return nil, fmt.Errorf("Bundle does not exist: %s", bundleID)
+	}
+
+	bundle := &Bundle{}
+	err = json.Unmarshal(bundleAsBytes, bundle)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to decode JSON of: %s", string(bundleAsBytes))
+	}
+	return bundle, nil
+}
+
+func putBundle(stub shim.ChaincodeStubInterface, bundle *Bundle) error {
+	bundleAsBytes, err := json.Marshal(bundle)
+	if err != nil {
+		return err
+	}
+	return stub.PutPrivateData("collectionMarbles", bundlePrefix+bundle.BundleID, bundleAsBytes)
+}
+
+// requireBundleOwner fails unless the caller is the owner of the bundle.
+func requireBundleOwner(stub shim.ChaincodeStubInterface, bundle *Bundle) error {
+	isOwner, err := callerIs(stub, bundle.Owner)
+	if err != nil {
+		return err
+	}
+	if !isOwner {
+		return fmt.Errorf("caller is not the owner of bundle %s", bundle.BundleID)
+	}
+	return nil
+}
+
+// bundleEvent is the payload of the bundle events.
+type bundleEvent struct {
+	BundleID string `json:"bundleID"`
+	Owner    string `json:"owner"`
+}
+
+// ===========================================================================
+// createBundle - owner grouping of some of their marbles into a lot
+// ===========================================================================
+func (t *SimpleChaincode) createBundle(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	fmt.Println("- start create bundle")
+
+	type bundleTransientInput struct {
+		BundleID    string   `json:"bundleID"`
+		MarbleNames []string `json:"marbleNames"`
+		ListPrice   int      `json:"listPrice"`
+	}
+
+	if len(args) != 0 {
+		return shim.Error("Incorrect number of arguments. Private marble data must be passed in transient map.")
+	}
+
+	var bundleInput bundleTransientInput
+	err := getTransientInput(stub, "marble_bundle", &bundleInput)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if !marbleNamePattern.MatchString(bundleInput.BundleID) {
+		return shim.Error(fmt.Sprintf("bundleID %q must match %s", bundleInput.BundleID, marbleNamePattern.String()))
+	}
+	if len(bundleInput.MarbleNames) == 0 {
+		return shim.Error("marbleNames field must list at least one marble")
+	}
+	if bundleInput.ListPrice <= 0 {
+		return shim.Error("listPrice field must be a positive integer")
+	}
+
+	bundleAsBytes, err := stub.GetPrivateData("collectionMarbles", bundlePrefix+bundleInput.BundleID)
+	if err != nil {
+		return shim.Error("Failed to get bundle: " + err.Error())
+	} else if bundleAsBytes != nil {
+		return shim.Error("This bundle already exists: " + bundleInput.BundleID)
+	}
+
+	owner := ""
+	for i, name := range bundleInput.MarbleNames {
+		if containsString(bundleInput.MarbleNames[:i], name) {
+			return shim.Error("Marble " + name + " is listed twice")
+		}
+		m, err := getMarble(stub, name)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		if i == 0 {
+			owner = m.Owner
+		} else if m.Owner != owner {
+			return shim.Error("Marble " + name + " does not belong to " + owner + " like the other marbles of the bundle")
+		}
+		err = requireOwner(stub, m)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+	}
+
+	bundle := &Bundle{
+		ObjectType:  "bundle",
+		BundleID:    bundleInput.BundleID,
+		MarbleNames: bundleInput.MarbleNames,
+		Owner:       owner,
+		ListPrice:   bundleInput.ListPrice,
+	}
+	err = putBundle(stub, bundle)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = emitEvent(stub, "BundleCreated", bundleEvent{BundleID: bundle.BundleID, Owner: bundle.Owner})
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	fmt.Println("- end create bundle")
+	return shim.Success(nil)
+}
+
+// ===========================================================================
+// disbandBundle - owner removal of a bundle. Its marbles are not changed.
+// ===========================================================================
+func (t *SimpleChaincode) disbandBundle(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	fmt.Println("- start disband bundle")
+
+	type bundleDisbandTransientInput struct {
+		BundleID string `json:"bundleID"`
+	}
+
+	if len(args) != 0 {
+		return shim.Error("Incorrect number of arguments. Private marble data must be passed in transient map.")
+	}
+
+	var disbandInput bundleDisbandTransientInput
+	err := getTransientInput(stub, "bundle_disband", &disbandInput)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if len(disbandInput.BundleID) == 0 {
+		return shim.Error("bundleID field must be a non-empty string")
+	}
+
+	bundle, err := getBundle(stub, disbandInput.BundleID)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = requireBundleOwner(stub, bundle)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	err = stub.DelPrivateData("collectionMarbles", bundlePrefix+bundle.BundleID)
+	if err != nil {
+		return shim.Error("Failed to delete state:" + err.Error())
+	}
+	err = emitEvent(stub, "BundleDisbanded", bundleEvent{BundleID: bundle.BundleID, Owner: bundle.Owner})
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	fmt.Println("- end disband bundle")
+	return shim.Success(nil)
+}
+
+// ===========================================================================
+// transferBundle - owner transfer of every marble of a bundle to a new
+// owner. Either all marbles change hands or none do; a marble that left the
+// bundle owner or is held in escrow fails the whole transfer. The bundle
+// itself passes to the new owner.
+// ===========================================================================
+func (t *SimpleChaincode) transferBundle(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	fmt.Println("- start transfer bundle")
+
+	type bundleTransferTransientInput struct {
+		BundleID string `json:"bundleID"`
+		Owner    string `json:"owner"`
+	}
+
+	if len(args) != 0 {
+		return shim.Error("Incorrect number of arguments. Private marble data must be passed in transient map.")
+	}
+
+	var transferInput bundleTransferTransientInput
+	err := getTransientInput(stub, "bundle_transfer", &transferInput)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if len(transferInput.BundleID) == 0 {
+		return shim.Error("bundleID field must be a non-empty string")
+	}
+	if len(transferInput.Owner) == 0 {
+		return shim.Error("owner field must be a non-empty string")
+	}
+
+	gov, err := getGovernance(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = checkCallerNotBlacklisted(stub, gov)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	bundle, err := getBundle(stub, transferInput.BundleID)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = requireBundleOwner(stub, bundle)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if transferInput.Owner != bundle.Owner {
+		err = checkOwnerMaxMarbles(stub, transferInput.Owner, len(bundle.MarbleNames))
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+	}
+
+	for _, name := range bundle.MarbleNames {
+		m, err := getMarble(stub, name)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		if m.Owner != bundle.Owner {
+			return shim.Error("Marble " + name + " no longer belongs to the bundle owner " + bundle.Owner)
+		}
+		if m.LockedBy == escrowLock {
+			return shim.Error("Marble " + name + " is in escrow")
+		}
+		err = changeMarbleOwner(stub, m, transferInput.Owner)
+		if err != nil {
+			return shim.Error("Failed to transfer marble " + name + ": " + err.Error())
+		}
+		err = putMarble(stub, m)
+		if err != nil {
+			return shim.Error("Failed to transfer marble " + name + ": " + err.Error())
+		}
+	}
+
+	bundle.Owner = transferInput.Owner
+	err = putBundle(stub, bundle)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = emitEvent(stub, "BundleTransferred", bundleEvent{BundleID: bundle.BundleID, Owner: bundle.Owner})
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	fmt.Println("- end transfer bundle")
+	return shim.Success(nil)
+}
+
+// =========================================================================================
+// queryBundles returns every bundle.
+// Only available on state databases that support rich query (e.g. CouchDB)
+// =========================================================================================
+func (t *SimpleChaincode) queryBundles(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	if len(args) != 0 {
+		return shim.Error("Incorrect number of arguments. Expecting 0")
+	}
+
+	queryResults, err := getQueryResultForQueryString(stub, "{\"selector\":{\"docType\":\"bundle\"}}")
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	return shim.Success(queryResults)
+}
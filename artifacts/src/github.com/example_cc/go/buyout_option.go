@@ -0,0 +1,309 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// buyoutOptionLock is the LockedBy value of a marble under an active buyout option, so
+// the owner cannot dispose of it while the holder may still buy it. A locked marble
+// cannot be granted another option.
+const buyoutOptionLock = "buyoutOption"
+
+// BuyoutOption gives Holder the right to buy a marble at StrikePrice until
+// ExpiresAtTime. It is kept in collectionMarbleBuyoutOptions under the ID of the
+// transaction that granted it.
+type BuyoutOption struct {
+	ObjectType    string `json:"docType"`
+	OptionID      string `json:"optionID"`
+	MarbleName    string `json:"marbleName"`
+	Writer        string `json:"writer"`
+	Holder        string `json:"holder"`
+	StrikePrice   int    `json:"strikePrice"`
+	ExpiresAtTime int64  `json:"expiresAtTime"`
+	Exercised     bool   `json:"exercised"`
+}
+
+func getBuyoutOption(stub shim.ChaincodeStubInterface, optionID string) (*BuyoutOption, error) {
+	optionAsBytes, err := stub.GetPrivateData("collectionMarbleBuyoutOptions", optionID)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get buyout option: %s", err.Error())
+	} else if optionAsBytes == nil {
+		return nil, fmt.Errorf("Buyout option does not exist: %s", optionID)
+	}
+
+	option := &BuyoutOption{}
+	err = json.Unmarshal(optionAsBytes, option)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to decode JSON of: %s", string(optionAsBytes))
+	}
+	return option, nil
+}
+
+func putBuyoutOption(stub shim.ChaincodeStubInterface, option *BuyoutOption) error {
+	optionAsBytes, err := json.Marshal(option)
+	if err != nil {
+		return err
+	}
+	return stub.PutPrivateData("collectionMarbleBuyoutOptions", option.OptionID, optionAsBytes)
+}
+
+// queryBuyoutOptions scans collectionMarbleBuyoutOptions for the options accepted by match.
+func queryBuyoutOptions(stub shim.ChaincodeStubInterface, match func(*BuyoutOption) bool) ([]BuyoutOption, error) {
+	resultsIterator, err := stub.GetPrivateDataByRange("collectionMarbleBuyoutOptions", "", "")
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	options := []BuyoutOption{}
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var option BuyoutOption
+		err = json.Unmarshal(queryResponse.Value, &option)
+		if err != nil {
+			return nil, err
+		}
+		if match(&option) {
+			options = append(options, option)
+		}
+	}
+	return options, nil
+}
+
+// ===========================================================================
+// grantBuyoutOption - owner grant of the right to buy a marble at a fixed
+// price until a future time
+// ===========================================================================
+func (t *SimpleChaincode) grantBuyoutOption(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	fmt.Println("- start grant buyout option")
+
+	type buyoutOptionTransientInput struct {
+		MarbleName    string `json:"marbleName"`
+		Holder        string `json:"holder"`
+		StrikePrice   int    `json:"strikePrice"`
+		ExpiresAtTime int64  `json:"expiresAtTime"`
+	}
+
+	if len(args) != 0 {
+		return shim.Error("Incorrect number of arguments. Private marble data must be passed in transient map.")
+	}
+
+	var optionInput buyoutOptionTransientInput
+	err := getTransientInput(stub, "buyout_option", &optionInput)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if len(optionInput.MarbleName) == 0 {
+		return shim.Error("marbleName field must be a non-empty string")
+	}
+	if len(optionInput.Holder) == 0 {
+		return shim.Error("holder field must be a non-empty string")
+	}
+	if optionInput.StrikePrice <= 0 {
+		return shim.Error("strikePrice field must be a positive integer")
+	}
+
+	currentTime, err := getTxUnixTime(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if optionInput.ExpiresAtTime <= currentTime {
+		return shim.Error("expiresAtTime field must be a future time")
+	}
+
+	m, err := getMarble(stub, optionInput.MarbleName)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = requireOwner(stub, m)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = checkNotLocked(m)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if m.Owner == optionInput.Holder {
+		return shim.Error("Marble is already owned by " + m.Owner)
+	}
+
+	option := &BuyoutOption{
+		ObjectType:    "buyoutOption",
+		OptionID:      stub.GetTxID(),
+		MarbleName:    m.Name,
+		Writer:        m.Owner,
+		Holder:        optionInput.Holder,
+		StrikePrice:   optionInput.StrikePrice,
+		ExpiresAtTime: optionInput.ExpiresAtTime,
+	}
+	err = putBuyoutOption(stub, option)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	m.LockedBy = buyoutOptionLock
+	err = putMarble(stub, m)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	fmt.Println("- end grant buyout option")
+	return shim.Success([]byte(option.OptionID))
+}
+
+// ===========================================================================
+// exerciseBuyoutOption - holder purchase of the marble at the strike price,
+// whatever its current price
+// ===========================================================================
+func (t *SimpleChaincode) exerciseBuyoutOption(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	fmt.Println("- start exercise buyout option")
+
+	//     0
+	// "optionID"
+	if len(args) != 1 {
+		return shim.Error("Incorrect number of arguments. Expecting buyout option ID")
+	}
+
+	option, err := getBuyoutOption(stub, args[0])
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if option.Exercised {
+		return shim.Error("Buyout option has already been exercised: " + option.OptionID)
+	}
+	isHolder, err := callerIs(stub, option.Holder)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if !isHolder {
+		return shim.Error("caller is not the holder of buyout option " + option.OptionID)
+	}
+	currentTime, err := getTxUnixTime(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if currentTime >= option.ExpiresAtTime {
+		return shim.Error("Buyout option has expired: " + option.OptionID)
+	}
+
+	m, err := getMarble(stub, option.MarbleName)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	m.LockedBy = ""
+	err = changeMarbleOwner(stub, m, option.Holder)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = putMarble(stub, m)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	option.Exercised = true
+	err = putBuyoutOption(stub, option)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	fmt.Println("- end exercise buyout option")
+	return shim.Success([]byte(fmt.Sprintf("{\"marbleName\":%q,\"newOwner\":%q,\"strikePrice\":%d}", m.Name, m.Owner, option.StrikePrice)))
+}
+
+// ===========================================================================
+// expireOption - delete an unexercised option past its expiry and release
+// its marble. Anyone may call it.
+// ===========================================================================
+func (t *SimpleChaincode) expireOption(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+
+	//     0
+	// "optionID"
+	if len(args) != 1 {
+		return shim.Error("Incorrect number of arguments. Expecting buyout option ID")
+	}
+
+	option, err := getBuyoutOption(stub, args[0])
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if option.Exercised {
+		return shim.Error("Buyout option has been exercised: " + option.OptionID)
+	}
+	currentTime, err := getTxUnixTime(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if currentTime < option.ExpiresAtTime {
+		return shim.Error(fmt.Sprintf("Buyout option %s does not expire before %d", option.OptionID, option.ExpiresAtTime))
+	}
+
+	err = stub.DelPrivateData("collectionMarbleBuyoutOptions", option.OptionID)
+	if err != nil {
+		return shim.Error("Failed to delete state:" + err.Error())
+	}
+	m, err := getMarble(stub, option.MarbleName)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if m.LockedBy == buyoutOptionLock {
+		m.LockedBy = ""
+		err = putMarble(stub, m)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+	}
+	return shim.Success(nil)
+}
+
+// ===========================================================================
+// getOptionsByHolder - list the buyout options held by a holder
+// ===========================================================================
+func (t *SimpleChaincode) getOptionsByHolder(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	if len(args) != 1 {
+		return shim.Error("Incorrect number of arguments. Expecting holder to query")
+	}
+
+	options, err := queryBuyoutOptions(stub, func(o *BuyoutOption) bool {
+		return o.Holder == args[0]
+	})
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	optionsAsBytes, err := json.Marshal(options)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	return shim.Success(optionsAsBytes)
+}
+
+// ===========================================================================
+// getOptionsByMarble - list the buyout options granted on a marble
+// ===========================================================================
+func (t *SimpleChaincode) getOptionsByMarble(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+
+	//   0
+	// "marble1"
+	if len(args) != 1 {
+		return shim.Error("Incorrect number of arguments. Expecting name of the marble to query")
+	}
+
+	options, err := queryBuyoutOptions(stub, func(o *BuyoutOption) bool {
+		return o.MarbleName == args[0]
+	})
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	optionsAsBytes, err := json.Marshal(options)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	return shim.Success(optionsAsBytes)
+}
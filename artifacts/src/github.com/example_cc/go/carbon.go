@@ -0,0 +1,213 @@
+package main
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strconv"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+type carbonOffsetEvent struct {
+	MarbleName      string  `json:"marbleName"`
+	OffsetKg        float64 `json:"offsetKg"`
+	CertificateHash string  `json:"certificateHash"`
+	TxID            string  `json:"txID"`
+}
+
+// parseCarbonKg parses a non-negative kg CO2e amount.
+func parseCarbonKg(arg string) (float64, error) {
+	kg, err := strconv.ParseFloat(arg, 64)
+	if err != nil || kg < 0 {
+		return 0, fmt.Errorf("carbon amount must be a non-negative number")
+	}
+	return kg, nil
+}
+
+// ===========================================================================
+// setCarbonCostPerTransfer - admin setting of the kg CO2e added per transfer
+// ===========================================================================
+func (t *SimpleChaincode) setCarbonCostPerTransfer(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+
+	//   0
+	// "0.25"
+	if len(args) != 1 {
+		return shim.Error("Incorrect number of arguments. Expecting 1")
+	}
+
+	err := requireAdmin(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	cost, err := parseCarbonKg(args[0])
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	gov, err := getGovernance(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	gov.CarbonCostPerTransfer = cost
+	err = putGovernance(stub, gov)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	return shim.Success(nil)
+}
+
+// ===========================================================================
+// updateMarbleCarbon - add footprint to a marble. Only organizations in the
+// governance CarbonCertifiers list may call it.
+// ===========================================================================
+func (t *SimpleChaincode) updateMarbleCarbon(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+
+	//     0         1
+	// "marble1", "1.5"
+	if len(args) != 2 {
+		return shim.Error("Incorrect number of arguments. Expecting 2")
+	}
+
+	additionalKg, err := parseCarbonKg(args[1])
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	gov, err := getGovernance(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	callerMSPID, err := getCallerMSPID(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if !containsString(gov.CarbonCertifiers, callerMSPID) {
+		return shim.Error("organization " + callerMSPID + " is not a certified carbon assessor")
+	}
+
+	m, err := getMarble(stub, args[0])
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	m.CarbonFootprint += additionalKg
+	err = putMarble(stub, m)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	return shim.Success(nil)
+}
+
+// ===========================================================================================
+// offsetCarbon reduces a marble's footprint against an offset certificate, whose SHA-256
+// hash is passed in the transient map and kept on the marble. A CARBON_OFFSET event is
+// emitted when the footprint reaches zero.
+// ===========================================================================================
+func (t *SimpleChaincode) offsetCarbon(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	fmt.Println("- start offset carbon")
+
+	type carbonOffsetTransientInput struct {
+		CertificateHash string `json:"certificateHash"`
+	}
+
+	//     0         1
+	// "marble1", "1.5"
+	if len(args) != 2 {
+		return shim.Error("Incorrect number of arguments. Expecting 2")
+	}
+
+	offsetKg, err := parseCarbonKg(args[1])
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	var offsetInput carbonOffsetTransientInput
+	err = getTransientInput(stub, "carbon_offset", &offsetInput)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if hashBytes, err := hex.DecodeString(offsetInput.CertificateHash); err != nil || len(hashBytes) != 32 {
+		return shim.Error("certificateHash field must be a hex encoded SHA-256 hash")
+	}
+
+	m, err := getMarble(stub, args[0])
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if m.CarbonFootprint == 0 {
+		return shim.Error("Marble has no carbon footprint to offset: " + m.Name)
+	}
+
+	m.CarbonFootprint -= offsetKg
+	if m.CarbonFootprint < 0 {
+		m.CarbonFootprint = 0
+	}
+	m.CarbonOffsetCertificates = append(m.CarbonOffsetCertificates, offsetInput.CertificateHash)
+	err = putMarble(stub, m)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	if m.CarbonFootprint == 0 {
+		err = emitEvent(stub, "CARBON_OFFSET", carbonOffsetEvent{
+			MarbleName:      m.Name,
+			OffsetKg:        offsetKg,
+			CertificateHash: offsetInput.CertificateHash,
+			TxID:            stub.GetTxID(),
+		})
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+	}
+
+	fmt.Println("- end offset carbon")
+	return shim.Success(nil)
+}
+
+// ===========================================================================
+// getTotalCarbonByOwner - sum of the footprints of an owner's marbles
+// ===========================================================================
+func (t *SimpleChaincode) getTotalCarbonByOwner(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+
+	//   0
+	// "bob"
+	if len(args) != 1 {
+		return shim.Error("Incorrect number of arguments. Expecting owner to query")
+	}
+
+	owned, err := getOwnedMarbles(stub, args[0])
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	total := 0.0
+	for _, m := range owned {
+		total += m.CarbonFootprint
+	}
+	return shim.Success([]byte(fmt.Sprintf("{\"owner\":%q,\"totalCarbonKg\":%g}", args[0], total)))
+}
+
+// ===== Example: Parameterized rich query =================================================
+// queryHighCarbonMarbles queries for marbles whose footprint is above a threshold.
+// Only available on state databases that support rich query (e.g. CouchDB)
+// =========================================================================================
+func (t *SimpleChaincode) queryHighCarbonMarbles(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+
+	//   0
+	// "10.5"
+	if len(args) != 1 {
+		return shim.Error("Incorrect number of arguments. Expecting 1")
+	}
+
+	threshold, err := parseCarbonKg(args[0])
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	queryString := fmt.Sprintf("{\"selector\":{\"docType\":\"marble\",\"carbonFootprint\":{\"$gt\":%g}}}", threshold)
+
+	queryResults, err := getQueryResultForQueryString(stub, queryString)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	return shim.Success(queryResults)
+}
@@ -0,0 +1,283 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// Values of marble.CertificationStatus. Only a certified marble can be listed for sale.
+const (
+	certificationPending   = "PendingCertification"
+	certificationCertified = "Certified"
+	certificationRevoked   = "Revoked"
+)
+
+// CertificationApproval is issued by a certification body and kept in
+// collectionMarbleCertifications under the marble name.
+type CertificationApproval struct {
+	ObjectType     string `json:"docType"`
+	CertBodyMSPID  string `json:"certBodyMSPID"`
+	MarbleName     string `json:"marbleName"`
+	CertificateRef string `json:"certificateRef"`
+	ApprovedAt     string `json:"approvedAt"`
+}
+
+func getCertificationApproval(stub shim.ChaincodeStubInterface, marbleName string) (*CertificationApproval, error) {
+	approvalAsBytes, err := stub.GetPrivateData("collectionMarbleCertifications", marbleName)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get certification: %s", err.Error())
+	} else if approvalAsBytes == nil {
+		return nil, nil
+	}
+
+	approval := &CertificationApproval{}
+	err = json.Unmarshal(approvalAsBytes, approval)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to decode JSON of: %s", string(approvalAsBytes))
+	}
+	return approval, nil
+}
+
+// checkCertified fails unless the marble holds a valid certification.
+func checkCertified(stub shim.ChaincodeStubInterface, m *marble) error {
+	if m.CertificationStatus != certificationCertified {
+		return fmt.Errorf("marble %s is not certified for sale", m.Name)
+	}
+	approval, err := getCertificationApproval(stub, m.Name)
+	if err != nil {
+		return err
+	}
+	if approval == nil {
+		return fmt.Errorf("marble %s has no certification on record", m.Name)
+	}
+	return nil
+}
+
+// requireCertBody fails unless the caller is in the governance AuthorizedCertBodies list.
+// It returns the caller's MSP ID.
+func requireCertBody(stub shim.ChaincodeStubInterface) (string, error) {
+	gov, err := getGovernance(stub)
+	if err != nil {
+		return "", err
+	}
+	callerMSPID, err := getCallerMSPID(stub)
+	if err != nil {
+		return "", err
+	}
+	if !containsString(gov.AuthorizedCertBodies, callerMSPID) {
+		return "", fmt.Errorf("organization %s is not an authorized certification body", callerMSPID)
+	}
+	return callerMSPID, nil
+}
+
+// ===========================================================================
+// requestCertification - queue an owned marble for certification
+// ===========================================================================
+func (t *SimpleChaincode) requestCertification(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	fmt.Println("- start request certification")
+
+	type certificationRequestTransientInput struct {
+		Name string `json:"name"`
+	}
+
+	if len(args) != 0 {
+		return shim.Error("Incorrect number of arguments. Private marble data must be passed in transient map.")
+	}
+
+	var requestInput certificationRequestTransientInput
+	err := getTransientInput(stub, "certification_request", &requestInput)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if len(requestInput.Name) == 0 {
+		return shim.Error("name field must be a non-empty string")
+	}
+
+	m, err := getMarble(stub, requestInput.Name)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = requireOwner(stub, m)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if m.CertificationStatus == certificationPending || m.CertificationStatus == certificationCertified {
+		return shim.Error("Marble is already " + m.CertificationStatus + ": " + m.Name)
+	}
+
+	m.CertificationStatus = certificationPending
+	err = putMarble(stub, m)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	fmt.Println("- end request certification")
+	return shim.Success(nil)
+}
+
+// ===========================================================================
+// issueCertification - approve a marble awaiting certification
+// ===========================================================================
+func (t *SimpleChaincode) issueCertification(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	fmt.Println("- start issue certification")
+
+	type certificationTransientInput struct {
+		MarbleName     string `json:"marbleName"`
+		CertificateRef string `json:"certificateRef"`
+	}
+
+	if len(args) != 0 {
+		return shim.Error("Incorrect number of arguments. Private marble data must be passed in transient map.")
+	}
+
+	var certInput certificationTransientInput
+	err := getTransientInput(stub, "certification", &certInput)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if len(certInput.MarbleName) == 0 {
+		return shim.Error("marbleName field must be a non-empty string")
+	}
+	if len(certInput.CertificateRef) == 0 {
+		return shim.Error("certificateRef field must be a non-empty string")
+	}
+
+	certBodyMSPID, err := requireCertBody(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	m, err := getMarble(stub, certInput.MarbleName)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if m.CertificationStatus != certificationPending {
+		return shim.Error("Marble is not awaiting certification: " + m.Name)
+	}
+
+	txTime, err := getTxTime(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	approvalAsBytes, err := json.Marshal(&CertificationApproval{
+		ObjectType:     "certificationApproval",
+		CertBodyMSPID:  certBodyMSPID,
+		MarbleName:     m.Name,
+		CertificateRef: certInput.CertificateRef,
+		ApprovedAt:     txTime.Format(time.RFC3339),
+	})
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = stub.PutPrivateData("collectionMarbleCertifications", m.Name, approvalAsBytes)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	m.CertificationStatus = certificationCertified
+	err = putMarble(stub, m)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	fmt.Println("- end issue certification")
+	return shim.Success(approvalAsBytes)
+}
+
+// ===========================================================================
+// revokeCertification - withdraw a marble's certification and take it off
+// the market
+// ===========================================================================
+func (t *SimpleChaincode) revokeCertification(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+
+	//   0
+	// "marble1"
+	if len(args) != 1 {
+		return shim.Error("Incorrect number of arguments. Expecting name of the marble")
+	}
+
+	_, err := requireCertBody(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	m, err := getMarble(stub, args[0])
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if m.CertificationStatus != certificationCertified {
+		return shim.Error("Marble is not certified: " + m.Name)
+	}
+
+	err = stub.DelPrivateData("collectionMarbleCertifications", m.Name)
+	if err != nil {
+		return shim.Error("Failed to delete state:" + err.Error())
+	}
+	if m.IsForSale {
+		err = removeListingIndex(stub, m)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		m.IsForSale = false
+		m.AskingPrice = 0
+	}
+	m.CertificationStatus = certificationRevoked
+	err = putMarble(stub, m)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	return shim.Success(nil)
+}
+
+// ===========================================================================
+// getCertificationStatus - read a marble's certification state
+// ===========================================================================
+func (t *SimpleChaincode) getCertificationStatus(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+
+	//   0
+	// "marble1"
+	if len(args) != 1 {
+		return shim.Error("Incorrect number of arguments. Expecting name of the marble")
+	}
+
+	m, err := getMarble(stub, args[0])
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	approval, err := getCertificationApproval(stub, m.Name)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	status := struct {
+		Name     string                 `json:"name"`
+		Status   string                 `json:"status"`
+		Approval *CertificationApproval `json:"approval"`
+	}{m.Name, m.CertificationStatus, approval}
+	statusAsBytes, err := json.Marshal(status)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	return shim.Success(statusAsBytes)
+}
+
+// ===========================================================================
+// listCertificationsPending - marbles awaiting certification
+// ===========================================================================
+func (t *SimpleChaincode) listCertificationsPending(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	if len(args) != 0 {
+		return shim.Error("Incorrect number of arguments. Expecting 0")
+	}
+
+	records, err := scanMarbles(stub, func(m *marble) bool {
+		return m.CertificationStatus == certificationPending
+	})
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	return marshalQueryRecords(records)
+}
@@ -0,0 +1,3195 @@
+package main
+
+import (
+	"encoding/json"
+	"runtime"
+	"sort"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// buildTimestamp is set at build time with -ldflags "-X main.buildTimestamp=...". It is
+// empty when the peer builds the chaincode.
+var buildTimestamp string
+
+// FunctionMeta describes an invokable function: the transient map keys it reads, the
+// number of arguments it expects (-1 for a variable number) and the collections it
+// reads and writes, directly or through the helpers it calls.
+type FunctionMeta struct {
+	Name          string   `json:"name"`
+	Description   string   `json:"description"`
+	TransientKeys []string `json:"transientKeys"`
+	ArgCount      int      `json:"argCount"`
+	Reads         []string `json:"reads"`
+	Writes        []string `json:"writes"`
+}
+
+// ChaincodeMeta describes the chaincode for clients building on it.
+type ChaincodeMeta struct {
+	Version            string         `json:"version"`
+	SupportedFunctions []FunctionMeta `json:"supportedFunctions"`
+	CollectionNames    []string       `json:"collectionNames"`
+	GoVersion          string         `json:"goVersion"`
+	BuildTimestamp     string         `json:"buildTimestamp"`
+}
+
+// registeredFunction is an entry of the function registry.
+type registeredFunction struct {
+	FunctionMeta
+	handler func(*SimpleChaincode, shim.ChaincodeStubInterface, []string) pb.Response
+}
+
+// chaincodeFunctions is the registry of invokable functions, in the order they were
+// added. Invoke dispatches through it, so a function cannot be invoked without being
+// described here. functionsByName indexes it.
+var (
+	chaincodeFunctions []registeredFunction
+	functionsByName    map[string]*registeredFunction
+)
+
+// The registry is filled in init because its handlers refer back to it through Invoke
+// and getChaincodeMeta, which a package level initializer does not allow.
+func init() {
+	chaincodeFunctions = []registeredFunction{
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "initMarble",
+				Description:   "create a new marble",
+				TransientKeys: []string{"marble", "marble_provenance"},
+				ArgCount:      0,
+				Reads:         []string{"collectionMarbleEventLog", "collectionMarbleMarketCap", "collectionMarbleProvenance", "collectionMarbles"},
+				Writes:        []string{"collectionMarbleEndorsements", "collectionMarbleEventLog", "collectionMarbleMarketCap", "collectionMarblePrivateDetails", "collectionMarbleProvenance", "collectionMarbles"},
+			},
+			handler: (*SimpleChaincode).initMarble,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "readMarble",
+				Description:   "read a marble",
+				TransientKeys: []string{},
+				ArgCount:      1,
+				Reads:         []string{"collectionMarbles"},
+				Writes:        []string{},
+			},
+			handler: (*SimpleChaincode).readMarble,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "readMarblePrivateDetails",
+				Description:   "read a marble private details",
+				TransientKeys: []string{},
+				ArgCount:      1,
+				Reads:         []string{"collectionMarblePrivateDetails"},
+				Writes:        []string{},
+			},
+			handler: (*SimpleChaincode).readMarblePrivateDetails,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "transferMarble",
+				Description:   "change owner of a specific marble",
+				TransientKeys: []string{"marble_owner"},
+				ArgCount:      0,
+				Reads:         []string{"collectionMarbleCredentials", "collectionMarbleEventLog", "collectionMarblePrivateDetails", "collectionMarbleShares", "collectionMarbles"},
+				Writes:        []string{"collectionMarbleAutoList", "collectionMarbleCredentials", "collectionMarbleEndorsements", "collectionMarbleEventLog", "collectionMarbleShares", "collectionMarbleTaxReceipts", "collectionMarbles"},
+			},
+			handler: (*SimpleChaincode).transferMarble,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "delete",
+				Description:   "delete a marble",
+				TransientKeys: []string{"marble_delete"},
+				ArgCount:      0,
+				Reads:         []string{"collectionMarbleEventLog", "collectionMarbleMarketCap", "collectionMarblePrivateDetails", "collectionMarbleShares", "collectionMarbles"},
+				Writes:        []string{"collectionMarbleEndorsements", "collectionMarbleEventLog", "collectionMarbleMarketCap", "collectionMarblePrivateDetails", "collectionMarbleShares", "collectionMarbles"},
+			},
+			handler: (*SimpleChaincode).delete,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "queryMarblesByOwner",
+				Description:   "find marbles for owner X using rich query",
+				TransientKeys: []string{},
+				ArgCount:      1,
+				Reads:         []string{"collectionMarbles", "collectionOwnerProfiles"},
+				Writes:        []string{},
+			},
+			handler: (*SimpleChaincode).queryMarblesByOwner,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "queryMarbles",
+				Description:   "find marbles based on an ad hoc rich query",
+				TransientKeys: []string{},
+				ArgCount:      1,
+				Reads:         []string{"collectionMarbles"},
+				Writes:        []string{},
+			},
+			handler: (*SimpleChaincode).queryMarbles,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "getMarblesByRange",
+				Description:   "get marbles based on range query",
+				TransientKeys: []string{},
+				ArgCount:      2,
+				Reads:         []string{"collectionMarbles"},
+				Writes:        []string{},
+			},
+			handler: (*SimpleChaincode).getMarblesByRange,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "listMarbleForSale",
+				Description:   "offer a marble for sale at an asking price",
+				TransientKeys: []string{"marble_listing"},
+				ArgCount:      0,
+				Reads:         []string{"collectionMarbleCertifications", "collectionMarbleEventLog", "collectionMarbles"},
+				Writes:        []string{"collectionMarbleEndorsements", "collectionMarbleEventLog", "collectionMarbles"},
+			},
+			handler: (*SimpleChaincode).listMarbleForSale,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "unlistMarbleFromSale",
+				Description:   "withdraw a marble from sale",
+				TransientKeys: []string{"marble_unlist"},
+				ArgCount:      0,
+				Reads:         []string{"collectionMarbleEventLog", "collectionMarbles"},
+				Writes:        []string{"collectionMarbleEndorsements", "collectionMarbleEventLog", "collectionMarbles"},
+			},
+			handler: (*SimpleChaincode).unlistMarbleFromSale,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "floorSweep",
+				Description:   "buy the cheapest listed marbles",
+				TransientKeys: []string{"floor_sweep"},
+				ArgCount:      0,
+				Reads:         []string{"collectionMarbleCredentials", "collectionMarbleEventLog", "collectionMarblePrivateDetails", "collectionMarbleShares", "collectionMarbles"},
+				Writes:        []string{"collectionMarbleAutoList", "collectionMarbleCredentials", "collectionMarbleEndorsements", "collectionMarbleEventLog", "collectionMarbleShares", "collectionMarbleTaxReceipts", "collectionMarbles"},
+			},
+			handler: (*SimpleChaincode).floorSweep,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "queryMarblesWithFilter",
+				Description:   "find marbles matching a filter without a rich query",
+				TransientKeys: []string{},
+				ArgCount:      1,
+				Reads:         []string{"collectionMarbles"},
+				Writes:        []string{},
+			},
+			handler: (*SimpleChaincode).queryMarblesWithFilter,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "getInitInfo",
+				Description:   "read the chaincode init metadata",
+				TransientKeys: []string{},
+				ArgCount:      0,
+				Reads:         []string{"collectionMarbles"},
+				Writes:        []string{},
+			},
+			handler: (*SimpleChaincode).getInitInfo,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "reinitialize",
+				Description:   "update the governance defaults",
+				TransientKeys: []string{"governance"},
+				ArgCount:      0,
+				Reads:         []string{},
+				Writes:        []string{},
+			},
+			handler: (*SimpleChaincode).reinitialize,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "flashBorrowMarble",
+				Description:   "lend a marble to the caller for one chaincode callback",
+				TransientKeys: []string{"flash_loan"},
+				ArgCount:      0,
+				Reads:         []string{"collectionMarbleShares", "collectionMarbles"},
+				Writes:        []string{"collectionMarbles"},
+			},
+			handler: (*SimpleChaincode).flashBorrowMarble,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "getFlashLoanHistory",
+				Description:   "list the flash loans taken against a marble",
+				TransientKeys: []string{},
+				ArgCount:      1,
+				Reads:         []string{"collectionMarbles"},
+				Writes:        []string{},
+			},
+			handler: (*SimpleChaincode).getFlashLoanHistory,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "getLoyaltyPoints",
+				Description:   "read the loyalty point balance of an organization",
+				TransientKeys: []string{},
+				ArgCount:      1,
+				Reads:         []string{"collectionMarbles"},
+				Writes:        []string{},
+			},
+			handler: (*SimpleChaincode).getLoyaltyPoints,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "grantLoyaltyPoints",
+				Description:   "credit loyalty points to an organization",
+				TransientKeys: []string{},
+				ArgCount:      2,
+				Reads:         []string{"collectionMarbles"},
+				Writes:        []string{"collectionMarbles"},
+			},
+			handler: (*SimpleChaincode).grantLoyaltyPoints,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "reserveMarbleName",
+				Description:   "hold a marble name before creating the marble",
+				TransientKeys: []string{"marble_name_reservation"},
+				ArgCount:      0,
+				Reads:         []string{"collectionMarbles"},
+				Writes:        []string{"collectionMarbles"},
+			},
+			handler: (*SimpleChaincode).reserveMarbleName,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "listMyReservations",
+				Description:   "list the caller's active name reservations",
+				TransientKeys: []string{},
+				ArgCount:      0,
+				Reads:         []string{"collectionMarbles"},
+				Writes:        []string{},
+			},
+			handler: (*SimpleChaincode).listMyReservations,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "cancelNameReservation",
+				Description:   "release a reserved marble name",
+				TransientKeys: []string{},
+				ArgCount:      1,
+				Reads:         []string{"collectionMarbles"},
+				Writes:        []string{"collectionMarbles"},
+			},
+			handler: (*SimpleChaincode).cancelNameReservation,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "fileActivityReport",
+				Description:   "report suspicious activity around a marble",
+				TransientKeys: []string{"activity_report"},
+				ArgCount:      0,
+				Reads:         []string{"collectionMarbles"},
+				Writes:        []string{"collectionMarbleSARs"},
+			},
+			handler: (*SimpleChaincode).fileActivityReport,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "reviewReport",
+				Description:   "approve or reject an activity report",
+				TransientKeys: []string{},
+				ArgCount:      2,
+				Reads:         []string{"collectionMarbleSARs"},
+				Writes:        []string{"collectionMarbleSARs"},
+			},
+			handler: (*SimpleChaincode).reviewReport,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "dismissReport",
+				Description:   "remove an activity report",
+				TransientKeys: []string{},
+				ArgCount:      1,
+				Reads:         []string{"collectionMarbleSARs"},
+				Writes:        []string{"collectionMarbleSARs"},
+			},
+			handler: (*SimpleChaincode).dismissReport,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "getReportsByOwner",
+				Description:   "list activity reports against an owner",
+				TransientKeys: []string{},
+				ArgCount:      1,
+				Reads:         []string{"collectionMarbleSARs"},
+				Writes:        []string{},
+			},
+			handler: (*SimpleChaincode).getReportsByOwner,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "getOpenReports",
+				Description:   "list activity reports awaiting review",
+				TransientKeys: []string{},
+				ArgCount:      0,
+				Reads:         []string{"collectionMarbleSARs"},
+				Writes:        []string{},
+			},
+			handler: (*SimpleChaincode).getOpenReports,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "getReportsByMarble",
+				Description:   "list activity reports about a marble",
+				TransientKeys: []string{},
+				ArgCount:      1,
+				Reads:         []string{"collectionMarbleSARs"},
+				Writes:        []string{},
+			},
+			handler: (*SimpleChaincode).getReportsByMarble,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "generatePurchaseReceipt",
+				Description:   "issue a receipt for the last sale of a marble",
+				TransientKeys: []string{},
+				ArgCount:      2,
+				Reads:         []string{"collectionMarblePrivateDetails", "collectionMarbleReceipts", "collectionMarbles"},
+				Writes:        []string{"collectionMarbleReceipts"},
+			},
+			handler: (*SimpleChaincode).generatePurchaseReceipt,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "verifyReceipt",
+				Description:   "check a purchase receipt hash",
+				TransientKeys: []string{},
+				ArgCount:      2,
+				Reads:         []string{"collectionMarbleReceipts"},
+				Writes:        []string{},
+			},
+			handler: (*SimpleChaincode).verifyReceipt,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "getReceiptsByBuyer",
+				Description:   "list purchase receipts of a buyer",
+				TransientKeys: []string{},
+				ArgCount:      1,
+				Reads:         []string{"collectionMarbleReceipts"},
+				Writes:        []string{},
+			},
+			handler: (*SimpleChaincode).getReceiptsByBuyer,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "getReceiptsBySeller",
+				Description:   "list purchase receipts of a seller",
+				TransientKeys: []string{},
+				ArgCount:      1,
+				Reads:         []string{"collectionMarbleReceipts"},
+				Writes:        []string{},
+			},
+			handler: (*SimpleChaincode).getReceiptsBySeller,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "exportReceiptsAsCSV",
+				Description:   "export all purchase receipts as CSV",
+				TransientKeys: []string{},
+				ArgCount:      0,
+				Reads:         []string{"collectionMarbleReceipts"},
+				Writes:        []string{},
+			},
+			handler: (*SimpleChaincode).exportReceiptsAsCSV,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "createSetBonus",
+				Description:   "define a bonus for owning a complete color set",
+				TransientKeys: []string{"set_bonus"},
+				ArgCount:      0,
+				Reads:         []string{},
+				Writes:        []string{},
+			},
+			handler: (*SimpleChaincode).createSetBonus,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "checkSetBonus",
+				Description:   "report the best set bonus an owner qualifies for",
+				TransientKeys: []string{},
+				ArgCount:      1,
+				Reads:         []string{"collectionMarbles"},
+				Writes:        []string{},
+			},
+			handler: (*SimpleChaincode).checkSetBonus,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "applySetBonusToAppraisal",
+				Description:   "value an owner's marbles with set bonuses applied",
+				TransientKeys: []string{},
+				ArgCount:      1,
+				Reads:         []string{"collectionMarblePrivateDetails", "collectionMarbles"},
+				Writes:        []string{},
+			},
+			handler: (*SimpleChaincode).applySetBonusToAppraisal,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "listCompletedSetsByOwner",
+				Description:   "list the color sets an owner has completed",
+				TransientKeys: []string{},
+				ArgCount:      1,
+				Reads:         []string{"collectionMarbles"},
+				Writes:        []string{},
+			},
+			handler: (*SimpleChaincode).listCompletedSetsByOwner,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "estimateOperationCost",
+				Description:   "count the state operations another function would perform",
+				TransientKeys: []string{},
+				ArgCount:      -1,
+				Reads:         []string{},
+				Writes:        []string{},
+			},
+			handler: (*SimpleChaincode).estimateOperationCost,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "useMarble",
+				Description:   "record one use of a marble",
+				TransientKeys: []string{"marble_use"},
+				ArgCount:      0,
+				Reads:         []string{"collectionMarbleEventLog", "collectionMarbles"},
+				Writes:        []string{"collectionMarbleEndorsements", "collectionMarbleEventLog", "collectionMarbles"},
+			},
+			handler: (*SimpleChaincode).useMarble,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "repairMarble",
+				Description:   "restore a marble's condition",
+				TransientKeys: []string{"marble_repair"},
+				ArgCount:      0,
+				Reads:         []string{"collectionMarbleEventLog", "collectionMarbles"},
+				Writes:        []string{"collectionMarbleEndorsements", "collectionMarbleEventLog", "collectionMarbles"},
+			},
+			handler: (*SimpleChaincode).repairMarble,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "getWornOutMarbles",
+				Description:   "list marbles that can no longer be used",
+				TransientKeys: []string{},
+				ArgCount:      0,
+				Reads:         []string{"collectionMarbles"},
+				Writes:        []string{},
+			},
+			handler: (*SimpleChaincode).getWornOutMarbles,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "batchAppraise",
+				Description:   "record appraisals for a batch of marbles",
+				TransientKeys: []string{"appraisals"},
+				ArgCount:      0,
+				Reads:         []string{"collectionMarblePrivateDetails"},
+				Writes:        []string{"collectionMarblePrivateDetails"},
+			},
+			handler: (*SimpleChaincode).batchAppraise,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "recordMarbleView",
+				Description:   "count a view of a marble",
+				TransientKeys: []string{"marble_view"},
+				ArgCount:      0,
+				Reads:         []string{"collectionMarbleEventLog", "collectionMarbles"},
+				Writes:        []string{"collectionMarbleEndorsements", "collectionMarbleEventLog", "collectionMarbles"},
+			},
+			handler: (*SimpleChaincode).recordMarbleView,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "recordMarbleShare",
+				Description:   "count a share of a marble",
+				TransientKeys: []string{"marble_share"},
+				ArgCount:      0,
+				Reads:         []string{"collectionMarbleEventLog", "collectionMarbles"},
+				Writes:        []string{"collectionMarbleEndorsements", "collectionMarbleEventLog", "collectionMarbles"},
+			},
+			handler: (*SimpleChaincode).recordMarbleShare,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "batchRecordViews",
+				Description:   "count views of many marbles at once",
+				TransientKeys: []string{"marble_views"},
+				ArgCount:      0,
+				Reads:         []string{"collectionMarbleEventLog", "collectionMarbles"},
+				Writes:        []string{"collectionMarbleEndorsements", "collectionMarbleEventLog", "collectionMarbles"},
+			},
+			handler: (*SimpleChaincode).batchRecordViews,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "resetSocialStats",
+				Description:   "clear a marble's views and shares",
+				TransientKeys: []string{},
+				ArgCount:      1,
+				Reads:         []string{"collectionMarbleEventLog", "collectionMarbles"},
+				Writes:        []string{"collectionMarbleEndorsements", "collectionMarbleEventLog", "collectionMarbles"},
+			},
+			handler: (*SimpleChaincode).resetSocialStats,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "queryTrendingMarbles",
+				Description:   "find the marbles with the highest social score",
+				TransientKeys: []string{},
+				ArgCount:      1,
+				Reads:         []string{"collectionMarbles"},
+				Writes:        []string{},
+			},
+			handler: (*SimpleChaincode).queryTrendingMarbles,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "recordHandover",
+				Description:   "hand a marble over to a new custodian",
+				TransientKeys: []string{"marble_handover"},
+				ArgCount:      0,
+				Reads:         []string{"collectionMarbleEventLog", "collectionMarbles"},
+				Writes:        []string{"collectionMarbleEndorsements", "collectionMarbleEventLog", "collectionMarbles"},
+			},
+			handler: (*SimpleChaincode).recordHandover,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "acknowledgeReceipt",
+				Description:   "confirm receipt of a handed over marble",
+				TransientKeys: []string{"marble_custody_receipt"},
+				ArgCount:      0,
+				Reads:         []string{"collectionMarbleEventLog", "collectionMarbles"},
+				Writes:        []string{"collectionMarbleEndorsements", "collectionMarbleEventLog", "collectionMarbles"},
+			},
+			handler: (*SimpleChaincode).acknowledgeReceipt,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "getCustodyChain",
+				Description:   "read a marble's custody records",
+				TransientKeys: []string{},
+				ArgCount:      1,
+				Reads:         []string{"collectionMarbles"},
+				Writes:        []string{},
+			},
+			handler: (*SimpleChaincode).getCustodyChain,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "getCurrentCustodian",
+				Description:   "read a marble's open custody record",
+				TransientKeys: []string{},
+				ArgCount:      1,
+				Reads:         []string{"collectionMarbles"},
+				Writes:        []string{},
+			},
+			handler: (*SimpleChaincode).getCurrentCustodian,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "setCarbonCostPerTransfer",
+				Description:   "set the carbon footprint added by each transfer",
+				TransientKeys: []string{},
+				ArgCount:      1,
+				Reads:         []string{},
+				Writes:        []string{},
+			},
+			handler: (*SimpleChaincode).setCarbonCostPerTransfer,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "updateMarbleCarbon",
+				Description:   "add to a marble's carbon footprint",
+				TransientKeys: []string{},
+				ArgCount:      2,
+				Reads:         []string{"collectionMarbleEventLog", "collectionMarbles"},
+				Writes:        []string{"collectionMarbleEndorsements", "collectionMarbleEventLog", "collectionMarbles"},
+			},
+			handler: (*SimpleChaincode).updateMarbleCarbon,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "offsetCarbon",
+				Description:   "reduce a marble's carbon footprint",
+				TransientKeys: []string{"carbon_offset"},
+				ArgCount:      2,
+				Reads:         []string{"collectionMarbleEventLog", "collectionMarbles"},
+				Writes:        []string{"collectionMarbleEndorsements", "collectionMarbleEventLog", "collectionMarbles"},
+			},
+			handler: (*SimpleChaincode).offsetCarbon,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "getTotalCarbonByOwner",
+				Description:   "sum the carbon footprint of an owner's marbles",
+				TransientKeys: []string{},
+				ArgCount:      1,
+				Reads:         []string{"collectionMarbles"},
+				Writes:        []string{},
+			},
+			handler: (*SimpleChaincode).getTotalCarbonByOwner,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "queryHighCarbonMarbles",
+				Description:   "find marbles above a carbon footprint threshold",
+				TransientKeys: []string{},
+				ArgCount:      1,
+				Reads:         []string{"collectionMarbles"},
+				Writes:        []string{},
+			},
+			handler: (*SimpleChaincode).queryHighCarbonMarbles,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "depositToFarm",
+				Description:   "lock a marble in the farm to earn yield",
+				TransientKeys: []string{"farm_deposit"},
+				ArgCount:      0,
+				Reads:         []string{"collectionMarbleEventLog", "collectionMarbles"},
+				Writes:        []string{"collectionMarbleEndorsements", "collectionMarbleEventLog", "collectionMarbleFarming", "collectionMarbles"},
+			},
+			handler: (*SimpleChaincode).depositToFarm,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "harvestYield",
+				Description:   "collect the yield of a farmed marble",
+				TransientKeys: []string{},
+				ArgCount:      1,
+				Reads:         []string{"collectionMarbleFarming"},
+				Writes:        []string{"collectionMarbleFarming"},
+			},
+			handler: (*SimpleChaincode).harvestYield,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "withdrawFromFarm",
+				Description:   "take a marble out of the farm",
+				TransientKeys: []string{},
+				ArgCount:      1,
+				Reads:         []string{"collectionMarbleEventLog", "collectionMarbleFarming", "collectionMarbles"},
+				Writes:        []string{"collectionMarbleEndorsements", "collectionMarbleEventLog", "collectionMarbleFarming", "collectionMarbles"},
+			},
+			handler: (*SimpleChaincode).withdrawFromFarm,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "getTotalFarmYield",
+				Description:   "read the yield harvested by an organization",
+				TransientKeys: []string{},
+				ArgCount:      1,
+				Reads:         []string{"collectionMarbleFarming"},
+				Writes:        []string{},
+			},
+			handler: (*SimpleChaincode).getTotalFarmYield,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "getFarmingAPR",
+				Description:   "read the annualized farming yield rate",
+				TransientKeys: []string{},
+				ArgCount:      0,
+				Reads:         []string{},
+				Writes:        []string{},
+			},
+			handler: (*SimpleChaincode).getFarmingAPR,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "openSecretAuction",
+				Description:   "start a sealed bid auction on a marble",
+				TransientKeys: []string{"secret_auction"},
+				ArgCount:      0,
+				Reads:         []string{"collectionMarbleSecretBids", "collectionMarbles"},
+				Writes:        []string{"collectionMarbleSecretBids"},
+			},
+			handler: (*SimpleChaincode).openSecretAuction,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "submitSecretBid",
+				Description:   "place a sealed bid",
+				TransientKeys: []string{"secret_bid"},
+				ArgCount:      0,
+				Reads:         []string{"collectionMarbleSecretBids"},
+				Writes:        []string{"collectionMarbleSecretBids"},
+			},
+			handler: (*SimpleChaincode).submitSecretBid,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "revealSecretBid",
+				Description:   "prove the amount of a sealed bid",
+				TransientKeys: []string{"secret_bid_reveal"},
+				ArgCount:      0,
+				Reads:         []string{"collectionMarbleSecretBids"},
+				Writes:        []string{"collectionMarbleRevealedBids"},
+			},
+			handler: (*SimpleChaincode).revealSecretBid,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "closeSecretAuction",
+				Description:   "sell a marble to the highest revealed bid",
+				TransientKeys: []string{},
+				ArgCount:      1,
+				Reads:         []string{"collectionMarbleCredentials", "collectionMarbleEventLog", "collectionMarbleRevealedBids", "collectionMarbleSecretBids", "collectionMarbleShares", "collectionMarbles"},
+				Writes:        []string{"collectionMarbleAutoList", "collectionMarbleCredentials", "collectionMarbleEndorsements", "collectionMarbleEventLog", "collectionMarbleRevealedBids", "collectionMarbleSecretBids", "collectionMarbleShares", "collectionMarbles"},
+			},
+			handler: (*SimpleChaincode).closeSecretAuction,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "getRevealedBids",
+				Description:   "list the revealed bids on a marble",
+				TransientKeys: []string{},
+				ArgCount:      1,
+				Reads:         []string{"collectionMarbleRevealedBids"},
+				Writes:        []string{},
+			},
+			handler: (*SimpleChaincode).getRevealedBids,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "requestCertification",
+				Description:   "ask for a marble to be certified for sale",
+				TransientKeys: []string{"certification_request"},
+				ArgCount:      0,
+				Reads:         []string{"collectionMarbleEventLog", "collectionMarbles"},
+				Writes:        []string{"collectionMarbleEndorsements", "collectionMarbleEventLog", "collectionMarbles"},
+			},
+			handler: (*SimpleChaincode).requestCertification,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "issueCertification",
+				Description:   "certify a marble for sale",
+				TransientKeys: []string{"certification"},
+				ArgCount:      0,
+				Reads:         []string{"collectionMarbleEventLog", "collectionMarbles"},
+				Writes:        []string{"collectionMarbleCertifications", "collectionMarbleEndorsements", "collectionMarbleEventLog", "collectionMarbles"},
+			},
+			handler: (*SimpleChaincode).issueCertification,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "revokeCertification",
+				Description:   "withdraw a marble's certification",
+				TransientKeys: []string{},
+				ArgCount:      1,
+				Reads:         []string{"collectionMarbleEventLog", "collectionMarbles"},
+				Writes:        []string{"collectionMarbleCertifications", "collectionMarbleEndorsements", "collectionMarbleEventLog", "collectionMarbles"},
+			},
+			handler: (*SimpleChaincode).revokeCertification,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "getCertificationStatus",
+				Description:   "read a marble's certification state",
+				TransientKeys: []string{},
+				ArgCount:      1,
+				Reads:         []string{"collectionMarbleCertifications", "collectionMarbles"},
+				Writes:        []string{},
+			},
+			handler: (*SimpleChaincode).getCertificationStatus,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "listCertificationsPending",
+				Description:   "list marbles awaiting certification",
+				TransientKeys: []string{},
+				ArgCount:      0,
+				Reads:         []string{"collectionMarbles"},
+				Writes:        []string{},
+			},
+			handler: (*SimpleChaincode).listCertificationsPending,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "addMarblePhoto",
+				Description:   "add a photo to a marble's gallery",
+				TransientKeys: []string{"marble_photo"},
+				ArgCount:      0,
+				Reads:         []string{"collectionMarbleEventLog", "collectionMarbles"},
+				Writes:        []string{"collectionMarbleEndorsements", "collectionMarbleEventLog", "collectionMarbles"},
+			},
+			handler: (*SimpleChaincode).addMarblePhoto,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "removeMarblePhoto",
+				Description:   "remove a photo from a marble's gallery",
+				TransientKeys: []string{"marble_photo_remove"},
+				ArgCount:      0,
+				Reads:         []string{"collectionMarbleEventLog", "collectionMarbles"},
+				Writes:        []string{"collectionMarbleEndorsements", "collectionMarbleEventLog", "collectionMarbles"},
+			},
+			handler: (*SimpleChaincode).removeMarblePhoto,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "getMarbleGallery",
+				Description:   "list a marble's photos",
+				TransientKeys: []string{},
+				ArgCount:      1,
+				Reads:         []string{"collectionMarbles"},
+				Writes:        []string{},
+			},
+			handler: (*SimpleChaincode).getMarbleGallery,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "setHeroPhoto",
+				Description:   "choose a marble's hero photo",
+				TransientKeys: []string{},
+				ArgCount:      2,
+				Reads:         []string{"collectionMarbleEventLog", "collectionMarbles"},
+				Writes:        []string{"collectionMarbleEndorsements", "collectionMarbleEventLog", "collectionMarbles"},
+			},
+			handler: (*SimpleChaincode).setHeroPhoto,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "verifyPhoto",
+				Description:   "check an image against a marble photo",
+				TransientKeys: []string{},
+				ArgCount:      3,
+				Reads:         []string{"collectionMarbles"},
+				Writes:        []string{},
+			},
+			handler: (*SimpleChaincode).verifyPhoto,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "setWhitepaper",
+				Description:   "link a marble to its specification document",
+				TransientKeys: []string{"marble_whitepaper"},
+				ArgCount:      0,
+				Reads:         []string{"collectionMarbleEventLog", "collectionMarbles"},
+				Writes:        []string{"collectionMarbleEndorsements", "collectionMarbleEventLog", "collectionMarbles"},
+			},
+			handler: (*SimpleChaincode).setWhitepaper,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "updateWhitepaper",
+				Description:   "replace a marble's specification document",
+				TransientKeys: []string{},
+				ArgCount:      3,
+				Reads:         []string{"collectionMarbleEventLog", "collectionMarbles"},
+				Writes:        []string{"collectionMarbleEndorsements", "collectionMarbleEventLog", "collectionMarbles"},
+			},
+			handler: (*SimpleChaincode).updateWhitepaper,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "verifyWhitepaper",
+				Description:   "check a document against a marble's whitepaper",
+				TransientKeys: []string{},
+				ArgCount:      2,
+				Reads:         []string{"collectionMarbles"},
+				Writes:        []string{},
+			},
+			handler: (*SimpleChaincode).verifyWhitepaper,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "getMarblesByWhitepaper",
+				Description:   "find marbles by specification document",
+				TransientKeys: []string{},
+				ArgCount:      1,
+				Reads:         []string{"collectionMarbles"},
+				Writes:        []string{},
+			},
+			handler: (*SimpleChaincode).getMarblesByWhitepaper,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "getMarblesWithoutWhitepaper",
+				Description:   "list marbles with no specification document",
+				TransientKeys: []string{},
+				ArgCount:      0,
+				Reads:         []string{"collectionMarbles"},
+				Writes:        []string{},
+			},
+			handler: (*SimpleChaincode).getMarblesWithoutWhitepaper,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "addBlackoutPeriod",
+				Description:   "schedule a transfer blackout",
+				TransientKeys: []string{"blackout_period"},
+				ArgCount:      0,
+				Reads:         []string{},
+				Writes:        []string{},
+			},
+			handler: (*SimpleChaincode).addBlackoutPeriod,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "removeBlackoutPeriod",
+				Description:   "cancel a transfer blackout",
+				TransientKeys: []string{},
+				ArgCount:      1,
+				Reads:         []string{},
+				Writes:        []string{},
+			},
+			handler: (*SimpleChaincode).removeBlackoutPeriod,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "getActiveBlackout",
+				Description:   "read the blackout in force",
+				TransientKeys: []string{},
+				ArgCount:      0,
+				Reads:         []string{},
+				Writes:        []string{},
+			},
+			handler: (*SimpleChaincode).getActiveBlackout,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "getBlackoutHistory",
+				Description:   "list the blackouts that have ended",
+				TransientKeys: []string{},
+				ArgCount:      0,
+				Reads:         []string{},
+				Writes:        []string{},
+			},
+			handler: (*SimpleChaincode).getBlackoutHistory,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "updateMarblePrice",
+				Description:   "change the private price of a marble",
+				TransientKeys: []string{"marble_price"},
+				ArgCount:      0,
+				Reads:         []string{"collectionMarbleAutoList", "collectionMarbleCertifications", "collectionMarbleEventLog", "collectionMarbleMarketCap", "collectionMarblePrivateDetails", "collectionMarbles"},
+				Writes:        []string{"collectionMarbleEndorsements", "collectionMarbleEventLog", "collectionMarbleMarketCap", "collectionMarblePrivateDetails", "collectionMarbles"},
+			},
+			handler: (*SimpleChaincode).updateMarblePrice,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "getMarketCap",
+				Description:   "read the sum of all marble prices",
+				TransientKeys: []string{},
+				ArgCount:      0,
+				Reads:         []string{"collectionMarbleMarketCap"},
+				Writes:        []string{},
+			},
+			handler: (*SimpleChaincode).getMarketCap,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "getMarketCapHistory",
+				Description:   "list recent market cap values",
+				TransientKeys: []string{},
+				ArgCount:      0,
+				Reads:         []string{"collectionMarbleMarketCap"},
+				Writes:        []string{},
+			},
+			handler: (*SimpleChaincode).getMarketCapHistory,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "computeMarketCapFromScratch",
+				Description:   "rebuild the market cap from every marble",
+				TransientKeys: []string{},
+				ArgCount:      0,
+				Reads:         []string{"collectionMarbleMarketCap", "collectionMarblePrivateDetails"},
+				Writes:        []string{"collectionMarbleMarketCap"},
+			},
+			handler: (*SimpleChaincode).computeMarketCapFromScratch,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "openDisputeWithStake",
+				Description:   "open a dispute backed by a loyalty point stake",
+				TransientKeys: []string{"dispute"},
+				ArgCount:      0,
+				Reads:         []string{"collectionMarbles"},
+				Writes:        []string{"collectionMarbleDisputeStakes", "collectionMarbleDisputes", "collectionMarbles"},
+			},
+			handler: (*SimpleChaincode).openDisputeWithStake,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "resolveDisputeWithSlashing",
+				Description:   "rule on a dispute and pay out its stake",
+				TransientKeys: []string{},
+				ArgCount:      2,
+				Reads:         []string{"collectionMarbleDisputeStakes", "collectionMarbleDisputes", "collectionMarbles"},
+				Writes:        []string{"collectionMarbleDisputeStakes", "collectionMarbleDisputes", "collectionMarbles"},
+			},
+			handler: (*SimpleChaincode).resolveDisputeWithSlashing,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "withdrawDispute",
+				Description:   "withdraw an open dispute",
+				TransientKeys: []string{},
+				ArgCount:      1,
+				Reads:         []string{"collectionMarbleDisputes"},
+				Writes:        []string{"collectionMarbleDisputes"},
+			},
+			handler: (*SimpleChaincode).withdrawDispute,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "withdrawStake",
+				Description:   "reclaim the stake of a withdrawn dispute",
+				TransientKeys: []string{},
+				ArgCount:      1,
+				Reads:         []string{"collectionMarbleDisputeStakes", "collectionMarbleDisputes", "collectionMarbles"},
+				Writes:        []string{"collectionMarbleDisputeStakes", "collectionMarbles"},
+			},
+			handler: (*SimpleChaincode).withdrawStake,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "getStakesByClaimant",
+				Description:   "list the outstanding stakes of a complainant",
+				TransientKeys: []string{},
+				ArgCount:      1,
+				Reads:         []string{"collectionMarbleDisputeStakes"},
+				Writes:        []string{},
+			},
+			handler: (*SimpleChaincode).getStakesByClaimant,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "initiateBridge",
+				Description:   "lock a marble for representation on another network",
+				TransientKeys: []string{"bridge"},
+				ArgCount:      0,
+				Reads:         []string{"collectionMarbleEventLog", "collectionMarbles"},
+				Writes:        []string{"collectionMarbleBridges", "collectionMarbleEndorsements", "collectionMarbleEventLog", "collectionMarbles"},
+			},
+			handler: (*SimpleChaincode).initiateBridge,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "confirmBridgeReceipt",
+				Description:   "record the external network's confirmation",
+				TransientKeys: []string{"bridge_receipt"},
+				ArgCount:      0,
+				Reads:         []string{"collectionMarbleBridges"},
+				Writes:        []string{"collectionMarbleBridges"},
+			},
+			handler: (*SimpleChaincode).confirmBridgeReceipt,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "bridgeBack",
+				Description:   "unlock a marble returned from another network",
+				TransientKeys: []string{"bridge_back"},
+				ArgCount:      0,
+				Reads:         []string{"collectionMarbleBridges", "collectionMarbleEventLog", "collectionMarbles"},
+				Writes:        []string{"collectionMarbleBridges", "collectionMarbleEndorsements", "collectionMarbleEventLog", "collectionMarbles"},
+			},
+			handler: (*SimpleChaincode).bridgeBack,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "getBridgeStatus",
+				Description:   "read a marble's bridge record",
+				TransientKeys: []string{},
+				ArgCount:      1,
+				Reads:         []string{"collectionMarbleBridges"},
+				Writes:        []string{},
+			},
+			handler: (*SimpleChaincode).getBridgeStatus,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "payInsurancePremium",
+				Description:   "insure a marble against the pool",
+				TransientKeys: []string{},
+				ArgCount:      1,
+				Reads:         []string{"collectionMarbleClaimQueue", "collectionMarbleEventLog", "collectionMarbles"},
+				Writes:        []string{"collectionMarbleClaimQueue", "collectionMarbleEndorsements", "collectionMarbleEventLog", "collectionMarbles"},
+			},
+			handler: (*SimpleChaincode).payInsurancePremium,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "filePoolClaim",
+				Description:   "claim against the pool for an insured marble",
+				TransientKeys: []string{},
+				ArgCount:      2,
+				Reads:         []string{"collectionMarbles"},
+				Writes:        []string{"collectionMarbleClaimQueue"},
+			},
+			handler: (*SimpleChaincode).filePoolClaim,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "settlePoolClaim",
+				Description:   "pay or queue an insurance claim",
+				TransientKeys: []string{},
+				ArgCount:      1,
+				Reads:         []string{"collectionMarbleClaimQueue", "collectionMarbles"},
+				Writes:        []string{"collectionMarbleClaimQueue", "collectionMarbles"},
+			},
+			handler: (*SimpleChaincode).settlePoolClaim,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "getPoolBalance",
+				Description:   "read the insurance pool funds",
+				TransientKeys: []string{},
+				ArgCount:      0,
+				Reads:         []string{},
+				Writes:        []string{},
+			},
+			handler: (*SimpleChaincode).getPoolBalance,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "addPoolFunds",
+				Description:   "top up the insurance pool",
+				TransientKeys: []string{},
+				ArgCount:      1,
+				Reads:         []string{"collectionMarbleClaimQueue", "collectionMarbles"},
+				Writes:        []string{"collectionMarbleClaimQueue", "collectionMarbles"},
+			},
+			handler: (*SimpleChaincode).addPoolFunds,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "getInsuredMarbles",
+				Description:   "list marbles covered by the pool",
+				TransientKeys: []string{},
+				ArgCount:      0,
+				Reads:         []string{"collectionMarbles"},
+				Writes:        []string{},
+			},
+			handler: (*SimpleChaincode).getInsuredMarbles,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "setTieredRoyalty",
+				Description:   "set a marble's royalty tiers",
+				TransientKeys: []string{"tiered_royalty"},
+				ArgCount:      0,
+				Reads:         []string{"collectionMarblePrivateDetails"},
+				Writes:        []string{"collectionMarblePrivateDetails"},
+			},
+			handler: (*SimpleChaincode).setTieredRoyalty,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "computeRoyalty",
+				Description:   "compute the royalty on a sale price",
+				TransientKeys: []string{},
+				ArgCount:      2,
+				Reads:         []string{"collectionMarblePrivateDetails"},
+				Writes:        []string{},
+			},
+			handler: (*SimpleChaincode).computeRoyalty,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "getRoyaltiesOwedToCreator",
+				Description:   "sum the unsettled royalties of a creator",
+				TransientKeys: []string{},
+				ArgCount:      1,
+				Reads:         []string{"collectionMarbleReceipts"},
+				Writes:        []string{},
+			},
+			handler: (*SimpleChaincode).getRoyaltiesOwedToCreator,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "markRoyaltySettled",
+				Description:   "record payment of a marble's royalties",
+				TransientKeys: []string{},
+				ArgCount:      2,
+				Reads:         []string{"collectionMarblePrivateDetails", "collectionMarbleReceipts"},
+				Writes:        []string{"collectionMarbleReceipts"},
+			},
+			handler: (*SimpleChaincode).markRoyaltySettled,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "setAutoListPolicy",
+				Description:   "list a marble automatically when its price changes",
+				TransientKeys: []string{"auto_list_policy"},
+				ArgCount:      0,
+				Reads:         []string{"collectionMarbles"},
+				Writes:        []string{"collectionMarbleAutoList"},
+			},
+			handler: (*SimpleChaincode).setAutoListPolicy,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "disableAutoList",
+				Description:   "stop listing a marble automatically",
+				TransientKeys: []string{},
+				ArgCount:      1,
+				Reads:         []string{"collectionMarbles"},
+				Writes:        []string{"collectionMarbleAutoList"},
+			},
+			handler: (*SimpleChaincode).disableAutoList,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "createDataRoom",
+				Description:   "share notes about a marble between two organizations",
+				TransientKeys: []string{"data_room"},
+				ArgCount:      0,
+				Reads:         []string{"collectionMarbles"},
+				Writes:        []string{"collectionMarbleDataRooms"},
+			},
+			handler: (*SimpleChaincode).createDataRoom,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "readDataRoom",
+				Description:   "read a data room as one of its parties",
+				TransientKeys: []string{},
+				ArgCount:      1,
+				Reads:         []string{"collectionMarbleDataRooms"},
+				Writes:        []string{},
+			},
+			handler: (*SimpleChaincode).readDataRoom,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "updateDataRoomNotes",
+				Description:   "replace the notes of a data room",
+				TransientKeys: []string{},
+				ArgCount:      2,
+				Reads:         []string{"collectionMarbleDataRooms"},
+				Writes:        []string{"collectionMarbleDataRooms"},
+			},
+			handler: (*SimpleChaincode).updateDataRoomNotes,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "closeDataRoom",
+				Description:   "delete a data room",
+				TransientKeys: []string{},
+				ArgCount:      1,
+				Reads:         []string{"collectionMarbleDataRooms"},
+				Writes:        []string{"collectionMarbleDataRooms"},
+			},
+			handler: (*SimpleChaincode).closeDataRoom,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "listDataRoomsByMarble",
+				Description:   "list the data rooms about a marble",
+				TransientKeys: []string{},
+				ArgCount:      1,
+				Reads:         []string{"collectionMarbleDataRooms"},
+				Writes:        []string{},
+			},
+			handler: (*SimpleChaincode).listDataRoomsByMarble,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "submitOraclePrice",
+				Description:   "record a signed oracle price",
+				TransientKeys: []string{"oracle_price"},
+				ArgCount:      0,
+				Reads:         []string{"collectionMarblePrivateDetails"},
+				Writes:        []string{"collectionMarbleOracle"},
+			},
+			handler: (*SimpleChaincode).submitOraclePrice,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "getOraclePrice",
+				Description:   "read the latest oracle price of a marble",
+				TransientKeys: []string{},
+				ArgCount:      1,
+				Reads:         []string{"collectionMarbleOracle"},
+				Writes:        []string{},
+			},
+			handler: (*SimpleChaincode).getOraclePrice,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "verifyOraclePriceSignature",
+				Description:   "check the signature of an oracle price",
+				TransientKeys: []string{},
+				ArgCount:      1,
+				Reads:         []string{"collectionMarbleOracle"},
+				Writes:        []string{},
+			},
+			handler: (*SimpleChaincode).verifyOraclePriceSignature,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "updatePriceFromOracle",
+				Description:   "set a marble's price from its oracle feed",
+				TransientKeys: []string{},
+				ArgCount:      1,
+				Reads:         []string{"collectionMarbleAutoList", "collectionMarbleCertifications", "collectionMarbleEventLog", "collectionMarbleMarketCap", "collectionMarbleOracle", "collectionMarblePrivateDetails", "collectionMarbles"},
+				Writes:        []string{"collectionMarbleEndorsements", "collectionMarbleEventLog", "collectionMarbleMarketCap", "collectionMarblePrivateDetails", "collectionMarbles"},
+			},
+			handler: (*SimpleChaincode).updatePriceFromOracle,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "setOracleAuthority",
+				Description:   "authorize an oracle organization",
+				TransientKeys: []string{},
+				ArgCount:      1,
+				Reads:         []string{},
+				Writes:        []string{},
+			},
+			handler: (*SimpleChaincode).setOracleAuthority,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "scheduleDeferredTransfer",
+				Description:   "schedule a marble transfer for a future time",
+				TransientKeys: []string{"deferred_transfer"},
+				ArgCount:      0,
+				Reads:         []string{"collectionMarbleEventLog", "collectionMarbles"},
+				Writes:        []string{"collectionMarbleDeferredTransfers", "collectionMarbleEndorsements", "collectionMarbleEventLog", "collectionMarbles"},
+			},
+			handler: (*SimpleChaincode).scheduleDeferredTransfer,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "executeDeferredTransfer",
+				Description:   "carry out a due deferred transfer",
+				TransientKeys: []string{},
+				ArgCount:      1,
+				Reads:         []string{"collectionMarbleCredentials", "collectionMarbleDeferredTransfers", "collectionMarbleEventLog", "collectionMarbleShares", "collectionMarbles"},
+				Writes:        []string{"collectionMarbleAutoList", "collectionMarbleCredentials", "collectionMarbleDeferredTransfers", "collectionMarbleEndorsements", "collectionMarbleEventLog", "collectionMarbleShares", "collectionMarbles"},
+			},
+			handler: (*SimpleChaincode).executeDeferredTransfer,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "cancelDeferredTransfer",
+				Description:   "cancel a deferred transfer",
+				TransientKeys: []string{},
+				ArgCount:      1,
+				Reads:         []string{"collectionMarbleDeferredTransfers", "collectionMarbleEventLog", "collectionMarbles"},
+				Writes:        []string{"collectionMarbleDeferredTransfers", "collectionMarbleEndorsements", "collectionMarbleEventLog", "collectionMarbles"},
+			},
+			handler: (*SimpleChaincode).cancelDeferredTransfer,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "listDueDeferredTransfers",
+				Description:   "list deferred transfers ready to execute",
+				TransientKeys: []string{},
+				ArgCount:      0,
+				Reads:         []string{"collectionMarbleDeferredTransfers"},
+				Writes:        []string{},
+			},
+			handler: (*SimpleChaincode).listDueDeferredTransfers,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "proposeFieldChange",
+				Description:   "put a change of a marble field to a vote",
+				TransientKeys: []string{"field_change"},
+				ArgCount:      0,
+				Reads:         []string{"collectionMarbles"},
+				Writes:        []string{"collectionMarbleProposals"},
+			},
+			handler: (*SimpleChaincode).proposeFieldChange,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "voteOnFieldChange",
+				Description:   "vote on a field change proposal",
+				TransientKeys: []string{},
+				ArgCount:      2,
+				Reads:         []string{"collectionMarbleProposals"},
+				Writes:        []string{"collectionMarbleProposals"},
+			},
+			handler: (*SimpleChaincode).voteOnFieldChange,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "executeFieldChange",
+				Description:   "apply an approved field change",
+				TransientKeys: []string{},
+				ArgCount:      1,
+				Reads:         []string{"collectionMarbleEventLog", "collectionMarbleProposals", "collectionMarbles"},
+				Writes:        []string{"collectionMarbleEndorsements", "collectionMarbleEventLog", "collectionMarbleProposals", "collectionMarbles"},
+			},
+			handler: (*SimpleChaincode).executeFieldChange,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "rejectFieldChange",
+				Description:   "archive a field change that did not pass",
+				TransientKeys: []string{},
+				ArgCount:      1,
+				Reads:         []string{"collectionMarbleProposals"},
+				Writes:        []string{"collectionMarbleProposals"},
+			},
+			handler: (*SimpleChaincode).rejectFieldChange,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "getTaxReceiptsByBuyer",
+				Description:   "list the tax receipts of a buyer",
+				TransientKeys: []string{},
+				ArgCount:      1,
+				Reads:         []string{"collectionMarbleTaxReceipts"},
+				Writes:        []string{},
+			},
+			handler: (*SimpleChaincode).getTaxReceiptsByBuyer,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "getTaxReceiptsBySeller",
+				Description:   "list the tax receipts of a seller",
+				TransientKeys: []string{},
+				ArgCount:      1,
+				Reads:         []string{"collectionMarbleTaxReceipts"},
+				Writes:        []string{},
+			},
+			handler: (*SimpleChaincode).getTaxReceiptsBySeller,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "generateTaxSummary",
+				Description:   "sum the tax owed by an organization",
+				TransientKeys: []string{},
+				ArgCount:      3,
+				Reads:         []string{"collectionMarbleTaxReceipts"},
+				Writes:        []string{},
+			},
+			handler: (*SimpleChaincode).generateTaxSummary,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "issueOwnershipCredential",
+				Description:   "issue a credential proving marble ownership",
+				TransientKeys: []string{"ownership_credential"},
+				ArgCount:      0,
+				Reads:         []string{"collectionMarbles"},
+				Writes:        []string{"collectionMarbleCredentials"},
+			},
+			handler: (*SimpleChaincode).issueOwnershipCredential,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "verifyOwnershipCredential",
+				Description:   "verify an ownership credential",
+				TransientKeys: []string{},
+				ArgCount:      2,
+				Reads:         []string{"collectionMarbleCredentials", "collectionMarbles"},
+				Writes:        []string{},
+			},
+			handler: (*SimpleChaincode).verifyOwnershipCredential,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "listActiveCredentials",
+				Description:   "list the valid credentials of a marble",
+				TransientKeys: []string{},
+				ArgCount:      1,
+				Reads:         []string{"collectionMarbleCredentials"},
+				Writes:        []string{},
+			},
+			handler: (*SimpleChaincode).listActiveCredentials,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "revokeCredential",
+				Description:   "revoke an ownership credential",
+				TransientKeys: []string{},
+				ArgCount:      1,
+				Reads:         []string{"collectionMarbleCredentials", "collectionMarbles"},
+				Writes:        []string{"collectionMarbleCredentials"},
+			},
+			handler: (*SimpleChaincode).revokeCredential,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "getEventLog",
+				Description:   "list the logged events of a marble",
+				TransientKeys: []string{},
+				ArgCount:      1,
+				Reads:         []string{"collectionMarbleEventLog"},
+				Writes:        []string{},
+			},
+			handler: (*SimpleChaincode).getEventLog,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "replayMarbleState",
+				Description:   "rebuild a marble from its event log",
+				TransientKeys: []string{},
+				ArgCount:      2,
+				Reads:         []string{"collectionMarbleEventLog"},
+				Writes:        []string{},
+			},
+			handler: (*SimpleChaincode).replayMarbleState,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "pruneEventLog",
+				Description:   "remove old events of a marble",
+				TransientKeys: []string{},
+				ArgCount:      2,
+				Reads:         []string{"collectionMarbleEventLog"},
+				Writes:        []string{"collectionMarbleEventLog"},
+			},
+			handler: (*SimpleChaincode).pruneEventLog,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "setExternalValidator",
+				Description:   "set the chaincode approving marble writes",
+				TransientKeys: []string{},
+				ArgCount:      2,
+				Reads:         []string{},
+				Writes:        []string{},
+			},
+			handler: (*SimpleChaincode).setExternalValidator,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "clearExternalValidator",
+				Description:   "remove the external validator",
+				TransientKeys: []string{},
+				ArgCount:      0,
+				Reads:         []string{},
+				Writes:        []string{},
+			},
+			handler: (*SimpleChaincode).clearExternalValidator,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "genesisImport",
+				Description:   "refused, genesis import runs only from Init",
+				TransientKeys: []string{"genesis_marbles"},
+				ArgCount:      0,
+				Reads:         []string{"collectionMarbleEventLog", "collectionMarbleMarketCap", "collectionMarbles"},
+				Writes:        []string{"collectionMarbleEndorsements", "collectionMarbleEventLog", "collectionMarbleMarketCap", "collectionMarblePrivateDetails", "collectionMarbleProvenance", "collectionMarbles"},
+			},
+			handler: (*SimpleChaincode).genesisImport,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "grantBuyoutOption",
+				Description:   "grant the option to buy a marble at a fixed price",
+				TransientKeys: []string{"buyout_option"},
+				ArgCount:      0,
+				Reads:         []string{"collectionMarbleEventLog", "collectionMarbles"},
+				Writes:        []string{"collectionMarbleBuyoutOptions", "collectionMarbleEndorsements", "collectionMarbleEventLog", "collectionMarbles"},
+			},
+			handler: (*SimpleChaincode).grantBuyoutOption,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "exerciseBuyoutOption",
+				Description:   "buy a marble at its option strike price",
+				TransientKeys: []string{},
+				ArgCount:      1,
+				Reads:         []string{"collectionMarbleBuyoutOptions", "collectionMarbleCredentials", "collectionMarbleEventLog", "collectionMarbleShares", "collectionMarbles"},
+				Writes:        []string{"collectionMarbleAutoList", "collectionMarbleBuyoutOptions", "collectionMarbleCredentials", "collectionMarbleEndorsements", "collectionMarbleEventLog", "collectionMarbleShares", "collectionMarbles"},
+			},
+			handler: (*SimpleChaincode).exerciseBuyoutOption,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "expireOption",
+				Description:   "delete an expired buyout option",
+				TransientKeys: []string{},
+				ArgCount:      1,
+				Reads:         []string{"collectionMarbleBuyoutOptions", "collectionMarbleEventLog", "collectionMarbles"},
+				Writes:        []string{"collectionMarbleBuyoutOptions", "collectionMarbleEndorsements", "collectionMarbleEventLog", "collectionMarbles"},
+			},
+			handler: (*SimpleChaincode).expireOption,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "getOptionsByHolder",
+				Description:   "list the buyout options of a holder",
+				TransientKeys: []string{},
+				ArgCount:      1,
+				Reads:         []string{"collectionMarbleBuyoutOptions"},
+				Writes:        []string{},
+			},
+			handler: (*SimpleChaincode).getOptionsByHolder,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "getOptionsByMarble",
+				Description:   "list the buyout options on a marble",
+				TransientKeys: []string{},
+				ArgCount:      1,
+				Reads:         []string{"collectionMarbleBuyoutOptions"},
+				Writes:        []string{},
+			},
+			handler: (*SimpleChaincode).getOptionsByMarble,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "startCharityAuction",
+				Description:   "start an auction whose proceeds go to charity",
+				TransientKeys: []string{"charity_auction"},
+				ArgCount:      0,
+				Reads:         []string{"collectionMarbleEventLog", "collectionMarbles"},
+				Writes:        []string{"collectionMarbleCharityAuctions", "collectionMarbleEndorsements", "collectionMarbleEventLog", "collectionMarbles"},
+			},
+			handler: (*SimpleChaincode).startCharityAuction,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "placeCharityBid",
+				Description:   "bid on a charity auction",
+				TransientKeys: []string{},
+				ArgCount:      2,
+				Reads:         []string{"collectionMarbleCharityAuctions"},
+				Writes:        []string{"collectionMarbleCharityAuctions"},
+			},
+			handler: (*SimpleChaincode).placeCharityBid,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "cancelCharityAuction",
+				Description:   "cancel a charity auction without bids",
+				TransientKeys: []string{},
+				ArgCount:      1,
+				Reads:         []string{"collectionMarbleCharityAuctions", "collectionMarbleEventLog", "collectionMarbles"},
+				Writes:        []string{"collectionMarbleCharityAuctions", "collectionMarbleEndorsements", "collectionMarbleEventLog", "collectionMarbles"},
+			},
+			handler: (*SimpleChaincode).cancelCharityAuction,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "closeCharityAuction",
+				Description:   "settle a charity auction",
+				TransientKeys: []string{},
+				ArgCount:      1,
+				Reads:         []string{"collectionMarbleCharityAuctions", "collectionMarbleCredentials", "collectionMarbleEventLog", "collectionMarbleShares", "collectionMarbles"},
+				Writes:        []string{"collectionMarbleAutoList", "collectionMarbleCharityAuctions", "collectionMarbleCharityDonations", "collectionMarbleCredentials", "collectionMarbleEndorsements", "collectionMarbleEventLog", "collectionMarbleShares", "collectionMarbles"},
+			},
+			handler: (*SimpleChaincode).closeCharityAuction,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "getCharityDonations",
+				Description:   "list the donations to a beneficiary",
+				TransientKeys: []string{},
+				ArgCount:      1,
+				Reads:         []string{"collectionMarbleCharityDonations"},
+				Writes:        []string{},
+			},
+			handler: (*SimpleChaincode).getCharityDonations,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "getTotalDonated",
+				Description:   "sum the donations to a beneficiary",
+				TransientKeys: []string{},
+				ArgCount:      1,
+				Reads:         []string{"collectionMarbleCharityDonations"},
+				Writes:        []string{},
+			},
+			handler: (*SimpleChaincode).getTotalDonated,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "setMutationConfig",
+				Description:   "set how a marble mutates",
+				TransientKeys: []string{"mutation_config"},
+				ArgCount:      0,
+				Reads:         []string{"collectionMarbleMutations", "collectionMarbles"},
+				Writes:        []string{"collectionMarbleMutations"},
+			},
+			handler: (*SimpleChaincode).setMutationConfig,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "triggerMutation",
+				Description:   "roll for a random marble trait change",
+				TransientKeys: []string{"marble_mutation"},
+				ArgCount:      0,
+				Reads:         []string{"collectionMarbleEventLog", "collectionMarbleMutations", "collectionMarbles"},
+				Writes:        []string{"collectionMarbleEndorsements", "collectionMarbleEventLog", "collectionMarbleMutations", "collectionMarbles"},
+			},
+			handler: (*SimpleChaincode).triggerMutation,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "getMutationLog",
+				Description:   "read the mutations of a marble",
+				TransientKeys: []string{},
+				ArgCount:      1,
+				Reads:         []string{"collectionMarbleMutations"},
+				Writes:        []string{},
+			},
+			handler: (*SimpleChaincode).getMutationLog,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "freezeMutations",
+				Description:   "stop a marble from mutating",
+				TransientKeys: []string{},
+				ArgCount:      1,
+				Reads:         []string{"collectionMarbleMutations", "collectionMarbles"},
+				Writes:        []string{"collectionMarbleMutations"},
+			},
+			handler: (*SimpleChaincode).freezeMutations,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "initiateCoCreation",
+				Description:   "propose a marble created with another organization",
+				TransientKeys: []string{"co_creation"},
+				ArgCount:      0,
+				Reads:         []string{},
+				Writes:        []string{"collectionMarbleCoCreations"},
+			},
+			handler: (*SimpleChaincode).initiateCoCreation,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "contributeToCreation",
+				Description:   "supply the contributor attributes of a co-created marble",
+				TransientKeys: []string{"co_creation_attributes"},
+				ArgCount:      1,
+				Reads:         []string{"collectionMarbleCoCreations"},
+				Writes:        []string{"collectionMarbleCoCreations"},
+			},
+			handler: (*SimpleChaincode).contributeToCreation,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "finalizeCoCreation",
+				Description:   "create a co-created marble",
+				TransientKeys: []string{},
+				ArgCount:      1,
+				Reads:         []string{"collectionMarbleCoCreations", "collectionMarbleEventLog", "collectionMarbleMarketCap", "collectionMarbleProvenance", "collectionMarbles"},
+				Writes:        []string{"collectionMarbleCoCreations", "collectionMarbleEndorsements", "collectionMarbleEventLog", "collectionMarbleMarketCap", "collectionMarblePrivateDetails", "collectionMarbleProvenance", "collectionMarbles"},
+			},
+			handler: (*SimpleChaincode).finalizeCoCreation,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "redeemMarble",
+				Description:   "redeem a marble for physical delivery",
+				TransientKeys: []string{"marble_redemption"},
+				ArgCount:      0,
+				Reads:         []string{"collectionMarbleEventLog", "collectionMarbles"},
+				Writes:        []string{"collectionMarbleEndorsements", "collectionMarbleEventLog", "collectionMarbleRedemptions", "collectionMarbles"},
+			},
+			handler: (*SimpleChaincode).redeemMarble,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "getRedemptionRecord",
+				Description:   "read the redemption record of a marble",
+				TransientKeys: []string{},
+				ArgCount:      1,
+				Reads:         []string{"collectionMarbleRedemptions"},
+				Writes:        []string{},
+			},
+			handler: (*SimpleChaincode).getRedemptionRecord,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "getTotalRedemptions",
+				Description:   "count the redeemed marbles",
+				TransientKeys: []string{},
+				ArgCount:      0,
+				Reads:         []string{"collectionMarbleRedemptions"},
+				Writes:        []string{},
+			},
+			handler: (*SimpleChaincode).getTotalRedemptions,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "getRedemptionsByRedeemer",
+				Description:   "list the redemptions of a redeemer",
+				TransientKeys: []string{},
+				ArgCount:      1,
+				Reads:         []string{"collectionMarbleRedemptions"},
+				Writes:        []string{},
+			},
+			handler: (*SimpleChaincode).getRedemptionsByRedeemer,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "reportDataIssue",
+				Description:   "report inconsistent marble data for a bounty",
+				TransientKeys: []string{"data_issue"},
+				ArgCount:      0,
+				Reads:         []string{},
+				Writes:        []string{"collectionMarbleBounties"},
+			},
+			handler: (*SimpleChaincode).reportDataIssue,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "approveDataBounty",
+				Description:   "reward a data issue report and repair the marble",
+				TransientKeys: []string{},
+				ArgCount:      2,
+				Reads:         []string{"collectionMarbleBounties", "collectionMarbles"},
+				Writes:        []string{"collectionMarbleBounties", "collectionMarbles"},
+			},
+			handler: (*SimpleChaincode).approveDataBounty,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "rejectBounty",
+				Description:   "close a data issue report without reward",
+				TransientKeys: []string{},
+				ArgCount:      1,
+				Reads:         []string{"collectionMarbleBounties"},
+				Writes:        []string{"collectionMarbleBounties"},
+			},
+			handler: (*SimpleChaincode).rejectBounty,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "getOpenBounties",
+				Description:   "list the open data issue reports",
+				TransientKeys: []string{},
+				ArgCount:      0,
+				Reads:         []string{"collectionMarbleBounties"},
+				Writes:        []string{},
+			},
+			handler: (*SimpleChaincode).getOpenBounties,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "getBountiesByReporter",
+				Description:   "list the data issue reports of an organization",
+				TransientKeys: []string{},
+				ArgCount:      1,
+				Reads:         []string{"collectionMarbleBounties"},
+				Writes:        []string{},
+			},
+			handler: (*SimpleChaincode).getBountiesByReporter,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "getTotalBountyPayouts",
+				Description:   "sum the awarded bounty points",
+				TransientKeys: []string{},
+				ArgCount:      0,
+				Reads:         []string{"collectionMarbleBounties"},
+				Writes:        []string{},
+			},
+			handler: (*SimpleChaincode).getTotalBountyPayouts,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "computeDecayedValue",
+				Description:   "compute the decayed value of a marble",
+				TransientKeys: []string{},
+				ArgCount:      1,
+				Reads:         []string{"collectionMarblePrivateDetails", "collectionMarbles"},
+				Writes:        []string{},
+			},
+			handler: (*SimpleChaincode).computeDecayedValue,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "applyDecay",
+				Description:   "write the decayed value of a marble",
+				TransientKeys: []string{},
+				ArgCount:      1,
+				Reads:         []string{"collectionMarbleAutoList", "collectionMarbleCertifications", "collectionMarbleEventLog", "collectionMarbleMarketCap", "collectionMarblePrivateDetails", "collectionMarbles"},
+				Writes:        []string{"collectionMarbleEndorsements", "collectionMarbleEventLog", "collectionMarbleMarketCap", "collectionMarblePrivateDetails", "collectionMarbles"},
+			},
+			handler: (*SimpleChaincode).applyDecay,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "getDecayedPortfolioValue",
+				Description:   "sum the decayed values of an owner's marbles",
+				TransientKeys: []string{},
+				ArgCount:      1,
+				Reads:         []string{"collectionMarblePrivateDetails", "collectionMarbles"},
+				Writes:        []string{},
+			},
+			handler: (*SimpleChaincode).getDecayedPortfolioValue,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "setDecayImmunity",
+				Description:   "exempt a marble from decay",
+				TransientKeys: []string{},
+				ArgCount:      1,
+				Reads:         []string{"collectionMarbleEventLog", "collectionMarbles"},
+				Writes:        []string{"collectionMarbleEndorsements", "collectionMarbleEventLog", "collectionMarbles"},
+			},
+			handler: (*SimpleChaincode).setDecayImmunity,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "getEndorsementHistory",
+				Description:   "list the endorsement records of a marble",
+				TransientKeys: []string{},
+				ArgCount:      1,
+				Reads:         []string{"collectionMarbleEndorsements"},
+				Writes:        []string{},
+			},
+			handler: (*SimpleChaincode).getEndorsementHistory,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "getTransactionsByOrg",
+				Description:   "list the marble writes attributed to an organization",
+				TransientKeys: []string{},
+				ArgCount:      1,
+				Reads:         []string{"collectionMarbleEndorsements"},
+				Writes:        []string{},
+			},
+			handler: (*SimpleChaincode).getTransactionsByOrg,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "validateEndorsementPolicy",
+				Description:   "check the latest writes of a marble against required organizations",
+				TransientKeys: []string{},
+				ArgCount:      -1,
+				Reads:         []string{"collectionMarbleEndorsements", "collectionMarbleEventLog"},
+				Writes:        []string{},
+			},
+			handler: (*SimpleChaincode).validateEndorsementPolicy,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "submitOracleVote",
+				Description:   "propose a marble price as a consensus oracle",
+				TransientKeys: []string{"oracle_vote"},
+				ArgCount:      0,
+				Reads:         []string{"collectionMarbles"},
+				Writes:        []string{"collectionMarbleOracleVotes"},
+			},
+			handler: (*SimpleChaincode).submitOracleVote,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "tallyOracleVotes",
+				Description:   "set a marble price from agreeing oracle votes",
+				TransientKeys: []string{},
+				ArgCount:      1,
+				Reads:         []string{"collectionMarbleAutoList", "collectionMarbleCertifications", "collectionMarbleEventLog", "collectionMarbleMarketCap", "collectionMarbleOracleVotes", "collectionMarblePrivateDetails", "collectionMarbles"},
+				Writes:        []string{"collectionMarbleEndorsements", "collectionMarbleEventLog", "collectionMarbleMarketCap", "collectionMarbleOracleVotes", "collectionMarblePrivateDetails", "collectionMarbles"},
+			},
+			handler: (*SimpleChaincode).tallyOracleVotes,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "getOracleVotes",
+				Description:   "list the current oracle votes on a marble",
+				TransientKeys: []string{},
+				ArgCount:      1,
+				Reads:         []string{"collectionMarbleOracleVotes"},
+				Writes:        []string{},
+			},
+			handler: (*SimpleChaincode).getOracleVotes,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "generateRangeProof",
+				Description:   "prove a marble price is above a threshold",
+				TransientKeys: []string{"range_proof"},
+				ArgCount:      0,
+				Reads:         []string{"collectionMarblePrivateDetails", "collectionMarbles"},
+				Writes:        []string{"collectionMarbleProofs"},
+			},
+			handler: (*SimpleChaincode).generateRangeProof,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "verifyRangeProof",
+				Description:   "check a marble's range proof",
+				TransientKeys: []string{},
+				ArgCount:      3,
+				Reads:         []string{"collectionMarblePrivateDetails", "collectionMarbleProofs"},
+				Writes:        []string{},
+			},
+			handler: (*SimpleChaincode).verifyRangeProof,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "getRangeProof",
+				Description:   "read the range proof of a marble",
+				TransientKeys: []string{},
+				ArgCount:      1,
+				Reads:         []string{"collectionMarbleProofs"},
+				Writes:        []string{},
+			},
+			handler: (*SimpleChaincode).getRangeProof,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "scheduleAudit",
+				Description:   "schedule an in-person inspection of a marble",
+				TransientKeys: []string{"audit_schedule"},
+				ArgCount:      0,
+				Reads:         []string{"collectionMarbles"},
+				Writes:        []string{"collectionMarbleAudits"},
+			},
+			handler: (*SimpleChaincode).scheduleAudit,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "recordAuditResult",
+				Description:   "record the result of a marble inspection",
+				TransientKeys: []string{},
+				ArgCount:      3,
+				Reads:         []string{"collectionMarbleAudits", "collectionMarbleEventLog", "collectionMarbles"},
+				Writes:        []string{"collectionMarbleAudits", "collectionMarbleEndorsements", "collectionMarbleEventLog", "collectionMarbles"},
+			},
+			handler: (*SimpleChaincode).recordAuditResult,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "getUpcomingAudits",
+				Description:   "list the pending inspections",
+				TransientKeys: []string{},
+				ArgCount:      0,
+				Reads:         []string{"collectionMarbleAudits"},
+				Writes:        []string{},
+			},
+			handler: (*SimpleChaincode).getUpcomingAudits,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "getAuditHistory",
+				Description:   "list the completed inspections of a marble",
+				TransientKeys: []string{},
+				ArgCount:      1,
+				Reads:         []string{"collectionMarbleAudits"},
+				Writes:        []string{},
+			},
+			handler: (*SimpleChaincode).getAuditHistory,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "getFailedAuditMarbles",
+				Description:   "rich query for marbles that failed their last inspection",
+				TransientKeys: []string{},
+				ArgCount:      0,
+				Reads:         []string{"collectionMarbles"},
+				Writes:        []string{},
+			},
+			handler: (*SimpleChaincode).getFailedAuditMarbles,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "setTenureRoyalty",
+				Description:   "set a marble's tenure based royalty",
+				TransientKeys: []string{"tenure_royalty"},
+				ArgCount:      0,
+				Reads:         []string{"collectionMarblePrivateDetails"},
+				Writes:        []string{"collectionMarblePrivateDetails"},
+			},
+			handler: (*SimpleChaincode).setTenureRoyalty,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "computeTenureRoyalty",
+				Description:   "compute the tenure based royalty on a sale price",
+				TransientKeys: []string{},
+				ArgCount:      2,
+				Reads:         []string{"collectionMarblePrivateDetails", "collectionMarbles"},
+				Writes:        []string{},
+			},
+			handler: (*SimpleChaincode).computeTenureRoyalty,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "getOwnerReputation",
+				Description:   "read the reputation score of an owner",
+				TransientKeys: []string{},
+				ArgCount:      1,
+				Reads:         []string{},
+				Writes:        []string{},
+			},
+			handler: (*SimpleChaincode).getOwnerReputation,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "getTopReputationOwners",
+				Description:   "list the owners with the highest reputation",
+				TransientKeys: []string{},
+				ArgCount:      1,
+				Reads:         []string{},
+				Writes:        []string{},
+			},
+			handler: (*SimpleChaincode).getTopReputationOwners,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "getChaincodeMeta",
+				Description:   "describe the chaincode's functions and collections",
+				TransientKeys: []string{},
+				ArgCount:      0,
+				Reads:         []string{},
+				Writes:        []string{},
+			},
+			handler: (*SimpleChaincode).getChaincodeMeta,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "getCollectionMeta",
+				Description:   "list the functions that read or write a collection",
+				TransientKeys: []string{},
+				ArgCount:      1,
+				Reads:         []string{},
+				Writes:        []string{},
+			},
+			handler: (*SimpleChaincode).getCollectionMeta,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "getMarblesByRangePaginated",
+				Description:   "get marbles based on range query, one page at a time",
+				TransientKeys: []string{},
+				ArgCount:      4,
+				Reads:         []string{"collectionMarbles"},
+				Writes:        []string{},
+			},
+			handler: (*SimpleChaincode).getMarblesByRangePaginated,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "queryMarblesPaginated",
+				Description:   "find marbles based on an ad hoc rich query, one page at a time",
+				TransientKeys: []string{},
+				ArgCount:      3,
+				Reads:         []string{"collectionMarbles"},
+				Writes:        []string{},
+			},
+			handler: (*SimpleChaincode).queryMarblesPaginated,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "queryMarblesByOwnerPaginated",
+				Description:   "find marbles for owner X using rich query, one page at a time",
+				TransientKeys: []string{},
+				ArgCount:      3,
+				Reads:         []string{"collectionMarbles"},
+				Writes:        []string{},
+			},
+			handler: (*SimpleChaincode).queryMarblesByOwnerPaginated,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "getMarbleHistory",
+				Description:   "list the writes of a marble with their timestamps",
+				TransientKeys: []string{},
+				ArgCount:      1,
+				Reads:         []string{"collectionMarbleEndorsements", "collectionMarbleEventLog"},
+				Writes:        []string{},
+			},
+			handler: (*SimpleChaincode).getMarbleHistory,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "setAuthorizedCreators",
+				Description:   "set the organizations allowed to create marbles",
+				TransientKeys: []string{"authorized_creators"},
+				ArgCount:      0,
+				Reads:         []string{},
+				Writes:        []string{},
+			},
+			handler: (*SimpleChaincode).setAuthorizedCreators,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "getAuthorizedCreators",
+				Description:   "list the organizations allowed to create marbles",
+				TransientKeys: []string{},
+				ArgCount:      0,
+				Reads:         []string{},
+				Writes:        []string{},
+			},
+			handler: (*SimpleChaincode).getAuthorizedCreators,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "bulkInitMarbles",
+				Description:   "create several marbles in one transaction",
+				TransientKeys: []string{"marbles"},
+				ArgCount:      0,
+				Reads:         []string{"collectionMarbleEventLog", "collectionMarbleMarketCap", "collectionMarbles"},
+				Writes:        []string{"collectionMarbleEndorsements", "collectionMarbleEventLog", "collectionMarbleMarketCap", "collectionMarblePrivateDetails", "collectionMarbles"},
+			},
+			handler: (*SimpleChaincode).bulkInitMarbles,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "bulkTransferMarbles",
+				Description:   "move all marbles of one owner to another",
+				TransientKeys: []string{"marble_bulk_owner"},
+				ArgCount:      0,
+				Reads:         []string{"collectionMarbleCredentials", "collectionMarbleEventLog", "collectionMarblePrivateDetails", "collectionMarbleShares", "collectionMarbles"},
+				Writes:        []string{"collectionMarbleAutoList", "collectionMarbleCredentials", "collectionMarbleEndorsements", "collectionMarbleEventLog", "collectionMarbleShares", "collectionMarbleTaxReceipts", "collectionMarbles"},
+			},
+			handler: (*SimpleChaincode).bulkTransferMarbles,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "getMarblesByOwnerRange",
+				Description:   "list the marble names of an owner from the owner~name index",
+				TransientKeys: []string{},
+				ArgCount:      1,
+				Reads:         []string{"collectionMarbles"},
+				Writes:        []string{},
+			},
+			handler: (*SimpleChaincode).getMarblesByOwnerRange,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "getMarblesBySizeRange",
+				Description:   "list marbles whose size lies in a range",
+				TransientKeys: []string{},
+				ArgCount:      2,
+				Reads:         []string{"collectionMarbles"},
+				Writes:        []string{},
+			},
+			handler: (*SimpleChaincode).getMarblesBySizeRange,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "setColorConfig",
+				Description:   "admin setting of the allowed marble colors",
+				TransientKeys: []string{"color_config"},
+				ArgCount:      0,
+				Reads:         []string{},
+				Writes:        []string{},
+			},
+			handler: (*SimpleChaincode).setColorConfig,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "getColorConfig",
+				Description:   "the allowed marble colors",
+				TransientKeys: []string{},
+				ArgCount:      0,
+				Reads:         []string{},
+				Writes:        []string{},
+			},
+			handler: (*SimpleChaincode).getColorConfig,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "updateMarbleColor",
+				Description:   "admin correction of a marble's color",
+				TransientKeys: []string{"marble_color"},
+				ArgCount:      0,
+				Reads:         []string{"collectionMarbleEventLog", "collectionMarbles"},
+				Writes:        []string{"collectionMarbleEndorsements", "collectionMarbleEventLog", "collectionMarbles"},
+			},
+			handler: (*SimpleChaincode).updateMarbleColor,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "getMarblesByColor",
+				Description:   "list the marbles of a color from the color~name index",
+				TransientKeys: []string{},
+				ArgCount:      1,
+				Reads:         []string{"collectionMarbles"},
+				Writes:        []string{},
+			},
+			handler: (*SimpleChaincode).getMarblesByColor,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "archiveMarble",
+				Description:   "retire a marble, keeping its record",
+				TransientKeys: []string{"marble_archive"},
+				ArgCount:      0,
+				Reads:         []string{"collectionMarbleEventLog", "collectionMarbles"},
+				Writes:        []string{"collectionMarbleEndorsements", "collectionMarbleEventLog", "collectionMarbles"},
+			},
+			handler: (*SimpleChaincode).archiveMarble,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "restoreMarble",
+				Description:   "bring an archived marble back into use",
+				TransientKeys: []string{"marble_restore"},
+				ArgCount:      0,
+				Reads:         []string{"collectionMarbleEventLog", "collectionMarbles"},
+				Writes:        []string{"collectionMarbleEndorsements", "collectionMarbleEventLog", "collectionMarbles"},
+			},
+			handler: (*SimpleChaincode).restoreMarble,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "getMarblePrivateDataHash",
+				Description:   "the hashes of a marble's private data, readable by any organization",
+				TransientKeys: []string{},
+				ArgCount:      1,
+				Reads:         []string{"collectionMarblePrivateDetails", "collectionMarbles"},
+				Writes:        []string{},
+			},
+			handler: (*SimpleChaincode).getMarblePrivateDataHash,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "verifyMarbleOwner",
+				Description:   "whether the caller owns a marble, optionally failing when it does not",
+				TransientKeys: []string{},
+				ArgCount:      1,
+				Reads:         []string{"collectionMarbles"},
+				Writes:        []string{},
+			},
+			handler: (*SimpleChaincode).verifyMarbleOwner,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "requestTransfer",
+				Description:   "offer a marble to a new owner, pending acceptance",
+				TransientKeys: []string{"transfer_request"},
+				ArgCount:      0,
+				Reads:         []string{"collectionMarbleEventLog", "collectionMarbles"},
+				Writes:        []string{"collectionMarbleEndorsements", "collectionMarbleEventLog", "collectionMarbles"},
+			},
+			handler: (*SimpleChaincode).requestTransfer,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "acceptTransfer",
+				Description:   "accept a pending transfer as its recipient",
+				TransientKeys: []string{},
+				ArgCount:      1,
+				Reads:         []string{"collectionMarbleCredentials", "collectionMarbleEventLog", "collectionMarbleShares", "collectionMarbles"},
+				Writes:        []string{"collectionMarbleAutoList", "collectionMarbleCredentials", "collectionMarbleEndorsements", "collectionMarbleEventLog", "collectionMarbleShares", "collectionMarbles"},
+			},
+			handler: (*SimpleChaincode).acceptTransfer,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "rejectTransfer",
+				Description:   "withdraw or refuse a pending transfer",
+				TransientKeys: []string{},
+				ArgCount:      1,
+				Reads:         []string{"collectionMarbleEventLog", "collectionMarbles"},
+				Writes:        []string{"collectionMarbleEndorsements", "collectionMarbleEventLog", "collectionMarbles"},
+			},
+			handler: (*SimpleChaincode).rejectTransfer,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "startAuction",
+				Description:   "put an owned marble up for auction",
+				TransientKeys: []string{"auction"},
+				ArgCount:      0,
+				Reads:         []string{"collectionMarbleEventLog", "collectionMarbles"},
+				Writes:        []string{"collectionMarbleAuctions", "collectionMarbleEndorsements", "collectionMarbleEventLog", "collectionMarbles"},
+			},
+			handler: (*SimpleChaincode).startAuction,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "placeBid",
+				Description:   "bid on a marble auction",
+				TransientKeys: []string{"auction_bid"},
+				ArgCount:      0,
+				Reads:         []string{"collectionMarbleAuctions"},
+				Writes:        []string{"collectionMarbleAuctions"},
+			},
+			handler: (*SimpleChaincode).placeBid,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "closeAuction",
+				Description:   "settle a marble auction after its end time",
+				TransientKeys: []string{},
+				ArgCount:      1,
+				Reads:         []string{"collectionMarbleAuctions", "collectionMarbleAutoList", "collectionMarbleCertifications", "collectionMarbleCredentials", "collectionMarbleEventLog", "collectionMarbleMarketCap", "collectionMarblePrivateDetails", "collectionMarbleShares", "collectionMarbles"},
+				Writes:        []string{"collectionMarbleAuctions", "collectionMarbleAutoList", "collectionMarbleCredentials", "collectionMarbleEndorsements", "collectionMarbleEventLog", "collectionMarbleMarketCap", "collectionMarblePrivateDetails", "collectionMarbleShares", "collectionMarbleTaxReceipts", "collectionMarbles"},
+			},
+			handler: (*SimpleChaincode).closeAuction,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "makeOffer",
+				Description:   "a buyer organization's private offer on a marble",
+				TransientKeys: []string{"marble_offer"},
+				ArgCount:      0,
+				Reads:         []string{"collectionMarblePrivateDetails", "collectionMarbles"},
+				Writes:        []string{"collectionMarblePrivateDetails"},
+			},
+			handler: (*SimpleChaincode).makeOffer,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "acceptOffer",
+				Description:   "owner acceptance of a pending offer",
+				TransientKeys: []string{},
+				ArgCount:      2,
+				Reads:         []string{"collectionMarbleAutoList", "collectionMarbleCertifications", "collectionMarbleCredentials", "collectionMarbleEventLog", "collectionMarbleMarketCap", "collectionMarblePrivateDetails", "collectionMarbleShares", "collectionMarbles"},
+				Writes:        []string{"collectionMarbleAutoList", "collectionMarbleCredentials", "collectionMarbleEndorsements", "collectionMarbleEventLog", "collectionMarbleMarketCap", "collectionMarblePrivateDetails", "collectionMarbleShares", "collectionMarbles"},
+			},
+			handler: (*SimpleChaincode).acceptOffer,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "rejectOffer",
+				Description:   "owner refusal of a pending offer",
+				TransientKeys: []string{},
+				ArgCount:      2,
+				Reads:         []string{"collectionMarblePrivateDetails", "collectionMarbles"},
+				Writes:        []string{"collectionMarblePrivateDetails"},
+			},
+			handler: (*SimpleChaincode).rejectOffer,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "readOffer",
+				Description:   "an offer, for its buyer or the marble's owner",
+				TransientKeys: []string{},
+				ArgCount:      2,
+				Reads:         []string{"collectionMarblePrivateDetails", "collectionMarbles"},
+				Writes:        []string{},
+			},
+			handler: (*SimpleChaincode).readOffer,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "escrowMarble",
+				Description:   "owner placement of a marble in escrow for a buyer",
+				TransientKeys: []string{"marble_escrow"},
+				ArgCount:      0,
+				Reads:         []string{"collectionMarbleEventLog", "collectionMarbles"},
+				Writes:        []string{"collectionMarbleEndorsements", "collectionMarbleEventLog", "collectionMarbles"},
+			},
+			handler: (*SimpleChaincode).escrowMarble,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "releaseEscrow",
+				Description:   "escrow agent transfer of an escrowed marble to its buyer",
+				TransientKeys: []string{},
+				ArgCount:      1,
+				Reads:         []string{"collectionMarbleAutoList", "collectionMarbleCertifications", "collectionMarbleCredentials", "collectionMarbleEventLog", "collectionMarbleMarketCap", "collectionMarblePrivateDetails", "collectionMarbleShares", "collectionMarbles"},
+				Writes:        []string{"collectionMarbleAutoList", "collectionMarbleCredentials", "collectionMarbleEndorsements", "collectionMarbleEventLog", "collectionMarbleMarketCap", "collectionMarblePrivateDetails", "collectionMarbleShares", "collectionMarbles"},
+			},
+			handler: (*SimpleChaincode).releaseEscrow,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "cancelEscrow",
+				Description:   "escrow agent return of an escrowed marble to its owner",
+				TransientKeys: []string{},
+				ArgCount:      1,
+				Reads:         []string{"collectionMarbleEventLog", "collectionMarbles"},
+				Writes:        []string{"collectionMarbleEndorsements", "collectionMarbleEventLog", "collectionMarbles"},
+			},
+			handler: (*SimpleChaincode).cancelEscrow,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "queryMarblesForSale",
+				Description:   "rich query for the marbles listed for sale",
+				TransientKeys: []string{},
+				ArgCount:      0,
+				Reads:         []string{"collectionMarbles"},
+				Writes:        []string{},
+			},
+			handler: (*SimpleChaincode).queryMarblesForSale,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "getMarblesForSale",
+				Description:   "list the marbles for sale from the askingPrice~name index",
+				TransientKeys: []string{},
+				ArgCount:      0,
+				Reads:         []string{"collectionMarbles"},
+				Writes:        []string{},
+			},
+			handler: (*SimpleChaincode).getMarblesForSale,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "reserveMarble",
+				Description:   "owner reservation of a marble for a buyer",
+				TransientKeys: []string{"marble_reservation"},
+				ArgCount:      0,
+				Reads:         []string{"collectionMarbleEventLog", "collectionMarbles"},
+				Writes:        []string{"collectionMarbleEndorsements", "collectionMarbleEventLog", "collectionMarbles"},
+			},
+			handler: (*SimpleChaincode).reserveMarble,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "cancelReservation",
+				Description:   "release of a reservation by the owner or the buyer",
+				TransientKeys: []string{},
+				ArgCount:      1,
+				Reads:         []string{"collectionMarbleEventLog", "collectionMarbles"},
+				Writes:        []string{"collectionMarbleEndorsements", "collectionMarbleEventLog", "collectionMarbles"},
+			},
+			handler: (*SimpleChaincode).cancelReservation,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "allocateShares",
+				Description:   "split the ownership of a marble between shareholders",
+				TransientKeys: []string{"share_allocation"},
+				ArgCount:      0,
+				Reads:         []string{"collectionMarbleShares", "collectionMarbles"},
+				Writes:        []string{"collectionMarbleShares"},
+			},
+			handler: (*SimpleChaincode).allocateShares,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "transferShares",
+				Description:   "a shareholder's transfer of some of its shares",
+				TransientKeys: []string{"share_transfer"},
+				ArgCount:      0,
+				Reads:         []string{"collectionMarbleShares"},
+				Writes:        []string{"collectionMarbleShares"},
+			},
+			handler: (*SimpleChaincode).transferShares,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "getShareholders",
+				Description:   "the shares of a jointly owned marble",
+				TransientKeys: []string{},
+				ArgCount:      1,
+				Reads:         []string{"collectionMarbleShares"},
+				Writes:        []string{},
+			},
+			handler: (*SimpleChaincode).getShareholders,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "approvePendingAction",
+				Description:   "a shareholder's approval of a decision on a jointly owned marble",
+				TransientKeys: []string{"pending_action"},
+				ArgCount:      0,
+				Reads:         []string{"collectionMarbleShares"},
+				Writes:        []string{"collectionMarbleShares"},
+			},
+			handler: (*SimpleChaincode).approvePendingAction,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "recordProvenance",
+				Description:   "provenance of a marble, recorded by an authorized organization",
+				TransientKeys: []string{"marble_provenance"},
+				ArgCount:      0,
+				Reads:         []string{},
+				Writes:        []string{"collectionMarbleProvenance"},
+			},
+			handler: (*SimpleChaincode).recordProvenance,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "readProvenance",
+				Description:   "the provenance of a marble",
+				TransientKeys: []string{},
+				ArgCount:      1,
+				Reads:         []string{"collectionMarbleProvenance"},
+				Writes:        []string{},
+			},
+			handler: (*SimpleChaincode).readProvenance,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "verifyProvenance",
+				Description:   "whether a marble's provenance has a known certificate",
+				TransientKeys: []string{},
+				ArgCount:      1,
+				Reads:         []string{"collectionMarbleProvenance"},
+				Writes:        []string{},
+			},
+			handler: (*SimpleChaincode).verifyProvenance,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "registerProvenanceCertificate",
+				Description:   "admin registration of a provenance certificate",
+				TransientKeys: []string{"provenance_certificate"},
+				ArgCount:      0,
+				Reads:         []string{},
+				Writes:        []string{},
+			},
+			handler: (*SimpleChaincode).registerProvenanceCertificate,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "updateMarbleCondition",
+				Description:   "owner record of a marble's wear",
+				TransientKeys: []string{"marble_condition"},
+				ArgCount:      0,
+				Reads:         []string{"collectionMarbleEventLog", "collectionMarbles"},
+				Writes:        []string{"collectionMarbleEndorsements", "collectionMarbleEventLog", "collectionMarbles"},
+			},
+			handler: (*SimpleChaincode).updateMarbleCondition,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "queryMarblesByCondition",
+				Description:   "the marbles in a condition",
+				TransientKeys: []string{},
+				ArgCount:      1,
+				Reads:         []string{"collectionMarbles"},
+				Writes:        []string{},
+			},
+			handler: (*SimpleChaincode).queryMarblesByCondition,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "rateMarble",
+				Description:   "a past owner's rating of a marble",
+				TransientKeys: []string{"marble_rating"},
+				ArgCount:      0,
+				Reads:         []string{"collectionMarbleEventLog", "collectionMarbleRatings", "collectionMarbles"},
+				Writes:        []string{"collectionMarbleRatings"},
+			},
+			handler: (*SimpleChaincode).rateMarble,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "getAverageRating",
+				Description:   "the average rating of a marble",
+				TransientKeys: []string{},
+				ArgCount:      1,
+				Reads:         []string{"collectionMarbleRatings"},
+				Writes:        []string{},
+			},
+			handler: (*SimpleChaincode).getAverageRating,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "attachDocument",
+				Description:   "owner attachment of a document hash to a marble",
+				TransientKeys: []string{"marble_document"},
+				ArgCount:      0,
+				Reads:         []string{"collectionMarbleEventLog", "collectionMarbles"},
+				Writes:        []string{"collectionMarbleEndorsements", "collectionMarbleEventLog", "collectionMarbles"},
+			},
+			handler: (*SimpleChaincode).attachDocument,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "removeDocument",
+				Description:   "owner removal of a document hash from a marble",
+				TransientKeys: []string{"marble_document_remove"},
+				ArgCount:      0,
+				Reads:         []string{"collectionMarbleEventLog", "collectionMarbles"},
+				Writes:        []string{"collectionMarbleEndorsements", "collectionMarbleEventLog", "collectionMarbles"},
+			},
+			handler: (*SimpleChaincode).removeDocument,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "listDocuments",
+				Description:   "the document hashes attached to a marble",
+				TransientKeys: []string{},
+				ArgCount:      1,
+				Reads:         []string{"collectionMarbles"},
+				Writes:        []string{},
+			},
+			handler: (*SimpleChaincode).listDocuments,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "getMarblesBySeries",
+				Description:   "the marbles of a series",
+				TransientKeys: []string{},
+				ArgCount:      1,
+				Reads:         []string{"collectionMarbles"},
+				Writes:        []string{},
+			},
+			handler: (*SimpleChaincode).getMarblesBySeries,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "getSeriesStats",
+				Description:   "the size, total price and owners of a series",
+				TransientKeys: []string{},
+				ArgCount:      1,
+				Reads:         []string{"collectionMarblePrivateDetails", "collectionMarbles"},
+				Writes:        []string{},
+			},
+			handler: (*SimpleChaincode).getSeriesStats,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "listSeries",
+				Description:   "every series a marble was created in",
+				TransientKeys: []string{},
+				ArgCount:      0,
+				Reads:         []string{},
+				Writes:        []string{},
+			},
+			handler: (*SimpleChaincode).listSeries,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "getMarblesByRarity",
+				Description:   "the marbles of a rarity",
+				TransientKeys: []string{},
+				ArgCount:      1,
+				Reads:         []string{"collectionMarbles"},
+				Writes:        []string{},
+			},
+			handler: (*SimpleChaincode).getMarblesByRarity,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "getRarityDistribution",
+				Description:   "the number of marbles of each rarity",
+				TransientKeys: []string{},
+				ArgCount:      0,
+				Reads:         []string{"collectionMarbles"},
+				Writes:        []string{},
+			},
+			handler: (*SimpleChaincode).getRarityDistribution,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "updateMarbleWeight",
+				Description:   "owner correction of a marble's weight",
+				TransientKeys: []string{"marble_weight"},
+				ArgCount:      0,
+				Reads:         []string{"collectionMarbleEventLog", "collectionMarbles"},
+				Writes:        []string{"collectionMarbleEndorsements", "collectionMarbleEventLog", "collectionMarbles"},
+			},
+			handler: (*SimpleChaincode).updateMarbleWeight,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "getMarblesByWeightRange",
+				Description:   "rich query for marbles in a weight range",
+				TransientKeys: []string{},
+				ArgCount:      2,
+				Reads:         []string{"collectionMarbles"},
+				Writes:        []string{},
+			},
+			handler: (*SimpleChaincode).getMarblesByWeightRange,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "addTag",
+				Description:   "owner labelling of a marble",
+				TransientKeys: []string{"marble_tag"},
+				ArgCount:      0,
+				Reads:         []string{"collectionMarbleEventLog", "collectionMarbles"},
+				Writes:        []string{"collectionMarbleEndorsements", "collectionMarbleEventLog", "collectionMarbles"},
+			},
+			handler: (*SimpleChaincode).addTag,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "removeTag",
+				Description:   "owner removal of a tag from a marble",
+				TransientKeys: []string{"marble_tag_remove"},
+				ArgCount:      0,
+				Reads:         []string{"collectionMarbleEventLog", "collectionMarbles"},
+				Writes:        []string{"collectionMarbleEndorsements", "collectionMarbleEventLog", "collectionMarbles"},
+			},
+			handler: (*SimpleChaincode).removeTag,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "queryMarblesByTag",
+				Description:   "rich query for marbles carrying a tag",
+				TransientKeys: []string{},
+				ArgCount:      1,
+				Reads:         []string{"collectionMarbles"},
+				Writes:        []string{},
+			},
+			handler: (*SimpleChaincode).queryMarblesByTag,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "getRecentlyModifiedMarbles",
+				Description:   "rich query for marbles written since a timestamp",
+				TransientKeys: []string{},
+				ArgCount:      1,
+				Reads:         []string{"collectionMarbles"},
+				Writes:        []string{},
+			},
+			handler: (*SimpleChaincode).getRecentlyModifiedMarbles,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "updateMarbleDescription",
+				Description:   "owner update of a marble's description",
+				TransientKeys: []string{"marble_description"},
+				ArgCount:      0,
+				Reads:         []string{"collectionMarbleEventLog", "collectionMarbles"},
+				Writes:        []string{"collectionMarbleEndorsements", "collectionMarbleEventLog", "collectionMarbles"},
+			},
+			handler: (*SimpleChaincode).updateMarbleDescription,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "cloneMarble",
+				Description:   "owner creation of a copy of a marble under a new name",
+				TransientKeys: []string{"marble_clone"},
+				ArgCount:      0,
+				Reads:         []string{"collectionMarbleEventLog", "collectionMarbleMarketCap", "collectionMarbleProvenance", "collectionMarbles"},
+				Writes:        []string{"collectionMarbleEndorsements", "collectionMarbleEventLog", "collectionMarbleMarketCap", "collectionMarblePrivateDetails", "collectionMarbles"},
+			},
+			handler: (*SimpleChaincode).cloneMarble,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "mergeMarbles",
+				Description:   "owner combination of two marbles of the same color into a new one",
+				TransientKeys: []string{"marble_merge"},
+				ArgCount:      0,
+				Reads:         []string{"collectionMarbleEventLog", "collectionMarblePrivateDetails", "collectionMarbleProvenance", "collectionMarbleShares", "collectionMarbles"},
+				Writes:        []string{"collectionMarbleEndorsements", "collectionMarbleEventLog", "collectionMarblePrivateDetails", "collectionMarbleShares", "collectionMarbles"},
+			},
+			handler: (*SimpleChaincode).mergeMarbles,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "splitMarble",
+				Description:   "owner division of a marble into two new ones",
+				TransientKeys: []string{"marble_split"},
+				ArgCount:      0,
+				Reads:         []string{"collectionMarbleEventLog", "collectionMarbleMarketCap", "collectionMarblePrivateDetails", "collectionMarbleProvenance", "collectionMarbleShares", "collectionMarbles"},
+				Writes:        []string{"collectionMarbleEndorsements", "collectionMarbleEventLog", "collectionMarbleMarketCap", "collectionMarblePrivateDetails", "collectionMarbleShares", "collectionMarbles"},
+			},
+			handler: (*SimpleChaincode).splitMarble,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "createBundle",
+				Description:   "owner grouping of marbles into a lot",
+				TransientKeys: []string{"marble_bundle"},
+				ArgCount:      0,
+				Reads:         []string{"collectionMarbles"},
+				Writes:        []string{"collectionMarbles"},
+			},
+			handler: (*SimpleChaincode).createBundle,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "disbandBundle",
+				Description:   "owner removal of a bundle",
+				TransientKeys: []string{"bundle_disband"},
+				ArgCount:      0,
+				Reads:         []string{"collectionMarbles"},
+				Writes:        []string{"collectionMarbles"},
+			},
+			handler: (*SimpleChaincode).disbandBundle,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "transferBundle",
+				Description:   "owner transfer of every marble of a bundle",
+				TransientKeys: []string{"bundle_transfer"},
+				ArgCount:      0,
+				Reads:         []string{"collectionMarbleCredentials", "collectionMarbleEventLog", "collectionMarbleShares", "collectionMarbles"},
+				Writes:        []string{"collectionMarbleAutoList", "collectionMarbleCredentials", "collectionMarbleEndorsements", "collectionMarbleEventLog", "collectionMarbleShares", "collectionMarbles"},
+			},
+			handler: (*SimpleChaincode).transferBundle,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "queryBundles",
+				Description:   "rich query for every bundle",
+				TransientKeys: []string{},
+				ArgCount:      0,
+				Reads:         []string{"collectionMarbles"},
+				Writes:        []string{},
+			},
+			handler: (*SimpleChaincode).queryBundles,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "setQuotaConfig",
+				Description:   "admin setting of the daily marble creation quota",
+				TransientKeys: []string{"quota_config"},
+				ArgCount:      0,
+				Reads:         []string{},
+				Writes:        []string{},
+			},
+			handler: (*SimpleChaincode).setQuotaConfig,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "getQuotaUsage",
+				Description:   "the marbles an organization created on a day",
+				TransientKeys: []string{},
+				ArgCount:      2,
+				Reads:         []string{},
+				Writes:        []string{},
+			},
+			handler: (*SimpleChaincode).getQuotaUsage,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "setOwnerMaxMarbles",
+				Description:   "set the most marbles a single owner may hold",
+				TransientKeys: []string{},
+				ArgCount:      1,
+				Reads:         []string{},
+				Writes:        []string{},
+			},
+			handler: (*SimpleChaincode).setOwnerMaxMarbles,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "getRegistryStats",
+				Description:   "count marbles, owners and total size, with the total value for private details members",
+				TransientKeys: []string{},
+				ArgCount:      0,
+				Reads:         []string{"collectionMarbleMarketCap", "collectionMarbles"},
+				Writes:        []string{},
+			},
+			handler: (*SimpleChaincode).getRegistryStats,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "invalidateRegistryCache",
+				Description:   "drop the cached registry stats",
+				TransientKeys: []string{},
+				ArgCount:      0,
+				Reads:         []string{},
+				Writes:        []string{},
+			},
+			handler: (*SimpleChaincode).invalidateRegistryCache,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "createOwnerProfile",
+				Description:   "create the caller's owner profile",
+				TransientKeys: []string{"owner_profile"},
+				ArgCount:      0,
+				Reads:         []string{"collectionOwnerProfiles"},
+				Writes:        []string{"collectionOwnerProfiles"},
+			},
+			handler: (*SimpleChaincode).createOwnerProfile,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "updateOwnerProfile",
+				Description:   "update the caller's owner profile",
+				TransientKeys: []string{"owner_profile_update"},
+				ArgCount:      0,
+				Reads:         []string{"collectionOwnerProfiles"},
+				Writes:        []string{"collectionOwnerProfiles"},
+			},
+			handler: (*SimpleChaincode).updateOwnerProfile,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "readOwnerProfile",
+				Description:   "read an owner profile",
+				TransientKeys: []string{},
+				ArgCount:      1,
+				Reads:         []string{"collectionOwnerProfiles"},
+				Writes:        []string{},
+			},
+			handler: (*SimpleChaincode).readOwnerProfile,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "deleteOwnerProfile",
+				Description:   "delete the caller's owner profile",
+				TransientKeys: []string{},
+				ArgCount:      1,
+				Reads:         []string{"collectionOwnerProfiles"},
+				Writes:        []string{"collectionOwnerProfiles"},
+			},
+			handler: (*SimpleChaincode).deleteOwnerProfile,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "getCollectionStats",
+				Description:   "count the keys of a collection by docType",
+				TransientKeys: []string{},
+				ArgCount:      1,
+				Reads:         []string{},
+				Writes:        []string{},
+			},
+			handler: (*SimpleChaincode).getCollectionStats,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "getMarblesByRangeWithMetadata",
+				Description:   "range query for marbles with a count and the query time",
+				TransientKeys: []string{},
+				ArgCount:      2,
+				Reads:         []string{"collectionMarbles"},
+				Writes:        []string{},
+			},
+			handler: (*SimpleChaincode).getMarblesByRangeWithMetadata,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "queryMarblesByOwnerAndColor",
+				Description:   "find the marbles of a color held by an owner",
+				TransientKeys: []string{},
+				ArgCount:      2,
+				Reads:         []string{"collectionMarbles"},
+				Writes:        []string{},
+			},
+			handler: (*SimpleChaincode).queryMarblesByOwnerAndColor,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "queryMarblesBySizeRange",
+				Description:   "rich query for marbles in a size range, in pages",
+				TransientKeys: []string{},
+				ArgCount:      5,
+				Reads:         []string{"collectionMarbles"},
+				Writes:        []string{},
+			},
+			handler: (*SimpleChaincode).queryMarblesBySizeRange,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "queryMarblesByPriceRange",
+				Description:   "marbles within a private price range, with their public data",
+				TransientKeys: []string{},
+				ArgCount:      2,
+				Reads:         []string{"collectionMarbleMarketCap", "collectionMarblePrivateDetails", "collectionMarbles"},
+				Writes:        []string{},
+			},
+			handler: (*SimpleChaincode).queryMarblesByPriceRange,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "readMarbleFull",
+				Description:   "a marble with its private details merged in, when readable",
+				TransientKeys: []string{},
+				ArgCount:      1,
+				Reads:         []string{"collectionMarblePrivateDetails", "collectionMarbles"},
+				Writes:        []string{},
+			},
+			handler: (*SimpleChaincode).readMarbleFull,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "exportMarblesCSV",
+				Description:   "marbles matching a query string, as CSV",
+				TransientKeys: []string{},
+				ArgCount:      1,
+				Reads:         []string{"collectionMarbles"},
+				Writes:        []string{},
+			},
+			handler: (*SimpleChaincode).exportMarblesCSV,
+		},
+		{
+			FunctionMeta: FunctionMeta{
+				Name:          "migrateMarbleConditions",
+				Description:   "rewrite legacy marble conditions as new, used or damaged",
+				TransientKeys: []string{},
+				ArgCount:      0,
+				Reads:         []string{"collectionMarbleEventLog", "collectionMarbles"},
+				Writes:        []string{"collectionMarbleEndorsements", "collectionMarbleEventLog", "collectionMarbles"},
+			},
+			handler: (*SimpleChaincode).migrateMarbleConditions,
+		},
+	}
+
+	functionsByName = map[string]*registeredFunction{}
+	for i := range chaincodeFunctions {
+		functionsByName[chaincodeFunctions[i].Name] = &chaincodeFunctions[i]
+	}
+}
+
+// collectionNames returns every collection a registered function reads or writes.
+func collectionNames() []string {
+	seen := map[string]bool{}
+	names := []string{}
+	for _, f := range chaincodeFunctions {
+		for _, collection := range append(append([]string{}, f.Reads...), f.Writes...) {
+			if !seen[collection] {
+				seen[collection] = true
+				names = append(names, collection)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ===========================================================================
+// getChaincodeMeta - describe the chaincode's functions and collections
+// ===========================================================================
+func (t *SimpleChaincode) getChaincodeMeta(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	if len(args) != 0 {
+		return shim.Error("Incorrect number of arguments. Expecting 0")
+	}
+
+	meta := &ChaincodeMeta{
+		Version:            chaincodeVersion,
+		SupportedFunctions: make([]FunctionMeta, len(chaincodeFunctions)),
+		CollectionNames:    collectionNames(),
+		GoVersion:          runtime.Version(),
+		BuildTimestamp:     buildTimestamp,
+	}
+	for i, f := range chaincodeFunctions {
+		meta.SupportedFunctions[i] = f.FunctionMeta
+	}
+	metaAsBytes, err := json.Marshal(meta)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	return shim.Success(metaAsBytes)
+}
+
+// ===========================================================================
+// getCollectionMeta - the functions that read or write a collection
+// ===========================================================================
+func (t *SimpleChaincode) getCollectionMeta(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+
+	//         0
+	// "collectionMarbles"
+	if len(args) != 1 {
+		return shim.Error("Incorrect number of arguments. Expecting name of the collection to query")
+	}
+
+	result := struct {
+		CollectionName string   `json:"collectionName"`
+		ReadBy         []string `json:"readBy"`
+		WrittenBy      []string `json:"writtenBy"`
+	}{args[0], []string{}, []string{}}
+	for _, f := range chaincodeFunctions {
+		if containsString(f.Reads, args[0]) {
+			result.ReadBy = append(result.ReadBy, f.Name)
+		}
+		if containsString(f.Writes, args[0]) {
+			result.WrittenBy = append(result.WrittenBy, f.Name)
+		}
+	}
+	if len(result.ReadBy) == 0 && len(result.WrittenBy) == 0 {
+		return shim.Error("Unknown collection: " + args[0])
+	}
+
+	resultAsBytes, err := json.Marshal(result)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	return shim.Success(resultAsBytes)
+}
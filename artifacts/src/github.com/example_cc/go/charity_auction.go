@@ -0,0 +1,367 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// charityAuctionLock is the LockedBy value of a marble under a charity auction.
+const charityAuctionLock = "charityAuction"
+
+// CharityAuction is an open bid auction on a marble whose proceeds go to Beneficiary.
+// Bids are accepted up to BidDeadline. It is kept in collectionMarbleCharityAuctions
+// under the ID of the transaction that started it.
+type CharityAuction struct {
+	ObjectType    string `json:"docType"`
+	AuctionID     string `json:"auctionID"`
+	MarbleName    string `json:"marbleName"`
+	Seller        string `json:"seller"`
+	Beneficiary   string `json:"beneficiary"`
+	MinBid        int    `json:"minBid"`
+	BidDeadline   int64  `json:"bidDeadline"`
+	HighestBid    int    `json:"highestBid"`
+	HighestBidder string `json:"highestBidder,omitempty"`
+}
+
+// CharityDonation records the winning bid of a charity auction, donated by the seller.
+// It is kept in collectionMarbleCharityDonations under the closing transaction ID.
+type CharityDonation struct {
+	ObjectType  string `json:"docType"`
+	AuctionID   string `json:"auctionID"`
+	MarbleName  string `json:"marbleName"`
+	Donor       string `json:"donor"`
+	Beneficiary string `json:"beneficiary"`
+	Amount      int    `json:"amount"`
+	TxID        string `json:"txID"`
+}
+
+func getCharityAuction(stub shim.ChaincodeStubInterface, auctionID string) (*CharityAuction, error) {
+	auctionAsBytes, err := stub.GetPrivateData("collectionMarbleCharityAuctions", auctionID)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get charity auction: %s", err.Error())
+	} else if auctionAsBytes == nil {
+		return nil, fmt.Errorf("Charity auction does not exist: %s", auctionID)
+	}
+
+	auction := &CharityAuction{}
+	err = json.Unmarshal(auctionAsBytes, auction)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to decode JSON of: %s", string(auctionAsBytes))
+	}
+	return auction, nil
+}
+
+func putCharityAuction(stub shim.ChaincodeStubInterface, auction *CharityAuction) error {
+	auctionAsBytes, err := json.Marshal(auction)
+	if err != nil {
+		return err
+	}
+	return stub.PutPrivateData("collectionMarbleCharityAuctions", auction.AuctionID, auctionAsBytes)
+}
+
+// getDonationsTo returns the donations made to a beneficiary.
+func getDonationsTo(stub shim.ChaincodeStubInterface, beneficiary string) ([]CharityDonation, error) {
+	resultsIterator, err := stub.GetPrivateDataByRange("collectionMarbleCharityDonations", "", "")
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	donations := []CharityDonation{}
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+		var donation CharityDonation
+		err = json.Unmarshal(queryResponse.Value, &donation)
+		if err != nil {
+			return nil, err
+		}
+		if donation.Beneficiary == beneficiary {
+			donations = append(donations, donation)
+		}
+	}
+	return donations, nil
+}
+
+// ===========================================================================
+// startCharityAuction - put an owned marble up for an auction whose proceeds
+// go to a beneficiary
+// ===========================================================================
+func (t *SimpleChaincode) startCharityAuction(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	fmt.Println("- start start charity auction")
+
+	type charityAuctionTransientInput struct {
+		MarbleName  string `json:"marbleName"`
+		Beneficiary string `json:"beneficiary"`
+		MinBid      int    `json:"minBid"`
+		BidDeadline int64  `json:"bidDeadline"`
+	}
+
+	if len(args) != 0 {
+		return shim.Error("Incorrect number of arguments. Private marble data must be passed in transient map.")
+	}
+
+	var auctionInput charityAuctionTransientInput
+	err := getTransientInput(stub, "charity_auction", &auctionInput)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if len(auctionInput.MarbleName) == 0 {
+		return shim.Error("marbleName field must be a non-empty string")
+	}
+	if len(auctionInput.Beneficiary) == 0 {
+		return shim.Error("beneficiary field must be a non-empty string")
+	}
+	if auctionInput.MinBid <= 0 {
+		return shim.Error("minBid field must be a positive integer")
+	}
+
+	currentTime, err := getTxUnixTime(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if auctionInput.BidDeadline <= currentTime {
+		return shim.Error("bidDeadline field must be a future time")
+	}
+
+	m, err := getMarble(stub, auctionInput.MarbleName)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = requireOwner(stub, m)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = checkNotLocked(m)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	auction := &CharityAuction{
+		ObjectType:  "charityAuction",
+		AuctionID:   stub.GetTxID(),
+		MarbleName:  m.Name,
+		Seller:      m.Owner,
+		Beneficiary: auctionInput.Beneficiary,
+		MinBid:      auctionInput.MinBid,
+		BidDeadline: auctionInput.BidDeadline,
+	}
+	err = putCharityAuction(stub, auction)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	m.LockedBy = charityAuctionLock
+	err = putMarble(stub, m)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	fmt.Println("- end start charity auction")
+	return shim.Success([]byte(auction.AuctionID))
+}
+
+// ===========================================================================
+// placeCharityBid - open bid on a charity auction. A bid must reach the
+// minimum bid and beat the highest bid so far.
+// ===========================================================================
+func (t *SimpleChaincode) placeCharityBid(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+
+	//      0        1
+	// "auctionID", "120"
+	if len(args) != 2 {
+		return shim.Error("Incorrect number of arguments. Expecting 2")
+	}
+	amount, err := strconv.Atoi(args[1])
+	if err != nil || amount <= 0 {
+		return shim.Error("bid must be a positive integer")
+	}
+
+	auction, err := getCharityAuction(stub, args[0])
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	currentTime, err := getTxUnixTime(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if currentTime > auction.BidDeadline {
+		return shim.Error("Bidding has closed on charity auction: " + auction.AuctionID)
+	}
+	if amount < auction.MinBid {
+		return shim.Error(fmt.Sprintf("bid %d is below the minimum bid %d", amount, auction.MinBid))
+	}
+	if amount <= auction.HighestBid {
+		return shim.Error(fmt.Sprintf("bid %d does not beat the highest bid %d", amount, auction.HighestBid))
+	}
+
+	bidderMSPID, err := getCallerMSPID(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	auction.HighestBid = amount
+	auction.HighestBidder = bidderMSPID
+	err = putCharityAuction(stub, auction)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	return shim.Success(nil)
+}
+
+// ===========================================================================
+// cancelCharityAuction - seller cancellation of a charity auction that has
+// not received a bid
+// ===========================================================================
+func (t *SimpleChaincode) cancelCharityAuction(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+
+	//      0
+	// "auctionID"
+	if len(args) != 1 {
+		return shim.Error("Incorrect number of arguments. Expecting charity auction ID")
+	}
+
+	auction, err := getCharityAuction(stub, args[0])
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if len(auction.HighestBidder) != 0 {
+		return shim.Error("Charity auction has received a bid and cannot be cancelled: " + auction.AuctionID)
+	}
+	m, err := getMarble(stub, auction.MarbleName)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = requireOwner(stub, m)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	err = stub.DelPrivateData("collectionMarbleCharityAuctions", auction.AuctionID)
+	if err != nil {
+		return shim.Error("Failed to delete state:" + err.Error())
+	}
+	m.LockedBy = ""
+	err = putMarble(stub, m)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	return shim.Success(nil)
+}
+
+// ===========================================================================
+// closeCharityAuction - after the bid deadline, transfer the marble to the
+// highest bidder and record the winning bid as the seller's donation. Anyone
+// may call it, so the seller cannot hold the auction open.
+// ===========================================================================
+func (t *SimpleChaincode) closeCharityAuction(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	fmt.Println("- start close charity auction")
+
+	//      0
+	// "auctionID"
+	if len(args) != 1 {
+		return shim.Error("Incorrect number of arguments. Expecting charity auction ID")
+	}
+
+	auction, err := getCharityAuction(stub, args[0])
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	currentTime, err := getTxUnixTime(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if currentTime <= auction.BidDeadline {
+		return shim.Error("Charity auction cannot be closed before its bid deadline: " + auction.AuctionID)
+	}
+
+	m, err := getMarble(stub, auction.MarbleName)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	m.LockedBy = ""
+	var donation *CharityDonation
+	if len(auction.HighestBidder) != 0 {
+		err = changeMarbleOwner(stub, m, auction.HighestBidder)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		donation = &CharityDonation{
+			ObjectType:  "charityDonation",
+			AuctionID:   auction.AuctionID,
+			MarbleName:  auction.MarbleName,
+			Donor:       auction.Seller,
+			Beneficiary: auction.Beneficiary,
+			Amount:      auction.HighestBid,
+			TxID:        stub.GetTxID(),
+		}
+		donationAsBytes, err := json.Marshal(donation)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		err = stub.PutPrivateData("collectionMarbleCharityDonations", donation.TxID, donationAsBytes)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+	}
+	err = putMarble(stub, m)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	err = stub.DelPrivateData("collectionMarbleCharityAuctions", auction.AuctionID)
+	if err != nil {
+		return shim.Error("Failed to delete state:" + err.Error())
+	}
+
+	donationAsBytes, err := json.Marshal(donation)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	fmt.Println("- end close charity auction")
+	return shim.Success(donationAsBytes)
+}
+
+// ===========================================================================
+// getCharityDonations - list the donations made to a beneficiary
+// ===========================================================================
+func (t *SimpleChaincode) getCharityDonations(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	if len(args) != 1 {
+		return shim.Error("Incorrect number of arguments. Expecting beneficiary to query")
+	}
+
+	donations, err := getDonationsTo(stub, args[0])
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	donationsAsBytes, err := json.Marshal(donations)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	return shim.Success(donationsAsBytes)
+}
+
+// ===========================================================================
+// getTotalDonated - the sum of the donations made to a beneficiary
+// ===========================================================================
+func (t *SimpleChaincode) getTotalDonated(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	if len(args) != 1 {
+		return shim.Error("Incorrect number of arguments. Expecting beneficiary to query")
+	}
+
+	donations, err := getDonationsTo(stub, args[0])
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	total := 0
+	for _, donation := range donations {
+		total += donation.Amount
+	}
+	return shim.Success([]byte(fmt.Sprintf("{\"beneficiary\":%q,\"totalDonated\":%d}", args[0], total)))
+}
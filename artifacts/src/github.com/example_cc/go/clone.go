@@ -0,0 +1,132 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// marbleClonedEvent is the payload of the MarbleCloned event.
+type marbleClonedEvent struct {
+	SourceName string `json:"sourceName"`
+	NewName    string `json:"newName"`
+}
+
+// ===========================================================================
+// cloneMarble - owner creation of a copy of a marble under a new name. The
+// copy takes the source's attributes, but not its tags or history: it is
+// created like any new marble, unlisted and unlocked, with its own price.
+// ===========================================================================
+func (t *SimpleChaincode) cloneMarble(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	fmt.Println("- start clone marble")
+
+	type marbleCloneTransientInput struct {
+		SourceName string      `json:"sourceName"`
+		NewName    string      `json:"newName"`
+		NewPrice   json.Number `json:"newPrice"`
+	}
+
+	if len(args) != 0 {
+		return shim.Error("Incorrect number of arguments. Private marble data must be passed in transient map.")
+	}
+
+	var cloneInput marbleCloneTransientInput
+	err := getTransientInput(stub, "marble_clone", &cloneInput)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if len(cloneInput.SourceName) == 0 {
+		return shim.Error("sourceName field must be a non-empty string")
+	}
+	newPrice, err := parsePrice(cloneInput.NewPrice)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	source, err := getMarble(stub, cloneInput.SourceName)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = requireOwner(stub, source)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	marbleInput := marbleTransientInput{
+		Name:           cloneInput.NewName,
+		Color:          source.Color,
+		Size:           source.Size,
+		Owner:          source.Owner,
+		Price:          newPrice,
+		Weight:         source.Weight,
+		MaxUsages:      source.MaxUsages,
+		Condition:      source.Condition,
+		DocumentHashes: source.DocumentHashes,
+		Series:         source.Series,
+		Rarity:         rarityOf(source),
+		Description:    source.Description,
+	}
+
+	gov, err := getGovernance(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = checkMarbleInput(stub, gov, &marbleInput)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = checkCallerNotBlacklisted(stub, gov)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	creatorMSPID, err := getCallerMSPID(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = checkAuthorizedCreator(stub, creatorMSPID)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = consumeCreationQuota(stub, creatorMSPID, 1)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = checkOwnerMaxMarbles(stub, source.Owner, 1)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = checkProvenanceRecorded(stub, gov, marbleInput.Name)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	currentTime, err := getTxUnixTime(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	// prepareMarble fails if newName is already taken
+	clone, cloneJSONasBytes, err := prepareMarble(stub, gov, &marbleInput, currentTime)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	err = writeNewMarble(stub, clone, cloneJSONasBytes, newPrice, creatorMSPID)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = adjustMarketCap(stub, clone.Name, newPrice)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	err = emitEvent(stub, "MarbleCloned", marbleClonedEvent{SourceName: source.Name, NewName: clone.Name})
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	fmt.Println("- end clone marble")
+	return shim.Success(nil)
+}
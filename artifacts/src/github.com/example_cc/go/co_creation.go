@@ -0,0 +1,273 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// States of a co-creation request.
+const (
+	coCreationInitiated   = "initiated"
+	coCreationContributed = "contributed"
+	coCreationFinalized   = "finalized"
+)
+
+// CoCreationAttributes is one organization's share of a co-created marble's fields.
+// Fields left empty are for the other organization to supply.
+type CoCreationAttributes struct {
+	Name  string `json:"name,omitempty"`
+	Color string `json:"color,omitempty"`
+	Size  int    `json:"size,omitempty"`
+	Owner string `json:"owner,omitempty"`
+	Price int    `json:"price,omitempty"`
+}
+
+// merge combines two shares. An attribute supplied by both must agree.
+func (a CoCreationAttributes) merge(b CoCreationAttributes) (CoCreationAttributes, error) {
+	mergeString := func(field, x, y string) (string, error) {
+		if len(x) != 0 && len(y) != 0 && x != y {
+			return "", fmt.Errorf("both organizations set %s, to %s and %s", field, x, y)
+		} else if len(x) != 0 {
+			return x, nil
+		}
+		return y, nil
+	}
+	mergeInt := func(field string, x, y int) (int, error) {
+		if x != 0 && y != 0 && x != y {
+			return 0, fmt.Errorf("both organizations set %s, to %d and %d", field, x, y)
+		} else if x != 0 {
+			return x, nil
+		}
+		return y, nil
+	}
+
+	var merged CoCreationAttributes
+	var err error
+	if merged.Name, err = mergeString("name", a.Name, b.Name); err != nil {
+		return merged, err
+	}
+	if merged.Color, err = mergeString("color", a.Color, b.Color); err != nil {
+		return merged, err
+	}
+	if merged.Size, err = mergeInt("size", a.Size, b.Size); err != nil {
+		return merged, err
+	}
+	if merged.Owner, err = mergeString("owner", a.Owner, b.Owner); err != nil {
+		return merged, err
+	}
+	if merged.Price, err = mergeInt("price", a.Price, b.Price); err != nil {
+		return merged, err
+	}
+	return merged, nil
+}
+
+// CoCreationRequest collects the attributes of a marble from two organizations. It is
+// kept in collectionMarbleCoCreations under the ID of the transaction that initiated it.
+type CoCreationRequest struct {
+	ObjectType            string               `json:"docType"`
+	RequestID             string               `json:"requestID"`
+	InitiatorMSP          string               `json:"initiatorMSP"`
+	ContributorMSP        string               `json:"contributorMSP"`
+	InitiatorAttributes   CoCreationAttributes `json:"initiatorAttributes"`
+	ContributorAttributes CoCreationAttributes `json:"contributorAttributes"`
+	Status                string               `json:"status"`
+}
+
+func getCoCreationRequest(stub shim.ChaincodeStubInterface, requestID string) (*CoCreationRequest, error) {
+	requestAsBytes, err := stub.GetPrivateData("collectionMarbleCoCreations", requestID)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get co-creation request: %s", err.Error())
+	} else if requestAsBytes == nil {
+		return nil, fmt.Errorf("Co-creation request does not exist: %s", requestID)
+	}
+
+	request := &CoCreationRequest{}
+	err = json.Unmarshal(requestAsBytes, request)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to decode JSON of: %s", string(requestAsBytes))
+	}
+	return request, nil
+}
+
+func putCoCreationRequest(stub shim.ChaincodeStubInterface, request *CoCreationRequest) error {
+	requestAsBytes, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+	return stub.PutPrivateData("collectionMarbleCoCreations", request.RequestID, requestAsBytes)
+}
+
+// coCreationStub wraps the real stub to run initMarble on the merged attributes of a
+// co-creation request, passed as its "marble" transient input.
+type coCreationStub struct {
+	shim.ChaincodeStubInterface
+	transient  map[string][]byte
+	coCreators []string
+}
+
+func (s *coCreationStub) GetTransient() (map[string][]byte, error) {
+	return s.transient, nil
+}
+
+// coCreatorsOf returns the organizations co-creating the marble initMarble is creating,
+// or nil for a marble created by a single organization.
+func coCreatorsOf(stub shim.ChaincodeStubInterface) []string {
+	if s, ok := stub.(*coCreationStub); ok {
+		return s.coCreators
+	}
+	return nil
+}
+
+// ===========================================================================
+// initiateCoCreation - propose a marble to be created together with another
+// organization, supplying the caller's share of its attributes
+// ===========================================================================
+func (t *SimpleChaincode) initiateCoCreation(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	fmt.Println("- start initiate co-creation")
+
+	type coCreationTransientInput struct {
+		ContributorMSP string               `json:"contributorMSP"`
+		Attributes     CoCreationAttributes `json:"attributes"`
+	}
+
+	if len(args) != 0 {
+		return shim.Error("Incorrect number of arguments. Private marble data must be passed in transient map.")
+	}
+
+	var requestInput coCreationTransientInput
+	err := getTransientInput(stub, "co_creation", &requestInput)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if len(requestInput.ContributorMSP) == 0 {
+		return shim.Error("contributorMSP field must be a non-empty string")
+	}
+
+	initiatorMSP, err := getCallerMSPID(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if initiatorMSP == requestInput.ContributorMSP {
+		return shim.Error("contributorMSP must be another organization than the initiator")
+	}
+
+	request := &CoCreationRequest{
+		ObjectType:          "coCreationRequest",
+		RequestID:           stub.GetTxID(),
+		InitiatorMSP:        initiatorMSP,
+		ContributorMSP:      requestInput.ContributorMSP,
+		InitiatorAttributes: requestInput.Attributes,
+		Status:              coCreationInitiated,
+	}
+	err = putCoCreationRequest(stub, request)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	fmt.Println("- end initiate co-creation")
+	return shim.Success([]byte(request.RequestID))
+}
+
+// ===========================================================================
+// contributeToCreation - the contributor organization's share of the
+// attributes of a co-created marble
+// ===========================================================================
+func (t *SimpleChaincode) contributeToCreation(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+
+	//      0
+	// "requestID"
+	if len(args) != 1 {
+		return shim.Error("Incorrect number of arguments. Expecting co-creation request ID, with the attributes in the transient map")
+	}
+
+	var attributes CoCreationAttributes
+	err := getTransientInput(stub, "co_creation_attributes", &attributes)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	request, err := getCoCreationRequest(stub, args[0])
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if request.Status != coCreationInitiated {
+		return shim.Error("Co-creation request is already " + request.Status + ": " + request.RequestID)
+	}
+	callerMSPID, err := getCallerMSPID(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if callerMSPID != request.ContributorMSP {
+		return shim.Error("organization " + callerMSPID + " is not the contributor of co-creation request " + request.RequestID)
+	}
+	_, err = request.InitiatorAttributes.merge(attributes)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	request.ContributorAttributes = attributes
+	request.Status = coCreationContributed
+	err = putCoCreationRequest(stub, request)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	return shim.Success(nil)
+}
+
+// ===========================================================================
+// finalizeCoCreation - initiator creation of the co-created marble from the
+// merged attributes, through initMarble
+// ===========================================================================
+func (t *SimpleChaincode) finalizeCoCreation(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	fmt.Println("- start finalize co-creation")
+
+	//      0
+	// "requestID"
+	if len(args) != 1 {
+		return shim.Error("Incorrect number of arguments. Expecting co-creation request ID")
+	}
+
+	request, err := getCoCreationRequest(stub, args[0])
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if request.Status != coCreationContributed {
+		return shim.Error("Co-creation request is " + request.Status + ", expecting " + coCreationContributed + ": " + request.RequestID)
+	}
+	callerMSPID, err := getCallerMSPID(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if callerMSPID != request.InitiatorMSP {
+		return shim.Error("organization " + callerMSPID + " is not the initiator of co-creation request " + request.RequestID)
+	}
+
+	merged, err := request.InitiatorAttributes.merge(request.ContributorAttributes)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	mergedAsBytes, err := json.Marshal(merged)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	response := t.initMarble(&coCreationStub{
+		ChaincodeStubInterface: stub,
+		transient:              map[string][]byte{"marble": mergedAsBytes},
+		coCreators:             []string{request.InitiatorMSP, request.ContributorMSP},
+	}, []string{})
+	if response.Status != shim.OK {
+		return response
+	}
+
+	request.Status = coCreationFinalized
+	err = putCoCreationRequest(stub, request)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	fmt.Println("- end finalize co-creation")
+	return shim.Success([]byte(merged.Name))
+}
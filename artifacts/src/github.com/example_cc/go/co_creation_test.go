@@ -0,0 +1,32 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestThirdOrganizationCannotContributeToCoCreation(t *testing.T) {
+	s := newTestStub(t)
+	requestID := string(s.mustInvoke("initiateCoCreation", map[string]interface{}{"co_creation": map[string]interface{}{
+		"contributorMSP": "Org2MSP",
+		"attributes":     map[string]interface{}{"name": "marble1", "color": "blue", "owner": "Org1MSP"},
+	}}))
+	contribution := map[string]interface{}{"co_creation_attributes": map[string]interface{}{"size": 35, "price": 99}}
+
+	s.setCaller("Org3MSP", "user1")
+	s.mustFail("organization Org3MSP is not the contributor of co-creation request "+requestID, "contributeToCreation", contribution, requestID)
+	s.setCaller("Org1MSP", "user1")
+	s.mustFail("organization Org1MSP is not the contributor of co-creation request "+requestID, "contributeToCreation", contribution, requestID)
+
+	var request CoCreationRequest
+	err := json.Unmarshal(s.PvtState["collectionMarbleCoCreations"][requestID], &request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if request.Status != coCreationInitiated || request.ContributorAttributes != (CoCreationAttributes{}) {
+		t.Fatalf("expected the request to still await its contributor, got %+v", request)
+	}
+
+	s.setCaller("Org2MSP", "user1")
+	s.mustInvoke("contributeToCreation", contribution, requestID)
+}
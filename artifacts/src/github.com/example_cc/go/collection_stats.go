@@ -0,0 +1,66 @@
+package main
+
+import (
+	"encoding/json"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// unknownDocType counts the entries whose value is not a JSON object with a docType,
+// such as counters and marker values.
+const unknownDocType = "unknown"
+
+// ===========================================================================
+// getCollectionStats - the number of simple keys in a private data
+// collection, by docType. Composite index keys are not part of a range
+// query over "" to "" and are not counted. Organizations that are not
+// members of the collection see it empty.
+// ===========================================================================
+func (t *SimpleChaincode) getCollectionStats(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+
+	type collectionStats struct {
+		Total     int            `json:"total"`
+		ByDocType map[string]int `json:"byDocType"`
+	}
+
+	//          0
+	// "collectionMarbles"
+	if len(args) != 1 {
+		return shim.Error("Incorrect number of arguments. Expecting collection name")
+	}
+	if len(args[0]) == 0 {
+		return shim.Error("collection name must be a non-empty string")
+	}
+
+	resultsIterator, err := stub.GetPrivateDataByRange(args[0], "", "")
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	defer resultsIterator.Close()
+
+	stats := collectionStats{ByDocType: map[string]int{}}
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+
+		// the docType is not always the first field, so the whole value is decoded
+		var doc struct {
+			ObjectType string `json:"docType"`
+		}
+		docType := unknownDocType
+		if json.Unmarshal(queryResponse.Value, &doc) == nil && len(doc.ObjectType) != 0 {
+			docType = doc.ObjectType
+		}
+		stats.Total++
+		stats.ByDocType[docType]++
+	}
+
+	statsAsBytes, err := json.Marshal(stats)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	return shim.Success(statsAsBytes)
+}
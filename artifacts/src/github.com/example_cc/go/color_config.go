@@ -0,0 +1,106 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// allowedColors are the marble colors accepted until an admin sets a color config.
+var allowedColors = []string{"red", "blue", "green", "white", "black", "purple", "yellow", "pink", "orange"}
+
+// colorConfigKey holds, in public state, the JSON array of allowed marble colors.
+const colorConfigKey = "colorConfig"
+
+// getAllowedColors returns the configured marble colors, or allowedColors if the color
+// config has never been set.
+func getAllowedColors(stub shim.ChaincodeStubInterface) ([]string, error) {
+	colorsAsBytes, err := stub.GetState(colorConfigKey)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get color config: %s", err.Error())
+	} else if colorsAsBytes == nil {
+		return allowedColors, nil
+	}
+
+	var colors []string
+	err = json.Unmarshal(colorsAsBytes, &colors)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to decode JSON of: %s", string(colorsAsBytes))
+	}
+	return colors, nil
+}
+
+// checkAllowedColor fails unless color is one of the allowed marble colors.
+func checkAllowedColor(stub shim.ChaincodeStubInterface, color string) error {
+	colors, err := getAllowedColors(stub)
+	if err != nil {
+		return err
+	}
+	if !containsString(colors, color) {
+		return fmt.Errorf("color %s is not allowed, expecting one of %v", color, colors)
+	}
+	return nil
+}
+
+// ===========================================================================
+// setColorConfig - admin setting of the allowed marble colors
+// ===========================================================================
+func (t *SimpleChaincode) setColorConfig(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	fmt.Println("- start set color config")
+
+	if len(args) != 0 {
+		return shim.Error("Incorrect number of arguments. Color config must be passed in transient map.")
+	}
+
+	err := requireAdmin(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	var colors []string
+	err = getTransientInput(stub, "color_config", &colors)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if len(colors) == 0 {
+		return shim.Error("color config must list at least one color")
+	}
+	for _, color := range colors {
+		if len(color) == 0 {
+			return shim.Error("allowed colors must be non-empty strings")
+		}
+	}
+
+	colorsAsBytes, err := json.Marshal(colors)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = stub.PutState(colorConfigKey, colorsAsBytes)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	fmt.Println("- end set color config")
+	return shim.Success(nil)
+}
+
+// ===========================================================================
+// getColorConfig - the allowed marble colors
+// ===========================================================================
+func (t *SimpleChaincode) getColorConfig(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	if len(args) != 0 {
+		return shim.Error("Incorrect number of arguments. Expecting 0")
+	}
+
+	colors, err := getAllowedColors(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	colorsAsBytes, err := json.Marshal(colors)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	return shim.Success(colorsAsBytes)
+}
@@ -0,0 +1,256 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// conditionGrades lists marble conditions from best to worst. A marble only ever moves
+// to the next one, or back up through a repairMarble.
+var conditionGrades = []string{"new", "used", "damaged"}
+
+// legacyConditions maps the grades marbles were once recorded with onto
+// conditionGrades, see migrateMarbleConditions.
+var legacyConditions = map[string]string{"good": "used", "fair": "used", "poor": "damaged"}
+
+// conditionGrade returns the position of a condition in conditionGrades. Marbles
+// created before conditions were tracked count as new.
+func conditionGrade(condition string) int {
+	if current, ok := legacyConditions[condition]; ok {
+		condition = current
+	}
+	for i, grade := range conditionGrades {
+		if grade == condition {
+			return i
+		}
+	}
+	return 0
+}
+
+// conditionIndexName keys marbles by condition. Marbles created before conditions were
+// tracked are indexed as new, marbles with a legacy grade under that grade until they
+// are migrated.
+const conditionIndexName = "condition~name"
+
+// indexedCondition returns the condition a marble is indexed under.
+func indexedCondition(m *marble) string {
+	if _, ok := legacyConditions[m.Condition]; ok {
+		return m.Condition
+	}
+	return conditionGrades[conditionGrade(m.Condition)]
+}
+
+func conditionIndexKey(stub shim.ChaincodeStubInterface, m *marble) (string, error) {
+	return stub.CreateCompositeKey(conditionIndexName, []string{indexedCondition(m), m.Name})
+}
+
+func addConditionIndex(stub shim.ChaincodeStubInterface, m *marble) error {
+	key, err := conditionIndexKey(stub, m)
+	if err != nil {
+		return err
+	}
+	return stub.PutPrivateData("collectionMarbles", key, []byte{0x00})
+}
+
+func removeConditionIndex(stub shim.ChaincodeStubInterface, m *marble) error {
+	key, err := conditionIndexKey(stub, m)
+	if err != nil {
+		return err
+	}
+	return stub.DelPrivateData("collectionMarbles", key)
+}
+
+// checkCondition fails unless condition is one of the conditionGrades.
+func checkCondition(condition string) error {
+	for _, grade := range conditionGrades {
+		if grade == condition {
+			return nil
+		}
+	}
+	return fmt.Errorf("condition %s is not valid, expecting one of %v", condition, conditionGrades)
+}
+
+// setMarbleCondition changes the condition of a marble, stamping the change and keeping
+// the condition~name index in step. The caller is responsible for writing the marble
+// back.
+func setMarbleCondition(stub shim.ChaincodeStubInterface, m *marble, condition string) error {
+	if indexedCondition(m) == condition {
+		m.Condition = condition
+		return nil
+	}
+	txTime, err := getTxTime(stub)
+	if err != nil {
+		return err
+	}
+	err = removeConditionIndex(stub, m)
+	if err != nil {
+		return err
+	}
+	m.Condition = condition
+	m.ConditionChangedAt = txTime.Format(time.RFC3339)
+	return addConditionIndex(stub, m)
+}
+
+// ===========================================================================
+// updateMarbleCondition - owner record of a marble's wear. The condition may
+// only drop to the next grade, new to used or used to damaged; raising it
+// again takes a repairMarble.
+// ===========================================================================
+func (t *SimpleChaincode) updateMarbleCondition(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	fmt.Println("- start update marble condition")
+
+	type marbleConditionTransientInput struct {
+		Name      string `json:"name"`
+		Condition string `json:"condition"`
+	}
+
+	if len(args) != 0 {
+		return shim.Error("Incorrect number of arguments. Private marble data must be passed in transient map.")
+	}
+
+	var conditionInput marbleConditionTransientInput
+	err := getTransientInput(stub, "marble_condition", &conditionInput)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if len(conditionInput.Name) == 0 {
+		return shim.Error("name field must be a non-empty string")
+	}
+	err = checkCondition(conditionInput.Condition)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	m, err := getMarble(stub, conditionInput.Name)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = requireOwner(stub, m)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	grade := conditionGrade(m.Condition)
+	if grade == len(conditionGrades)-1 || conditionInput.Condition != conditionGrades[grade+1] {
+		return shim.Error(fmt.Sprintf("marble %s is %s and cannot become %s", m.Name, conditionGrades[grade], conditionInput.Condition))
+	}
+
+	err = setMarbleCondition(stub, m, conditionInput.Condition)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = putMarble(stub, m)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	fmt.Println("- end update marble condition")
+	return shim.Success(nil)
+}
+
+// ===========================================================================
+// queryMarblesByCondition - the marbles in a condition, from the
+// condition~name index
+// ===========================================================================
+func (t *SimpleChaincode) queryMarblesByCondition(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+
+	//   0
+	// "new"
+	if len(args) != 1 {
+		return shim.Error("Incorrect number of arguments. Expecting condition")
+	}
+	err := checkCondition(args[0])
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	resultsIterator, err := stub.GetPrivateDataByPartialCompositeKey("collectionMarbles", conditionIndexName, []string{args[0]})
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	defer resultsIterator.Close()
+
+	records := []queryRecord{}
+	for resultsIterator.HasNext() {
+		responseRange, err := resultsIterator.Next()
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		_, compositeKeyParts, err := stub.SplitCompositeKey(responseRange.Key)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+
+		name := compositeKeyParts[1]
+		marbleAsBytes, err := stub.GetPrivateData("collectionMarbles", name)
+		if err != nil {
+			return shim.Error(err.Error())
+		} else if marbleAsBytes == nil {
+			continue
+		}
+		records = append(records, queryRecord{Key: name, Record: marbleAsBytes})
+	}
+	return marshalQueryRecords(records)
+}
+
+// ===========================================================================
+// migrateMarbleConditions - admin rewrite of the marbles still recorded with
+// a legacy condition grade, moving their condition~name index entries along.
+// The condition change is not stamped, as the marble itself did not change.
+// ===========================================================================
+func (t *SimpleChaincode) migrateMarbleConditions(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	fmt.Println("- start migrate marble conditions")
+
+	if len(args) != 0 {
+		return shim.Error("Incorrect number of arguments. Expecting 0")
+	}
+
+	err := requireAdmin(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	records, err := scanMarbles(stub, func(m *marble) bool {
+		_, ok := legacyConditions[m.Condition]
+		return ok
+	})
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	for _, record := range records {
+		m := &marble{}
+		err = json.Unmarshal(record.Record, m)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		err = removeConditionIndex(stub, m)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		m.Condition = legacyConditions[m.Condition]
+		err = addConditionIndex(stub, m)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+
+		marbleJSONasBytes, err := json.Marshal(m)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		err = stub.PutPrivateData("collectionMarbles", m.Name, marbleJSONasBytes)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		err = logMarbleWrite(stub, m.Name, marbleJSONasBytes)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+	}
+
+	fmt.Println("- end migrate marble conditions")
+	return shim.Success([]byte(fmt.Sprintf("{\"migrated\":%d}", len(records))))
+}
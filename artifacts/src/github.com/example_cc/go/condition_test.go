@@ -0,0 +1,80 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func conditionTo(name, condition string) map[string]interface{} {
+	return map[string]interface{}{"marble_condition": map[string]interface{}{"name": name, "condition": condition}}
+}
+
+func (s *testStub) marblesInCondition(condition string) []string {
+	var records []queryRecord
+	err := json.Unmarshal(s.mustInvoke("queryMarblesByCondition", nil, condition), &records)
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	names := []string{}
+	for _, record := range records {
+		names = append(names, record.Key)
+	}
+	return names
+}
+
+func TestConditionOnlyMovesNewToUsedToDamaged(t *testing.T) {
+	s := newTestStub(t)
+	s.createMarble("marble1", "blue", 35, "Org1MSP", 99)
+	if m := s.readTestMarble("marble1"); m.Condition != "new" {
+		t.Fatalf("expected a new marble, got %s", m.Condition)
+	}
+
+	s.mustFail("marble marble1 is new and cannot become damaged", "updateMarbleCondition", conditionTo("marble1", "damaged"))
+	s.mustFail("condition good is not valid", "updateMarbleCondition", conditionTo("marble1", "good"))
+	s.mustInvoke("updateMarbleCondition", conditionTo("marble1", "used"))
+	if m := s.readTestMarble("marble1"); m.Condition != "used" || m.ConditionChangedAt == "" {
+		t.Fatalf("expected a stamped change to used, got %s at %q", m.Condition, m.ConditionChangedAt)
+	}
+	s.mustFail("marble marble1 is used and cannot become new", "updateMarbleCondition", conditionTo("marble1", "new"))
+	s.mustInvoke("updateMarbleCondition", conditionTo("marble1", "damaged"))
+	s.mustFail("marble marble1 is damaged and cannot become damaged", "updateMarbleCondition", conditionTo("marble1", "damaged"))
+
+	if names := s.marblesInCondition("damaged"); len(names) != 1 || names[0] != "marble1" {
+		t.Fatalf("expected marble1 to be indexed as damaged, got %v", names)
+	}
+	if names := s.marblesInCondition("used"); len(names) != 0 {
+		t.Fatalf("expected no used marbles, got %v", names)
+	}
+}
+
+func TestMigrateMarbleConditionsMovesLegacyGrades(t *testing.T) {
+	s := newTestStub(t)
+	s.createMarble("marble1", "blue", 35, "Org1MSP", 99)
+	s.createMarble("marble2", "red", 35, "Org1MSP", 99)
+
+	// marble2 was recorded as poor before the grades changed
+	m := s.readTestMarble("marble2")
+	newKey, _ := s.CreateCompositeKey(conditionIndexName, []string{"new", "marble2"})
+	poorKey, _ := s.CreateCompositeKey(conditionIndexName, []string{"poor", "marble2"})
+	delete(s.PvtState["collectionMarbles"], newKey)
+	s.PvtState["collectionMarbles"][poorKey] = []byte{0x00}
+	m.Condition = "poor"
+	marbleAsBytes, _ := json.Marshal(m)
+	s.PvtState["collectionMarbles"]["marble2"] = marbleAsBytes
+
+	if payload := string(s.mustInvoke("migrateMarbleConditions", nil)); payload != `{"migrated":1}` {
+		t.Fatalf("expected one marble to be migrated, got %s", payload)
+	}
+	if m := s.readTestMarble("marble2"); m.Condition != "damaged" {
+		t.Fatalf("expected marble2 to be damaged, got %s", m.Condition)
+	}
+	if s.PvtState["collectionMarbles"][poorKey] != nil {
+		t.Fatal("expected the legacy index entry to be removed")
+	}
+	if names := s.marblesInCondition("damaged"); len(names) != 1 || names[0] != "marble2" {
+		t.Fatalf("expected marble2 to be indexed as damaged, got %v", names)
+	}
+	if names := s.marblesInCondition("new"); len(names) != 1 || names[0] != "marble1" {
+		t.Fatalf("expected marble1 to stay new, got %v", names)
+	}
+}
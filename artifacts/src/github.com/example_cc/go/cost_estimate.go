@@ -0,0 +1,151 @@
+package main
+
+import (
+	"encoding/json"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// operationCost counts the state operations performed by one chaincode function.
+type operationCost struct {
+	Reads         int `json:"reads"`
+	Writes        int `json:"writes"`
+	Deletes       int `json:"deletes"`
+	CompositeKeys int `json:"compositeKeys"`
+}
+
+// stagedValue is a write or delete held back by the counting stub.
+type stagedValue struct {
+	value   []byte
+	deleted bool
+}
+
+// costCountingStub wraps the real stub for a dry run. Reads go to the ledger, while
+// writes and deletes are counted and staged in memory so later reads in the same dry run
+// see them but nothing reaches the transaction's write set. Range and rich queries are
+// passed through and do not see staged writes. Events and chaincode-to-chaincode calls
+// are suppressed.
+type costCountingStub struct {
+	shim.ChaincodeStubInterface
+	function string
+	args     []string
+	cost     operationCost
+	staged   map[string]stagedValue
+}
+
+func newCostCountingStub(stub shim.ChaincodeStubInterface, function string, args []string) *costCountingStub {
+	return &costCountingStub{
+		ChaincodeStubInterface: stub,
+		function:               function,
+		args:                   args,
+		staged:                 map[string]stagedValue{},
+	}
+}
+
+func stagedKey(collection, key string) string {
+	return collection + "\x00" + key
+}
+
+func (s *costCountingStub) GetFunctionAndParameters() (string, []string) {
+	return s.function, s.args
+}
+
+func (s *costCountingStub) GetPrivateData(collection, key string) ([]byte, error) {
+	s.cost.Reads++
+	if staged, ok := s.staged[stagedKey(collection, key)]; ok {
+		if staged.deleted {
+			return nil, nil
+		}
+		return staged.value, nil
+	}
+	return s.ChaincodeStubInterface.GetPrivateData(collection, key)
+}
+
+func (s *costCountingStub) PutPrivateData(collection string, key string, value []byte) error {
+	s.cost.Writes++
+	s.staged[stagedKey(collection, key)] = stagedValue{value: value}
+	return nil
+}
+
+func (s *costCountingStub) DelPrivateData(collection, key string) error {
+	s.cost.Deletes++
+	s.staged[stagedKey(collection, key)] = stagedValue{deleted: true}
+	return nil
+}
+
+func (s *costCountingStub) GetState(key string) ([]byte, error) {
+	s.cost.Reads++
+	if staged, ok := s.staged[stagedKey("", key)]; ok {
+		if staged.deleted {
+			return nil, nil
+		}
+		return staged.value, nil
+	}
+	return s.ChaincodeStubInterface.GetState(key)
+}
+
+func (s *costCountingStub) PutState(key string, value []byte) error {
+	s.cost.Writes++
+	s.staged[stagedKey("", key)] = stagedValue{value: value}
+	return nil
+}
+
+func (s *costCountingStub) DelState(key string) error {
+	s.cost.Deletes++
+	s.staged[stagedKey("", key)] = stagedValue{deleted: true}
+	return nil
+}
+
+func (s *costCountingStub) CreateCompositeKey(objectType string, attributes []string) (string, error) {
+	s.cost.CompositeKeys++
+	return s.ChaincodeStubInterface.CreateCompositeKey(objectType, attributes)
+}
+
+func (s *costCountingStub) SetEvent(name string, payload []byte) error {
+	return nil
+}
+
+func (s *costCountingStub) InvokeChaincode(chaincodeName string, args [][]byte, channel string) pb.Response {
+	return shim.Error("chaincode invocation is not available while estimating operation cost")
+}
+
+// ===========================================================================================
+// estimateOperationCost dry-runs another chaincode function and reports how many state
+// operations it performed. The simulated function sees the current ledger but its writes
+// are discarded, so the estimate itself is read-only. A function that fails part way is
+// still reported, with the counts up to the failure.
+// ===========================================================================================
+func (t *SimpleChaincode) estimateOperationCost(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+
+	type operationCostEstimate struct {
+		operationCost
+		Function        string `json:"function"`
+		SimulatedStatus int32  `json:"simulatedStatus"`
+		Message         string `json:"message,omitempty"`
+	}
+
+	//        0              1...
+	// "transferMarble", args...
+	if len(args) < 1 {
+		return shim.Error("Incorrect number of arguments. Expecting function name followed by its arguments")
+	}
+	if args[0] == "estimateOperationCost" {
+		return shim.Error("estimateOperationCost cannot estimate itself")
+	}
+
+	countingStub := newCostCountingStub(stub, args[0], args[1:])
+	response := t.Invoke(countingStub)
+
+	estimate := operationCostEstimate{
+		operationCost:   countingStub.cost,
+		Function:        args[0],
+		SimulatedStatus: response.Status,
+		Message:         response.Message,
+	}
+	estimateAsBytes, err := json.Marshal(estimate)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	return shim.Success(estimateAsBytes)
+}
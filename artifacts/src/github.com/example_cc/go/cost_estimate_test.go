@@ -0,0 +1,122 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+// countingTestStub counts the state operations of a real run, to check estimates
+// against.
+type countingTestStub struct {
+	*testStub
+	cost operationCost
+}
+
+func (s *countingTestStub) GetPrivateData(collection, key string) ([]byte, error) {
+	s.cost.Reads++
+	return s.testStub.GetPrivateData(collection, key)
+}
+
+func (s *countingTestStub) PutPrivateData(collection, key string, value []byte) error {
+	s.cost.Writes++
+	return s.testStub.PutPrivateData(collection, key, value)
+}
+
+func (s *countingTestStub) DelPrivateData(collection, key string) error {
+	s.cost.Deletes++
+	return s.testStub.DelPrivateData(collection, key)
+}
+
+func (s *countingTestStub) GetState(key string) ([]byte, error) {
+	s.cost.Reads++
+	return s.testStub.GetState(key)
+}
+
+func (s *countingTestStub) PutState(key string, value []byte) error {
+	s.cost.Writes++
+	return s.testStub.PutState(key, value)
+}
+
+func (s *countingTestStub) DelState(key string) error {
+	s.cost.Deletes++
+	return s.testStub.DelState(key)
+}
+
+func (s *countingTestStub) CreateCompositeKey(objectType string, attributes []string) (string, error) {
+	s.cost.CompositeKeys++
+	return s.testStub.CreateCompositeKey(objectType, attributes)
+}
+
+// snapshot copies the public and private state.
+func (s *testStub) snapshot() map[string]map[string][]byte {
+	copied := map[string]map[string][]byte{"": {}}
+	for key, value := range s.State {
+		copied[""][key] = value
+	}
+	for collection, values := range s.PvtState {
+		copied[collection] = map[string][]byte{}
+		for key, value := range values {
+			copied[collection][key] = value
+		}
+	}
+	return copied
+}
+
+func TestEstimateOperationCostOfTransferMarble(t *testing.T) {
+	s := newTestStub(t)
+	s.createMarble("marble1", "blue", 35, "Org1MSP", 99)
+	transfer := transferTo("marble1", "Org2MSP")
+
+	before := s.snapshot()
+	var estimate struct {
+		operationCost
+		Function        string `json:"function"`
+		SimulatedStatus int32  `json:"simulatedStatus"`
+	}
+	err := json.Unmarshal(s.mustInvoke("estimateOperationCost", transfer, "transferMarble"), &estimate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if estimate.Function != "transferMarble" || estimate.SimulatedStatus != 200 {
+		t.Fatalf("expected a successful simulated transfer, got %+v", estimate)
+	}
+	if !reflect.DeepEqual(s.snapshot(), before) {
+		t.Fatal("expected the estimate not to change any state")
+	}
+	if s.Events[s.TxID] != nil {
+		t.Fatal("expected the estimate not to set an event")
+	}
+
+	// the real transfer performs exactly the estimated operations
+	s.startTx(transfer, []string{"transferMarble"})
+	counter := &countingTestStub{testStub: s}
+	response := s.cc.Invoke(counter)
+	s.MockTransactionEnd(s.TxID)
+	if response.Status != 200 {
+		t.Fatalf("transfer failed: %s", response.Message)
+	}
+	if estimate.operationCost != counter.cost {
+		t.Fatalf("expected the estimate %+v to match the transfer's %+v", estimate.operationCost, counter.cost)
+	}
+	if counter.cost.Writes == 0 || counter.cost.CompositeKeys == 0 {
+		t.Fatalf("expected the transfer to write and to key its indexes, got %+v", counter.cost)
+	}
+}
+
+func TestEstimateOperationCostReportsFailures(t *testing.T) {
+	s := newTestStub(t)
+	var estimate struct {
+		operationCost
+		SimulatedStatus int32  `json:"simulatedStatus"`
+		Message         string `json:"message"`
+	}
+	err := json.Unmarshal(s.mustInvoke("estimateOperationCost", transferTo("marble1", "Org2MSP"), "transferMarble"), &estimate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if estimate.SimulatedStatus == 200 || estimate.Message != "Marble does not exist: marble1" || estimate.Reads == 0 || estimate.Writes != 0 {
+		t.Fatalf("expected a failed simulation with reads and no writes, got %+v", estimate)
+	}
+	s.mustFail("estimateOperationCost cannot estimate itself", "estimateOperationCost", nil, "estimateOperationCost")
+}
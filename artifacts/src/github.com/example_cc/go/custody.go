@@ -0,0 +1,208 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// CustodyRecord is one leg of a marble's physical delivery. A record with no
+// ReceivedTime is open: the marble has been handed over but not yet received.
+// OwnershipTransfer records are opened by every change of owner.
+type CustodyRecord struct {
+	Custodian         string `json:"custodian"`
+	HandoverTime      int64  `json:"handoverTime"`
+	ReceivedTime      int64  `json:"receivedTime"`
+	Notes             string `json:"notes"`
+	OwnershipTransfer bool   `json:"ownershipTransfer,omitempty"`
+}
+
+// openCustodyRecord returns the open custody record of a marble, or nil.
+func openCustodyRecord(m *marble) *CustodyRecord {
+	if len(m.CustodyChain) == 0 {
+		return nil
+	}
+	last := &m.CustodyChain[len(m.CustodyChain)-1]
+	if last.ReceivedTime != 0 {
+		return nil
+	}
+	return last
+}
+
+// checkNoOpenHandover fails while a handover recorded with recordHandover awaits
+// receipt. An open ownership transfer record does not hold the marble up.
+func checkNoOpenHandover(m *marble) error {
+	if open := openCustodyRecord(m); open != nil && !open.OwnershipTransfer {
+		return fmt.Errorf("marble %s has an unacknowledged handover to %s", m.Name, open.Custodian)
+	}
+	return nil
+}
+
+// appendCustodyRecord hands a marble over to a new custodian. A marble cannot be handed
+// over while a previous handover is still open. An ownership transfer record that was
+// never acknowledged is closed by the next record, so that only one record is ever open.
+func appendCustodyRecord(stub shim.ChaincodeStubInterface, m *marble, custodian, notes string, ownershipTransfer bool) error {
+	err := checkNoOpenHandover(m)
+	if err != nil {
+		return err
+	}
+	currentTime, err := getTxUnixTime(stub)
+	if err != nil {
+		return err
+	}
+	if open := openCustodyRecord(m); open != nil {
+		open.ReceivedTime = currentTime
+	}
+	m.CustodyChain = append(m.CustodyChain, CustodyRecord{
+		Custodian:         custodian,
+		HandoverTime:      currentTime,
+		Notes:             notes,
+		OwnershipTransfer: ownershipTransfer,
+	})
+	return nil
+}
+
+// ===========================================================================
+// recordHandover - hand a marble over to a new custodian
+// ===========================================================================
+func (t *SimpleChaincode) recordHandover(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	fmt.Println("- start record handover")
+
+	type handoverTransientInput struct {
+		Name      string `json:"name"`
+		Custodian string `json:"custodian"`
+		Notes     string `json:"notes"`
+	}
+
+	if len(args) != 0 {
+		return shim.Error("Incorrect number of arguments. Private marble data must be passed in transient map.")
+	}
+
+	var handoverInput handoverTransientInput
+	err := getTransientInput(stub, "marble_handover", &handoverInput)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if len(handoverInput.Name) == 0 {
+		return shim.Error("name field must be a non-empty string")
+	}
+	if len(handoverInput.Custodian) == 0 {
+		return shim.Error("custodian field must be a non-empty string")
+	}
+
+	m, err := getMarble(stub, handoverInput.Name)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = appendCustodyRecord(stub, m, handoverInput.Custodian, handoverInput.Notes, false)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = putMarble(stub, m)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	fmt.Println("- end record handover")
+	return shim.Success(nil)
+}
+
+// ===========================================================================
+// acknowledgeReceipt - the receiving custodian confirms the open handover
+// ===========================================================================
+func (t *SimpleChaincode) acknowledgeReceipt(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	fmt.Println("- start acknowledge receipt")
+
+	type custodyReceiptTransientInput struct {
+		Name string `json:"name"`
+	}
+
+	if len(args) != 0 {
+		return shim.Error("Incorrect number of arguments. Private marble data must be passed in transient map.")
+	}
+
+	var receiptInput custodyReceiptTransientInput
+	err := getTransientInput(stub, "marble_custody_receipt", &receiptInput)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if len(receiptInput.Name) == 0 {
+		return shim.Error("name field must be a non-empty string")
+	}
+
+	m, err := getMarble(stub, receiptInput.Name)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	open := openCustodyRecord(m)
+	if open == nil {
+		return shim.Error("Marble has no open custody handover: " + m.Name)
+	}
+	isCustodian, err := callerIs(stub, open.Custodian)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if !isCustodian {
+		return shim.Error("only the receiving custodian " + open.Custodian + " can acknowledge receipt")
+	}
+
+	open.ReceivedTime, err = getTxUnixTime(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = putMarble(stub, m)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	fmt.Println("- end acknowledge receipt")
+	return shim.Success(nil)
+}
+
+// ===========================================================================
+// getCustodyChain - every custody record of a marble, oldest first
+// ===========================================================================
+func (t *SimpleChaincode) getCustodyChain(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	if len(args) != 1 {
+		return shim.Error("Incorrect number of arguments. Expecting name of the marble to query")
+	}
+
+	m, err := getMarble(stub, args[0])
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	chain := m.CustodyChain
+	if chain == nil {
+		chain = []CustodyRecord{}
+	}
+	chainAsBytes, err := json.Marshal(chain)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	return shim.Success(chainAsBytes)
+}
+
+// ===========================================================================
+// getCurrentCustodian - the open custody record of a marble
+// ===========================================================================
+func (t *SimpleChaincode) getCurrentCustodian(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	if len(args) != 1 {
+		return shim.Error("Incorrect number of arguments. Expecting name of the marble to query")
+	}
+
+	m, err := getMarble(stub, args[0])
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	open := openCustodyRecord(m)
+	if open == nil {
+		return shim.Error("Marble has no open custody handover: " + m.Name)
+	}
+	recordAsBytes, err := json.Marshal(open)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	return shim.Success(recordAsBytes)
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func transferTo(name, owner string) map[string]interface{} {
+	return map[string]interface{}{"marble_owner": map[string]interface{}{"name": name, "owner": owner}}
+}
+
+func TestTransfersCloseOpenOwnershipRecords(t *testing.T) {
+	s := newTestStub(t)
+	s.createMarble("marble1", "blue", 35, "Org1MSP", 99)
+
+	s.mustInvoke("transferMarble", transferTo("marble1", "Org2MSP"))
+	s.setCaller("Org2MSP", "user2")
+	s.mustInvoke("transferMarble", transferTo("marble1", "Org3MSP"))
+
+	chain := s.readTestMarble("marble1").CustodyChain
+	if len(chain) != 2 {
+		t.Fatalf("expected 2 custody records, got %d", len(chain))
+	}
+	if chain[0].Custodian != "Org2MSP" || chain[0].ReceivedTime == 0 {
+		t.Fatalf("expected the first ownership record to be closed, got %+v", chain[0])
+	}
+	if chain[1].Custodian != "Org3MSP" || chain[1].ReceivedTime != 0 || !chain[1].OwnershipTransfer {
+		t.Fatalf("expected an open ownership record for Org3MSP, got %+v", chain[1])
+	}
+}
+
+func TestOpenHandoverBlocksSecondHandoverAndTransfer(t *testing.T) {
+	s := newTestStub(t)
+	s.createMarble("marble1", "blue", 35, "Org1MSP", 99)
+	handover := func(custodian string) map[string]interface{} {
+		return map[string]interface{}{"marble_handover": map[string]interface{}{"name": "marble1", "custodian": custodian}}
+	}
+
+	s.mustInvoke("recordHandover", handover("courier"))
+	s.mustFail("unacknowledged handover to courier", "recordHandover", handover("warehouse"))
+	s.mustFail("unacknowledged handover to courier", "transferMarble", transferTo("marble1", "Org2MSP"))
+
+	current := s.mustInvoke("getCurrentCustodian", nil, "marble1")
+	var open CustodyRecord
+	err := json.Unmarshal(current, &open)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if open.Custodian != "courier" {
+		t.Fatalf("expected courier to be the current custodian, got %s", open.Custodian)
+	}
+
+	s.mustFail("only the receiving custodian", "acknowledgeReceipt", map[string]interface{}{"marble_custody_receipt": map[string]interface{}{"name": "marble1"}})
+	s.setCaller("Org1MSP", "courier")
+	s.mustInvoke("acknowledgeReceipt", map[string]interface{}{"marble_custody_receipt": map[string]interface{}{"name": "marble1"}})
+	s.mustInvoke("transferMarble", transferTo("marble1", "Org2MSP"))
+
+	chain := s.readTestMarble("marble1").CustodyChain
+	if len(chain) != 2 || chain[0].ReceivedTime == 0 || chain[1].Custodian != "Org2MSP" {
+		t.Fatalf("unexpected custody chain %+v", chain)
+	}
+}
@@ -0,0 +1,354 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// States of a data bounty.
+const (
+	bountyStatusOpen     = "open"
+	bountyStatusApproved = "approved"
+	bountyStatusRejected = "rejected"
+)
+
+// Bounty is a report of inconsistent marble data. An approved report earns its
+// reporter Reward loyalty points. It is kept in collectionMarbleBounties under the ID
+// of the transaction that reported it.
+type Bounty struct {
+	ObjectType    string `json:"docType"`
+	BountyID      string `json:"bountyID"`
+	ReporterMSPID string `json:"reporterMSPID"`
+	MarbleName    string `json:"marbleName"`
+	IssueType     string `json:"issueType"`
+	EvidenceHash  string `json:"evidenceHash"`
+	Status        string `json:"status"`
+	Reward        int    `json:"reward"`
+}
+
+func getBounty(stub shim.ChaincodeStubInterface, bountyID string) (*Bounty, error) {
+	bountyAsBytes, err := stub.GetPrivateData("collectionMarbleBounties", bountyID)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get bounty: %s", err.Error())
+	} else if bountyAsBytes == nil {
+		return nil, fmt.Errorf("Bounty does not exist: %s", bountyID)
+	}
+
+	bounty := &Bounty{}
+	err = json.Unmarshal(bountyAsBytes, bounty)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to decode JSON of: %s", string(bountyAsBytes))
+	}
+	return bounty, nil
+}
+
+func putBounty(stub shim.ChaincodeStubInterface, bounty *Bounty) error {
+	bountyAsBytes, err := json.Marshal(bounty)
+	if err != nil {
+		return err
+	}
+	return stub.PutPrivateData("collectionMarbleBounties", bounty.BountyID, bountyAsBytes)
+}
+
+// queryBounties scans collectionMarbleBounties for the bounties accepted by match.
+func queryBounties(stub shim.ChaincodeStubInterface, match func(*Bounty) bool) ([]Bounty, error) {
+	resultsIterator, err := stub.GetPrivateDataByRange("collectionMarbleBounties", "", "")
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	bounties := []Bounty{}
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+		var bounty Bounty
+		err = json.Unmarshal(queryResponse.Value, &bounty)
+		if err != nil {
+			return nil, err
+		}
+		if match(&bounty) {
+			bounties = append(bounties, bounty)
+		}
+	}
+	return bounties, nil
+}
+
+// restoreIndexEntry writes an index entry that is missing and reports whether it did.
+func restoreIndexEntry(stub shim.ChaincodeStubInterface, indexKey string) (bool, error) {
+	entry, err := stub.GetPrivateData("collectionMarbles", indexKey)
+	if err != nil {
+		return false, err
+	}
+	if entry != nil {
+		return false, nil
+	}
+	return true, stub.PutPrivateData("collectionMarbles", indexKey, []byte{0x00})
+}
+
+// fixConsistencyIssues restores the missing index entries of a marble and returns the
+// names of the indexes it repaired.
+func fixConsistencyIssues(stub shim.ChaincodeStubInterface, marbleName string) ([]string, error) {
+	m, err := getMarble(stub, marbleName)
+	if err != nil {
+		return nil, err
+	}
+
+	colorKey, err := stub.CreateCompositeKey("color~name", []string{m.Color, m.Name})
+	if err != nil {
+		return nil, err
+	}
+	ownerKey, err := stub.CreateCompositeKey(ownerNameIndexName, []string{m.Owner, m.Name})
+	if err != nil {
+		return nil, err
+	}
+	sizeKey, err := sizeIndexKey(stub, m)
+	if err != nil {
+		return nil, err
+	}
+	conditionKey, err := conditionIndexKey(stub, m)
+	if err != nil {
+		return nil, err
+	}
+	rarityKey, err := rarityIndexKey(stub, m)
+	if err != nil {
+		return nil, err
+	}
+	indexNames := []string{"color~name", ownerNameIndexName, sizeIndexName, conditionIndexName, rarityIndexName}
+	indexKeys := []string{colorKey, ownerKey, sizeKey, conditionKey, rarityKey}
+	if len(m.Series) != 0 {
+		seriesKey, err := stub.CreateCompositeKey(seriesIndexName, []string{m.Series, m.Name})
+		if err != nil {
+			return nil, err
+		}
+		indexNames = append(indexNames, seriesIndexName)
+		indexKeys = append(indexKeys, seriesKey)
+	}
+	if m.IsForSale {
+		listingKey, err := listingIndexKey(stub, m)
+		if err != nil {
+			return nil, err
+		}
+		indexNames = append(indexNames, listingIndexName)
+		indexKeys = append(indexKeys, listingKey)
+	}
+
+	fixed := []string{}
+	for i, indexKey := range indexKeys {
+		restored, err := restoreIndexEntry(stub, indexKey)
+		if err != nil {
+			return nil, err
+		}
+		if restored {
+			fixed = append(fixed, indexNames[i])
+		}
+	}
+	return fixed, nil
+}
+
+// ===========================================================================
+// reportDataIssue - report inconsistent data of a marble for a bounty
+// ===========================================================================
+func (t *SimpleChaincode) reportDataIssue(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	fmt.Println("- start report data issue")
+
+	type dataIssueTransientInput struct {
+		MarbleName   string `json:"marbleName"`
+		IssueType    string `json:"issueType"`
+		EvidenceHash string `json:"evidenceHash"`
+	}
+
+	if len(args) != 0 {
+		return shim.Error("Incorrect number of arguments. Private marble data must be passed in transient map.")
+	}
+
+	var issueInput dataIssueTransientInput
+	err := getTransientInput(stub, "data_issue", &issueInput)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if len(issueInput.MarbleName) == 0 {
+		return shim.Error("marbleName field must be a non-empty string")
+	}
+	if len(issueInput.IssueType) == 0 {
+		return shim.Error("issueType field must be a non-empty string")
+	}
+	if len(issueInput.EvidenceHash) == 0 {
+		return shim.Error("evidenceHash field must be a non-empty string")
+	}
+
+	reporterMSPID, err := getCallerMSPID(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	bounty := &Bounty{
+		ObjectType:    "bounty",
+		BountyID:      stub.GetTxID(),
+		ReporterMSPID: reporterMSPID,
+		MarbleName:    issueInput.MarbleName,
+		IssueType:     issueInput.IssueType,
+		EvidenceHash:  issueInput.EvidenceHash,
+		Status:        bountyStatusOpen,
+	}
+	err = putBounty(stub, bounty)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	fmt.Println("- end report data issue")
+	return shim.Success([]byte(bounty.BountyID))
+}
+
+// openBounty returns a bounty for the admin to decide on.
+func openBounty(stub shim.ChaincodeStubInterface, bountyID string) (*Bounty, error) {
+	err := requireAdmin(stub)
+	if err != nil {
+		return nil, err
+	}
+	bounty, err := getBounty(stub, bountyID)
+	if err != nil {
+		return nil, err
+	}
+	if bounty.Status != bountyStatusOpen {
+		return nil, fmt.Errorf("bounty %s is already %s", bounty.BountyID, bounty.Status)
+	}
+	return bounty, nil
+}
+
+// ===========================================================================
+// approveDataBounty - admin approval of a data issue report. The reporter is
+// rewarded and the marble's indexes are repaired.
+// ===========================================================================
+func (t *SimpleChaincode) approveDataBounty(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+
+	//      0         1
+	// "bountyID", "50"
+	if len(args) != 2 {
+		return shim.Error("Incorrect number of arguments. Expecting 2")
+	}
+	rewardPoints, err := strconv.Atoi(args[1])
+	if err != nil || rewardPoints < 0 {
+		return shim.Error("rewardPoints must be a non-negative integer")
+	}
+
+	bounty, err := openBounty(stub, args[0])
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = creditLoyaltyPoints(stub, bounty.ReporterMSPID, rewardPoints)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	fixed, err := fixConsistencyIssues(stub, bounty.MarbleName)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	bounty.Status = bountyStatusApproved
+	bounty.Reward = rewardPoints
+	err = putBounty(stub, bounty)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	fixedAsBytes, err := json.Marshal(fixed)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	return shim.Success([]byte(fmt.Sprintf("{\"bountyID\":%q,\"reward\":%d,\"fixedIndexes\":%s}", bounty.BountyID, bounty.Reward, fixedAsBytes)))
+}
+
+// ===========================================================================
+// rejectBounty - admin closure of a data issue report without reward
+// ===========================================================================
+func (t *SimpleChaincode) rejectBounty(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+
+	//      0
+	// "bountyID"
+	if len(args) != 1 {
+		return shim.Error("Incorrect number of arguments. Expecting bounty ID")
+	}
+
+	bounty, err := openBounty(stub, args[0])
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	bounty.Status = bountyStatusRejected
+	err = putBounty(stub, bounty)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	return shim.Success(nil)
+}
+
+// ===========================================================================
+// getOpenBounties - list the reports awaiting a decision
+// ===========================================================================
+func (t *SimpleChaincode) getOpenBounties(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	if len(args) != 0 {
+		return shim.Error("Incorrect number of arguments. Expecting 0")
+	}
+
+	bounties, err := queryBounties(stub, func(b *Bounty) bool {
+		return b.Status == bountyStatusOpen
+	})
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	bountiesAsBytes, err := json.Marshal(bounties)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	return shim.Success(bountiesAsBytes)
+}
+
+// ===========================================================================
+// getBountiesByReporter - list the reports of an organization
+// ===========================================================================
+func (t *SimpleChaincode) getBountiesByReporter(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+
+	//     0
+	// "Org1MSP"
+	if len(args) != 1 {
+		return shim.Error("Incorrect number of arguments. Expecting MSP ID of the reporter to query")
+	}
+
+	bounties, err := queryBounties(stub, func(b *Bounty) bool {
+		return b.ReporterMSPID == args[0]
+	})
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	bountiesAsBytes, err := json.Marshal(bounties)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	return shim.Success(bountiesAsBytes)
+}
+
+// ===========================================================================
+// getTotalBountyPayouts - the loyalty points awarded for approved reports
+// ===========================================================================
+func (t *SimpleChaincode) getTotalBountyPayouts(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	if len(args) != 0 {
+		return shim.Error("Incorrect number of arguments. Expecting 0")
+	}
+
+	bounties, err := queryBounties(stub, func(b *Bounty) bool {
+		return b.Status == bountyStatusApproved
+	})
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	total := 0
+	for _, bounty := range bounties {
+		total += bounty.Reward
+	}
+	return shim.Success([]byte(fmt.Sprintf("{\"totalPayouts\":%d}", total)))
+}
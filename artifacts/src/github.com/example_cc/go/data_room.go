@@ -0,0 +1,275 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// dataRoomIndexName indexes data rooms by the marble they discuss.
+const dataRoomIndexName = "marble~room"
+
+// DataRoom holds notes about a marble shared between two organizations only. It is
+// kept in collectionMarbleDataRooms under the ID of the transaction that created it.
+type DataRoom struct {
+	ObjectType string `json:"docType"`
+	RoomID     string `json:"roomID"`
+	MarbleName string `json:"marbleName"`
+	PartyA     string `json:"partyA"`
+	PartyB     string `json:"partyB"`
+	Notes      string `json:"notes,omitempty"`
+	SharedAt   string `json:"sharedAt"`
+}
+
+func getDataRoom(stub shim.ChaincodeStubInterface, roomID string) (*DataRoom, error) {
+	roomAsBytes, err := stub.GetPrivateData("collectionMarbleDataRooms", roomID)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get data room: %s", err.Error())
+	} else if roomAsBytes == nil {
+		return nil, fmt.Errorf("Data room does not exist: %s", roomID)
+	}
+
+	room := &DataRoom{}
+	err = json.Unmarshal(roomAsBytes, room)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to decode JSON of: %s", string(roomAsBytes))
+	}
+	return room, nil
+}
+
+func putDataRoom(stub shim.ChaincodeStubInterface, room *DataRoom) error {
+	roomAsBytes, err := json.Marshal(room)
+	if err != nil {
+		return err
+	}
+	return stub.PutPrivateData("collectionMarbleDataRooms", room.RoomID, roomAsBytes)
+}
+
+// requireDataRoomParty fails unless the caller's MSP is one of the two parties of the room.
+func requireDataRoomParty(stub shim.ChaincodeStubInterface, room *DataRoom) error {
+	callerMSPID, err := getCallerMSPID(stub)
+	if err != nil {
+		return err
+	}
+	if callerMSPID != room.PartyA && callerMSPID != room.PartyB {
+		return fmt.Errorf("organization %s is not a party to data room %s", callerMSPID, room.RoomID)
+	}
+	return nil
+}
+
+// ===========================================================================
+// createDataRoom - open a data room about a marble between two organizations.
+// The caller must be one of them.
+// ===========================================================================
+func (t *SimpleChaincode) createDataRoom(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	fmt.Println("- start create data room")
+
+	type dataRoomTransientInput struct {
+		MarbleName string `json:"marbleName"`
+		PartyA     string `json:"partyA"`
+		PartyB     string `json:"partyB"`
+		Notes      string `json:"notes"`
+	}
+
+	if len(args) != 0 {
+		return shim.Error("Incorrect number of arguments. Private marble data must be passed in transient map.")
+	}
+
+	var roomInput dataRoomTransientInput
+	err := getTransientInput(stub, "data_room", &roomInput)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if len(roomInput.MarbleName) == 0 {
+		return shim.Error("marbleName field must be a non-empty string")
+	}
+	if len(roomInput.PartyA) == 0 || len(roomInput.PartyB) == 0 {
+		return shim.Error("partyA and partyB fields must be non-empty strings")
+	}
+	if roomInput.PartyA == roomInput.PartyB {
+		return shim.Error("partyA and partyB must be different organizations")
+	}
+
+	_, err = getMarble(stub, roomInput.MarbleName)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	txTime, err := getTxTime(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	room := &DataRoom{
+		ObjectType: "dataRoom",
+		RoomID:     stub.GetTxID(),
+		MarbleName: roomInput.MarbleName,
+		PartyA:     roomInput.PartyA,
+		PartyB:     roomInput.PartyB,
+		Notes:      roomInput.Notes,
+		SharedAt:   txTime.Format(time.RFC3339),
+	}
+	err = requireDataRoomParty(stub, room)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = putDataRoom(stub, room)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	indexKey, err := stub.CreateCompositeKey(dataRoomIndexName, []string{room.MarbleName, room.RoomID})
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = stub.PutPrivateData("collectionMarbleDataRooms", indexKey, []byte{0x00})
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	fmt.Println("- end create data room")
+	return shim.Success([]byte(room.RoomID))
+}
+
+// ===========================================================================
+// readDataRoom - read a data room, including its notes, as one of its parties
+// ===========================================================================
+func (t *SimpleChaincode) readDataRoom(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+
+	//    0
+	// "roomID"
+	if len(args) != 1 {
+		return shim.Error("Incorrect number of arguments. Expecting data room ID")
+	}
+
+	room, err := getDataRoom(stub, args[0])
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = requireDataRoomParty(stub, room)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	roomAsBytes, err := json.Marshal(room)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	return shim.Success(roomAsBytes)
+}
+
+// ===========================================================================
+// updateDataRoomNotes - replace the notes of a data room as one of its parties
+// ===========================================================================
+func (t *SimpleChaincode) updateDataRoomNotes(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+
+	//    0          1
+	// "roomID", "newNotes"
+	if len(args) != 2 {
+		return shim.Error("Incorrect number of arguments. Expecting 2")
+	}
+
+	room, err := getDataRoom(stub, args[0])
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = requireDataRoomParty(stub, room)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	txTime, err := getTxTime(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	room.Notes = args[1]
+	room.SharedAt = txTime.Format(time.RFC3339)
+	err = putDataRoom(stub, room)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	return shim.Success(nil)
+}
+
+// ===========================================================================
+// closeDataRoom - delete a data room as one of its parties
+// ===========================================================================
+func (t *SimpleChaincode) closeDataRoom(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+
+	//    0
+	// "roomID"
+	if len(args) != 1 {
+		return shim.Error("Incorrect number of arguments. Expecting data room ID")
+	}
+
+	room, err := getDataRoom(stub, args[0])
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = requireDataRoomParty(stub, room)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	err = stub.DelPrivateData("collectionMarbleDataRooms", room.RoomID)
+	if err != nil {
+		return shim.Error("Failed to delete state:" + err.Error())
+	}
+	indexKey, err := stub.CreateCompositeKey(dataRoomIndexName, []string{room.MarbleName, room.RoomID})
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = stub.DelPrivateData("collectionMarbleDataRooms", indexKey)
+	if err != nil {
+		return shim.Error("Failed to delete state:" + err.Error())
+	}
+	return shim.Success(nil)
+}
+
+// ===========================================================================
+// listDataRoomsByMarble - admin listing of the data rooms about a marble,
+// without their notes
+// ===========================================================================
+func (t *SimpleChaincode) listDataRoomsByMarble(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+
+	//   0
+	// "marble1"
+	if len(args) != 1 {
+		return shim.Error("Incorrect number of arguments. Expecting name of the marble to query")
+	}
+
+	err := requireAdmin(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	resultsIterator, err := stub.GetPrivateDataByPartialCompositeKey("collectionMarbleDataRooms", dataRoomIndexName, []string{args[0]})
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	defer resultsIterator.Close()
+
+	rooms := []DataRoom{}
+	for resultsIterator.HasNext() {
+		responseRange, err := resultsIterator.Next()
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		_, compositeKeyParts, err := stub.SplitCompositeKey(responseRange.Key)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		room, err := getDataRoom(stub, compositeKeyParts[1])
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		room.Notes = ""
+		rooms = append(rooms, *room)
+	}
+
+	roomsAsBytes, err := json.Marshal(rooms)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	return shim.Success(roomsAsBytes)
+}
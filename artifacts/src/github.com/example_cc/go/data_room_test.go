@@ -0,0 +1,37 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestThirdOrganizationCannotReadDataRoomNotes(t *testing.T) {
+	s := newTestStub(t)
+	s.createMarble("marble1", "blue", 35, "Org1MSP", 99)
+	roomID := string(s.mustInvoke("createDataRoom", map[string]interface{}{"data_room": map[string]interface{}{
+		"marbleName": "marble1", "partyA": "Org1MSP", "partyB": "Org2MSP", "notes": "reserve is 80",
+	}}))
+
+	s.setCaller("Org2MSP", "user1")
+	var room DataRoom
+	err := json.Unmarshal(s.mustInvoke("readDataRoom", nil, roomID), &room)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if room.Notes != "reserve is 80" {
+		t.Fatalf("expected party B to read the notes, got %+v", room)
+	}
+
+	s.setCaller("Org3MSP", "user1")
+	s.mustFail("organization Org3MSP is not a party to data room "+roomID, "readDataRoom", nil, roomID)
+	s.mustFail("organization Org3MSP is not a party to data room "+roomID, "updateDataRoomNotes", nil, roomID, "")
+	s.mustFail("organization Org3MSP is not a party to data room "+roomID, "closeDataRoom", nil, roomID)
+
+	// the admin listing shows that the room exists, but not what it says
+	s.setCaller("Org1MSP", "admin")
+	listing := string(s.mustInvoke("listDataRoomsByMarble", nil, "marble1"))
+	if !strings.Contains(listing, roomID) || strings.Contains(listing, "reserve is 80") {
+		t.Fatalf("expected the listing to name the room without its notes, got %s", listing)
+	}
+}
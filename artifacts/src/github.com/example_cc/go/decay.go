@@ -0,0 +1,163 @@
+package main
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// DecayPolicy makes idle marbles lose value: a marble's price falls by
+// DecayRatePerThousandSeconds, compounded, for every thousand seconds since its last
+// activity.
+type DecayPolicy struct {
+	DecayRatePerThousandSeconds float64 `json:"decayRatePerThousandSeconds"`
+}
+
+// decayedValue is the value of a marble priced at price after it has been idle until
+// currentTime.
+func (p *DecayPolicy) decayedValue(m *marble, price int64, currentTime int64) int64 {
+	if m.DecayImmune || p.DecayRatePerThousandSeconds == 0 || currentTime <= m.LastActivityTime {
+		return price
+	}
+	periods := float64(currentTime-m.LastActivityTime) / 1000
+	return int64(float64(price) * math.Pow(1-p.DecayRatePerThousandSeconds, periods))
+}
+
+// computeMarbleDecay returns a marble with its current and decayed price.
+func computeMarbleDecay(stub shim.ChaincodeStubInterface, gov *governance, name string) (*marble, int64, int64, error) {
+	m, err := getMarble(stub, name)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	details, err := getMarblePrivateDetails(stub, name)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	currentTime, err := getTxUnixTime(stub)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	return m, details.Price, gov.DecayPolicy.decayedValue(m, details.Price, currentTime), nil
+}
+
+// ===========================================================================
+// computeDecayedValue - what a marble's price would be after decay, without
+// writing it
+// ===========================================================================
+func (t *SimpleChaincode) computeDecayedValue(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+
+	//   0
+	// "marble1"
+	if len(args) != 1 {
+		return shim.Error("Incorrect number of arguments. Expecting name of the marble to query")
+	}
+
+	gov, err := getGovernance(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	m, price, decayed, err := computeMarbleDecay(stub, gov, args[0])
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	return shim.Success([]byte(fmt.Sprintf("{\"marbleName\":%q,\"price\":%d,\"decayedValue\":%d,\"lastActivityTime\":%d}",
+		m.Name, price, decayed, m.LastActivityTime)))
+}
+
+// ===========================================================================
+// applyDecay - write a marble's decayed value as its new price. The price
+// never decays below the governance minimum price.
+// ===========================================================================
+func (t *SimpleChaincode) applyDecay(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+
+	//   0
+	// "marble1"
+	if len(args) != 1 {
+		return shim.Error("Incorrect number of arguments. Expecting name of the marble")
+	}
+
+	gov, err := getGovernance(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	m, price, decayed, err := computeMarbleDecay(stub, gov, args[0])
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if decayed < int64(gov.PricePolicy.MinPrice) {
+		decayed = int64(gov.PricePolicy.MinPrice)
+	}
+	if decayed == price {
+		return shim.Success([]byte(fmt.Sprintf("{\"marbleName\":%q,\"price\":%d}", m.Name, price)))
+	}
+
+	err = setMarblePrice(stub, m, decayed)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	// writing the marble restarts its idle period, so decay is never applied twice
+	err = putMarble(stub, m)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	return shim.Success([]byte(fmt.Sprintf("{\"marbleName\":%q,\"price\":%d}", m.Name, decayed)))
+}
+
+// ===========================================================================
+// getDecayedPortfolioValue - sum of the decayed values of an owner's marbles
+// ===========================================================================
+func (t *SimpleChaincode) getDecayedPortfolioValue(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+
+	//   0
+	// "bob"
+	if len(args) != 1 {
+		return shim.Error("Incorrect number of arguments. Expecting owner to query")
+	}
+
+	gov, err := getGovernance(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	owned, err := getOwnedMarbles(stub, args[0])
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	total := int64(0)
+	for _, m := range owned {
+		_, _, decayed, err := computeMarbleDecay(stub, gov, m.Name)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		total += decayed
+	}
+	return shim.Success([]byte(fmt.Sprintf("{\"owner\":%q,\"decayedValue\":%d}", args[0], total)))
+}
+
+// ===========================================================================
+// setDecayImmunity - admin exemption of a marble from decay
+// ===========================================================================
+func (t *SimpleChaincode) setDecayImmunity(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+
+	//   0
+	// "marble1"
+	if len(args) != 1 {
+		return shim.Error("Incorrect number of arguments. Expecting name of the marble")
+	}
+
+	err := requireAdmin(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	m, err := getMarble(stub, args[0])
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	m.DecayImmune = true
+	err = putMarble(stub, m)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	return shim.Success(nil)
+}
@@ -0,0 +1,268 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// deferredTransferLock is the LockedBy value of a marble with a scheduled transfer.
+// Since a locked marble cannot be scheduled again, a marble has at most one pending
+// deferred transfer.
+const deferredTransferLock = "deferredTransfer"
+
+// DeferredTransfer moves a marble to NewOwner once ExecuteAtTime is reached. The owner
+// may cancel it before CancelDeadline. It is kept in collectionMarbleDeferredTransfers
+// under the ID of the transaction that scheduled it.
+type DeferredTransfer struct {
+	ObjectType     string `json:"docType"`
+	TransferID     string `json:"transferID"`
+	MarbleName     string `json:"marbleName"`
+	NewOwner       string `json:"newOwner"`
+	ExecuteAtTime  int64  `json:"executeAtTime"`
+	CancelDeadline int64  `json:"cancelDeadline"`
+}
+
+func getDeferredTransfer(stub shim.ChaincodeStubInterface, transferID string) (*DeferredTransfer, error) {
+	transferAsBytes, err := stub.GetPrivateData("collectionMarbleDeferredTransfers", transferID)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get deferred transfer: %s", err.Error())
+	} else if transferAsBytes == nil {
+		return nil, fmt.Errorf("Deferred transfer does not exist: %s", transferID)
+	}
+
+	transfer := &DeferredTransfer{}
+	err = json.Unmarshal(transferAsBytes, transfer)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to decode JSON of: %s", string(transferAsBytes))
+	}
+	return transfer, nil
+}
+
+// closeDeferredTransfer removes a deferred transfer and unlocks its marble. The caller
+// is responsible for writing the marble back.
+func closeDeferredTransfer(stub shim.ChaincodeStubInterface, transfer *DeferredTransfer, m *marble) error {
+	err := stub.DelPrivateData("collectionMarbleDeferredTransfers", transfer.TransferID)
+	if err != nil {
+		return err
+	}
+	m.LockedBy = ""
+	return nil
+}
+
+// ===========================================================================
+// scheduleDeferredTransfer - lock an owned marble until a transfer to a new
+// owner can be executed
+// ===========================================================================
+func (t *SimpleChaincode) scheduleDeferredTransfer(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	fmt.Println("- start schedule deferred transfer")
+
+	type deferredTransferTransientInput struct {
+		MarbleName     string `json:"marbleName"`
+		NewOwner       string `json:"newOwner"`
+		ExecuteAtTime  int64  `json:"executeAtTime"`
+		CancelDeadline int64  `json:"cancelDeadline"`
+	}
+
+	if len(args) != 0 {
+		return shim.Error("Incorrect number of arguments. Private marble data must be passed in transient map.")
+	}
+
+	var transferInput deferredTransferTransientInput
+	err := getTransientInput(stub, "deferred_transfer", &transferInput)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if len(transferInput.MarbleName) == 0 {
+		return shim.Error("marbleName field must be a non-empty string")
+	}
+	if len(transferInput.NewOwner) == 0 {
+		return shim.Error("newOwner field must be a non-empty string")
+	}
+
+	currentTime, err := getTxUnixTime(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if transferInput.ExecuteAtTime <= currentTime {
+		return shim.Error("executeAtTime field must be a future time")
+	}
+	if transferInput.CancelDeadline < currentTime || transferInput.CancelDeadline > transferInput.ExecuteAtTime {
+		return shim.Error("cancelDeadline field must be between the current time and executeAtTime")
+	}
+
+	m, err := getMarble(stub, transferInput.MarbleName)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = requireOwner(stub, m)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = checkNotLocked(m)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if m.Owner == transferInput.NewOwner {
+		return shim.Error("Marble is already owned by " + m.Owner)
+	}
+
+	transfer := &DeferredTransfer{
+		ObjectType:     "deferredTransfer",
+		TransferID:     stub.GetTxID(),
+		MarbleName:     m.Name,
+		NewOwner:       transferInput.NewOwner,
+		ExecuteAtTime:  transferInput.ExecuteAtTime,
+		CancelDeadline: transferInput.CancelDeadline,
+	}
+	transferAsBytes, err := json.Marshal(transfer)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = stub.PutPrivateData("collectionMarbleDeferredTransfers", transfer.TransferID, transferAsBytes)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	m.LockedBy = deferredTransferLock
+	err = putMarble(stub, m)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	fmt.Println("- end schedule deferred transfer")
+	return shim.Success([]byte(transfer.TransferID))
+}
+
+// ===========================================================================
+// executeDeferredTransfer - carry out a due deferred transfer. Anyone may
+// call it once the execution time is reached.
+// ===========================================================================
+func (t *SimpleChaincode) executeDeferredTransfer(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	fmt.Println("- start execute deferred transfer")
+
+	//     0
+	// "transferID"
+	if len(args) != 1 {
+		return shim.Error("Incorrect number of arguments. Expecting deferred transfer ID")
+	}
+
+	transfer, err := getDeferredTransfer(stub, args[0])
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	currentTime, err := getTxUnixTime(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if currentTime < transfer.ExecuteAtTime {
+		return shim.Error(fmt.Sprintf("Deferred transfer %s is not due before %d", transfer.TransferID, transfer.ExecuteAtTime))
+	}
+
+	m, err := getMarble(stub, transfer.MarbleName)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = closeDeferredTransfer(stub, transfer, m)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = changeMarbleOwner(stub, m, transfer.NewOwner)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = putMarble(stub, m)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	fmt.Println("- end execute deferred transfer")
+	return shim.Success(nil)
+}
+
+// ===========================================================================
+// cancelDeferredTransfer - owner cancellation of a deferred transfer before
+// its cancel deadline
+// ===========================================================================
+func (t *SimpleChaincode) cancelDeferredTransfer(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+
+	//     0
+	// "transferID"
+	if len(args) != 1 {
+		return shim.Error("Incorrect number of arguments. Expecting deferred transfer ID")
+	}
+
+	transfer, err := getDeferredTransfer(stub, args[0])
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	currentTime, err := getTxUnixTime(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if currentTime >= transfer.CancelDeadline {
+		return shim.Error("The cancel deadline of deferred transfer " + transfer.TransferID + " has passed")
+	}
+
+	m, err := getMarble(stub, transfer.MarbleName)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = requireOwner(stub, m)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = closeDeferredTransfer(stub, transfer, m)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = putMarble(stub, m)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	return shim.Success(nil)
+}
+
+// ===========================================================================
+// listDueDeferredTransfers - deferred transfers ready to be executed
+// ===========================================================================
+func (t *SimpleChaincode) listDueDeferredTransfers(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	if len(args) != 0 {
+		return shim.Error("Incorrect number of arguments. Expecting 0")
+	}
+
+	currentTime, err := getTxUnixTime(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	resultsIterator, err := stub.GetPrivateDataByRange("collectionMarbleDeferredTransfers", "", "")
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	defer resultsIterator.Close()
+
+	due := []DeferredTransfer{}
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		var transfer DeferredTransfer
+		err = json.Unmarshal(queryResponse.Value, &transfer)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		if transfer.ExecuteAtTime <= currentTime {
+			due = append(due, transfer)
+		}
+	}
+
+	dueAsBytes, err := json.Marshal(due)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	return shim.Success(dueAsBytes)
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"unicode/utf8"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// maxDescriptionRunes caps the length of a marble description, in characters rather
+// than bytes.
+const maxDescriptionRunes = 500
+
+// checkDescription fails when description is longer than maxDescriptionRunes characters
+// or is not valid UTF-8.
+func checkDescription(description string) error {
+	if !utf8.ValidString(description) {
+		return fmt.Errorf("description must be valid UTF-8")
+	}
+	if utf8.RuneCountInString(description) > maxDescriptionRunes {
+		return fmt.Errorf("description must be at most %d characters", maxDescriptionRunes)
+	}
+	return nil
+}
+
+// descriptionEvent is the payload of the DescriptionUpdated event.
+type descriptionEvent struct {
+	MarbleName  string `json:"marbleName"`
+	Description string `json:"description"`
+}
+
+// ===========================================================================
+// updateMarbleDescription - owner update of a marble's description. An empty
+// description clears it.
+// ===========================================================================
+func (t *SimpleChaincode) updateMarbleDescription(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	fmt.Println("- start update marble description")
+
+	type marbleDescriptionTransientInput struct {
+		Name        string `json:"name"`
+		Description string `json:"description"`
+	}
+
+	if len(args) != 0 {
+		return shim.Error("Incorrect number of arguments. Private marble data must be passed in transient map.")
+	}
+
+	var descriptionInput marbleDescriptionTransientInput
+	err := getTransientInput(stub, "marble_description", &descriptionInput)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if len(descriptionInput.Name) == 0 {
+		return shim.Error("name field must be a non-empty string")
+	}
+	err = checkDescription(descriptionInput.Description)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	m, err := getMarble(stub, descriptionInput.Name)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = requireOwner(stub, m)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	m.Description = descriptionInput.Description
+	err = putMarble(stub, m)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = emitEvent(stub, "DescriptionUpdated", descriptionEvent{MarbleName: m.Name, Description: m.Description})
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	fmt.Println("- end update marble description")
+	return shim.Success(nil)
+}
@@ -0,0 +1,350 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// States of a dispute.
+const (
+	disputeStatusOpen      = "open"
+	disputeStatusResolved  = "resolved"
+	disputeStatusWithdrawn = "withdrawn"
+)
+
+// Dispute is a complaint about a marble against its owner, kept in
+// collectionMarbleDisputes under the ID of the transaction that opened it.
+type Dispute struct {
+	ObjectType       string `json:"docType"`
+	DisputeID        string `json:"disputeID"`
+	MarbleName       string `json:"marbleName"`
+	ComplainantMSPID string `json:"complainantMSPID"`
+	Respondent       string `json:"respondent"`
+	Reason           string `json:"reason"`
+	Status           string `json:"status"`
+	ComplainantWon   bool   `json:"complainantWon"`
+}
+
+// DisputeStake holds the loyalty points a complainant deposited to open a dispute. It
+// is kept in collectionMarbleDisputeStakes under the dispute ID until it is paid out.
+type DisputeStake struct {
+	ObjectType  string `json:"docType"`
+	DisputeID   string `json:"disputeID"`
+	StakerMSPID string `json:"stakerMSPID"`
+	MarbleName  string `json:"marbleName"`
+	StakeAmount int    `json:"stakeAmount"`
+	DepositTxID string `json:"depositTxID"`
+}
+
+func getDispute(stub shim.ChaincodeStubInterface, disputeID string) (*Dispute, error) {
+	disputeAsBytes, err := stub.GetPrivateData("collectionMarbleDisputes", disputeID)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get dispute: %s", err.Error())
+	} else if disputeAsBytes == nil {
+		return nil, fmt.Errorf("Dispute does not exist: %s", disputeID)
+	}
+
+	dispute := &Dispute{}
+	err = json.Unmarshal(disputeAsBytes, dispute)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to decode JSON of: %s", string(disputeAsBytes))
+	}
+	return dispute, nil
+}
+
+func putDispute(stub shim.ChaincodeStubInterface, dispute *Dispute) error {
+	disputeAsBytes, err := json.Marshal(dispute)
+	if err != nil {
+		return err
+	}
+	return stub.PutPrivateData("collectionMarbleDisputes", dispute.DisputeID, disputeAsBytes)
+}
+
+func getDisputeStake(stub shim.ChaincodeStubInterface, disputeID string) (*DisputeStake, error) {
+	stakeAsBytes, err := stub.GetPrivateData("collectionMarbleDisputeStakes", disputeID)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get dispute stake: %s", err.Error())
+	} else if stakeAsBytes == nil {
+		return nil, fmt.Errorf("No outstanding stake for dispute: %s", disputeID)
+	}
+
+	stake := &DisputeStake{}
+	err = json.Unmarshal(stakeAsBytes, stake)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to decode JSON of: %s", string(stakeAsBytes))
+	}
+	return stake, nil
+}
+
+// payOutStake credits a dispute stake to recipient and removes it.
+func payOutStake(stub shim.ChaincodeStubInterface, stake *DisputeStake, recipient string) error {
+	err := creditLoyaltyPoints(stub, recipient, stake.StakeAmount)
+	if err != nil {
+		return err
+	}
+	return stub.DelPrivateData("collectionMarbleDisputeStakes", stake.DisputeID)
+}
+
+// =================================================================================
+// openDisputeWithStake - open a dispute about a marble, staking loyalty points
+// that are lost to the owner if the dispute fails
+// =================================================================================
+func (t *SimpleChaincode) openDisputeWithStake(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	fmt.Println("- start open dispute with stake")
+
+	type disputeTransientInput struct {
+		MarbleName  string `json:"marbleName"`
+		Reason      string `json:"reason"`
+		StakeAmount int    `json:"stakeAmount"`
+	}
+
+	if len(args) != 0 {
+		return shim.Error("Incorrect number of arguments. Dispute data must be passed in transient map.")
+	}
+
+	var disputeInput disputeTransientInput
+	err := getTransientInput(stub, "dispute", &disputeInput)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if len(disputeInput.MarbleName) == 0 {
+		return shim.Error("marbleName field must be a non-empty string")
+	}
+	if len(disputeInput.Reason) == 0 {
+		return shim.Error("reason field must be a non-empty string")
+	}
+
+	gov, err := getGovernance(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if disputeInput.StakeAmount <= 0 || disputeInput.StakeAmount < gov.MinDisputeStake {
+		return shim.Error(fmt.Sprintf("stakeAmount must be a positive integer of at least %d", gov.MinDisputeStake))
+	}
+
+	m, err := getMarble(stub, disputeInput.MarbleName)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	complainantMSPID, err := getCallerMSPID(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = debitLoyaltyPoints(stub, complainantMSPID, disputeInput.StakeAmount)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	dispute := &Dispute{
+		ObjectType:       "dispute",
+		DisputeID:        stub.GetTxID(),
+		MarbleName:       m.Name,
+		ComplainantMSPID: complainantMSPID,
+		Respondent:       m.Owner,
+		Reason:           disputeInput.Reason,
+		Status:           disputeStatusOpen,
+	}
+	err = putDispute(stub, dispute)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	stakeAsBytes, err := json.Marshal(&DisputeStake{
+		ObjectType:  "disputeStake",
+		DisputeID:   dispute.DisputeID,
+		StakerMSPID: complainantMSPID,
+		MarbleName:  m.Name,
+		StakeAmount: disputeInput.StakeAmount,
+		DepositTxID: stub.GetTxID(),
+	})
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = stub.PutPrivateData("collectionMarbleDisputeStakes", dispute.DisputeID, stakeAsBytes)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	fmt.Println("- end open dispute with stake")
+	return shim.Success([]byte(dispute.DisputeID))
+}
+
+// =================================================================================
+// resolveDisputeWithSlashing - admin ruling on a dispute. The stake goes back to
+// the complainant if they won and to the respondent if they lost.
+// =================================================================================
+func (t *SimpleChaincode) resolveDisputeWithSlashing(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+
+	//      0          1
+	// "disputeID", "true"
+	if len(args) != 2 {
+		return shim.Error("Incorrect number of arguments. Expecting 2")
+	}
+	complainantWon, err := strconv.ParseBool(args[1])
+	if err != nil {
+		return shim.Error("complainantWon must be true or false")
+	}
+
+	err = requireAdmin(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	dispute, err := getDispute(stub, args[0])
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if dispute.Status != disputeStatusOpen {
+		return shim.Error("Dispute is already " + dispute.Status + ": " + dispute.DisputeID)
+	}
+	stake, err := getDisputeStake(stub, dispute.DisputeID)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	winner := dispute.Respondent
+	if complainantWon {
+		winner = dispute.ComplainantMSPID
+	}
+	err = payOutStake(stub, stake, winner)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	gov, err := getGovernance(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	loser := dispute.ComplainantMSPID
+	if complainantWon {
+		loser = dispute.Respondent
+	}
+	gov.adjustReputation(winner, disputeReputationDelta)
+	gov.adjustReputation(loser, -disputeReputationDelta)
+	err = putGovernance(stub, gov)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	dispute.Status = disputeStatusResolved
+	dispute.ComplainantWon = complainantWon
+	err = putDispute(stub, dispute)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	return shim.Success(nil)
+}
+
+// =================================================================================
+// withdrawDispute - complainant withdrawal of an open dispute
+// =================================================================================
+func (t *SimpleChaincode) withdrawDispute(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+
+	//      0
+	// "disputeID"
+	if len(args) != 1 {
+		return shim.Error("Incorrect number of arguments. Expecting dispute ID")
+	}
+
+	dispute, err := getDispute(stub, args[0])
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	callerMSPID, err := getCallerMSPID(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if callerMSPID != dispute.ComplainantMSPID {
+		return shim.Error("only the complainant can withdraw dispute " + dispute.DisputeID)
+	}
+	if dispute.Status != disputeStatusOpen {
+		return shim.Error("Dispute is already " + dispute.Status + ": " + dispute.DisputeID)
+	}
+
+	dispute.Status = disputeStatusWithdrawn
+	err = putDispute(stub, dispute)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	return shim.Success(nil)
+}
+
+// =================================================================================
+// withdrawStake - return the stake of a withdrawn dispute to the complainant
+// =================================================================================
+func (t *SimpleChaincode) withdrawStake(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+
+	//      0
+	// "disputeID"
+	if len(args) != 1 {
+		return shim.Error("Incorrect number of arguments. Expecting dispute ID")
+	}
+
+	dispute, err := getDispute(stub, args[0])
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if dispute.Status != disputeStatusWithdrawn {
+		return shim.Error("Stake can only be withdrawn from a withdrawn dispute: " + dispute.DisputeID)
+	}
+	stake, err := getDisputeStake(stub, dispute.DisputeID)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	callerMSPID, err := getCallerMSPID(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if callerMSPID != stake.StakerMSPID {
+		return shim.Error("only the staker can withdraw the stake of dispute " + dispute.DisputeID)
+	}
+
+	err = payOutStake(stub, stake, stake.StakerMSPID)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	return shim.Success(nil)
+}
+
+// =================================================================================
+// getStakesByClaimant - list the outstanding stakes of a complainant organization
+// =================================================================================
+func (t *SimpleChaincode) getStakesByClaimant(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+
+	//     0
+	// "Org1MSP"
+	if len(args) != 1 {
+		return shim.Error("Incorrect number of arguments. Expecting claimant MSP ID to query")
+	}
+
+	resultsIterator, err := stub.GetPrivateDataByRange("collectionMarbleDisputeStakes", "", "")
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	defer resultsIterator.Close()
+
+	stakes := []DisputeStake{}
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		var stake DisputeStake
+		err = json.Unmarshal(queryResponse.Value, &stake)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		if stake.StakerMSPID == args[0] {
+			stakes = append(stakes, stake)
+		}
+	}
+
+	stakesAsBytes, err := json.Marshal(stakes)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	return shim.Success(stakesAsBytes)
+}
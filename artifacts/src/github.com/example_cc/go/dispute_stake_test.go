@@ -0,0 +1,112 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func (s *testStub) loyaltyPoints(mspID string) int {
+	var account loyaltyAccount
+	err := json.Unmarshal(s.mustInvoke("getLoyaltyPoints", nil, mspID), &account)
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	return account.Points
+}
+
+func (s *testStub) stakesOf(mspID string) []DisputeStake {
+	var stakes []DisputeStake
+	err := json.Unmarshal(s.mustInvoke("getStakesByClaimant", nil, mspID), &stakes)
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	return stakes
+}
+
+func dispute(name string, stakeAmount int) map[string]interface{} {
+	return map[string]interface{}{"dispute": map[string]interface{}{"marbleName": name, "reason": "not as described", "stakeAmount": stakeAmount}}
+}
+
+// newDisputedMarble creates marble1, owned by Org1MSP, and has Org2MSP open a dispute
+// about it with a stake of 20 out of its 50 loyalty points. The caller is left as Org2MSP.
+func newDisputedMarble(t *testing.T) (*testStub, string) {
+	s := newTestStub(t)
+	s.createMarble("marble1", "blue", 35, "Org1MSP", 99)
+	s.mustInvoke("grantLoyaltyPoints", nil, "Org2MSP", "50")
+	s.setCaller("Org2MSP", "user2")
+	disputeID := string(s.mustInvoke("openDisputeWithStake", dispute("marble1", 20)))
+	return s, disputeID
+}
+
+func TestOpenDisputeRequiresMinimumStake(t *testing.T) {
+	s := newTestStub(t)
+	s.createMarble("marble1", "blue", 35, "Org1MSP", 99)
+	s.mustInvoke("grantLoyaltyPoints", nil, "Org2MSP", "15")
+	s.setCaller("Org2MSP", "user2")
+
+	s.mustFail("stakeAmount must be a positive integer of at least 10", "openDisputeWithStake", dispute("marble1", 9))
+	s.mustFail("insufficient loyalty points for Org2MSP", "openDisputeWithStake", dispute("marble1", 20))
+	if points := s.loyaltyPoints("Org2MSP"); points != 15 {
+		t.Fatalf("expected the failed disputes to leave 15 points, got %d", points)
+	}
+}
+
+func TestLostDisputeStakeGoesToRespondent(t *testing.T) {
+	s, disputeID := newDisputedMarble(t)
+	if points := s.loyaltyPoints("Org2MSP"); points != 30 {
+		t.Fatalf("expected 20 of 50 points to be staked, got %d left", points)
+	}
+	stakes := s.stakesOf("Org2MSP")
+	if len(stakes) != 1 || stakes[0].DisputeID != disputeID || stakes[0].StakeAmount != 20 || stakes[0].MarbleName != "marble1" {
+		t.Fatalf("expected the stake of dispute %s, got %+v", disputeID, stakes)
+	}
+
+	s.mustFail("is not the admin organization", "resolveDisputeWithSlashing", nil, disputeID, "false")
+	s.setCaller("Org1MSP", "admin")
+	s.mustInvoke("resolveDisputeWithSlashing", nil, disputeID, "false")
+	if points := s.loyaltyPoints("Org1MSP"); points != 20 {
+		t.Fatalf("expected the respondent to receive the stake, got %d points", points)
+	}
+	if points := s.loyaltyPoints("Org2MSP"); points != 30 {
+		t.Fatalf("expected the complainant to lose the stake, got %d points", points)
+	}
+	if stakes := s.stakesOf("Org2MSP"); len(stakes) != 0 {
+		t.Fatalf("expected no outstanding stakes, got %+v", stakes)
+	}
+
+	s.mustFail("Dispute is already resolved", "resolveDisputeWithSlashing", nil, disputeID, "true")
+	s.setCaller("Org2MSP", "user2")
+	s.mustFail("Stake can only be withdrawn from a withdrawn dispute", "withdrawStake", nil, disputeID)
+}
+
+func TestWonDisputeStakeGoesBackToComplainant(t *testing.T) {
+	s, disputeID := newDisputedMarble(t)
+	s.setCaller("Org1MSP", "admin")
+	s.mustInvoke("resolveDisputeWithSlashing", nil, disputeID, "true")
+	if points := s.loyaltyPoints("Org2MSP"); points != 50 {
+		t.Fatalf("expected the complainant to get the stake back, got %d points", points)
+	}
+	if points := s.loyaltyPoints("Org1MSP"); points != 0 {
+		t.Fatalf("expected the respondent to get nothing, got %d points", points)
+	}
+}
+
+func TestStakeOfWithdrawnDisputeCanBeWithdrawn(t *testing.T) {
+	s, disputeID := newDisputedMarble(t)
+	s.mustFail("Stake can only be withdrawn from a withdrawn dispute", "withdrawStake", nil, disputeID)
+
+	s.setCaller("Org1MSP", "admin")
+	s.mustFail("only the complainant can withdraw dispute", "withdrawDispute", nil, disputeID)
+	s.setCaller("Org2MSP", "user2")
+	s.mustInvoke("withdrawDispute", nil, disputeID)
+	s.setCaller("Org1MSP", "admin")
+	s.mustFail("Dispute is already withdrawn", "resolveDisputeWithSlashing", nil, disputeID, "true")
+	s.mustFail("only the staker can withdraw the stake", "withdrawStake", nil, disputeID)
+
+	s.setCaller("Org2MSP", "user2")
+	s.mustInvoke("withdrawStake", nil, disputeID)
+	if points := s.loyaltyPoints("Org2MSP"); points != 50 {
+		t.Fatalf("expected the stake to be returned, got %d points", points)
+	}
+	s.mustFail("No outstanding stake for dispute", "withdrawStake", nil, disputeID)
+}
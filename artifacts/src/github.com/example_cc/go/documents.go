@@ -0,0 +1,163 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// maxDocumentHashes caps the documents attached to a marble.
+const maxDocumentHashes = 10
+
+// checkDocumentHashes fails unless the hashes are valid, distinct and few enough to
+// attach to one marble.
+func checkDocumentHashes(hashes []string) error {
+	if len(hashes) > maxDocumentHashes {
+		return fmt.Errorf("a marble holds at most %d document hashes", maxDocumentHashes)
+	}
+	for i, hash := range hashes {
+		err := validateDocumentHash(hash)
+		if err != nil {
+			return err
+		}
+		if containsString(hashes[:i], hash) {
+			return fmt.Errorf("document hash %s is listed twice", hash)
+		}
+	}
+	return nil
+}
+
+// documentTransientInput names a document of a marble by its hash.
+type documentTransientInput struct {
+	Name string `json:"name"`
+	Hash string `json:"hash"`
+}
+
+func (d *documentTransientInput) validate() error {
+	if len(d.Name) == 0 {
+		return fmt.Errorf("name field must be a non-empty string")
+	}
+	return validateDocumentHash(d.Hash)
+}
+
+// ===========================================================================
+// attachDocument - owner attachment of an off-chain document to a marble by
+// its SHA-256 hash
+// ===========================================================================
+func (t *SimpleChaincode) attachDocument(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	fmt.Println("- start attach document")
+
+	if len(args) != 0 {
+		return shim.Error("Incorrect number of arguments. Private marble data must be passed in transient map.")
+	}
+
+	var documentInput documentTransientInput
+	err := getTransientInput(stub, "marble_document", &documentInput)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = documentInput.validate()
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	m, err := getMarble(stub, documentInput.Name)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = requireOwner(stub, m)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if containsString(m.DocumentHashes, documentInput.Hash) {
+		return shim.Error("Document " + documentInput.Hash + " is already attached to marble " + m.Name)
+	}
+	if len(m.DocumentHashes) >= maxDocumentHashes {
+		return shim.Error(fmt.Sprintf("Marble %s already has the maximum of %d documents", m.Name, maxDocumentHashes))
+	}
+
+	m.DocumentHashes = append(m.DocumentHashes, documentInput.Hash)
+	err = putMarble(stub, m)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	fmt.Println("- end attach document")
+	return shim.Success(nil)
+}
+
+// ===========================================================================
+// removeDocument - owner removal of a document attached to a marble
+// ===========================================================================
+func (t *SimpleChaincode) removeDocument(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	fmt.Println("- start remove document")
+
+	if len(args) != 0 {
+		return shim.Error("Incorrect number of arguments. Private marble data must be passed in transient map.")
+	}
+
+	var documentInput documentTransientInput
+	err := getTransientInput(stub, "marble_document_remove", &documentInput)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = documentInput.validate()
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	m, err := getMarble(stub, documentInput.Name)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = requireOwner(stub, m)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	kept := []string{}
+	for _, hash := range m.DocumentHashes {
+		if hash != documentInput.Hash {
+			kept = append(kept, hash)
+		}
+	}
+	if len(kept) == len(m.DocumentHashes) {
+		return shim.Error("Document " + documentInput.Hash + " is not attached to marble " + m.Name)
+	}
+	m.DocumentHashes = kept
+	err = putMarble(stub, m)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	fmt.Println("- end remove document")
+	return shim.Success(nil)
+}
+
+// ===========================================================================
+// listDocuments - the document hashes attached to a marble
+// ===========================================================================
+func (t *SimpleChaincode) listDocuments(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+
+	//   0
+	// "marble1"
+	if len(args) != 1 {
+		return shim.Error("Incorrect number of arguments. Expecting name of the marble to query")
+	}
+
+	m, err := getMarble(stub, args[0])
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	hashes := m.DocumentHashes
+	if hashes == nil {
+		hashes = []string{}
+	}
+	hashesAsBytes, err := json.Marshal(hashes)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	return shim.Success(hashesAsBytes)
+}
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// endorsementIndexName keys the endorsement records of each marble by transaction.
+const endorsementIndexName = "endorsement~name~txID"
+
+// endorsingOrgsTransientKey optionally carries a JSON array of further MSP IDs to
+// attribute a transaction to, alongside its submitter.
+const endorsingOrgsTransientKey = "endorsing_orgs"
+
+// recentEndorsementCount is the number of latest writes validateEndorsementPolicy checks.
+const recentEndorsementCount = 5
+
+// EndorsementRecord attributes a write of a marble to organizations. Chaincode cannot
+// see the endorsements of its own transaction, so EndorsingOrgs holds the submitter's
+// MSP and any MSP IDs the client passed in the endorsing_orgs transient key. Policy
+// renders them as a signature policy.
+type EndorsementRecord struct {
+	ObjectType    string   `json:"docType"`
+	TxID          string   `json:"txID"`
+	MarbleName    string   `json:"marbleName"`
+	EndorsingOrgs []string `json:"endorsingOrgs"`
+	Policy        string   `json:"policy"`
+}
+
+// endorsingOrgs returns the submitter's MSP followed by the extra organizations named in
+// the transient map, without duplicates.
+func endorsingOrgs(stub shim.ChaincodeStubInterface) ([]string, error) {
+	submitterMSPID, err := getCallerMSPID(stub)
+	if err != nil {
+		return nil, err
+	}
+	orgs := []string{submitterMSPID}
+
+	transMap, err := stub.GetTransient()
+	if err != nil {
+		return nil, fmt.Errorf("Error getting transient: %s", err.Error())
+	}
+	if len(transMap[endorsingOrgsTransientKey]) != 0 {
+		var extra []string
+		err = json.Unmarshal(transMap[endorsingOrgsTransientKey], &extra)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to decode JSON of: %s", string(transMap[endorsingOrgsTransientKey]))
+		}
+		for _, org := range extra {
+			if len(org) != 0 && !containsString(orgs, org) {
+				orgs = append(orgs, org)
+			}
+		}
+	}
+	return orgs, nil
+}
+
+// appendEndorsementRecord attributes the current transaction's write of a marble.
+func appendEndorsementRecord(stub shim.ChaincodeStubInterface, marbleName string) error {
+	orgs, err := endorsingOrgs(stub)
+	if err != nil {
+		return err
+	}
+	principals := make([]string, len(orgs))
+	for i, org := range orgs {
+		principals[i] = "'" + org + ".peer'"
+	}
+
+	record := &EndorsementRecord{
+		ObjectType:    "endorsementRecord",
+		TxID:          stub.GetTxID(),
+		MarbleName:    marbleName,
+		EndorsingOrgs: orgs,
+		Policy:        "AND(" + strings.Join(principals, ",") + ")",
+	}
+	recordAsBytes, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	recordKey, err := stub.CreateCompositeKey(endorsementIndexName, []string{record.MarbleName, record.TxID})
+	if err != nil {
+		return err
+	}
+	return stub.PutPrivateData("collectionMarbleEndorsements", recordKey, recordAsBytes)
+}
+
+// queryEndorsementRecords returns the endorsement records under a partial key, in key
+// order, accepted by match.
+func queryEndorsementRecords(stub shim.ChaincodeStubInterface, attributes []string, match func(*EndorsementRecord) bool) ([]EndorsementRecord, error) {
+	resultsIterator, err := stub.GetPrivateDataByPartialCompositeKey("collectionMarbleEndorsements", endorsementIndexName, attributes)
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	records := []EndorsementRecord{}
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+		var record EndorsementRecord
+		err = json.Unmarshal(queryResponse.Value, &record)
+		if err != nil {
+			return nil, err
+		}
+		if match(&record) {
+			records = append(records, record)
+		}
+	}
+	return records, nil
+}
+
+// ===========================================================================
+// getEndorsementHistory - the endorsement records of a marble's writes
+// ===========================================================================
+func (t *SimpleChaincode) getEndorsementHistory(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+
+	//   0
+	// "marble1"
+	if len(args) != 1 {
+		return shim.Error("Incorrect number of arguments. Expecting name of the marble to query")
+	}
+
+	records, err := queryEndorsementRecords(stub, []string{args[0]}, func(r *EndorsementRecord) bool { return true })
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	recordsAsBytes, err := json.Marshal(records)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	return shim.Success(recordsAsBytes)
+}
+
+// ===========================================================================
+// getTransactionsByOrg - the marble writes an organization is attributed with
+// ===========================================================================
+func (t *SimpleChaincode) getTransactionsByOrg(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+
+	//     0
+	// "Org1MSP"
+	if len(args) != 1 {
+		return shim.Error("Incorrect number of arguments. Expecting MSP ID of the organization to query")
+	}
+
+	records, err := queryEndorsementRecords(stub, []string{}, func(r *EndorsementRecord) bool {
+		return containsString(r.EndorsingOrgs, args[0])
+	})
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	recordsAsBytes, err := json.Marshal(records)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	return shim.Success(recordsAsBytes)
+}
+
+// ===========================================================================
+// validateEndorsementPolicy - check that the latest writes of a marble were
+// each attributed to every required organization
+// ===========================================================================
+func (t *SimpleChaincode) validateEndorsementPolicy(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+
+	//     0          1          2
+	// "marble1", "Org1MSP", "Org2MSP", ...
+	if len(args) < 2 {
+		return shim.Error("Incorrect number of arguments. Expecting name of the marble and at least one required MSP ID")
+	}
+	requiredOrgs := args[1:]
+
+	records, err := queryEndorsementRecords(stub, []string{args[0]}, func(r *EndorsementRecord) bool { return true })
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	// records are keyed by transaction ID, not time, so the latest writes are found
+	// through the event log order
+	events, err := getMarbleEvents(stub, args[0])
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	recordsByTx := map[string]*EndorsementRecord{}
+	for i := range records {
+		recordsByTx[records[i].TxID] = &records[i]
+	}
+
+	checked := 0
+	failing := []string{}
+	for i := len(events) - 1; i >= 0 && checked < recentEndorsementCount; i-- {
+		record, ok := recordsByTx[events[i].TxID]
+		if !ok {
+			continue
+		}
+		checked++
+		for _, org := range requiredOrgs {
+			if !containsString(record.EndorsingOrgs, org) {
+				failing = append(failing, record.TxID)
+				break
+			}
+		}
+	}
+
+	result := struct {
+		MarbleName   string   `json:"marbleName"`
+		Valid        bool     `json:"valid"`
+		Checked      int      `json:"checked"`
+		FailingTxIDs []string `json:"failingTxIDs"`
+	}{args[0], len(failing) == 0, checked, failing}
+	resultAsBytes, err := json.Marshal(result)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	return shim.Success(resultAsBytes)
+}
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// escrowLock is the LockedBy value of a marble held in escrow. Like any locked marble,
+// it can neither change owner nor be deleted until the escrow agent settles it.
+const escrowLock = "escrow"
+
+// escrowPlacedEvent is the payload of the EscrowPlaced event, which an off-chain payment
+// system listens for to collect the payment and then release the escrow.
+type escrowPlacedEvent struct {
+	MarbleName  string `json:"marbleName"`
+	EscrowAgent string `json:"escrowAgent"`
+	Buyer       string `json:"buyer"`
+	Amount      int    `json:"amount"`
+}
+
+// requireEscrowAgent fails unless the caller belongs to the governance escrow agent MSP.
+func requireEscrowAgent(stub shim.ChaincodeStubInterface, gov *governance) error {
+	callerMSPID, err := getCallerMSPID(stub)
+	if err != nil {
+		return err
+	}
+	if len(gov.EscrowAgentMSPID) == 0 || callerMSPID != gov.EscrowAgentMSPID {
+		return fmt.Errorf("caller %s is not the escrow agent", callerMSPID)
+	}
+	return nil
+}
+
+// getEscrowedMarble returns the named marble for the escrow agent, failing unless it
+// is held in escrow.
+func getEscrowedMarble(stub shim.ChaincodeStubInterface, name string) (*marble, error) {
+	gov, err := getGovernance(stub)
+	if err != nil {
+		return nil, err
+	}
+	err = requireEscrowAgent(stub, gov)
+	if err != nil {
+		return nil, err
+	}
+	m, err := getMarble(stub, name)
+	if err != nil {
+		return nil, err
+	}
+	if m.LockedBy != escrowLock {
+		return nil, fmt.Errorf("marble %s is not in escrow", m.Name)
+	}
+	return m, nil
+}
+
+// clearEscrow takes a marble out of escrow. The caller is responsible for writing the
+// marble back.
+func clearEscrow(m *marble) {
+	m.LockedBy = ""
+	m.EscrowedTo = ""
+	m.EscrowAmount = 0
+}
+
+// ===========================================================================
+// escrowMarble - owner placement of a marble in escrow for a buyer. The
+// marble keeps its owner until the escrow agent releases it.
+// ===========================================================================
+func (t *SimpleChaincode) escrowMarble(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	fmt.Println("- start escrow marble")
+
+	type escrowTransientInput struct {
+		Name   string `json:"name"`
+		Buyer  string `json:"buyer"`
+		Amount int    `json:"amount"`
+	}
+
+	if len(args) != 0 {
+		return shim.Error("Incorrect number of arguments. Private marble data must be passed in transient map.")
+	}
+
+	var escrowInput escrowTransientInput
+	err := getTransientInput(stub, "marble_escrow", &escrowInput)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if len(escrowInput.Name) == 0 {
+		return shim.Error("name field must be a non-empty string")
+	}
+	if len(escrowInput.Buyer) == 0 {
+		return shim.Error("buyer field must be a non-empty string")
+	}
+	if escrowInput.Amount <= 0 {
+		return shim.Error("amount field must be a positive integer")
+	}
+
+	gov, err := getGovernance(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if len(gov.EscrowAgentMSPID) == 0 {
+		return shim.Error("no escrow agent is configured")
+	}
+	m, err := getMarble(stub, escrowInput.Name)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = requireOwner(stub, m)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = checkNotLocked(m)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = checkNotArchived(m)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if m.Owner == escrowInput.Buyer {
+		return shim.Error("Marble is already owned by " + m.Owner)
+	}
+
+	// an escrowed marble is no longer offered at its asking price
+	if m.IsForSale {
+		err = removeListingIndex(stub, m)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		m.IsForSale = false
+		m.AskingPrice = 0
+	}
+	m.LockedBy = escrowLock
+	m.EscrowedTo = escrowInput.Buyer
+	m.EscrowAmount = escrowInput.Amount
+	err = putMarble(stub, m)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	err = emitEvent(stub, "EscrowPlaced", escrowPlacedEvent{
+		MarbleName:  m.Name,
+		EscrowAgent: gov.EscrowAgentMSPID,
+		Buyer:       escrowInput.Buyer,
+		Amount:      escrowInput.Amount,
+	})
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	fmt.Println("- end escrow marble")
+	return shim.Success(nil)
+}
+
+// ===========================================================================
+// releaseEscrow - escrow agent completion of an escrow once the buyer has
+// paid. The marble goes to the buyer at the escrowed amount.
+// ===========================================================================
+func (t *SimpleChaincode) releaseEscrow(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	fmt.Println("- start release escrow")
+
+	//   0
+	// "marble1"
+	if len(args) != 1 {
+		return shim.Error("Incorrect number of arguments. Expecting name of the marble")
+	}
+
+	m, err := getEscrowedMarble(stub, args[0])
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	buyer, amount := m.EscrowedTo, m.EscrowAmount
+	clearEscrow(m)
+	err = changeMarbleOwner(stub, m, buyer)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = setMarblePrice(stub, m, int64(amount))
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = putMarble(stub, m)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = emitMarbleLifecycleEvent(stub, "EscrowReleased", m.Name)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	fmt.Println("- end release escrow")
+	return shim.Success(nil)
+}
+
+// ===========================================================================
+// cancelEscrow - escrow agent cancellation of an escrow. The marble stays
+// with its owner.
+// ===========================================================================
+func (t *SimpleChaincode) cancelEscrow(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+
+	//   0
+	// "marble1"
+	if len(args) != 1 {
+		return shim.Error("Incorrect number of arguments. Expecting name of the marble")
+	}
+
+	m, err := getEscrowedMarble(stub, args[0])
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	clearEscrow(m)
+	err = putMarble(stub, m)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = emitMarbleLifecycleEvent(stub, "EscrowCancelled", m.Name)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	return shim.Success(nil)
+}
@@ -0,0 +1,278 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// eventLogIndexName keys the events of a marble by their zero-padded sequence number,
+// so that a partial key scan returns them in order.
+const eventLogIndexName = "event~name~seq"
+
+// eventCountPrefix holds the number of events ever logged for a marble in
+// collectionMarbleEventLog. It is not reduced by pruning.
+const eventCountPrefix = "eventCount_"
+
+// MarbleEvent records one write of a marble. Payload is the full marble as written, or
+// null when the marble was deleted, so replaying the log never depends on pruned events.
+// EventType is the chaincode function that made the write.
+type MarbleEvent struct {
+	ObjectType string          `json:"docType"`
+	EventID    int             `json:"eventID"`
+	MarbleName string          `json:"marbleName"`
+	EventType  string          `json:"eventType"`
+	Payload    json.RawMessage `json:"payload"`
+	TxID       string          `json:"txID"`
+	TxUnixTime int64           `json:"txUnixTime"`
+}
+
+func eventLogKey(stub shim.ChaincodeStubInterface, marbleName string, eventID int) (string, error) {
+	return stub.CreateCompositeKey(eventLogIndexName, []string{marbleName, fmt.Sprintf("%010d", eventID)})
+}
+
+// appendMarbleEvent logs a write of a marble, given as JSON, or its deletion when
+// marbleJSON is nil. Reads do not see the writes of their own transaction, so several
+// writes of one marble in a transaction log a single event holding the last of them.
+func appendMarbleEvent(stub shim.ChaincodeStubInterface, marbleName string, marbleJSON []byte) error {
+	countAsBytes, err := stub.GetPrivateData("collectionMarbleEventLog", eventCountPrefix+marbleName)
+	if err != nil {
+		return fmt.Errorf("Failed to get event count: %s", err.Error())
+	}
+	eventID := 0
+	if countAsBytes != nil {
+		eventID, err = strconv.Atoi(string(countAsBytes))
+		if err != nil {
+			return err
+		}
+	}
+	currentTime, err := getTxUnixTime(stub)
+	if err != nil {
+		return err
+	}
+	function, _ := stub.GetFunctionAndParameters()
+
+	event := &MarbleEvent{
+		ObjectType: "marbleEvent",
+		EventID:    eventID,
+		MarbleName: marbleName,
+		EventType:  function,
+		Payload:    marbleJSON,
+		TxID:       stub.GetTxID(),
+		TxUnixTime: currentTime,
+	}
+	eventAsBytes, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	key, err := eventLogKey(stub, marbleName, eventID)
+	if err != nil {
+		return err
+	}
+	err = stub.PutPrivateData("collectionMarbleEventLog", key, eventAsBytes)
+	if err != nil {
+		return err
+	}
+	return stub.PutPrivateData("collectionMarbleEventLog", eventCountPrefix+marbleName, []byte(strconv.Itoa(eventID+1)))
+}
+
+// getMarbleEvents returns the logged events of a marble in order.
+func getMarbleEvents(stub shim.ChaincodeStubInterface, marbleName string) ([]MarbleEvent, error) {
+	resultsIterator, err := stub.GetPrivateDataByPartialCompositeKey("collectionMarbleEventLog", eventLogIndexName, []string{marbleName})
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	events := []MarbleEvent{}
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+		var event MarbleEvent
+		err = json.Unmarshal(queryResponse.Value, &event)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+	return events, nil
+}
+
+// ===========================================================================
+// getEventLog - every logged event of a marble, oldest first
+// ===========================================================================
+func (t *SimpleChaincode) getEventLog(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+
+	//   0
+	// "marble1"
+	if len(args) != 1 {
+		return shim.Error("Incorrect number of arguments. Expecting name of the marble to query")
+	}
+
+	events, err := getMarbleEvents(stub, args[0])
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	eventsAsBytes, err := json.Marshal(events)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	return shim.Success(eventsAsBytes)
+}
+
+// ===========================================================================
+// replayMarbleState - the marble as it was after a given event, rebuilt from
+// the event log. Nothing is written.
+// ===========================================================================
+func (t *SimpleChaincode) replayMarbleState(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+
+	//     0        1
+	// "marble1", "12"
+	if len(args) != 2 {
+		return shim.Error("Incorrect number of arguments. Expecting 2")
+	}
+	upToEvent, err := strconv.Atoi(args[1])
+	if err != nil || upToEvent < 0 {
+		return shim.Error("upToEvent must be a non-negative integer")
+	}
+
+	events, err := getMarbleEvents(stub, args[0])
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	var state *marble
+	replayed := false
+	for _, event := range events {
+		if event.EventID > upToEvent {
+			break
+		}
+		replayed = true
+		state = nil
+		if event.Payload != nil && string(event.Payload) != "null" {
+			state = &marble{}
+			err = json.Unmarshal(event.Payload, state)
+			if err != nil {
+				return shim.Error(err.Error())
+			}
+		}
+	}
+	if !replayed {
+		return shim.Error(fmt.Sprintf("No logged event of marble %s up to event %d", args[0], upToEvent))
+	}
+
+	stateAsBytes, err := json.Marshal(state)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	return shim.Success(stateAsBytes)
+}
+
+// ===========================================================================
+// pruneEventLog - admin removal of a marble's events before a given event
+// ===========================================================================
+func (t *SimpleChaincode) pruneEventLog(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+
+	//     0        1
+	// "marble1", "12"
+	if len(args) != 2 {
+		return shim.Error("Incorrect number of arguments. Expecting 2")
+	}
+	beforeEvent, err := strconv.Atoi(args[1])
+	if err != nil || beforeEvent < 0 {
+		return shim.Error("beforeEvent must be a non-negative integer")
+	}
+
+	err = requireAdmin(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	events, err := getMarbleEvents(stub, args[0])
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	pruned := 0
+	for _, event := range events {
+		if event.EventID >= beforeEvent {
+			break
+		}
+		key, err := eventLogKey(stub, event.MarbleName, event.EventID)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		err = stub.DelPrivateData("collectionMarbleEventLog", key)
+		if err != nil {
+			return shim.Error("Failed to delete state:" + err.Error())
+		}
+		pruned++
+	}
+	return shim.Success([]byte(fmt.Sprintf("{\"pruned\":%d}", pruned)))
+}
+
+// ===========================================================================
+// getMarbleHistory - the writes of a marble, oldest first, with the
+// organizations each is attributed to. The peer keeps key history for public
+// state only, and marbles live in private data, so the history is read from
+// the event log rather than GetHistoryForKey.
+// ===========================================================================
+func (t *SimpleChaincode) getMarbleHistory(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+
+	type historyRecord struct {
+		TxID          string          `json:"txId"`
+		Timestamp     string          `json:"timestamp"`
+		IsDelete      bool            `json:"isDelete"`
+		Value         json.RawMessage `json:"value"`
+		EndorsingOrgs []string        `json:"endorsingOrgs"`
+	}
+
+	//   0
+	// "marble1"
+	if len(args) != 1 {
+		return shim.Error("Incorrect number of arguments. Expecting name of the marble to query")
+	}
+
+	events, err := getMarbleEvents(stub, args[0])
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if len(events) == 0 {
+		return shim.Error("Marble has no history: " + args[0])
+	}
+	records, err := queryEndorsementRecords(stub, []string{args[0]}, func(r *EndorsementRecord) bool { return true })
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	orgsByTx := map[string][]string{}
+	for _, record := range records {
+		orgsByTx[record.TxID] = record.EndorsingOrgs
+	}
+
+	history := make([]historyRecord, len(events))
+	for i, event := range events {
+		// a deleted marble was logged with a null payload
+		isDelete := len(event.Payload) == 0 || string(event.Payload) == "null"
+		value := event.Payload
+		if isDelete {
+			value = nil
+		}
+		history[i] = historyRecord{
+			TxID:          event.TxID,
+			Timestamp:     time.Unix(event.TxUnixTime, 0).UTC().Format(time.RFC3339),
+			IsDelete:      isDelete,
+			Value:         value,
+			EndorsingOrgs: orgsByTx[event.TxID],
+		}
+	}
+	historyAsBytes, err := json.Marshal(history)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	return shim.Success(historyAsBytes)
+}
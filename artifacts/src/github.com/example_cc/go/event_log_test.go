@@ -0,0 +1,83 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func (s *testStub) replay(name, upToEvent string) map[string]interface{} {
+	var state map[string]interface{}
+	err := json.Unmarshal(s.mustInvoke("replayMarbleState", nil, name, upToEvent), &state)
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	return state
+}
+
+func (s *testStub) liveMarble(name string) map[string]interface{} {
+	var state map[string]interface{}
+	err := json.Unmarshal(s.PvtState["collectionMarbles"][name], &state)
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	return state
+}
+
+func TestReplayMatchesLiveMarble(t *testing.T) {
+	s := newTestStub(t)
+	s.createMarble("marble1", "blue", 35, "Org1MSP", 99)
+	s.mustInvoke("updateMarbleCondition", conditionTo("marble1", "used"))
+	s.mustInvoke("transferMarble", transferTo("marble1", "Org2MSP"))
+
+	var events []MarbleEvent
+	err := json.Unmarshal(s.mustInvoke("getEventLog", nil, "marble1"), &events)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 3 || events[0].EventType != "initMarble" || events[2].EventType != "transferMarble" {
+		t.Fatalf("expected the creation, condition change and transfer to be logged, got %+v", events)
+	}
+
+	if replayed, live := s.replay("marble1", "2"), s.liveMarble("marble1"); !reflect.DeepEqual(replayed, live) {
+		t.Fatalf("replayed marble %v does not match the live marble %v", replayed, live)
+	}
+	if replayed := s.replay("marble1", "1"); replayed["owner"] != "Org1MSP" || replayed["condition"] != "used" {
+		t.Fatalf("expected the marble as it was before the transfer, got %v", replayed)
+	}
+	if replayed := s.replay("marble1", "0"); replayed["owner"] != "Org1MSP" || replayed["condition"] == "used" {
+		t.Fatalf("expected the marble as it was created, got %v", replayed)
+	}
+
+	// replaying writes nothing
+	before := s.snapshot()
+	s.replay("marble1", "2")
+	if after := s.snapshot(); !reflect.DeepEqual(before, after) {
+		t.Fatal("expected replay to leave the state unchanged")
+	}
+}
+
+func TestPruneEventLog(t *testing.T) {
+	s := newTestStub(t)
+	s.createMarble("marble1", "blue", 35, "Org1MSP", 99)
+	s.mustInvoke("updateMarbleCondition", conditionTo("marble1", "used"))
+	s.mustInvoke("updateMarbleCondition", conditionTo("marble1", "damaged"))
+
+	s.setCaller("Org2MSP", "user2")
+	s.mustFail("is not the admin organization", "pruneEventLog", nil, "marble1", "2")
+	s.setCaller("Org1MSP", "admin")
+	if pruned := string(s.mustInvoke("pruneEventLog", nil, "marble1", "2")); pruned != `{"pruned":2}` {
+		t.Fatalf("expected 2 events to be pruned, got %s", pruned)
+	}
+
+	s.mustFail("No logged event of marble marble1 up to event 1", "replayMarbleState", nil, "marble1", "1")
+	if replayed, live := s.replay("marble1", "2"), s.liveMarble("marble1"); !reflect.DeepEqual(replayed, live) {
+		t.Fatalf("replayed marble %v does not match the live marble %v", replayed, live)
+	}
+
+	// event numbers carry on after pruning
+	s.mustInvoke("delete", map[string]interface{}{"marble_delete": map[string]interface{}{"name": "marble1"}})
+	if payload := string(s.mustInvoke("replayMarbleState", nil, "marble1", "3")); payload != "null" {
+		t.Fatalf("expected the deleted marble to replay as null, got %s", payload)
+	}
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+// invokeForEvent runs a transaction that must succeed and returns the event it set,
+// failing if it set none.
+func (s *testStub) invokeForEvent(function string, transient map[string]interface{}, args ...string) (string, marbleLifecycleEvent) {
+	s.startTx(transient, append([]string{function}, args...))
+	defer s.MockTransactionEnd(s.TxID)
+	if called, _ := s.GetFunctionAndParameters(); called != function {
+		s.t.Fatalf("expected the transaction to call %s, got %s", function, called)
+	}
+	response := s.cc.Invoke(s)
+	if response.Status != shim.OK {
+		s.t.Fatalf("%s failed: %s", function, response.Message)
+	}
+	event := s.Events[s.TxID]
+	if event == nil {
+		s.t.Fatalf("%s set no event", function)
+	}
+	var payload marbleLifecycleEvent
+	err := json.Unmarshal(event.Payload, &payload)
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	if payload.TxID != s.TxID {
+		s.t.Fatalf("expected the event of %s to carry transaction %s, got %s", function, s.TxID, payload.TxID)
+	}
+	return event.EventName, payload
+}
+
+func TestMutatingOperationsEmitLifecycleEvents(t *testing.T) {
+	s := newTestStub(t)
+	name, payload := s.invokeForEvent("initMarble", map[string]interface{}{"marble": map[string]interface{}{
+		"name": "marble1", "color": "blue", "size": 35, "owner": "Org1MSP", "price": 99, "weight": 10,
+	}})
+	if name != "MarbleCreated" || payload.MarbleName != "marble1" || payload.MSPID != "Org1MSP" {
+		t.Fatalf("unexpected creation event %s %+v", name, payload)
+	}
+
+	s.setCaller("Org2MSP", "user2")
+	name, payload = s.invokeForEvent("transferMarble", transferTo("marble1", "Org2MSP"))
+	if name != "MarbleTransferred" || payload.MarbleName != "marble1" || payload.MSPID != "Org2MSP" {
+		t.Fatalf("unexpected transfer event %s %+v", name, payload)
+	}
+
+	s.setCaller("Org1MSP", "admin")
+	name, payload = s.invokeForEvent("delete", map[string]interface{}{"marble_delete": map[string]interface{}{"name": "marble1"}})
+	if name != "MarbleDeleted" || payload.MarbleName != "marble1" || payload.MSPID != "Org1MSP" {
+		t.Fatalf("unexpected deletion event %s %+v", name, payload)
+	}
+}
+
+func TestFailedOperationsEmitNoEvent(t *testing.T) {
+	s := newTestStub(t)
+	s.mustFail("Marble does not exist: marble1", "transferMarble", transferTo("marble1", "Org2MSP"))
+	for txID, event := range s.Events {
+		if event.EventName == "MarbleTransferred" {
+			t.Fatalf("expected no transfer event, got one in %s", txID)
+		}
+	}
+}
@@ -17,10 +17,12 @@ limitations under the License.
 package main
 
 import (
-	"bytes"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/hyperledger/fabric/core/chaincode/shim"
 	pb "github.com/hyperledger/fabric/protos/peer"
@@ -33,17 +35,105 @@ type SimpleChaincode struct {
 }
 
 type marble struct {
-	ObjectType string `json:"docType"` //docType is used to distinguish the various types of objects in state database
-	Name       string `json:"name"`    //the fieldtags are needed to keep case from bouncing around
-	Color      string `json:"color"`
-	Size       int    `json:"size"`
-	Owner      string `json:"owner"`
+	ObjectType    string          `json:"docType"` //docType is used to distinguish the various types of objects in state database
+	Name          string          `json:"name"`    //the fieldtags are needed to keep case from bouncing around
+	Color         string          `json:"color"`
+	Size          int             `json:"size"`
+	Owner         string          `json:"owner"`
+	IsForSale     bool            `json:"isForSale"`
+	AskingPrice   int64           `json:"askingPrice"`
+	PreviousOwner string          `json:"previousOwner"`
+	Condition     string          `json:"condition"`
+	UsageCount    int             `json:"usageCount"`
+	MaxUsages     int             `json:"maxUsages"`
+	ViewCount     int64           `json:"viewCount"`
+	ShareCount    int64           `json:"shareCount"`
+	SocialScore   int64           `json:"socialScore"`
+	CustodyChain  []CustodyRecord `json:"custodyChain,omitempty"`
+	// CarbonFootprint is in kg CO2e
+	CarbonFootprint          float64  `json:"carbonFootprint"`
+	CarbonOffsetCertificates []string `json:"carbonOffsetCertificates,omitempty"`
+	// LockedBy names the mechanism holding the marble, e.g. the farm. A locked marble
+	// cannot change owner, be listed or be deleted.
+	LockedBy string `json:"lockedBy,omitempty"`
+	// CertificationStatus is empty until certification is requested, see certification.go
+	CertificationStatus string `json:"certificationStatus,omitempty"`
+	// PhotoGallery holds up to maxGalleryPhotos image references, HeroPhotoHash is
+	// the one shown first
+	PhotoGallery  []PhotoEntry `json:"photoGallery,omitempty"`
+	HeroPhotoHash string       `json:"heroPhotoHash,omitempty"`
+	// WhitepaperCID references the marble's off-chain specification, whose SHA-256
+	// hash is WhitepaperHash. Replaced references are kept in WhitepaperHistory.
+	WhitepaperCID     string   `json:"whitepaperCID,omitempty"`
+	WhitepaperHash    string   `json:"whitepaperHash,omitempty"`
+	WhitepaperHistory []string `json:"whitepaperHistory,omitempty"`
+	InsuranceCovered  bool     `json:"insuranceCovered"`
+	// CreationTxID is the transaction that created the marble, or "genesis" for
+	// marbles imported when the chaincode was instantiated
+	CreationTxID string `json:"creationTxID,omitempty"`
+	// CoCreated marbles were created from the attributes of two organizations,
+	// CoCreatorMSPIDs, see co_creation.go
+	CoCreated       bool     `json:"coCreated,omitempty"`
+	CoCreatorMSPIDs []string `json:"coCreatorMSPIDs,omitempty"`
+	// Redeemed marbles have been exchanged for their physical counterpart and stay
+	// locked for good
+	Redeemed bool `json:"redeemed,omitempty"`
+	// LastActivityTime is the Unix time of the marble's last write. Idle marbles decay
+	// unless DecayImmune, see decay.go
+	LastActivityTime int64 `json:"lastActivityTime"`
+	DecayImmune      bool  `json:"decayImmune,omitempty"`
+	// FailedLastAudit marbles cannot be listed for sale until an audit passes, see
+	// audit.go
+	FailedLastAudit bool `json:"failedLastAudit,omitempty"`
+	// LastTransferTime is the Unix time the owner acquired the marble at and
+	// PreviousTransferTime the time the previous owner did. They measure tenure for
+	// royalties, see tenure_royalty.go
+	LastTransferTime     int64 `json:"lastTransferTime"`
+	PreviousTransferTime int64 `json:"previousTransferTime"`
+	// SellerReputation blends the reputations of the marble's owners, see reputation.go
+	SellerReputation int `json:"sellerReputation"`
+	// IsArchived marbles are retired: kept for audit, but left out of the default
+	// queries and no longer sold or transferred, see archive.go
+	IsArchived bool `json:"isArchived"`
+	// EscrowedTo is the buyer a marble is held in escrow for at EscrowAmount, see
+	// escrow.go
+	EscrowedTo   string `json:"escrowedTo,omitempty"`
+	EscrowAmount int    `json:"escrowAmount,omitempty"`
+	// ReservedFor is the only party the marble may be transferred to until
+	// ReservedUntilTime, see reservation.go
+	ReservedFor       string `json:"reservedFor,omitempty"`
+	ReservedUntilTime int64  `json:"reservedUntilTime,omitempty"`
+	// ConditionChangedAt is when Condition last changed, see condition.go
+	ConditionChangedAt string `json:"conditionChangedAt,omitempty"`
+	// DocumentHashes reference up to maxDocumentHashes off-chain documents by their
+	// SHA-256 hashes, see documents.go
+	DocumentHashes []string `json:"documentHashes,omitempty"`
+	// Series groups marbles issued together, e.g. "Galaxy 2024", see series.go
+	Series string `json:"series,omitempty"`
+	// Rarity is one of rarityLevels, see rarity.go
+	Rarity string `json:"rarity,omitempty"`
+	// Weight is in grams. Marbles imported at genesis have none.
+	Weight float64 `json:"weight,omitempty"`
+	// Tags are up to maxTags labels set by the owner, see tags.go
+	Tags []string `json:"tags,omitempty"`
+	// CreatedAt is when the marble was created and UpdatedAt when it was last written,
+	// both RFC3339 transaction timestamps. Marbles created before they were tracked
+	// have no CreatedAt.
+	CreatedAt string `json:"createdAt,omitempty"`
+	UpdatedAt string `json:"updatedAt,omitempty"`
+	// Description is up to maxDescriptionRunes characters, see description.go
+	Description string `json:"description,omitempty"`
 }
 
 type marblePrivateDetails struct {
-	ObjectType string `json:"docType"` //docType is used to distinguish the various types of objects in state database
-	Name       string `json:"name"`    //the fieldtags are needed to keep case from bouncing around
-	Price      int    `json:"price"`
+	ObjectType string            `json:"docType"` //docType is used to distinguish the various types of objects in state database
+	Name       string            `json:"name"`    //the fieldtags are needed to keep case from bouncing around
+	Price      int64             `json:"price"`
+	Appraisals []AppraisalRecord `json:"appraisals,omitempty"`
+	// CreatorMSPID is the organization that created the marble and is owed its royalties
+	CreatorMSPID  string               `json:"creatorMSPID,omitempty"`
+	TieredRoyalty *TieredRoyalty       `json:"tieredRoyalty,omitempty"`
+	TenureRoyalty *TenureRoyaltyPolicy `json:"tenureRoyalty,omitempty"`
 }
 
 // ===================================================================================
@@ -59,7 +149,20 @@ func main() {
 // Init initializes chaincode
 // ===========================
 func (t *SimpleChaincode) Init(stub shim.ChaincodeStubInterface) pb.Response {
-	return shim.Success(nil)
+	response := shim.Success(nil)
+	function, args := stub.GetFunctionAndParameters()
+	if function == "genesisImport" {
+		response = t.genesisImport(stub, args)
+		if response.Status != shim.OK {
+			return response
+		}
+	}
+
+	err := initialize(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	return response
 }
 
 // Invoke - Our entry point for Invocations
@@ -68,136 +171,201 @@ func (t *SimpleChaincode) Invoke(stub shim.ChaincodeStubInterface) pb.Response {
 	function, args := stub.GetFunctionAndParameters()
 	fmt.Println("invoke is running " + function)
 
-	// Handle different functions
-	switch function {
-	case "initMarble":
-		//create a new marble
-		return t.initMarble(stub, args)
-	case "readMarble":
-		//read a marble
-		return t.readMarble(stub, args)
-	case "readMarblePrivateDetails":
-		//read a marble private details
-		return t.readMarblePrivateDetails(stub, args)
-	case "transferMarble":
-		//change owner of a specific marble
-		return t.transferMarble(stub, args)
-	case "delete":
-		//delete a marble
-		return t.delete(stub, args)
-	case "queryMarblesByOwner":
-		//find marbles for owner X using rich query
-		return t.queryMarblesByOwner(stub, args)
-	case "queryMarbles":
-		//find marbles based on an ad hoc rich query
-		return t.queryMarbles(stub, args)
-	case "getMarblesByRange":
-		//get marbles based on range query
-		return t.getMarblesByRange(stub, args)
-	default:
+	// Handle different functions, see chaincode_meta.go
+	registered, ok := functionsByName[function]
+	if !ok {
 		//error
 		fmt.Println("invoke did not find func: " + function)
 		return shim.Error("Received unknown function invocation")
 	}
+	return registered.handler(t, stub, args)
 }
 
-// ============================================================
-// initMarble - create a new marble, store into chaincode state
-// ============================================================
-func (t *SimpleChaincode) initMarble(stub shim.ChaincodeStubInterface, args []string) pb.Response {
-	var err error
-
-	type marbleTransientInput struct {
-		Name  string `json:"name"` //the fieldtags are needed to keep case from bouncing around
-		Color string `json:"color"`
-		Size  int    `json:"size"`
-		Owner string `json:"owner"`
-		Price int    `json:"price"`
-	}
-
-	// ==== Input sanitation ====
-	fmt.Println("- start init marble")
-
-	if len(args) != 0 {
-		return shim.Error("Incorrect number of arguments. Private marble data must be passed in transient map.")
-	}
+// marbleTransientInput is the transient input of a new marble.
+type marbleTransientInput struct {
+	Name  string `json:"name"` //the fieldtags are needed to keep case from bouncing around
+	Color string `json:"color"`
+	Size  int    `json:"size"`
+	Owner string `json:"owner"`
+	Price int64  `json:"price"`
+	// Weight is in grams
+	Weight float64 `json:"weight"`
+	// MaxUsages is optional, defaultMaxUsages applies when it is omitted
+	MaxUsages int `json:"maxUsages"`
+	// Condition is optional, a marble starts out new when it is omitted
+	Condition string `json:"condition"`
+	// DocumentHashes is optional
+	DocumentHashes []string `json:"documentHashes"`
+	// Series is optional, a marble need not belong to one
+	Series string `json:"series"`
+	// Rarity is optional, a marble is common when it is omitted
+	Rarity string `json:"rarity"`
+	// Description is optional
+	Description string `json:"description"`
+}
 
-	transMap, err := stub.GetTransient()
+// UnmarshalJSON decodes the price through json.Number, so that a fractional or oversized
+// price is reported as such instead of as a generic decoding error.
+func (marbleInput *marbleTransientInput) UnmarshalJSON(data []byte) error {
+	type plainMarbleInput marbleTransientInput
+	aux := struct {
+		*plainMarbleInput
+		Price json.Number `json:"price"`
+	}{plainMarbleInput: (*plainMarbleInput)(marbleInput)}
+	err := json.Unmarshal(data, &aux)
 	if err != nil {
-		return shim.Error("Error getting transient: " + err.Error())
+		return err
 	}
-
-	if _, ok := transMap["marble"]; !ok {
-		return shim.Error("marble must be a key in the transient map")
+	if len(aux.Price) == 0 {
+		marbleInput.Price = 0
+		return nil
 	}
+	marbleInput.Price, err = parsePrice(aux.Price)
+	return err
+}
 
-	if len(transMap["marble"]) == 0 {
-		return shim.Error("marble value in the transient map must be a non-empty JSON string")
+// checkMarbleInput validates the fields of a new marble and fills in their defaults.
+func checkMarbleInput(stub shim.ChaincodeStubInterface, gov *governance, marbleInput *marbleTransientInput) error {
+	if len(marbleInput.Name) == 0 {
+		return fmt.Errorf("name field must be a non-empty string")
 	}
-
-	var marbleInput marbleTransientInput
-	err = json.Unmarshal(transMap["marble"], &marbleInput)
+	err := validateMarbleName(marbleInput.Name)
 	if err != nil {
-		return shim.Error("Failed to decode JSON of: " + string(transMap["marble"]))
-	}
-
-	if len(marbleInput.Name) == 0 {
-		return shim.Error("name field must be a non-empty string")
+		return err
 	}
 	if len(marbleInput.Color) == 0 {
-		return shim.Error("color field must be a non-empty string")
+		return fmt.Errorf("color field must be a non-empty string")
+	}
+	err = checkAllowedColor(stub, marbleInput.Color)
+	if err != nil {
+		return err
 	}
 	if marbleInput.Size <= 0 {
-		return shim.Error("size field must be a positive integer")
+		return fmt.Errorf("size field must be a positive integer")
+	}
+	err = checkWeight(marbleInput.Weight)
+	if err != nil {
+		return err
 	}
 	if len(marbleInput.Owner) == 0 {
-		return shim.Error("owner field must be a non-empty string")
+		return fmt.Errorf("owner field must be a non-empty string")
+	}
+	err = checkPriceRange(marbleInput.Price)
+	if err != nil {
+		return err
+	}
+	if marbleInput.MaxUsages < 0 {
+		return fmt.Errorf("maxUsages field must not be negative")
+	} else if marbleInput.MaxUsages == 0 {
+		marbleInput.MaxUsages = defaultMaxUsages
 	}
-	if marbleInput.Price <= 0 {
-		return shim.Error("price field must be a positive integer")
+	if len(marbleInput.Condition) == 0 {
+		marbleInput.Condition = conditionGrades[0]
+	} else if err = checkCondition(marbleInput.Condition); err != nil {
+		return err
 	}
+	err = checkDocumentHashes(marbleInput.DocumentHashes)
+	if err != nil {
+		return err
+	}
+	if len(marbleInput.Rarity) == 0 {
+		marbleInput.Rarity = rarityLevels[0]
+	} else if err = checkRarity(marbleInput.Rarity); err != nil {
+		return err
+	}
+	err = checkDescription(marbleInput.Description)
+	if err != nil {
+		return err
+	}
+	return gov.PricePolicy.checkPrice(marbleInput.Price)
+}
 
+// prepareMarble checks that a marble can be created from its input and returns the
+// marble with its JSON. Nothing is written.
+func prepareMarble(stub shim.ChaincodeStubInterface, gov *governance, marbleInput *marbleTransientInput, currentTime int64) (*marble, []byte, error) {
 	// ==== Check if marble already exists ====
 	marbleAsBytes, err := stub.GetPrivateData("collectionMarbles", marbleInput.Name)
 	if err != nil {
-		return shim.Error("Failed to get marble: " + err.Error())
+		return nil, nil, fmt.Errorf("Failed to get marble: %s", err.Error())
 	} else if marbleAsBytes != nil {
 		fmt.Println("This marble already exists: " + marbleInput.Name)
-		return shim.Error("This marble already exists: " + marbleInput.Name)
+		return nil, nil, fmt.Errorf("This marble already exists: %s", marbleInput.Name)
+	}
+
+	// ==== Only the holder of an active name reservation may claim the name ====
+	err = checkNameReservation(stub, marbleInput.Name)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	txTime, err := getTxTime(stub)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	// ==== Create marble object, marshal to JSON, and save to state ====
+	// ==== Create marble object and marshal to JSON ====
 	marble := &marble{
-		ObjectType: "marble",
-		Name:       marbleInput.Name,
-		Color:      marbleInput.Color,
-		Size:       marbleInput.Size,
-		Owner:      marbleInput.Owner,
+		ObjectType:         "marble",
+		Name:               marbleInput.Name,
+		Color:              marbleInput.Color,
+		Size:               marbleInput.Size,
+		Owner:              marbleInput.Owner,
+		Condition:          marbleInput.Condition,
+		ConditionChangedAt: txTime.Format(time.RFC3339),
+		MaxUsages:          marbleInput.MaxUsages,
+		CreationTxID:       stub.GetTxID(),
+		LastActivityTime:   currentTime,
+		LastTransferTime:   currentTime,
+		DocumentHashes:     marbleInput.DocumentHashes,
+		Series:             marbleInput.Series,
+		Rarity:             marbleInput.Rarity,
+		Weight:             marbleInput.Weight,
+		CreatedAt:          txTime.Format(time.RFC3339),
+		UpdatedAt:          txTime.Format(time.RFC3339),
+		Description:        marbleInput.Description,
+	}
+	if coCreators := coCreatorsOf(stub); coCreators != nil {
+		marble.CoCreated = true
+		marble.CoCreatorMSPIDs = coCreators
 	}
 	marbleJSONasBytes, err := json.Marshal(marble)
 	if err != nil {
-		return shim.Error(err.Error())
+		return nil, nil, err
+	}
+	err = checkExternalValidator(stub, gov, marbleJSONasBytes)
+	if err != nil {
+		return nil, nil, err
 	}
+	return marble, marbleJSONasBytes, nil
+}
 
+// writeNewMarble saves a prepared marble, its private details and its indexes. The
+// caller adjusts the market cap.
+func writeNewMarble(stub shim.ChaincodeStubInterface, marble *marble, marbleJSONasBytes []byte, price int64, creatorMSPID string) error {
 	// === Save marble to state ===
-	err = stub.PutPrivateData("collectionMarbles", marbleInput.Name, marbleJSONasBytes)
+	err := stub.PutPrivateData("collectionMarbles", marble.Name, marbleJSONasBytes)
 	if err != nil {
-		return shim.Error(err.Error())
+		return err
+	}
+	err = logMarbleWrite(stub, marble.Name, marbleJSONasBytes)
+	if err != nil {
+		return err
 	}
 
 	// ==== Create marble private details object with price, marshal to JSON, and save to state ====
 	marblePrivateDetails := &marblePrivateDetails{
-		ObjectType: "marblePrivateDetails",
-		Name:       marbleInput.Name,
-		Price:      marbleInput.Price,
+		ObjectType:   "marblePrivateDetails",
+		Name:         marble.Name,
+		Price:        price,
+		CreatorMSPID: creatorMSPID,
 	}
 	marblePrivateDetailsBytes, err := json.Marshal(marblePrivateDetails)
 	if err != nil {
-		return shim.Error(err.Error())
+		return err
 	}
-	err = stub.PutPrivateData("collectionMarblePrivateDetails", marbleInput.Name, marblePrivateDetailsBytes)
+	err = stub.PutPrivateData("collectionMarblePrivateDetails", marble.Name, marblePrivateDetailsBytes)
 	if err != nil {
-		return shim.Error(err.Error())
+		return err
 	}
 
 	//  ==== Index the marble to enable color-based range queries, e.g. return all blue marbles ====
@@ -208,78 +376,47 @@ func (t *SimpleChaincode) initMarble(stub shim.ChaincodeStubInterface, args []st
 	indexName := "color~name"
 	colorNameIndexKey, err := stub.CreateCompositeKey(indexName, []string{marble.Color, marble.Name})
 	if err != nil {
-		return shim.Error(err.Error())
+		return err
 	}
 	//  Save index entry to state. Only the key name is needed, no need to store a duplicate copy of the marble.
 	//  Note - passing a 'nil' value will effectively delete the key from state, therefore we pass null character as value
 	value := []byte{0x00}
-	stub.PutPrivateData("collectionMarbles", colorNameIndexKey, value)
-
-	// ==== Marble saved and indexed. Return success ====
-	fmt.Println("- end init marble")
-	return shim.Success(nil)
-}
-
-// ===============================================
-// readMarble - read a marble from chaincode state
-// ===============================================
-func (t *SimpleChaincode) readMarble(stub shim.ChaincodeStubInterface, args []string) pb.Response {
-	var name, jsonResp string
-	var err error
-
-	if len(args) != 1 {
-		return shim.Error("Incorrect number of arguments. Expecting name of the marble to query")
+	err = stub.PutPrivateData("collectionMarbles", colorNameIndexKey, value)
+	if err != nil {
+		return err
 	}
 
-	name = args[0]
-	valAsbytes, err := stub.GetPrivateData("collectionMarbles", name) //get the marble from chaincode state
+	//  ==== Index the marble by owner and size as well, so these lookups work without a rich query ====
+	err = addOwnerIndex(stub, marble)
 	if err != nil {
-		jsonResp = "{\"Error\":\"Failed to get state for " + name + "\"}"
-		return shim.Error(jsonResp)
-	} else if valAsbytes == nil {
-		jsonResp = "{\"Error\":\"Marble does not exist: " + name + "\"}"
-		return shim.Error(jsonResp)
+		return err
 	}
-
-	return shim.Success(valAsbytes)
-}
-
-// ===============================================
-// readMarblereadMarblePrivateDetails - read a marble private details from chaincode state
-// ===============================================
-func (t *SimpleChaincode) readMarblePrivateDetails(stub shim.ChaincodeStubInterface, args []string) pb.Response {
-	var name, jsonResp string
-	var err error
-
-	if len(args) != 1 {
-		return shim.Error("Incorrect number of arguments. Expecting name of the marble to query")
+	err = addSizeIndex(stub, marble)
+	if err != nil {
+		return err
 	}
-
-	name = args[0]
-	valAsbytes, err := stub.GetPrivateData("collectionMarblePrivateDetails", name) //get the marble private details from chaincode state
+	err = addConditionIndex(stub, marble)
 	if err != nil {
-		jsonResp = "{\"Error\":\"Failed to get private details for " + name + ": " + err.Error() + "\"}"
-		return shim.Error(jsonResp)
-	} else if valAsbytes == nil {
-		jsonResp = "{\"Error\":\"Marble private details does not exist: " + name + "\"}"
-		return shim.Error(jsonResp)
+		return err
 	}
-
-	return shim.Success(valAsbytes)
+	err = addRarityIndex(stub, marble)
+	if err != nil {
+		return err
+	}
+	return addSeriesIndex(stub, marble)
 }
 
-// ==================================================
-// delete - remove a marble key/value pair from state
-// ==================================================
-func (t *SimpleChaincode) delete(stub shim.ChaincodeStubInterface, args []string) pb.Response {
-	fmt.Println("- start delete marble")
+// ============================================================
+// initMarble - create a new marble, store into chaincode state
+// ============================================================
+func (t *SimpleChaincode) initMarble(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	var err error
 
-	type marbleDeleteTransientInput struct {
-		Name string `json:"name"`
-	}
+	// ==== Input sanitation ====
+	fmt.Println("- start init marble")
 
 	if len(args) != 0 {
-		return shim.Error("Incorrect number of arguments. Private marble name must be passed in transient map.")
+		return shim.Error("Incorrect number of arguments. Private marble data must be passed in transient map.")
 	}
 
 	transMap, err := stub.GetTransient()
@@ -287,135 +424,717 @@ func (t *SimpleChaincode) delete(stub shim.ChaincodeStubInterface, args []string
 		return shim.Error("Error getting transient: " + err.Error())
 	}
 
-	if _, ok := transMap["marble_delete"]; !ok {
-		return shim.Error("marble_delete must be a key in the transient map")
+	if _, ok := transMap["marble"]; !ok {
+		return shim.Error("marble must be a key in the transient map")
 	}
 
-	if len(transMap["marble_delete"]) == 0 {
-		return shim.Error("marble_delete value in the transient map must be a non-empty JSON string")
+	if len(transMap["marble"]) == 0 {
+		return shim.Error("marble value in the transient map must be a non-empty JSON string")
 	}
 
-	var marbleDeleteInput marbleDeleteTransientInput
-	err = json.Unmarshal(transMap["marble_delete"], &marbleDeleteInput)
+	var marbleInput marbleTransientInput
+	err = json.Unmarshal(transMap["marble"], &marbleInput)
 	if err != nil {
-		return shim.Error("Failed to decode JSON of: " + string(transMap["marble_delete"]))
+		return shim.Error("Failed to decode JSON of: " + string(transMap["marble"]) + ": " + err.Error())
 	}
 
-	if len(marbleDeleteInput.Name) == 0 {
-		return shim.Error("name field must be a non-empty string")
+	gov, err := getGovernance(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = checkMarbleInput(stub, gov, &marbleInput)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = checkCallerNotBlacklisted(stub, gov)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	creatorMSPID, err := getCallerMSPID(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = checkAuthorizedCreator(stub, creatorMSPID)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = consumeCreationQuota(stub, creatorMSPID, 1)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = checkOwnerMaxMarbles(stub, marbleInput.Owner, 1)
+	if err != nil {
+		return shim.Error(err.Error())
 	}
 
-	// to maintain the color~name index, we need to read the marble first and get its color
-	valAsbytes, err := stub.GetPrivateData("collectionMarbles", marbleDeleteInput.Name) //get the marble from chaincode state
+	currentTime, err := getTxUnixTime(stub)
 	if err != nil {
-		return shim.Error("Failed to get state for " + marbleDeleteInput.Name)
-	} else if valAsbytes == nil {
-		return shim.Error("Marble does not exist: " + marbleDeleteInput.Name)
+		return shim.Error(err.Error())
 	}
 
-	var marbleToDelete marble
-	err = json.Unmarshal([]byte(valAsbytes), &marbleToDelete)
+	marble, marbleJSONasBytes, err := prepareMarble(stub, gov, &marbleInput, currentTime)
 	if err != nil {
-		return shim.Error("Failed to decode JSON of: " + string(valAsbytes))
+		return shim.Error(err.Error())
 	}
 
-	// delete the marble from state
-	err = stub.DelPrivateData("collectionMarbles", marbleDeleteInput.Name)
+	// ==== Record the provenance passed along, or check one was recorded beforehand ====
+	if _, ok := transMap["marble_provenance"]; ok {
+		var provenanceInput MarbleProvenance
+		err = json.Unmarshal(transMap["marble_provenance"], &provenanceInput)
+		if err != nil {
+			return shim.Error("Failed to decode JSON of: " + string(transMap["marble_provenance"]))
+		}
+		err = provenanceInput.validate()
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		if provenanceInput.MarbleName != marble.Name {
+			return shim.Error("provenance is for " + provenanceInput.MarbleName + ", not " + marble.Name)
+		}
+		err = putProvenance(stub, &provenanceInput)
+	} else {
+		err = checkProvenanceRecorded(stub, gov, marble.Name)
+	}
 	if err != nil {
-		return shim.Error("Failed to delete state:" + err.Error())
+		return shim.Error(err.Error())
 	}
 
-	// Also delete the marble from the color~name index
-	indexName := "color~name"
-	colorNameIndexKey, err := stub.CreateCompositeKey(indexName, []string{marbleToDelete.Color, marbleToDelete.Name})
+	err = writeNewMarble(stub, marble, marbleJSONasBytes, marbleInput.Price, creatorMSPID)
 	if err != nil {
 		return shim.Error(err.Error())
 	}
-	err = stub.DelPrivateData("collectionMarbles", colorNameIndexKey)
+	err = registerSeries(stub, []string{marble.Series})
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = adjustMarketCap(stub, marble.Name, marbleInput.Price)
 	if err != nil {
-		return shim.Error("Failed to delete state:" + err.Error())
+		return shim.Error(err.Error())
 	}
 
-	// Finally, delete private details of marble
-	err = stub.DelPrivateData("collectionMarblePrivateDetails", marbleDeleteInput.Name)
+	err = emitMarbleLifecycleEvent(stub, "MarbleCreated", marble.Name)
 	if err != nil {
 		return shim.Error(err.Error())
 	}
 
+	// ==== Marble saved and indexed. Return success ====
+	fmt.Println("- end init marble")
 	return shim.Success(nil)
 }
 
-// ===========================================================
-// transfer a marble by setting a new owner name on the marble
-// ===========================================================
-func (t *SimpleChaincode) transferMarble(stub shim.ChaincodeStubInterface, args []string) pb.Response {
-
-	fmt.Println("- start transfer marble")
-
-	type marbleTransferTransientInput struct {
-		Name  string `json:"name"`
-		Owner string `json:"owner"`
-	}
+// ============================================================
+// bulkInitMarbles - create several marbles in one transaction. Every marble
+// is validated before any is written, so either all are created or none.
+// ============================================================
+func (t *SimpleChaincode) bulkInitMarbles(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	fmt.Println("- start bulk init marbles")
 
 	if len(args) != 0 {
 		return shim.Error("Incorrect number of arguments. Private marble data must be passed in transient map.")
 	}
 
-	transMap, err := stub.GetTransient()
+	var marbleInputs []marbleTransientInput
+	err := getTransientInput(stub, "marbles", &marbleInputs)
 	if err != nil {
-		return shim.Error("Error getting transient: " + err.Error())
-	}
-
-	if _, ok := transMap["marble_owner"]; !ok {
-		return shim.Error("marble_owner must be a key in the transient map")
+		return shim.Error(err.Error())
 	}
-
-	if len(transMap["marble_owner"]) == 0 {
-		return shim.Error("marble_owner value in the transient map must be a non-empty JSON string")
+	if len(marbleInputs) == 0 {
+		return shim.Error("marbles must be a non-empty JSON array")
 	}
 
-	var marbleTransferInput marbleTransferTransientInput
-	err = json.Unmarshal(transMap["marble_owner"], &marbleTransferInput)
+	gov, err := getGovernance(stub)
 	if err != nil {
-		return shim.Error("Failed to decode JSON of: " + string(transMap["marble_owner"]))
+		return shim.Error(err.Error())
 	}
-
-	if len(marbleTransferInput.Name) == 0 {
-		return shim.Error("name field must be a non-empty string")
+	err = checkCallerNotBlacklisted(stub, gov)
+	if err != nil {
+		return shim.Error(err.Error())
 	}
-	if len(marbleTransferInput.Owner) == 0 {
-		return shim.Error("owner field must be a non-empty string")
+	creatorMSPID, err := getCallerMSPID(stub)
+	if err != nil {
+		return shim.Error(err.Error())
 	}
-
-	marbleAsBytes, err := stub.GetPrivateData("collectionMarbles", marbleTransferInput.Name)
+	err = checkAuthorizedCreator(stub, creatorMSPID)
 	if err != nil {
-		return shim.Error("Failed to get marble:" + err.Error())
-	} else if marbleAsBytes == nil {
-		return shim.Error("Marble does not exist: " + marbleTransferInput.Name)
+		return shim.Error(err.Error())
 	}
-
-	marbleToTransfer := marble{}
-	err = json.Unmarshal(marbleAsBytes, &marbleToTransfer) //unmarshal it aka JSON.parse()
+	err = consumeCreationQuota(stub, creatorMSPID, len(marbleInputs))
 	if err != nil {
 		return shim.Error(err.Error())
 	}
-	marbleToTransfer.Owner = marbleTransferInput.Owner //change the owner
-
-	marbleJSONasBytes, _ := json.Marshal(marbleToTransfer)
-	err = stub.PutPrivateData("collectionMarbles", marbleToTransfer.Name, marbleJSONasBytes) //rewrite the marble
+	currentTime, err := getTxUnixTime(stub)
 	if err != nil {
 		return shim.Error(err.Error())
 	}
 
-	fmt.Println("- end transferMarble (success)")
-	return shim.Success(nil)
-}
-
-// ===========================================================================================
-// getMarblesByRange performs a range query based on the start and end keys provided.
-
-// Read-only function results are not typically submitted to ordering. If the read-only
-// results are submitted to ordering, or if the query is used in an update transaction
+	// ==== Validate every marble before writing any of them ====
+	marbles := make([]*marble, len(marbleInputs))
+	marblesJSON := make([][]byte, len(marbleInputs))
+	names := make([]string, len(marbleInputs))
+	series := make([]string, len(marbleInputs))
+	var totalPrice int64
+	added := map[string]int{}
+	for i := range marbleInputs {
+		marbleInput := &marbleInputs[i]
+		err = checkMarbleInput(stub, gov, marbleInput)
+		if err != nil {
+			return shim.Error(fmt.Sprintf("marble %d: %s", i, err.Error()))
+		}
+		if containsString(names[:i], marbleInput.Name) {
+			return shim.Error(fmt.Sprintf("marble %d: %s appears more than once", i, marbleInput.Name))
+		}
+		marbles[i], marblesJSON[i], err = prepareMarble(stub, gov, marbleInput, currentTime)
+		if err != nil {
+			return shim.Error(fmt.Sprintf("marble %d: %s", i, err.Error()))
+		}
+		names[i] = marbleInput.Name
+		series[i] = marbleInput.Series
+		totalPrice += marbleInput.Price
+		added[marbleInput.Owner]++
+	}
+	for owner, count := range added {
+		err = checkOwnerMaxMarbles(stub, owner, count)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+	}
+
+	for i, m := range marbles {
+		err = writeNewMarble(stub, m, marblesJSON[i], marbleInputs[i].Price, creatorMSPID)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+	}
+	err = registerSeries(stub, series)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = adjustMarketCap(stub, "", totalPrice)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	namesAsBytes, err := json.Marshal(names)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	fmt.Println("- end bulk init marbles")
+	return shim.Success(namesAsBytes)
+}
+
+// ===============================================
+// readMarble - read a marble from chaincode state
+// ===============================================
+func (t *SimpleChaincode) readMarble(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	var name, jsonResp string
+	var err error
+
+	if len(args) != 1 {
+		return shim.Error("Incorrect number of arguments. Expecting name of the marble to query")
+	}
+
+	name = args[0]
+	err = validateMarbleName(name)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	valAsbytes, err := stub.GetPrivateData("collectionMarbles", name) //get the marble from chaincode state
+	if err != nil {
+		jsonResp = "{\"Error\":\"Failed to get state for " + name + "\"}"
+		return shim.Error(jsonResp)
+	} else if valAsbytes == nil {
+		jsonResp = "{\"Error\":\"Marble does not exist: " + name + "\"}"
+		return shim.Error(jsonResp)
+	}
+
+	return shim.Success(valAsbytes)
+}
+
+// ===============================================
+// readMarblereadMarblePrivateDetails - read a marble private details from chaincode state
+// ===============================================
+func (t *SimpleChaincode) readMarblePrivateDetails(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	var name, jsonResp string
+	var err error
+
+	if len(args) != 1 {
+		return shim.Error("Incorrect number of arguments. Expecting name of the marble to query")
+	}
+
+	name = args[0]
+	err = validateMarbleName(name)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	valAsbytes, err := stub.GetPrivateData("collectionMarblePrivateDetails", name) //get the marble private details from chaincode state
+	if err != nil {
+		jsonResp = "{\"Error\":\"Failed to get private details for " + name + ": " + err.Error() + "\"}"
+		return shim.Error(jsonResp)
+	} else if valAsbytes == nil {
+		jsonResp = "{\"Error\":\"Marble private details does not exist: " + name + "\"}"
+		return shim.Error(jsonResp)
+	}
+
+	return shim.Success(valAsbytes)
+}
+
+// ===============================================
+// readMarbleFull - a marble from collectionMarbles merged with its private
+// details in one call. The merge is done field by field, so either
+// collection may gain fields without a change here; a field present in
+// both keeps its public value. When the caller's organization cannot read
+// collectionMarblePrivateDetails, only the public fields are returned; any
+// other failure to read the private details is an error.
+// ===============================================
+func (t *SimpleChaincode) readMarbleFull(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	if len(args) != 1 {
+		return shim.Error("Incorrect number of arguments. Expecting name of the marble to query")
+	}
+
+	name := args[0]
+	err := validateMarbleName(name)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	marbleAsBytes, err := stub.GetPrivateData("collectionMarbles", name)
+	if err != nil {
+		return shim.Error("Failed to get state for " + name + ": " + err.Error())
+	} else if marbleAsBytes == nil {
+		return shim.Error("Marble does not exist: " + name)
+	}
+
+	merged := map[string]json.RawMessage{}
+	err = json.Unmarshal(marbleAsBytes, &merged)
+	if err != nil {
+		return shim.Error("Failed to decode JSON of: " + string(marbleAsBytes))
+	}
+
+	// the caller's organization may not be a member of collectionMarblePrivateDetails
+	detailsAsBytes, err := stub.GetPrivateData("collectionMarblePrivateDetails", name)
+	if err != nil && !isReadAccessDenied(err) {
+		return shim.Error("Failed to get private details for " + name + ": " + err.Error())
+	}
+	if err == nil && detailsAsBytes != nil {
+		details := map[string]json.RawMessage{}
+		err = json.Unmarshal(detailsAsBytes, &details)
+		if err != nil {
+			return shim.Error("Failed to decode JSON of: " + string(detailsAsBytes))
+		}
+		for field, value := range details {
+			if _, ok := merged[field]; !ok {
+				merged[field] = value
+			}
+		}
+	}
+
+	mergedAsBytes, err := json.Marshal(merged)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	return shim.Success(mergedAsBytes)
+}
+
+// ===============================================
+// getMarblePrivateDataHash - the hashes of a marble's private data in both
+// collections. Peers keep these hashes for every organization, so members
+// of neither collection can still confirm that the data was committed.
+// ===============================================
+func (t *SimpleChaincode) getMarblePrivateDataHash(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+
+	//   0
+	// "marble1"
+	if len(args) != 1 {
+		return shim.Error("Incorrect number of arguments. Expecting name of the marble to query")
+	}
+	name := args[0]
+	err := validateMarbleName(name)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	marbleHash, err := stub.GetPrivateDataHash("collectionMarbles", name)
+	if err != nil {
+		return shim.Error("Failed to get private data hash: " + err.Error())
+	} else if marbleHash == nil {
+		return shim.Error("Marble does not exist: " + name)
+	}
+	detailsHash, err := stub.GetPrivateDataHash("collectionMarblePrivateDetails", name)
+	if err != nil {
+		return shim.Error("Failed to get private data hash: " + err.Error())
+	} else if detailsHash == nil {
+		return shim.Error("Marble private details does not exist: " + name)
+	}
+
+	hashes := map[string]string{
+		"collectionMarbles":              hex.EncodeToString(marbleHash),
+		"collectionMarblePrivateDetails": hex.EncodeToString(detailsHash),
+	}
+	hashesAsBytes, err := json.Marshal(hashes)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	return shim.Success(hashesAsBytes)
+}
+
+// ===============================================
+// verifyMarbleOwner - whether the caller owns a marble. The caller matches by
+// its certificate common name or its MSP ID, as in requireOwner. With the
+// "require" mode a mismatch is an error, so the call can gate the other
+// steps of a composite transaction.
+// ===============================================
+func (t *SimpleChaincode) verifyMarbleOwner(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+
+	//   0          1
+	// "marble1", "require"
+	if len(args) < 1 || len(args) > 2 {
+		return shim.Error("Incorrect number of arguments. Expecting name of the marble and an optional mode")
+	}
+	requireMatch := false
+	if len(args) == 2 {
+		if args[1] != "require" {
+			return shim.Error("mode must be require")
+		}
+		requireMatch = true
+	}
+
+	m, err := getMarble(stub, args[0])
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	isOwner, err := callerIs(stub, m.Owner)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if requireMatch && !isOwner {
+		return shim.Error("caller is not the owner of marble " + m.Name)
+	}
+	return shim.Success([]byte(fmt.Sprintf("{\"isOwner\":%t}", isOwner)))
+}
+
+// deleteMarbleState removes a marble, its index entries, its shares and its private
+// details. The caller adjusts the market cap.
+func deleteMarbleState(stub shim.ChaincodeStubInterface, m *marble) error {
+	// delete the marble from state
+	err := stub.DelPrivateData("collectionMarbles", m.Name)
+	if err != nil {
+		return fmt.Errorf("Failed to delete state: %s", err.Error())
+	}
+	err = logMarbleWrite(stub, m.Name, nil)
+	if err != nil {
+		return err
+	}
+
+	// Also delete the marble from the color~name index
+	indexName := "color~name"
+	colorNameIndexKey, err := stub.CreateCompositeKey(indexName, []string{m.Color, m.Name})
+	if err != nil {
+		return err
+	}
+	err = stub.DelPrivateData("collectionMarbles", colorNameIndexKey)
+	if err != nil {
+		return fmt.Errorf("Failed to delete state: %s", err.Error())
+	}
+
+	// ... and from the owner~name index
+	err = removeOwnerIndex(stub, m)
+	if err != nil {
+		return fmt.Errorf("Failed to delete state: %s", err.Error())
+	}
+
+	// ... and from the size~name index
+	err = removeSizeIndex(stub, m)
+	if err != nil {
+		return fmt.Errorf("Failed to delete state: %s", err.Error())
+	}
+
+	// ... and from the condition~name index
+	err = removeConditionIndex(stub, m)
+	if err != nil {
+		return fmt.Errorf("Failed to delete state: %s", err.Error())
+	}
+
+	// ... and from the rarity~name index
+	err = removeRarityIndex(stub, m)
+	if err != nil {
+		return fmt.Errorf("Failed to delete state: %s", err.Error())
+	}
+
+	// ... and from the series~name index
+	err = removeSeriesIndex(stub, m)
+	if err != nil {
+		return fmt.Errorf("Failed to delete state: %s", err.Error())
+	}
+
+	// Drop the listing index entry if the marble was offered for sale
+	if m.IsForSale {
+		err = removeListingIndex(stub, m)
+		if err != nil {
+			return fmt.Errorf("Failed to delete state: %s", err.Error())
+		}
+	}
+
+	// ... and from the whitepaper~name index
+	if len(m.WhitepaperCID) != 0 {
+		err = removeWhitepaperIndex(stub, m)
+		if err != nil {
+			return fmt.Errorf("Failed to delete state: %s", err.Error())
+		}
+	}
+
+	// ... and its shares, if it was jointly owned
+	err = removeMarbleShares(stub, m.Name)
+	if err != nil {
+		return fmt.Errorf("Failed to delete state: %s", err.Error())
+	}
+
+	// Finally, delete private details of marble
+	err = stub.DelPrivateData("collectionMarblePrivateDetails", m.Name)
+	if err != nil {
+		return fmt.Errorf("Failed to delete state: %s", err.Error())
+	}
+	return nil
+}
+
+// ==================================================
+// delete - remove a marble key/value pair from state
+// ==================================================
+func (t *SimpleChaincode) delete(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	fmt.Println("- start delete marble")
+
+	type marbleDeleteTransientInput struct {
+		Name string `json:"name"`
+		// Force is needed to delete an archived marble
+		Force bool `json:"force"`
+	}
+
+	if len(args) != 0 {
+		return shim.Error("Incorrect number of arguments. Private marble name must be passed in transient map.")
+	}
+
+	transMap, err := stub.GetTransient()
+	if err != nil {
+		return shim.Error("Error getting transient: " + err.Error())
+	}
+
+	if _, ok := transMap["marble_delete"]; !ok {
+		return shim.Error("marble_delete must be a key in the transient map")
+	}
+
+	if len(transMap["marble_delete"]) == 0 {
+		return shim.Error("marble_delete value in the transient map must be a non-empty JSON string")
+	}
+
+	var marbleDeleteInput marbleDeleteTransientInput
+	err = json.Unmarshal(transMap["marble_delete"], &marbleDeleteInput)
+	if err != nil {
+		return shim.Error("Failed to decode JSON of: " + string(transMap["marble_delete"]))
+	}
+
+	if len(marbleDeleteInput.Name) == 0 {
+		return shim.Error("name field must be a non-empty string")
+	}
+	err = validateMarbleName(marbleDeleteInput.Name)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	// to maintain the color~name index, we need to read the marble first and get its color
+	valAsbytes, err := stub.GetPrivateData("collectionMarbles", marbleDeleteInput.Name) //get the marble from chaincode state
+	if err != nil {
+		return shim.Error("Failed to get state for " + marbleDeleteInput.Name)
+	} else if valAsbytes == nil {
+		return shim.Error("Marble does not exist: " + marbleDeleteInput.Name)
+	}
+
+	var marbleToDelete marble
+	err = json.Unmarshal([]byte(valAsbytes), &marbleToDelete)
+	if err != nil {
+		return shim.Error("Failed to decode JSON of: " + string(valAsbytes))
+	}
+	err = checkNotLocked(&marbleToDelete)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if marbleToDelete.IsArchived && !marbleDeleteInput.Force {
+		return shim.Error("marble " + marbleToDelete.Name + " is archived, set force to delete it")
+	}
+	err = requireShareholderApproval(stub, marbleToDelete.Name, shareActionDelete, "")
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	// take its price off the market cap before its private details go
+	details, err := getMarblePrivateDetails(stub, marbleToDelete.Name)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = deleteMarbleState(stub, &marbleToDelete)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = adjustMarketCap(stub, marbleToDelete.Name, -details.Price)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	err = emitMarbleLifecycleEvent(stub, "MarbleDeleted", marbleDeleteInput.Name)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	return shim.Success(nil)
+}
+
+// ===========================================================
+// transfer a marble by setting a new owner name on the marble
+// ===========================================================
+func (t *SimpleChaincode) transferMarble(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+
+	fmt.Println("- start transfer marble")
+
+	type marbleTransferTransientInput struct {
+		Name  string `json:"name"`
+		Owner string `json:"owner"`
+	}
+
+	if len(args) != 0 {
+		return shim.Error("Incorrect number of arguments. Private marble data must be passed in transient map.")
+	}
+
+	transMap, err := stub.GetTransient()
+	if err != nil {
+		return shim.Error("Error getting transient: " + err.Error())
+	}
+
+	if _, ok := transMap["marble_owner"]; !ok {
+		return shim.Error("marble_owner must be a key in the transient map")
+	}
+
+	if len(transMap["marble_owner"]) == 0 {
+		return shim.Error("marble_owner value in the transient map must be a non-empty JSON string")
+	}
+
+	var marbleTransferInput marbleTransferTransientInput
+	err = json.Unmarshal(transMap["marble_owner"], &marbleTransferInput)
+	if err != nil {
+		return shim.Error("Failed to decode JSON of: " + string(transMap["marble_owner"]))
+	}
+
+	if len(marbleTransferInput.Name) == 0 {
+		return shim.Error("name field must be a non-empty string")
+	}
+	err = validateMarbleName(marbleTransferInput.Name)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if len(marbleTransferInput.Owner) == 0 {
+		return shim.Error("owner field must be a non-empty string")
+	}
+
+	gov, err := getGovernance(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = checkCallerNotBlacklisted(stub, gov)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	marbleAsBytes, err := stub.GetPrivateData("collectionMarbles", marbleTransferInput.Name)
+	if err != nil {
+		return shim.Error("Failed to get marble:" + err.Error())
+	} else if marbleAsBytes == nil {
+		return shim.Error("Marble does not exist: " + marbleTransferInput.Name)
+	}
+
+	marbleToTransfer := marble{}
+	err = json.Unmarshal(marbleAsBytes, &marbleToTransfer) //unmarshal it aka JSON.parse()
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = transferMarbleTo(stub, gov, &marbleToTransfer, marbleTransferInput.Owner) //change the owner
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = emitMarbleLifecycleEvent(stub, "MarbleTransferred", marbleToTransfer.Name)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	fmt.Println("- end transferMarble (success)")
+	return shim.Success(nil)
+}
+
+// ===========================================================================
+// bulkTransferMarbles - move every marble of one owner to another, e.g. when
+// an owner account is closed or merged. Admin only. Each marble goes through
+// every gate of a transfer, and the batch fails as a whole if one does not.
+// ===========================================================================
+func (t *SimpleChaincode) bulkTransferMarbles(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	fmt.Println("- start bulk transfer marbles")
+
+	type marbleBulkOwnerTransientInput struct {
+		FromOwner string `json:"fromOwner"`
+		ToOwner   string `json:"toOwner"`
+	}
+
+	if len(args) != 0 {
+		return shim.Error("Incorrect number of arguments. Private marble data must be passed in transient map.")
+	}
+
+	var bulkOwnerInput marbleBulkOwnerTransientInput
+	err := getTransientInput(stub, "marble_bulk_owner", &bulkOwnerInput)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if len(bulkOwnerInput.FromOwner) == 0 {
+		return shim.Error("fromOwner field must be a non-empty string")
+	}
+	if len(bulkOwnerInput.ToOwner) == 0 {
+		return shim.Error("toOwner field must be a non-empty string")
+	}
+	if bulkOwnerInput.FromOwner == bulkOwnerInput.ToOwner {
+		return shim.Error("fromOwner and toOwner must differ")
+	}
+
+	err = requireAdmin(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	gov, err := getGovernance(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	names, err := getMarbleNamesByOwner(stub, bulkOwnerInput.FromOwner)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	// the whole batch must fit under the new owner's limit before any marble moves
+	err = checkOwnerMaxMarbles(stub, bulkOwnerInput.ToOwner, len(names))
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	for _, name := range names {
+		m, err := getMarble(stub, name)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		err = transferMarbleTo(stub, gov, m, bulkOwnerInput.ToOwner)
+		if err != nil {
+			return shim.Error("Failed to transfer marble " + name + ": " + err.Error())
+		}
+	}
+
+	fmt.Println("- end bulk transfer marbles")
+	return shim.Success([]byte(fmt.Sprintf("{\"transferred\":%d}", len(names))))
+}
+
+// ===========================================================================================
+// getMarblesByRange performs a range query based on the start and end keys provided.
+
+// Read-only function results are not typically submitted to ordering. If the read-only
+// results are submitted to ordering, or if the query is used in an update transaction
 // and submitted to ordering, then the committing peers will re-execute to guarantee that
 // result sets are stable between endorsement time and commit time. The transaction is
 // invalidated by the committing peers if the result set has changed between endorsement
@@ -424,157 +1143,799 @@ func (t *SimpleChaincode) transferMarble(stub shim.ChaincodeStubInterface, args
 // ===========================================================================================
 func (t *SimpleChaincode) getMarblesByRange(stub shim.ChaincodeStubInterface, args []string) pb.Response {
 
-	if len(args) < 2 {
-		return shim.Error("Incorrect number of arguments. Expecting 2")
+	if len(args) < 2 {
+		return shim.Error("Incorrect number of arguments. Expecting 2")
+	}
+
+	startKey := args[0]
+	endKey := args[1]
+
+	resultsIterator, err := stub.GetPrivateDataByRange("collectionMarbles", startKey, endKey)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	defer resultsIterator.Close()
+
+	records, err := collectQueryRecords(resultsIterator)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	resultAsBytes, err := json.Marshal(records)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	fmt.Printf("- getMarblesByRange queryResult:\n%s\n", string(resultAsBytes))
+
+	return shim.Success(resultAsBytes)
+}
+
+// ===========================================================================================
+// getMarblesByRangeWithMetadata is getMarblesByRange with the results wrapped in an
+// envelope giving their count, the range and the transaction time. Entries in the range
+// that are not marbles, such as bundles or pending transfers, are left out of records
+// and count.
+// ===========================================================================================
+func (t *SimpleChaincode) getMarblesByRangeWithMetadata(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+
+	type rangeQueryEnvelope struct {
+		Records   []queryRecord `json:"records"`
+		Count     int           `json:"count"`
+		StartKey  string        `json:"startKey"`
+		EndKey    string        `json:"endKey"`
+		QueryTime string        `json:"queryTime"`
+	}
+
+	//    0        1
+	// "marble1", "marble9"
+	if len(args) != 2 {
+		return shim.Error("Incorrect number of arguments. Expecting 2")
+	}
+
+	txTime, err := getTxTime(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	resultsIterator, err := stub.GetPrivateDataByRange("collectionMarbles", args[0], args[1])
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	defer resultsIterator.Close()
+
+	records := []queryRecord{}
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		var candidate marble
+		if json.Unmarshal(queryResponse.Value, &candidate) != nil || candidate.ObjectType != "marble" {
+			continue
+		}
+		records = append(records, queryRecord{Key: queryResponse.Key, Record: queryResponse.Value})
+	}
+
+	envelopeAsBytes, err := json.Marshal(rangeQueryEnvelope{
+		Records:   records,
+		Count:     len(records),
+		StartKey:  args[0],
+		EndKey:    args[1],
+		QueryTime: txTime.Format(time.RFC3339),
+	})
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	return shim.Success(envelopeAsBytes)
+}
+
+// ===========================================================================================
+// getMarblesByRangePaginated performs a range query in pages of pageSize marbles.
+// The shim has no paginated range query for private data, so the page is cut from an
+// ordinary range query. The returned bookmark is the key to resume from, or empty once
+// the range is exhausted; pass an empty bookmark to start at startKey.
+// ===========================================================================================
+func (t *SimpleChaincode) getMarblesByRangePaginated(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+
+	//      0          1        2      3
+	// "marble1", "marble9", "25", "bookmark"
+	if len(args) != 4 {
+		return shim.Error("Incorrect number of arguments. Expecting 4")
+	}
+
+	startKey := args[0]
+	endKey := args[1]
+	pageSize, err := strconv.Atoi(args[2])
+	if err != nil || pageSize <= 0 {
+		return shim.Error("pageSize must be a positive integer")
+	}
+	bookmark := args[3]
+	if len(bookmark) != 0 {
+		if bookmark < startKey || (len(endKey) != 0 && bookmark >= endKey) {
+			return shim.Error("bookmark is outside of the range")
+		}
+		startKey = bookmark
+	}
+
+	resultsIterator, err := stub.GetPrivateDataByRange("collectionMarbles", startKey, endKey)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	defer resultsIterator.Close()
+
+	results := []queryRecord{}
+	nextBookmark := ""
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		if queryResponse.Key == initInfoKey {
+			continue
+		}
+		if len(results) == pageSize {
+			nextBookmark = queryResponse.Key
+			break
+		}
+		results = append(results, queryRecord{Key: queryResponse.Key, Record: queryResponse.Value})
+	}
+
+	page := struct {
+		Results             []queryRecord `json:"results"`
+		FetchedRecordsCount int           `json:"fetchedRecordsCount"`
+		Bookmark            string        `json:"bookmark"`
+	}{results, len(results), nextBookmark}
+	pageAsBytes, err := json.Marshal(page)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	return shim.Success(pageAsBytes)
+}
+
+// =======Rich queries =========================================================================
+// Two examples of rich queries are provided below (parameterized query and ad hoc query).
+// Rich queries pass a query string to the state database.
+// Rich queries are only supported by state database implementations
+//  that support rich query (e.g. CouchDB).
+// The query string is in the syntax of the underlying state database.
+// With rich queries there is no guarantee that the result set hasn't changed between
+//  endorsement time and commit time, aka 'phantom reads'.
+// Therefore, rich queries should not be used in update transactions, unless the
+// application handles the possibility of result set changes between endorsement and commit time.
+// Rich queries can be used for point-in-time queries against a peer.
+// ============================================================================================
+
+// ===== Example: Parameterized rich query =================================================
+// queryMarblesByOwner queries for marbles based on a passed in owner.
+// This is an example of a parameterized query where the query logic is baked into the chaincode,
+// and accepting a single query parameter (owner). Archived marbles are left out unless
+// the optional includeArchived parameter is "true". When the optional enrichWithProfile
+// parameter is "true", each result carries the display name of the owner's profile.
+// Only available on state databases that support rich query (e.g. CouchDB)
+// =========================================================================================
+func (t *SimpleChaincode) queryMarblesByOwner(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+
+	//   0       1        2
+	// "bob", "false", "true"
+	if len(args) < 1 {
+		return shim.Error("Incorrect number of arguments. Expecting 1")
+	}
+
+	owner := strings.ToLower(args[0])
+	includeArchived, err := parseIncludeArchived(args, 1)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	enrichWithProfile := false
+	if len(args) > 2 {
+		enrichWithProfile, err = strconv.ParseBool(args[2])
+		if err != nil {
+			return shim.Error("enrichWithProfile must be true or false")
+		}
+	}
+
+	queryString := fmt.Sprintf("{\"selector\":{\"docType\":\"marble\",\"owner\":\"%s\"}}", owner)
+	if !includeArchived {
+		queryString, err = excludeArchived(queryString)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+	}
+
+	if enrichWithProfile {
+		resultsIterator, err := stub.GetPrivateDataQueryResult("collectionMarbles", queryString)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		defer resultsIterator.Close()
+		records, err := collectQueryRecords(resultsIterator)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		enriched, err := enrichWithProfiles(stub, records)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		enrichedAsBytes, err := json.Marshal(enriched)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		return shim.Success(enrichedAsBytes)
+	}
+
+	queryResults, err := getQueryResultForQueryString(stub, queryString)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	return shim.Success(queryResults)
+}
+
+// ===== Example: Ad hoc rich query ========================================================
+// queryMarbles uses a query string to perform a query for marbles.
+// Query string matching state database syntax is passed in and executed as is, except
+// that archived marbles are left out unless includeArchived is "true".
+// Supports ad hoc queries that can be defined at runtime by the client.
+// If this is not desired, follow the queryMarblesForOwner example for parameterized queries.
+// Only available on state databases that support rich query (e.g. CouchDB)
+// =========================================================================================
+func (t *SimpleChaincode) queryMarbles(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+
+	//   0              1
+	// "queryString", "false"
+	if len(args) < 1 {
+		return shim.Error("Incorrect number of arguments. Expecting 1")
+	}
+
+	queryString := args[0]
+	includeArchived, err := parseIncludeArchived(args, 1)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if !includeArchived {
+		queryString, err = excludeArchived(queryString)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+	}
+
+	queryResults, err := getQueryResultForQueryString(stub, queryString)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	return shim.Success(queryResults)
+}
+
+// =========================================================================================
+// getRecentlyModifiedMarbles queries for the marbles written at or after an RFC3339
+// timestamp. UpdatedAt is always stored in UTC, so the timestamp is converted to UTC to
+// compare as a string.
+// Only available on state databases that support rich query (e.g. CouchDB)
+// =========================================================================================
+func (t *SimpleChaincode) getRecentlyModifiedMarbles(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+
+	//            0
+	// "2024-01-31T00:00:00Z"
+	if len(args) != 1 {
+		return shim.Error("Incorrect number of arguments. Expecting 1")
+	}
+	since, err := time.Parse(time.RFC3339, args[0])
+	if err != nil {
+		return shim.Error("since must be an RFC3339 timestamp")
+	}
+
+	queryString := fmt.Sprintf("{\"selector\":{\"docType\":\"marble\",\"updatedAt\":{\"$gte\":\"%s\"}}}", since.UTC().Format(time.RFC3339))
+
+	queryResults, err := getQueryResultForQueryString(stub, queryString)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	return shim.Success(queryResults)
+}
+
+// =========================================================================================
+// queryMarblesByOwnerPaginated is queryMarblesByOwner in pages of pageSize marbles.
+// Only available on state databases that support rich query (e.g. CouchDB)
+// =========================================================================================
+func (t *SimpleChaincode) queryMarblesByOwnerPaginated(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+
+	//   0     1      2
+	// "bob", "25", "bookmark"
+	if len(args) != 3 {
+		return shim.Error("Incorrect number of arguments. Expecting 3")
 	}
 
-	startKey := args[0]
-	endKey := args[1]
+	owner := strings.ToLower(args[0])
 
-	resultsIterator, err := stub.GetPrivateDataByRange("collectionMarbles", startKey, endKey)
+	queryString := fmt.Sprintf("{\"selector\":{\"docType\":\"marble\",\"owner\":\"%s\"}}", owner)
+
+	return paginatedQueryResponse(stub, queryString, args[1], args[2])
+}
+
+// =========================================================================================
+// queryMarblesPaginated is queryMarbles in pages of pageSize marbles.
+// Only available on state databases that support rich query (e.g. CouchDB)
+// =========================================================================================
+func (t *SimpleChaincode) queryMarblesPaginated(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+
+	//       0           1      2
+	// "queryString", "25", "bookmark"
+	if len(args) != 3 {
+		return shim.Error("Incorrect number of arguments. Expecting 3")
+	}
+
+	return paginatedQueryResponse(stub, args[0], args[1], args[2])
+}
+
+// paginatedQueryResponse runs a paginated rich query and wraps the page in an envelope
+// with the number of records fetched and the bookmark of the next page.
+func paginatedQueryResponse(stub shim.ChaincodeStubInterface, queryString, pageSizeArg, bookmark string) pb.Response {
+	pageSize, err := strconv.ParseInt(pageSizeArg, 10, 32)
+	if err != nil || pageSize <= 0 {
+		return shim.Error("pageSize must be a positive integer")
+	}
+
+	queryResults, nextBookmark, err := getQueryResultForQueryStringWithPagination(stub, queryString, int32(pageSize), bookmark)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	var records []json.RawMessage
+	err = json.Unmarshal(queryResults, &records)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	page := struct {
+		Results             json.RawMessage `json:"results"`
+		FetchedRecordsCount int             `json:"fetchedRecordsCount"`
+		Bookmark            string          `json:"bookmark"`
+	}{queryResults, len(records), nextBookmark}
+	pageAsBytes, err := json.Marshal(page)
 	if err != nil {
 		return shim.Error(err.Error())
 	}
+	return shim.Success(pageAsBytes)
+}
+
+// =========================================================================================
+// getQueryResultForQueryString executes the passed in query string.
+// Result set is built and returned as a byte array containing the JSON results.
+// =========================================================================================
+func getQueryResultForQueryString(stub shim.ChaincodeStubInterface, queryString string) ([]byte, error) {
+
+	fmt.Printf("- getQueryResultForQueryString queryString:\n%s\n", queryString)
+
+	resultsIterator, err := stub.GetPrivateDataQueryResult("collectionMarbles", queryString)
+	if err != nil {
+		return nil, err
+	}
 	defer resultsIterator.Close()
 
-	// buffer is a JSON array containing QueryResults
-	var buffer bytes.Buffer
-	buffer.WriteString("[")
+	records, err := collectQueryRecords(resultsIterator)
+	if err != nil {
+		return nil, err
+	}
+	resultAsBytes, err := json.Marshal(records)
+	if err != nil {
+		return nil, err
+	}
+
+	fmt.Printf("- getQueryResultForQueryString queryResult:\n%s\n", string(resultAsBytes))
+
+	return resultAsBytes, nil
+}
 
-	bArrayMemberAlreadyWritten := false
+// collectQueryRecords reads every result of a query as a queryRecord. Encoding the
+// records with encoding/json escapes keys properly, and fails on values that are not
+// valid JSON instead of producing a malformed result. The init metadata shares
+// collectionMarbles with the marbles and is left out.
+func collectQueryRecords(resultsIterator shim.StateQueryIteratorInterface) ([]queryRecord, error) {
+	records := []queryRecord{}
 	for resultsIterator.HasNext() {
 		queryResponse, err := resultsIterator.Next()
 		if err != nil {
-			return shim.Error(err.Error())
+			return nil, err
+		}
+		if queryResponse.Key == initInfoKey {
+			continue
 		}
-		// Add a comma before array members, suppress it for the first array member
-		if bArrayMemberAlreadyWritten == true {
-			buffer.WriteString(",")
+		records = append(records, queryRecord{Key: queryResponse.Key, Record: queryResponse.Value})
+	}
+	return records, nil
+}
+
+// =========================================================================================
+// getQueryResultForQueryStringWithPagination executes the passed in query string and
+// returns pageSize results from the bookmark on, with the bookmark of the next page.
+// The shim has no paginated rich query for private data, so the bookmark is the number
+// of results already returned and the page is cut from the full result set. The next
+// bookmark is empty once the results are exhausted.
+// =========================================================================================
+func getQueryResultForQueryStringWithPagination(stub shim.ChaincodeStubInterface, queryString string, pageSize int32, bookmark string) ([]byte, string, error) {
+
+	fmt.Printf("- getQueryResultForQueryStringWithPagination queryString:\n%s\n", queryString)
+
+	offset := 0
+	if len(bookmark) != 0 {
+		var err error
+		offset, err = strconv.Atoi(bookmark)
+		if err != nil || offset < 0 {
+			return nil, "", fmt.Errorf("bookmark is not valid: %s", bookmark)
 		}
-		buffer.WriteString("{\"Key\":")
-		buffer.WriteString("\"")
-		buffer.WriteString(queryResponse.Key)
-		buffer.WriteString("\"")
+	}
+
+	resultsIterator, err := stub.GetPrivateDataQueryResult("collectionMarbles", queryString)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resultsIterator.Close()
 
-		buffer.WriteString(", \"Record\":")
-		// Record is a JSON object, so we write as-is
-		buffer.WriteString(string(queryResponse.Value))
-		buffer.WriteString("}")
-		bArrayMemberAlreadyWritten = true
+	records := []queryRecord{}
+	skipped := 0
+	nextBookmark := ""
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, "", err
+		}
+		if skipped < offset {
+			skipped++
+			continue
+		}
+		if len(records) == int(pageSize) {
+			nextBookmark = strconv.Itoa(offset + len(records))
+			break
+		}
+		records = append(records, queryRecord{Key: queryResponse.Key, Record: queryResponse.Value})
+	}
+	resultAsBytes, err := json.Marshal(records)
+	if err != nil {
+		return nil, "", err
 	}
-	buffer.WriteString("]")
 
-	fmt.Printf("- getMarblesByRange queryResult:\n%s\n", buffer.String())
+	fmt.Printf("- getQueryResultForQueryStringWithPagination queryResult:\n%s\n", string(resultAsBytes))
 
-	return shim.Success(buffer.Bytes())
+	return resultAsBytes, nextBookmark, nil
 }
 
-// =======Rich queries =========================================================================
-// Two examples of rich queries are provided below (parameterized query and ad hoc query).
-// Rich queries pass a query string to the state database.
-// Rich queries are only supported by state database implementations
-//  that support rich query (e.g. CouchDB).
-// The query string is in the syntax of the underlying state database.
-// With rich queries there is no guarantee that the result set hasn't changed between
-//  endorsement time and commit time, aka 'phantom reads'.
-// Therefore, rich queries should not be used in update transactions, unless the
-// application handles the possibility of result set changes between endorsement and commit time.
-// Rich queries can be used for point-in-time queries against a peer.
-// ============================================================================================
-
-// ===== Example: Parameterized rich query =================================================
-// queryMarblesByOwner queries for marbles based on a passed in owner.
-// This is an example of a parameterized query where the query logic is baked into the chaincode,
-// and accepting a single query parameter (owner).
-// Only available on state databases that support rich query (e.g. CouchDB)
 // =========================================================================================
-func (t *SimpleChaincode) queryMarblesByOwner(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+// getTransientInput reads the JSON value stored under key in the transient map and
+// decodes it into input.
+// =========================================================================================
+func getTransientInput(stub shim.ChaincodeStubInterface, key string, input interface{}) error {
+	transMap, err := stub.GetTransient()
+	if err != nil {
+		return fmt.Errorf("Error getting transient: %s", err.Error())
+	}
 
-	//   0
-	// "bob"
-	if len(args) < 1 {
-		return shim.Error("Incorrect number of arguments. Expecting 1")
+	if _, ok := transMap[key]; !ok {
+		return fmt.Errorf("%s must be a key in the transient map", key)
 	}
 
-	owner := strings.ToLower(args[0])
+	if len(transMap[key]) == 0 {
+		return fmt.Errorf("%s value in the transient map must be a non-empty JSON string", key)
+	}
 
-	queryString := fmt.Sprintf("{\"selector\":{\"docType\":\"marble\",\"owner\":\"%s\"}}", owner)
+	err = json.Unmarshal(transMap[key], input)
+	if err != nil {
+		return fmt.Errorf("Failed to decode JSON of: %s: %s", string(transMap[key]), err.Error())
+	}
+	return nil
+}
 
-	queryResults, err := getQueryResultForQueryString(stub, queryString)
+// =========================================================================================
+// getMarble reads a marble from collectionMarbles, failing if it does not exist.
+// =========================================================================================
+func getMarble(stub shim.ChaincodeStubInterface, name string) (*marble, error) {
+	marbleAsBytes, err := stub.GetPrivateData("collectionMarbles", name)
 	if err != nil {
-		return shim.Error(err.Error())
+		return nil, fmt.Errorf("Failed to get marble: %s", err.Error())
+	} else if marbleAsBytes == nil {
+		return nil, fmt.Errorf("Marble does not exist: %s", name)
 	}
-	return shim.Success(queryResults)
+
+	m := &marble{}
+	err = json.Unmarshal(marbleAsBytes, m)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to decode JSON of: %s", string(marbleAsBytes))
+	}
+	return m, nil
 }
 
-// ===== Example: Ad hoc rich query ========================================================
-// queryMarbles uses a query string to perform a query for marbles.
-// Query string matching state database syntax is passed in and executed as is.
-// Supports ad hoc queries that can be defined at runtime by the client.
-// If this is not desired, follow the queryMarblesForOwner example for parameterized queries.
-// Only available on state databases that support rich query (e.g. CouchDB)
 // =========================================================================================
-func (t *SimpleChaincode) queryMarbles(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+// getMarblePrivateDetails reads the private details of a marble from
+// collectionMarblePrivateDetails, failing if they do not exist.
+// =========================================================================================
+func getMarblePrivateDetails(stub shim.ChaincodeStubInterface, name string) (*marblePrivateDetails, error) {
+	detailsAsBytes, err := stub.GetPrivateData("collectionMarblePrivateDetails", name)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get private details for %s: %s", name, err.Error())
+	} else if detailsAsBytes == nil {
+		return nil, fmt.Errorf("Marble private details does not exist: %s", name)
+	}
 
-	//   0
-	// "queryString"
-	if len(args) < 1 {
-		return shim.Error("Incorrect number of arguments. Expecting 1")
+	details := &marblePrivateDetails{}
+	err = json.Unmarshal(detailsAsBytes, details)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to decode JSON of: %s", string(detailsAsBytes))
 	}
+	return details, nil
+}
 
-	queryString := args[0]
+// ownerNameIndexName indexes marbles by owner so that owner lookups do not need a
+// rich query.
+const ownerNameIndexName = "owner~name"
 
-	queryResults, err := getQueryResultForQueryString(stub, queryString)
+func addOwnerIndex(stub shim.ChaincodeStubInterface, m *marble) error {
+	ownerNameIndexKey, err := stub.CreateCompositeKey(ownerNameIndexName, []string{m.Owner, m.Name})
 	if err != nil {
-		return shim.Error(err.Error())
+		return err
 	}
-	return shim.Success(queryResults)
+	return stub.PutPrivateData("collectionMarbles", ownerNameIndexKey, []byte{0x00})
+}
+
+func removeOwnerIndex(stub shim.ChaincodeStubInterface, m *marble) error {
+	ownerNameIndexKey, err := stub.CreateCompositeKey(ownerNameIndexName, []string{m.Owner, m.Name})
+	if err != nil {
+		return err
+	}
+	return stub.DelPrivateData("collectionMarbles", ownerNameIndexKey)
 }
 
 // =========================================================================================
-// getQueryResultForQueryString executes the passed in query string.
-// Result set is built and returned as a byte array containing the JSON results.
+// getMarbleNamesByOwner returns the names of the marbles held by owner, read from the
+// owner~name index.
 // =========================================================================================
-func getQueryResultForQueryString(stub shim.ChaincodeStubInterface, queryString string) ([]byte, error) {
-
-	fmt.Printf("- getQueryResultForQueryString queryString:\n%s\n", queryString)
-
-	resultsIterator, err := stub.GetPrivateDataQueryResult("collectionMarbles", queryString)
+func getMarbleNamesByOwner(stub shim.ChaincodeStubInterface, owner string) ([]string, error) {
+	resultsIterator, err := stub.GetPrivateDataByPartialCompositeKey("collectionMarbles", ownerNameIndexName, []string{owner})
 	if err != nil {
 		return nil, err
 	}
 	defer resultsIterator.Close()
 
-	// buffer is a JSON array containing QueryRecords
-	var buffer bytes.Buffer
-	buffer.WriteString("[")
-
-	bArrayMemberAlreadyWritten := false
+	names := []string{}
 	for resultsIterator.HasNext() {
-		queryResponse, err := resultsIterator.Next()
+		responseRange, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+		_, compositeKeyParts, err := stub.SplitCompositeKey(responseRange.Key)
 		if err != nil {
 			return nil, err
 		}
-		// Add a comma before array members, suppress it for the first array member
-		if bArrayMemberAlreadyWritten == true {
-			buffer.WriteString(",")
+		names = append(names, compositeKeyParts[1])
+	}
+	return names, nil
+}
+
+// ===========================================================================================
+// getMarblesByOwnerRange returns the names of the marbles held by an owner from the
+// owner~name index. Unlike queryMarblesByOwner it does not need rich query support, so it
+// works on LevelDB as well as CouchDB.
+// ===========================================================================================
+func (t *SimpleChaincode) getMarblesByOwnerRange(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+
+	//   0
+	// "bob"
+	if len(args) != 1 {
+		return shim.Error("Incorrect number of arguments. Expecting owner name")
+	}
+
+	names, err := getMarbleNamesByOwner(stub, args[0])
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	namesAsBytes, err := json.Marshal(names)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	return shim.Success(namesAsBytes)
+}
+
+// =========================================================================================
+// checkOwnerChange fails if the marble may not move to newOwner: while it is locked,
+// archived or reserved for another party, while a physical handover awaits receipt and
+// during a governance blackout period. It writes nothing.
+// =========================================================================================
+func checkOwnerChange(stub shim.ChaincodeStubInterface, gov *governance, m *marble, newOwner string) error {
+	err := checkNotLocked(m)
+	if err != nil {
+		return err
+	}
+	err = checkNotArchived(m)
+	if err != nil {
+		return err
+	}
+	err = checkNoOpenHandover(m)
+	if err != nil {
+		return err
+	}
+	currentTime, err := getTxUnixTime(stub)
+	if err != nil {
+		return err
+	}
+	if blackout := gov.isBlackoutActive(currentTime); blackout != nil {
+		return fmt.Errorf("transfers are blocked: %s", blackout.Reason)
+	}
+	if isReserved(m, currentTime) && newOwner != m.ReservedFor {
+		return fmt.Errorf("marble is reserved for another party")
+	}
+	return nil
+}
+
+// =========================================================================================
+// changeMarbleOwner moves a marble to a new owner. The marble leaves the market, the
+// owner~name index follows the new owner, the old owner is kept as PreviousOwner and a
+// custody handover to the new owner is opened. The transfer adds the governance carbon
+// cost to the marble's footprint. It fails, before writing anything, when
+// checkOwnerChange does or when the shareholders of a jointly owned marble have not
+// approved the transfer to newOwner; every change of owner goes through here, so no
+// path around that approval exists. The caller is responsible for writing the marble
+// back.
+// =========================================================================================
+func changeMarbleOwner(stub shim.ChaincodeStubInterface, m *marble, newOwner string) error {
+	gov, err := getGovernance(stub)
+	if err != nil {
+		return err
+	}
+	err = checkOwnerChange(stub, gov, m, newOwner)
+	if err != nil {
+		return err
+	}
+	err = requireShareholderApproval(stub, m.Name, shareActionTransfer, newOwner)
+	if err != nil {
+		return err
+	}
+	currentTime, err := getTxUnixTime(stub)
+	if err != nil {
+		return err
+	}
+
+	// a transferred marble is no longer offered by its previous owner
+	if m.IsForSale {
+		err := removeListingIndex(stub, m)
+		if err != nil {
+			return err
+		}
+		m.IsForSale = false
+		m.AskingPrice = 0
+	}
+
+	clearReservation(m)
+	m.CarbonFootprint += gov.CarbonCostPerTransfer
+	m.LastActivityTime = currentTime
+
+	err = removeOwnerIndex(stub, m)
+	if err != nil {
+		return err
+	}
+	err = invalidateOwnershipCredentials(stub, m.Name)
+	if err != nil {
+		return err
+	}
+	if newOwner != m.Owner {
+		err = clearAutoListPolicy(stub, m.Name)
+		if err != nil {
+			return err
+		}
+	}
+	m.PreviousOwner = m.Owner
+	m.Owner = newOwner
+	m.PreviousTransferTime = m.LastTransferTime
+	m.LastTransferTime = currentTime
+	m.SellerReputation = gov.inheritedReputation(m, newOwner)
+	err = appendCustodyRecord(stub, m, newOwner, "ownership transfer", true)
+	if err != nil {
+		return err
+	}
+	return addOwnerIndex(stub, m)
+}
+
+// =========================================================================================
+// transferMarbleTo sells a marble to newOwner through every gate of a transfer: the
+// new owner's marble limit, the owner change itself with its shareholder approval, the
+// tax receipt and the external validator. The marble is written back.
+// =========================================================================================
+func transferMarbleTo(stub shim.ChaincodeStubInterface, gov *governance, m *marble, newOwner string) error {
+	if newOwner != m.Owner {
+		err := checkOwnerMaxMarbles(stub, newOwner, 1)
+		if err != nil {
+			return err
 		}
-		buffer.WriteString("{\"Key\":")
-		buffer.WriteString("\"")
-		buffer.WriteString(queryResponse.Key)
-		buffer.WriteString("\"")
+	}
+	err := changeMarbleOwner(stub, m, newOwner)
+	if err != nil {
+		return err
+	}
+
+	err = writeTaxReceipt(stub, gov, m)
+	if err != nil {
+		return err
+	}
+
+	txTime, err := getTxTime(stub)
+	if err != nil {
+		return err
+	}
+	m.UpdatedAt = txTime.Format(time.RFC3339)
 
-		buffer.WriteString(", \"Record\":")
-		// Record is a JSON object, so we write as-is
-		buffer.WriteString(string(queryResponse.Value))
-		buffer.WriteString("}")
-		bArrayMemberAlreadyWritten = true
+	marbleJSONasBytes, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	err = checkExternalValidator(stub, gov, marbleJSONasBytes)
+	if err != nil {
+		return err
+	}
+	err = stub.PutPrivateData("collectionMarbles", m.Name, marbleJSONasBytes) //rewrite the marble
+	if err != nil {
+		return err
 	}
-	buffer.WriteString("]")
+	return logMarbleWrite(stub, m.Name, marbleJSONasBytes)
+}
+
+// marbleLifecycleEvent is the payload of the marble events, such as MarbleCreated or
+// TransferAccepted. Event payloads are visible to the whole channel, so it carries no
+// private marble data beyond the name.
+type marbleLifecycleEvent struct {
+	MarbleName string `json:"marbleName"`
+	MSPID      string `json:"mspID"`
+	TxID       string `json:"txID"`
+}
 
-	fmt.Printf("- getQueryResultForQueryString queryResult:\n%s\n", buffer.String())
+// emitMarbleLifecycleEvent emits the named lifecycle event for a marble. It replaces any
+// event set earlier in the transaction, see emitEvent.
+func emitMarbleLifecycleEvent(stub shim.ChaincodeStubInterface, eventName, marbleName string) error {
+	callerMSPID, err := getCallerMSPID(stub)
+	if err != nil {
+		return err
+	}
+	return emitEvent(stub, eventName, marbleLifecycleEvent{
+		MarbleName: marbleName,
+		MSPID:      callerMSPID,
+		TxID:       stub.GetTxID(),
+	})
+}
 
-	return buffer.Bytes(), nil
+// =========================================================================================
+// logMarbleWrite records a write of a marble, given as JSON, or its deletion when
+// marbleJSON is nil, in the event log and the endorsement log. The cached registry
+// stats no longer hold after the write and are dropped.
+// =========================================================================================
+func logMarbleWrite(stub shim.ChaincodeStubInterface, marbleName string, marbleJSON []byte) error {
+	err := appendMarbleEvent(stub, marbleName, marbleJSON)
+	if err != nil {
+		return err
+	}
+	err = invalidateRegistryStats(stub)
+	if err != nil {
+		return err
+	}
+	return appendEndorsementRecord(stub, marbleName)
 }
 
+// =========================================================================================
+// putMarble marshals a marble, writes it to collectionMarbles and logs the write.
+// The write counts as activity of the marble and stamps its UpdatedAt.
+// =========================================================================================
+func putMarble(stub shim.ChaincodeStubInterface, m *marble) error {
+	currentTime, err := getTxUnixTime(stub)
+	if err != nil {
+		return err
+	}
+	m.LastActivityTime = currentTime
+	txTime, err := getTxTime(stub)
+	if err != nil {
+		return err
+	}
+	m.UpdatedAt = txTime.Format(time.RFC3339)
 
+	marbleJSONasBytes, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	err = stub.PutPrivateData("collectionMarbles", m.Name, marbleJSONasBytes)
+	if err != nil {
+		return err
+	}
+	return logMarbleWrite(stub, m.Name, marbleJSONasBytes)
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"strconv"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// maxCSVRows caps the marbles in one CSV export, so a broad query cannot exhaust the
+// chaincode's memory.
+const maxCSVRows = 10000
+
+// =========================================================================================
+// exportMarblesCSV runs a query string like queryMarbles and returns the matching marbles
+// as CSV, one name,color,size,owner row per marble after a header row. Fields with commas,
+// quotes or newlines are quoted. Results that are not marbles are skipped. When the query
+// matches more than maxCSVRows marbles, the first maxCSVRows are returned followed by a
+// truncated,true row.
+// Only available on state databases that support rich query (e.g. CouchDB)
+// =========================================================================================
+func (t *SimpleChaincode) exportMarblesCSV(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+
+	//   0              1
+	// "queryString", "false"
+	if len(args) < 1 {
+		return shim.Error("Incorrect number of arguments. Expecting 1")
+	}
+
+	queryString := args[0]
+	includeArchived, err := parseIncludeArchived(args, 1)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if !includeArchived {
+		queryString, err = excludeArchived(queryString)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+	}
+
+	resultsIterator, err := stub.GetPrivateDataQueryResult("collectionMarbles", queryString)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	defer resultsIterator.Close()
+
+	var buffer bytes.Buffer
+	writer := csv.NewWriter(&buffer)
+	err = writer.Write([]string{"name", "color", "size", "owner"})
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	rows := 0
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		var m marble
+		if json.Unmarshal(queryResponse.Value, &m) != nil || m.ObjectType != "marble" {
+			continue
+		}
+		if rows == maxCSVRows {
+			err = writer.Write([]string{"truncated", "true"})
+			if err != nil {
+				return shim.Error(err.Error())
+			}
+			break
+		}
+		err = writer.Write([]string{m.Name, m.Color, strconv.Itoa(m.Size), m.Owner})
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		rows++
+	}
+
+	writer.Flush()
+	err = writer.Error()
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	return shim.Success(buffer.Bytes())
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// ExternalValidatorPolicy names a chaincode that must approve marble writes. It is
+// invoked as validate(marbleJSON) on ValidatorChannel, or on this channel when that
+// is empty. An empty ValidatorChaincode turns validation off.
+type ExternalValidatorPolicy struct {
+	ValidatorChaincode string `json:"validatorChaincode"`
+	ValidatorChannel   string `json:"validatorChannel"`
+}
+
+// checkExternalValidator asks the governance external validator, if any, to approve
+// a marble about to be written.
+func checkExternalValidator(stub shim.ChaincodeStubInterface, gov *governance, marbleJSON []byte) error {
+	policy := gov.ExternalValidator
+	if len(policy.ValidatorChaincode) == 0 {
+		return nil
+	}
+	response := stub.InvokeChaincode(policy.ValidatorChaincode, [][]byte{[]byte("validate"), marbleJSON}, policy.ValidatorChannel)
+	if response.Status != shim.OK {
+		return fmt.Errorf("external validator rejected: %s", response.Message)
+	}
+	return nil
+}
+
+// checkExternalValidatorForMarble is checkExternalValidator for an unmarshaled marble.
+func checkExternalValidatorForMarble(stub shim.ChaincodeStubInterface, gov *governance, m *marble) error {
+	marbleJSON, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	return checkExternalValidator(stub, gov, marbleJSON)
+}
+
+// ===========================================================================
+// setExternalValidator - admin choice of the chaincode approving marble writes
+// ===========================================================================
+func (t *SimpleChaincode) setExternalValidator(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+
+	//        0               1
+	// "validator_cc", "mychannel"
+	if len(args) != 2 {
+		return shim.Error("Incorrect number of arguments. Expecting 2")
+	}
+	if len(args[0]) == 0 {
+		return shim.Error("validator chaincode must be a non-empty string")
+	}
+
+	err := requireAdmin(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	gov, err := getGovernance(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	gov.ExternalValidator = ExternalValidatorPolicy{ValidatorChaincode: args[0], ValidatorChannel: args[1]}
+	err = putGovernance(stub, gov)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	return shim.Success(nil)
+}
+
+// ===========================================================================
+// clearExternalValidator - admin removal of the external validator
+// ===========================================================================
+func (t *SimpleChaincode) clearExternalValidator(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	if len(args) != 0 {
+		return shim.Error("Incorrect number of arguments. Expecting 0")
+	}
+
+	err := requireAdmin(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	gov, err := getGovernance(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	gov.ExternalValidator = ExternalValidatorPolicy{}
+	err = putGovernance(stub, gov)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	return shim.Success(nil)
+}
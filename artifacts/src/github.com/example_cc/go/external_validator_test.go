@@ -0,0 +1,83 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// blueRejectingValidator is a validator chaincode that rejects blue marbles. It keeps
+// the last marble it was asked about.
+type blueRejectingValidator struct {
+	last marble
+}
+
+func (v *blueRejectingValidator) Init(stub shim.ChaincodeStubInterface) pb.Response {
+	return shim.Success(nil)
+}
+
+func (v *blueRejectingValidator) Invoke(stub shim.ChaincodeStubInterface) pb.Response {
+	args := stub.GetArgs()
+	if len(args) != 2 || string(args[0]) != "validate" {
+		return shim.Error("expected validate and a marble")
+	}
+	v.last = marble{}
+	err := json.Unmarshal(args[1], &v.last)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if v.last.Color == "blue" {
+		return shim.Error("blue marbles are not allowed")
+	}
+	return shim.Success(nil)
+}
+
+func newValidatedTestStub(t *testing.T) (*testStub, *blueRejectingValidator) {
+	s := newTestStub(t)
+	validator := &blueRejectingValidator{}
+	s.MockPeerChaincode("validator_cc/mychannel", shim.NewMockStub("validator_cc", validator))
+	return s, validator
+}
+
+func TestExternalValidatorRejectsBlueMarbles(t *testing.T) {
+	s, validator := newValidatedTestStub(t)
+	s.createMarble("blue1", "blue", 35, "Org1MSP", 99)
+
+	s.setCaller("Org2MSP", "user2")
+	s.mustFail("is not the admin organization", "setExternalValidator", nil, "validator_cc", "mychannel")
+	s.setCaller("Org1MSP", "admin")
+	s.mustInvoke("setExternalValidator", nil, "validator_cc", "mychannel")
+
+	s.mustFail("external validator rejected: blue marbles are not allowed", "initMarble", map[string]interface{}{"marble": map[string]interface{}{
+		"name": "blue2", "color": "blue", "size": 35, "owner": "Org1MSP", "price": 99, "weight": 10,
+	}})
+	if s.PvtState["collectionMarbles"]["blue2"] != nil {
+		t.Fatal("expected the rejected marble not to be written")
+	}
+	s.mustFail("external validator rejected: blue marbles are not allowed", "transferMarble", transferTo("blue1", "Org2MSP"))
+	s.mustFail("external validator rejected: blue marbles are not allowed", "updateMarblePrice", map[string]interface{}{"marble_price": map[string]interface{}{"name": "blue1", "price": 120}})
+	if owner := s.readTestMarble("blue1").Owner; owner != "Org1MSP" {
+		t.Fatalf("expected the rejected transfer to leave blue1 with Org1MSP, got %s", owner)
+	}
+
+	s.createMarble("red1", "red", 35, "Org1MSP", 99)
+	s.mustInvoke("transferMarble", transferTo("red1", "Org2MSP"))
+	if validator.last.Name != "red1" || validator.last.Owner != "Org2MSP" {
+		t.Fatalf("expected the validator to be asked about red1 with its new owner, got %+v", validator.last)
+	}
+}
+
+func TestClearExternalValidator(t *testing.T) {
+	s, _ := newValidatedTestStub(t)
+	s.createMarble("blue1", "blue", 35, "Org1MSP", 99)
+	s.mustInvoke("setExternalValidator", nil, "validator_cc", "mychannel")
+	s.mustFail("external validator rejected", "transferMarble", transferTo("blue1", "Org2MSP"))
+
+	s.setCaller("Org2MSP", "user2")
+	s.mustFail("is not the admin organization", "clearExternalValidator", nil)
+	s.setCaller("Org1MSP", "admin")
+	s.mustInvoke("clearExternalValidator", nil)
+	s.mustInvoke("transferMarble", transferTo("blue1", "Org2MSP"))
+}
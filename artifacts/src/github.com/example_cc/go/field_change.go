@@ -0,0 +1,382 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// communityOwner owns marbles held by the network as a whole. Anyone may propose
+// changes to them.
+const communityOwner = "community"
+
+// States of a field change proposal.
+const (
+	proposalStatusOpen     = "open"
+	proposalStatusExecuted = "executed"
+	proposalStatusRejected = "rejected"
+)
+
+// FieldChangeProposal asks the network to change a field of a marble. Votes maps each
+// voting MSP ID to its approval. It is kept in collectionMarbleProposals under the ID
+// of the transaction that opened it.
+type FieldChangeProposal struct {
+	ObjectType     string          `json:"docType"`
+	ProposalID     string          `json:"proposalID"`
+	MarbleName     string          `json:"marbleName"`
+	FieldName      string          `json:"fieldName"`
+	ProposedValue  string          `json:"proposedValue"`
+	VotingDeadline int64           `json:"votingDeadline"`
+	Votes          map[string]bool `json:"votes"`
+	Status         string          `json:"status"`
+}
+
+func getFieldChangeProposal(stub shim.ChaincodeStubInterface, proposalID string) (*FieldChangeProposal, error) {
+	proposalAsBytes, err := stub.GetPrivateData("collectionMarbleProposals", proposalID)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get proposal: %s", err.Error())
+	} else if proposalAsBytes == nil {
+		return nil, fmt.Errorf("Proposal does not exist: %s", proposalID)
+	}
+
+	proposal := &FieldChangeProposal{}
+	err = json.Unmarshal(proposalAsBytes, proposal)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to decode JSON of: %s", string(proposalAsBytes))
+	}
+	return proposal, nil
+}
+
+func putFieldChangeProposal(stub shim.ChaincodeStubInterface, proposal *FieldChangeProposal) error {
+	proposalAsBytes, err := json.Marshal(proposal)
+	if err != nil {
+		return err
+	}
+	return stub.PutPrivateData("collectionMarbleProposals", proposal.ProposalID, proposalAsBytes)
+}
+
+// checkFieldChange fails unless value is valid for a changeable marble field.
+func checkFieldChange(stub shim.ChaincodeStubInterface, fieldName, value string) error {
+	switch fieldName {
+	case "color":
+		if len(value) == 0 {
+			return fmt.Errorf("color must be a non-empty string")
+		}
+		return checkAllowedColor(stub, value)
+	case "size":
+		size, err := strconv.Atoi(value)
+		if err != nil || size <= 0 {
+			return fmt.Errorf("size must be a positive integer")
+		}
+	default:
+		return fmt.Errorf("field %s cannot be changed by proposal, expecting color or size", fieldName)
+	}
+	return nil
+}
+
+// applyFieldChange sets a checked field change on a marble and writes it back, keeping
+// the color~name and size~name indexes in step.
+func applyFieldChange(stub shim.ChaincodeStubInterface, m *marble, fieldName, value string) error {
+	switch fieldName {
+	case "color":
+		oldIndexKey, err := stub.CreateCompositeKey("color~name", []string{m.Color, m.Name})
+		if err != nil {
+			return err
+		}
+		err = stub.DelPrivateData("collectionMarbles", oldIndexKey)
+		if err != nil {
+			return err
+		}
+		m.Color = value
+		newIndexKey, err := stub.CreateCompositeKey("color~name", []string{m.Color, m.Name})
+		if err != nil {
+			return err
+		}
+		err = stub.PutPrivateData("collectionMarbles", newIndexKey, []byte{0x00})
+		if err != nil {
+			return err
+		}
+	case "size":
+		err := removeSizeIndex(stub, m)
+		if err != nil {
+			return err
+		}
+		m.Size, _ = strconv.Atoi(value)
+		err = addSizeIndex(stub, m)
+		if err != nil {
+			return err
+		}
+	}
+	return putMarble(stub, m)
+}
+
+// tally counts the votes of a proposal and reports whether it passed: the quorum was
+// met and more organizations approved than rejected.
+func (p *FieldChangeProposal) tally(quorum int) (int, int, bool) {
+	approvals, rejections := 0, 0
+	for _, approve := range p.Votes {
+		if approve {
+			approvals++
+		} else {
+			rejections++
+		}
+	}
+	return approvals, rejections, approvals+rejections >= quorum && approvals > rejections
+}
+
+// closableProposal returns the open proposal once its voting deadline has passed.
+func closableProposal(stub shim.ChaincodeStubInterface, proposalID string) (*FieldChangeProposal, error) {
+	proposal, err := getFieldChangeProposal(stub, proposalID)
+	if err != nil {
+		return nil, err
+	}
+	if proposal.Status != proposalStatusOpen {
+		return nil, fmt.Errorf("proposal %s is already %s", proposal.ProposalID, proposal.Status)
+	}
+	currentTime, err := getTxUnixTime(stub)
+	if err != nil {
+		return nil, err
+	}
+	if currentTime <= proposal.VotingDeadline {
+		return nil, fmt.Errorf("voting on proposal %s is open until %d", proposal.ProposalID, proposal.VotingDeadline)
+	}
+	return proposal, nil
+}
+
+// ===========================================================================
+// proposeFieldChange - put a change of a marble's color or size to a vote.
+// Only the owner may propose, unless the marble is community owned.
+// ===========================================================================
+func (t *SimpleChaincode) proposeFieldChange(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	fmt.Println("- start propose field change")
+
+	type fieldChangeTransientInput struct {
+		MarbleName    string `json:"marbleName"`
+		FieldName     string `json:"fieldName"`
+		ProposedValue string `json:"proposedValue"`
+		VotingPeriod  int64  `json:"votingPeriod"` // in seconds, see getTxUnixTime
+	}
+
+	if len(args) != 0 {
+		return shim.Error("Incorrect number of arguments. Private marble data must be passed in transient map.")
+	}
+
+	var proposalInput fieldChangeTransientInput
+	err := getTransientInput(stub, "field_change", &proposalInput)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if len(proposalInput.MarbleName) == 0 {
+		return shim.Error("marbleName field must be a non-empty string")
+	}
+	if proposalInput.VotingPeriod <= 0 {
+		return shim.Error("votingPeriod field must be a positive integer")
+	}
+	err = checkFieldChange(stub, proposalInput.FieldName, proposalInput.ProposedValue)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	m, err := getMarble(stub, proposalInput.MarbleName)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if m.Owner != communityOwner {
+		err = requireOwner(stub, m)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+	}
+	currentTime, err := getTxUnixTime(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	proposal := &FieldChangeProposal{
+		ObjectType:     "fieldChangeProposal",
+		ProposalID:     stub.GetTxID(),
+		MarbleName:     m.Name,
+		FieldName:      proposalInput.FieldName,
+		ProposedValue:  proposalInput.ProposedValue,
+		VotingDeadline: currentTime + proposalInput.VotingPeriod,
+		Votes:          map[string]bool{},
+		Status:         proposalStatusOpen,
+	}
+	err = putFieldChangeProposal(stub, proposal)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	fmt.Println("- end propose field change")
+	return shim.Success([]byte(proposal.ProposalID))
+}
+
+// ===========================================================================
+// voteOnFieldChange - cast the caller organization's single vote
+// ===========================================================================
+func (t *SimpleChaincode) voteOnFieldChange(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+
+	//      0          1
+	// "proposalID", "true"
+	if len(args) != 2 {
+		return shim.Error("Incorrect number of arguments. Expecting 2")
+	}
+	approve, err := strconv.ParseBool(args[1])
+	if err != nil {
+		return shim.Error("approve must be true or false")
+	}
+
+	proposal, err := getFieldChangeProposal(stub, args[0])
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if proposal.Status != proposalStatusOpen {
+		return shim.Error("Proposal is already " + proposal.Status + ": " + proposal.ProposalID)
+	}
+	currentTime, err := getTxUnixTime(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if currentTime > proposal.VotingDeadline {
+		return shim.Error("Voting has closed on proposal: " + proposal.ProposalID)
+	}
+
+	voterMSPID, err := getCallerMSPID(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if _, voted := proposal.Votes[voterMSPID]; voted {
+		return shim.Error("organization " + voterMSPID + " has already voted on proposal " + proposal.ProposalID)
+	}
+	proposal.Votes[voterMSPID] = approve
+	err = putFieldChangeProposal(stub, proposal)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	return shim.Success(nil)
+}
+
+// ===========================================================================
+// executeFieldChange - apply an approved proposal after its voting deadline
+// ===========================================================================
+func (t *SimpleChaincode) executeFieldChange(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+
+	//      0
+	// "proposalID"
+	if len(args) != 1 {
+		return shim.Error("Incorrect number of arguments. Expecting proposal ID")
+	}
+
+	proposal, err := closableProposal(stub, args[0])
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	gov, err := getGovernance(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	approvals, rejections, passed := proposal.tally(gov.FieldChangeQuorum)
+	if !passed {
+		return shim.Error(fmt.Sprintf("Proposal %s was not approved (%d for, %d against, quorum %d)", proposal.ProposalID, approvals, rejections, gov.FieldChangeQuorum))
+	}
+
+	m, err := getMarble(stub, proposal.MarbleName)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = applyFieldChange(stub, m, proposal.FieldName, proposal.ProposedValue)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	proposal.Status = proposalStatusExecuted
+	err = putFieldChangeProposal(stub, proposal)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	return shim.Success(nil)
+}
+
+// ===========================================================================
+// rejectFieldChange - archive a proposal that did not pass
+// ===========================================================================
+func (t *SimpleChaincode) rejectFieldChange(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+
+	//      0
+	// "proposalID"
+	if len(args) != 1 {
+		return shim.Error("Incorrect number of arguments. Expecting proposal ID")
+	}
+
+	proposal, err := closableProposal(stub, args[0])
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	gov, err := getGovernance(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if _, _, passed := proposal.tally(gov.FieldChangeQuorum); passed {
+		return shim.Error("Proposal was approved, use executeFieldChange: " + proposal.ProposalID)
+	}
+
+	proposal.Status = proposalStatusRejected
+	err = putFieldChangeProposal(stub, proposal)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	return shim.Success(nil)
+}
+
+// ===========================================================================
+// updateMarbleColor - admin correction of a marble's color, outside of the
+// field change proposal process. The color~name index follows the change.
+// ===========================================================================
+func (t *SimpleChaincode) updateMarbleColor(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	fmt.Println("- start update marble color")
+
+	type marbleColorTransientInput struct {
+		Name  string `json:"name"`
+		Color string `json:"color"`
+	}
+
+	if len(args) != 0 {
+		return shim.Error("Incorrect number of arguments. Private marble data must be passed in transient map.")
+	}
+
+	var colorInput marbleColorTransientInput
+	err := getTransientInput(stub, "marble_color", &colorInput)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if len(colorInput.Name) == 0 {
+		return shim.Error("name field must be a non-empty string")
+	}
+	err = checkFieldChange(stub, "color", colorInput.Color)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	err = requireAdmin(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	m, err := getMarble(stub, colorInput.Name)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if m.Color == colorInput.Color {
+		return shim.Error("marble " + m.Name + " is already " + m.Color)
+	}
+	err = applyFieldChange(stub, m, "color", colorInput.Color)
+	if err != nil {
+		return shim.Error("Failed to update color of " + m.Name + ": " + err.Error())
+	}
+
+	fmt.Println("- end update marble color")
+	return shim.Success(nil)
+}
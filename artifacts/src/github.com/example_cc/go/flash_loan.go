@@ -0,0 +1,172 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// flashLoanIndexName keys the flash loan history of a marble by transaction ID.
+const flashLoanIndexName = "flashLoan~name~txID"
+
+type flashLoanRecord struct {
+	ObjectType        string `json:"docType"`
+	MarbleName        string `json:"marbleName"`
+	Lender            string `json:"lender"`
+	Borrower          string `json:"borrower"`
+	CallbackChaincode string `json:"callbackChaincode"`
+	Fee               int    `json:"fee"`
+	TxID              string `json:"txID"`
+	Timestamp         string `json:"timestamp"`
+}
+
+// ===========================================================================================
+// flashBorrowMarble lends a marble to the caller for the duration of a single chaincode
+// callback. The loan is gated like a transfer to the caller's MSP: it fails for a
+// blacklisted caller, for a marble that is locked (e.g. in escrow), archived, reserved
+// for someone else or mid handover, during a blackout, and without the shareholders'
+// approval of a transfer to the caller. The approval is not used up.
+// The callback chaincode is invoked on the same channel with "onFlashLoan", the marble
+// name, the lender and the borrower. That is all it can see of the loan: it cannot read
+// this chaincode's private collections, and a transaction never reads its own writes, so
+// the marble is not written with the borrower as its owner; its owner is unchanged
+// throughout. The borrower pays the governance FlashLoanFee to the lender in loyalty
+// points. Any failure, including the callback failing or the fee not being covered,
+// fails the whole transaction.
+// ===========================================================================================
+func (t *SimpleChaincode) flashBorrowMarble(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	fmt.Println("- start flash borrow marble")
+
+	type flashLoanTransientInput struct {
+		MarbleName        string `json:"marbleName"`
+		CallbackChaincode string `json:"callbackChaincode"`
+	}
+
+	if len(args) != 0 {
+		return shim.Error("Incorrect number of arguments. Private marble data must be passed in transient map.")
+	}
+
+	var loanInput flashLoanTransientInput
+	err := getTransientInput(stub, "flash_loan", &loanInput)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	if len(loanInput.MarbleName) == 0 {
+		return shim.Error("marbleName field must be a non-empty string")
+	}
+	if len(loanInput.CallbackChaincode) == 0 {
+		return shim.Error("callbackChaincode field must be a non-empty string")
+	}
+
+	gov, err := getGovernance(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = checkCallerNotBlacklisted(stub, gov)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	borrower, err := getCallerMSPID(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	loaned, err := getMarble(stub, loanInput.MarbleName)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = checkOwnerChange(stub, gov, loaned, borrower)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = checkShareholderApproval(stub, loaned.Name, shareActionTransfer, borrower)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	lender := loaned.Owner
+
+	callbackArgs := [][]byte{[]byte("onFlashLoan"), []byte(loaned.Name), []byte(lender), []byte(borrower)}
+	response := stub.InvokeChaincode(loanInput.CallbackChaincode, callbackArgs, "")
+	if response.Status != shim.OK {
+		return shim.Error("flash loan callback failed: " + response.Message)
+	}
+
+	// the borrower pays the fee to the lender, unless they are the same organization
+	if gov.FlashLoanFee > 0 && borrower != lender {
+		err = debitLoyaltyPoints(stub, borrower, gov.FlashLoanFee)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		err = creditLoyaltyPoints(stub, lender, gov.FlashLoanFee)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+	}
+
+	txTime, err := getTxTime(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	record := &flashLoanRecord{
+		ObjectType:        "flashLoanRecord",
+		MarbleName:        loanInput.MarbleName,
+		Lender:            lender,
+		Borrower:          borrower,
+		CallbackChaincode: loanInput.CallbackChaincode,
+		Fee:               gov.FlashLoanFee,
+		TxID:              stub.GetTxID(),
+		Timestamp:         txTime.Format(time.RFC3339),
+	}
+	recordAsBytes, err := json.Marshal(record)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	recordKey, err := stub.CreateCompositeKey(flashLoanIndexName, []string{record.MarbleName, record.TxID})
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = stub.PutPrivateData("collectionMarbles", recordKey, recordAsBytes)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	fmt.Println("- end flash borrow marble")
+	return shim.Success(response.Payload)
+}
+
+// ===========================================================================
+// getFlashLoanHistory - list every flash loan taken against a marble
+// ===========================================================================
+func (t *SimpleChaincode) getFlashLoanHistory(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+
+	//   0
+	// "marble1"
+	if len(args) != 1 {
+		return shim.Error("Incorrect number of arguments. Expecting name of the marble to query")
+	}
+
+	resultsIterator, err := stub.GetPrivateDataByPartialCompositeKey("collectionMarbles", flashLoanIndexName, []string{args[0]})
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	defer resultsIterator.Close()
+
+	records := []json.RawMessage{}
+	for resultsIterator.HasNext() {
+		responseRange, err := resultsIterator.Next()
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		records = append(records, responseRange.Value)
+	}
+
+	recordsAsBytes, err := json.Marshal(records)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	return shim.Success(recordsAsBytes)
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// flashLoanCallback is a borrower chaincode that keeps the arguments of its callbacks.
+type flashLoanCallback struct {
+	calls [][]string
+}
+
+func (c *flashLoanCallback) Init(stub shim.ChaincodeStubInterface) pb.Response {
+	return shim.Success(nil)
+}
+
+func (c *flashLoanCallback) Invoke(stub shim.ChaincodeStubInterface) pb.Response {
+	c.calls = append(c.calls, stub.GetStringArgs())
+	return shim.Success([]byte("done"))
+}
+
+func flashLoan(name string) map[string]interface{} {
+	return map[string]interface{}{"flash_loan": map[string]interface{}{"marbleName": name, "callbackChaincode": "borrower_cc"}}
+}
+
+func newFlashLoanTestStub(t *testing.T) (*testStub, *flashLoanCallback) {
+	s := newTestStub(t)
+	callback := &flashLoanCallback{}
+	s.MockPeerChaincode("borrower_cc", shim.NewMockStub("borrower_cc", callback))
+	s.createMarble("marble1", "blue", 35, "Org1MSP", 99)
+	return s, callback
+}
+
+func TestFlashLoanCallbackSeesLoanButOwnerIsUnchanged(t *testing.T) {
+	s, callback := newFlashLoanTestStub(t)
+	s.setCaller("Org2MSP", "user2")
+	if payload := string(s.mustInvoke("flashBorrowMarble", flashLoan("marble1"))); payload != "done" {
+		t.Fatalf("expected the callback result, got %s", payload)
+	}
+
+	if len(callback.calls) != 1 {
+		t.Fatalf("expected one callback, got %d", len(callback.calls))
+	}
+	if args := callback.calls[0]; len(args) != 4 || args[0] != "onFlashLoan" || args[1] != "marble1" || args[2] != "Org1MSP" || args[3] != "Org2MSP" {
+		t.Fatalf("unexpected callback arguments %v", args)
+	}
+	if owner := s.readTestMarble("marble1").Owner; owner != "Org1MSP" {
+		t.Fatalf("expected marble1 to stay with Org1MSP, got %s", owner)
+	}
+	var history []flashLoanRecord
+	err := json.Unmarshal(s.mustInvoke("getFlashLoanHistory", nil, "marble1"), &history)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 1 || history[0].Lender != "Org1MSP" || history[0].Borrower != "Org2MSP" {
+		t.Fatalf("unexpected flash loan history %+v", history)
+	}
+}
+
+func TestFlashLoanIsGatedLikeATransfer(t *testing.T) {
+	s, callback := newFlashLoanTestStub(t)
+
+	gov := s.readTestGovernance()
+	gov.addToBlacklist("Org3MSP")
+	s.State[governanceKey], _ = json.Marshal(gov)
+	s.setCaller("Org3MSP", "user3")
+	s.mustFail("organization Org3MSP is blacklisted", "flashBorrowMarble", flashLoan("marble1"))
+
+	s.setCaller("Org1MSP", "admin")
+	s.mustInvoke("addBlackoutPeriod", map[string]interface{}{"blackout_period": map[string]interface{}{"startTime": s.Now, "endTime": s.Now + 10, "reason": "audit"}})
+	s.setCaller("Org2MSP", "user2")
+	s.mustFail("transfers are blocked: audit", "flashBorrowMarble", flashLoan("marble1"))
+
+	s.Now += 20
+	s.setCaller("Org1MSP", "admin")
+	s.mustInvoke("allocateShares", map[string]interface{}{"share_allocation": map[string]interface{}{
+		"marbleName": "marble1", "shares": map[string]int{"alice": 60, "bob": 40},
+	}})
+	s.setCaller("Org2MSP", "user2")
+	s.mustFail("transfer of marble marble1 has not been approved", "flashBorrowMarble", flashLoan("marble1"))
+
+	if len(callback.calls) != 0 {
+		t.Fatalf("expected no callback for a refused loan, got %v", callback.calls)
+	}
+}
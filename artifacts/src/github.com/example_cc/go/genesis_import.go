@@ -0,0 +1,162 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// genesisTxID is the CreationTxID of marbles brought in by genesisImport.
+const genesisTxID = "genesis"
+
+// MarbleFull is a marble with its private price, as carried over from an existing
+// inventory by genesisImport. The optional fields default as they do for initMarble.
+// A marble imported without a provenance gets one naming the genesis import as its
+// origin.
+type MarbleFull struct {
+	Name       string            `json:"name"`
+	Color      string            `json:"color"`
+	Size       int               `json:"size"`
+	Owner      string            `json:"owner"`
+	Price      int64             `json:"price"`
+	Weight     float64           `json:"weight"`
+	MaxUsages  int               `json:"maxUsages"`
+	Condition  string            `json:"condition"`
+	Rarity     string            `json:"rarity"`
+	Provenance *MarbleProvenance `json:"provenance"`
+}
+
+func (f *MarbleFull) marbleInput() *marbleTransientInput {
+	return &marbleTransientInput{
+		Name:      f.Name,
+		Color:     f.Color,
+		Size:      f.Size,
+		Owner:     f.Owner,
+		Price:     f.Price,
+		Weight:    f.Weight,
+		MaxUsages: f.MaxUsages,
+		Condition: f.Condition,
+		Rarity:    f.Rarity,
+	}
+}
+
+// genesisProvenance returns the provenance to record for an imported marble.
+func (f *MarbleFull) genesisProvenance(stub shim.ChaincodeStubInterface) (*MarbleProvenance, error) {
+	if f.Provenance == nil {
+		txTime, err := getTxTime(stub)
+		if err != nil {
+			return nil, err
+		}
+		return &MarbleProvenance{
+			MarbleName:      f.Name,
+			Origin:          "genesis import",
+			CertifiedAt:     txTime.Format(time.RFC3339),
+			CertificationID: genesisTxID,
+		}, nil
+	}
+	if len(f.Provenance.MarbleName) == 0 {
+		f.Provenance.MarbleName = f.Name
+	}
+	err := f.Provenance.validate()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", f.Name, err.Error())
+	}
+	if f.Provenance.MarbleName != f.Name {
+		return nil, fmt.Errorf("provenance is for %s, not %s", f.Provenance.MarbleName, f.Name)
+	}
+	return f.Provenance, nil
+}
+
+// ==================================================================================
+// genesisImport - bring an existing inventory onto a new channel. It only runs as
+// part of the first Init, as Init("genesisImport") with the marbles in the
+// genesis_marbles transient key, before the init metadata is recorded. The marbles
+// are created as initMarble creates them, by the instantiating organization.
+// ==================================================================================
+func (t *SimpleChaincode) genesisImport(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	fmt.Println("- start genesis import")
+
+	if len(args) != 0 {
+		return shim.Error("Incorrect number of arguments. Private marble data must be passed in transient map.")
+	}
+
+	initAsBytes, err := stub.GetPrivateData("collectionMarbles", initInfoKey)
+	if err != nil {
+		return shim.Error("Failed to get init info: " + err.Error())
+	} else if initAsBytes != nil {
+		return shim.Error("genesis import only allowed during chaincode initialization")
+	}
+
+	var inventory []MarbleFull
+	err = getTransientInput(stub, "genesis_marbles", &inventory)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	// Governance is only written once the import is done, so the marbles are checked
+	// against the defaults it will be written with.
+	adminMSPID, err := getCallerMSPID(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	gov := defaultGovernance(adminMSPID)
+	currentTime, err := getTxUnixTime(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	// ==== Validate every marble before writing any of them ====
+	// Reads do not see this transaction's writes, so duplicates are caught here and
+	// the market cap is adjusted once for the whole inventory.
+	marbles := make([]*marble, len(inventory))
+	marblesJSON := make([][]byte, len(inventory))
+	provenances := make([]*MarbleProvenance, len(inventory))
+	names := make([]string, len(inventory))
+	var totalPrice int64
+	for i := range inventory {
+		marbleInput := inventory[i].marbleInput()
+		err = checkMarbleInput(stub, gov, marbleInput)
+		if err != nil {
+			return shim.Error(fmt.Sprintf("marble %d: %s", i, err.Error()))
+		}
+		if containsString(names[:i], marbleInput.Name) {
+			return shim.Error("Duplicate marble in genesis import: " + marbleInput.Name)
+		}
+		provenances[i], err = inventory[i].genesisProvenance(stub)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		marbles[i], _, err = prepareMarble(stub, gov, marbleInput, currentTime)
+		if err != nil {
+			return shim.Error(fmt.Sprintf("marble %d: %s", i, err.Error()))
+		}
+		marbles[i].CreationTxID = genesisTxID
+		marblesJSON[i], err = json.Marshal(marbles[i])
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		names[i] = marbleInput.Name
+		totalPrice += marbleInput.Price
+	}
+
+	for i, m := range marbles {
+		err = putProvenance(stub, provenances[i])
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		err = writeNewMarble(stub, m, marblesJSON[i], inventory[i].Price, adminMSPID)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+	}
+	err = adjustMarketCap(stub, "", totalPrice)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	fmt.Println("- end genesis import")
+	return shim.Success([]byte(fmt.Sprintf("{\"imported\":%d,\"totalPrice\":%d}", len(inventory), totalPrice)))
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func genesisMarbles(marbles ...map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{"genesis_marbles": marbles}
+}
+
+func genesisMarble(name, color string) map[string]interface{} {
+	return map[string]interface{}{"name": name, "color": color, "size": 35, "owner": "Org1MSP", "price": 99, "weight": 10}
+}
+
+func TestGenesisImportCreatesMarblesLikeInitMarble(t *testing.T) {
+	s := newUninitializedTestStub(t)
+	certified := genesisMarble("marble2", "red")
+	certified["provenance"] = map[string]interface{}{"origin": "quarry", "certificationID": "cert-1"}
+	response := s.init(genesisMarbles(genesisMarble("marble1", "blue"), certified), "genesisImport")
+	if response.Status != 200 {
+		t.Fatalf("expected the genesis import to succeed, got %s", response.Message)
+	}
+	if string(response.Payload) != `{"imported":2,"totalPrice":198}` {
+		t.Fatalf("unexpected summary %s", response.Payload)
+	}
+
+	m := s.readTestMarble("marble1")
+	if m.CreationTxID != genesisTxID || m.MaxUsages != defaultMaxUsages || m.Rarity != rarityLevels[0] || m.Condition != "new" {
+		t.Fatalf("expected a genesis marble with the initMarble defaults, got %+v", m)
+	}
+	colorKey, _ := s.CreateCompositeKey("color~name", []string{"blue", "marble1"})
+	if s.PvtState["collectionMarbles"][colorKey] == nil {
+		t.Fatal("expected marble1 to be indexed by color")
+	}
+	if s.PvtState["collectionMarblePrivateDetails"]["marble1"] == nil {
+		t.Fatal("expected the private details of marble1")
+	}
+
+	var provenance MarbleProvenance
+	for name, origin := range map[string]string{"marble1": "genesis import", "marble2": "quarry"} {
+		err := json.Unmarshal(s.PvtState["collectionMarbleProvenance"][name], &provenance)
+		if err != nil {
+			t.Fatalf("expected a provenance record for %s: %s", name, err)
+		}
+		if provenance.MarbleName != name || provenance.Origin != origin {
+			t.Fatalf("unexpected provenance of %s: %+v", name, provenance)
+		}
+	}
+
+	s.mustInvoke("getInitInfo", nil)
+}
+
+func TestGenesisImportChecksColors(t *testing.T) {
+	s := newUninitializedTestStub(t)
+	response := s.init(genesisMarbles(genesisMarble("marble1", "blue"), genesisMarble("marble2", "chartreuse")), "genesisImport")
+	if response.Status == 200 || !strings.Contains(response.Message, "color chartreuse is not allowed") {
+		t.Fatalf("expected the import to fail on the color, got %d %s", response.Status, response.Message)
+	}
+	if len(s.PvtState["collectionMarbles"]) != 0 {
+		t.Fatal("expected no marble to be written")
+	}
+}
+
+func TestSecondInitWithGenesisDataFails(t *testing.T) {
+	s := newUninitializedTestStub(t)
+	response := s.init(genesisMarbles(genesisMarble("marble1", "blue")), "genesisImport")
+	if response.Status != 200 {
+		t.Fatalf("expected the first genesis import to succeed, got %s", response.Message)
+	}
+
+	response = s.init(genesisMarbles(genesisMarble("marble2", "blue")), "genesisImport")
+	if response.Status == 200 || response.Message != "genesis import only allowed during chaincode initialization" {
+		t.Fatalf("expected the second genesis import to fail, got %d %s", response.Status, response.Message)
+	}
+	if s.PvtState["collectionMarbles"]["marble2"] != nil {
+		t.Fatal("expected marble2 not to be imported")
+	}
+}
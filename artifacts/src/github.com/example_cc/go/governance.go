@@ -0,0 +1,529 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// chaincodeVersion is recorded in the init metadata. Keep it in step with the version
+// the chaincode is installed under.
+const chaincodeVersion = "v0"
+
+// initInfoKey holds the init metadata in collectionMarbles. Its presence means Init has
+// already run on this channel.
+const initInfoKey = "__init__"
+
+// governanceKey holds the channel-wide governance values in public state.
+const governanceKey = "__governance__"
+
+type initInfo struct {
+	ObjectType    string `json:"docType"`
+	Version       string `json:"version"`
+	InitializedAt string `json:"initializedAt"`
+	InitTxID      string `json:"initTxID"`
+	AdminMSPID    string `json:"adminMSPID"`
+}
+
+// pricePolicy bounds the price a marble may be created with. A MaxPrice of zero means
+// no upper bound.
+type pricePolicy struct {
+	MinPrice int `json:"minPrice"`
+	MaxPrice int `json:"maxPrice"`
+}
+
+type governance struct {
+	ObjectType   string      `json:"docType"`
+	AdminMSPID   string      `json:"adminMSPID"`
+	PricePolicy  pricePolicy `json:"pricePolicy"`
+	FlashLoanFee int         `json:"flashLoanFee"`
+	// NameReservationCost is charged in loyalty points for each name reservation,
+	// which lasts NameReservationPeriod seconds.
+	NameReservationCost   int   `json:"nameReservationCost"`
+	NameReservationPeriod int64 `json:"nameReservationPeriod"`
+	// OwnerSuspicionCount counts approved activity reports per owner MSP. An owner
+	// with more than SuspicionThreshold of them is put on the Blacklist, and listed in
+	// SuspicionBlacklisted so that only those entries are lifted again when the count
+	// drops.
+	OwnerSuspicionCount  map[string]int `json:"ownerSuspicionCount"`
+	SuspicionThreshold   int            `json:"suspicionThreshold"`
+	Blacklist            []string       `json:"blacklist"`
+	SuspicionBlacklisted []string       `json:"suspicionBlacklisted"`
+	// RepairCost is charged in loyalty points for each marble repair.
+	RepairCost int `json:"repairCost"`
+	// CarbonCostPerTransfer is added, in kg CO2e, to a marble's footprint on each
+	// transfer. CarbonCertifiers may add footprint directly.
+	CarbonCostPerTransfer float64  `json:"carbonCostPerTransfer"`
+	CarbonCertifiers      []string `json:"carbonCertifiers"`
+	// FarmingYieldRate is the yield a farmed marble accrues per second.
+	FarmingYieldRate float64 `json:"farmingYieldRate"`
+	// AuthorizedCertBodies may certify marbles for sale.
+	AuthorizedCertBodies []string `json:"authorizedCertBodies"`
+	// BlackoutPeriods are time ranges during which no marble changes owner.
+	BlackoutPeriods []BlackoutPeriod `json:"blackoutPeriods"`
+	// MinDisputeStake is the least number of loyalty points staked to open a dispute.
+	MinDisputeStake int `json:"minDisputeStake"`
+	// InsurancePool holds the loyalty points that pay insurance claims.
+	InsurancePool InsurancePool `json:"insurancePool"`
+	// AuthorizedOracles may submit oracle price feeds.
+	AuthorizedOracles []string `json:"authorizedOracles"`
+	// FieldChangeQuorum is the least number of votes for a field change proposal to pass.
+	FieldChangeQuorum int `json:"fieldChangeQuorum"`
+	// TaxRateTable sets the tax due on marble transfers, paid to TaxAuthority.
+	TaxRateTable []TaxBracket `json:"taxRateTable"`
+	TaxAuthority string       `json:"taxAuthority"`
+	// ExternalValidator, when set, must approve marble creations, transfers and
+	// price changes.
+	ExternalValidator ExternalValidatorPolicy `json:"externalValidator"`
+	// DecayPolicy lowers the price of idle marbles.
+	DecayPolicy DecayPolicy `json:"decayPolicy"`
+	// OracleConsensus sets marble prices from agreeing votes of several oracles.
+	OracleConsensus OracleConsensusConfig `json:"oracleConsensus"`
+	// OwnerReputations holds the reputation score of each owner with a history of
+	// disputes or audits. Other owners score DefaultReputation.
+	OwnerReputations  map[string]int `json:"ownerReputations"`
+	DefaultReputation int            `json:"defaultReputation"`
+	// EscrowAgentMSPID releases or cancels marble escrows. Marbles cannot be put in
+	// escrow until it is set.
+	EscrowAgentMSPID string `json:"escrowAgentMSPID"`
+	// RequireProvenance makes marble creation fail without a provenance record.
+	// ProvenanceRecorders may record provenance for any marble.
+	RequireProvenance   bool     `json:"requireProvenance"`
+	ProvenanceRecorders []string `json:"provenanceRecorders"`
+	// RichQueryEnabled is set on channels whose state database is CouchDB. Queries
+	// with an index based alternative use rich queries only when it is set.
+	RichQueryEnabled bool `json:"richQueryEnabled"`
+}
+
+func defaultGovernance(adminMSPID string) *governance {
+	return &governance{
+		ObjectType:  "governance",
+		AdminMSPID:  adminMSPID,
+		PricePolicy: pricePolicy{MinPrice: 1, MaxPrice: 0},
+
+		NameReservationCost:   10,
+		NameReservationPeriod: 7 * 24 * 60 * 60,
+
+		OwnerSuspicionCount:  map[string]int{},
+		SuspicionThreshold:   3,
+		Blacklist:            []string{},
+		SuspicionBlacklisted: []string{},
+
+		RepairCost: 20,
+
+		CarbonCostPerTransfer: 0,
+		CarbonCertifiers:      []string{},
+
+		FarmingYieldRate: 0,
+
+		AuthorizedCertBodies: []string{},
+
+		BlackoutPeriods: []BlackoutPeriod{},
+
+		MinDisputeStake: 10,
+
+		InsurancePool: InsurancePool{TotalFunds: 0, PerMarblePremium: 5},
+
+		AuthorizedOracles: []string{},
+
+		FieldChangeQuorum: 2,
+
+		TaxRateTable: []TaxBracket{},
+
+		DecayPolicy: DecayPolicy{DecayRatePerThousandSeconds: 0},
+
+		OracleConsensus: OracleConsensusConfig{
+			RequiredAgreements:    3,
+			PriceTolerancePercent: 5,
+			OracleMSPIDs:          []string{},
+			VoteExpirySeconds:     24 * 60 * 60,
+		},
+
+		OwnerReputations:  map[string]int{},
+		DefaultReputation: 100,
+
+		ProvenanceRecorders: []string{},
+	}
+}
+
+// governanceDefaults are the governance values reinitialize may change. The rest of the
+// governance record is either kept by the chaincode itself, such as the blacklist, the
+// insurance pool funds and the owner reputations, or has its own admin functions, such
+// as the blackout periods and the external validator.
+type governanceDefaults struct {
+	PricePolicy           pricePolicy `json:"pricePolicy"`
+	FlashLoanFee          int         `json:"flashLoanFee"`
+	NameReservationCost   int         `json:"nameReservationCost"`
+	NameReservationPeriod int64       `json:"nameReservationPeriod"`
+	SuspicionThreshold    int         `json:"suspicionThreshold"`
+	RepairCost            int         `json:"repairCost"`
+	CarbonCostPerTransfer float64     `json:"carbonCostPerTransfer"`
+	CarbonCertifiers      []string    `json:"carbonCertifiers"`
+	FarmingYieldRate      float64     `json:"farmingYieldRate"`
+	AuthorizedCertBodies  []string    `json:"authorizedCertBodies"`
+	MinDisputeStake       int         `json:"minDisputeStake"`
+	InsurancePool         struct {
+		PerMarblePremium int `json:"perMarblePremium"`
+	} `json:"insurancePool"`
+	AuthorizedOracles   []string              `json:"authorizedOracles"`
+	FieldChangeQuorum   int                   `json:"fieldChangeQuorum"`
+	TaxRateTable        []TaxBracket          `json:"taxRateTable"`
+	TaxAuthority        string                `json:"taxAuthority"`
+	DecayPolicy         DecayPolicy           `json:"decayPolicy"`
+	OracleConsensus     OracleConsensusConfig `json:"oracleConsensus"`
+	DefaultReputation   int                   `json:"defaultReputation"`
+	EscrowAgentMSPID    string                `json:"escrowAgentMSPID"`
+	RequireProvenance   bool                  `json:"requireProvenance"`
+	ProvenanceRecorders []string              `json:"provenanceRecorders"`
+	RichQueryEnabled    bool                  `json:"richQueryEnabled"`
+}
+
+// UnmarshalJSON rejects fields that are not governance defaults, so that an attempt to
+// change the rest of the record fails instead of being silently ignored.
+func (d *governanceDefaults) UnmarshalJSON(data []byte) error {
+	type plainGovernanceDefaults governanceDefaults
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	return decoder.Decode((*plainGovernanceDefaults)(d))
+}
+
+func (gov *governance) defaults() *governanceDefaults {
+	d := &governanceDefaults{
+		PricePolicy:           gov.PricePolicy,
+		FlashLoanFee:          gov.FlashLoanFee,
+		NameReservationCost:   gov.NameReservationCost,
+		NameReservationPeriod: gov.NameReservationPeriod,
+		SuspicionThreshold:    gov.SuspicionThreshold,
+		RepairCost:            gov.RepairCost,
+		CarbonCostPerTransfer: gov.CarbonCostPerTransfer,
+		CarbonCertifiers:      gov.CarbonCertifiers,
+		FarmingYieldRate:      gov.FarmingYieldRate,
+		AuthorizedCertBodies:  gov.AuthorizedCertBodies,
+		MinDisputeStake:       gov.MinDisputeStake,
+		AuthorizedOracles:     gov.AuthorizedOracles,
+		FieldChangeQuorum:     gov.FieldChangeQuorum,
+		TaxRateTable:          gov.TaxRateTable,
+		TaxAuthority:          gov.TaxAuthority,
+		DecayPolicy:           gov.DecayPolicy,
+		OracleConsensus:       gov.OracleConsensus,
+		DefaultReputation:     gov.DefaultReputation,
+		EscrowAgentMSPID:      gov.EscrowAgentMSPID,
+		RequireProvenance:     gov.RequireProvenance,
+		ProvenanceRecorders:   gov.ProvenanceRecorders,
+		RichQueryEnabled:      gov.RichQueryEnabled,
+	}
+	d.InsurancePool.PerMarblePremium = gov.InsurancePool.PerMarblePremium
+	return d
+}
+
+func (gov *governance) setDefaults(d *governanceDefaults) {
+	gov.PricePolicy = d.PricePolicy
+	gov.FlashLoanFee = d.FlashLoanFee
+	gov.NameReservationCost = d.NameReservationCost
+	gov.NameReservationPeriod = d.NameReservationPeriod
+	gov.SuspicionThreshold = d.SuspicionThreshold
+	gov.RepairCost = d.RepairCost
+	gov.CarbonCostPerTransfer = d.CarbonCostPerTransfer
+	gov.CarbonCertifiers = d.CarbonCertifiers
+	gov.FarmingYieldRate = d.FarmingYieldRate
+	gov.AuthorizedCertBodies = d.AuthorizedCertBodies
+	gov.MinDisputeStake = d.MinDisputeStake
+	gov.InsurancePool.PerMarblePremium = d.InsurancePool.PerMarblePremium
+	gov.AuthorizedOracles = d.AuthorizedOracles
+	gov.FieldChangeQuorum = d.FieldChangeQuorum
+	gov.TaxRateTable = d.TaxRateTable
+	gov.TaxAuthority = d.TaxAuthority
+	gov.DecayPolicy = d.DecayPolicy
+	gov.OracleConsensus = d.OracleConsensus
+	gov.DefaultReputation = d.DefaultReputation
+	gov.EscrowAgentMSPID = d.EscrowAgentMSPID
+	gov.RequireProvenance = d.RequireProvenance
+	gov.ProvenanceRecorders = d.ProvenanceRecorders
+	gov.RichQueryEnabled = d.RichQueryEnabled
+}
+
+func getGovernance(stub shim.ChaincodeStubInterface) (*governance, error) {
+	govAsBytes, err := stub.GetState(governanceKey)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get governance: %s", err.Error())
+	} else if govAsBytes == nil {
+		return nil, fmt.Errorf("governance is not set, the chaincode has not been initialized")
+	}
+
+	gov := &governance{}
+	err = json.Unmarshal(govAsBytes, gov)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to decode JSON of: %s", string(govAsBytes))
+	}
+	return gov, nil
+}
+
+func putGovernance(stub shim.ChaincodeStubInterface, gov *governance) error {
+	govAsBytes, err := json.Marshal(gov)
+	if err != nil {
+		return err
+	}
+	return stub.PutState(governanceKey, govAsBytes)
+}
+
+// requireAdmin fails unless the caller belongs to the governance admin MSP.
+func requireAdmin(stub shim.ChaincodeStubInterface) error {
+	gov, err := getGovernance(stub)
+	if err != nil {
+		return err
+	}
+	callerMSPID, err := getCallerMSPID(stub)
+	if err != nil {
+		return err
+	}
+	if callerMSPID != gov.AdminMSPID {
+		return fmt.Errorf("caller %s is not the admin organization", callerMSPID)
+	}
+	return nil
+}
+
+// validate rejects governance values the chaincode cannot operate with.
+func (gov *governance) validate() error {
+	if len(gov.AdminMSPID) == 0 {
+		return fmt.Errorf("adminMSPID must be a non-empty string")
+	}
+	if gov.PricePolicy.MinPrice <= 0 {
+		return fmt.Errorf("pricePolicy.minPrice must be a positive integer")
+	}
+	if gov.PricePolicy.MaxPrice != 0 && gov.PricePolicy.MaxPrice < gov.PricePolicy.MinPrice {
+		return fmt.Errorf("pricePolicy.maxPrice must be zero or at least pricePolicy.minPrice")
+	}
+	if gov.FlashLoanFee < 0 {
+		return fmt.Errorf("flashLoanFee must not be negative")
+	}
+	if gov.NameReservationCost < 0 {
+		return fmt.Errorf("nameReservationCost must not be negative")
+	}
+	if gov.NameReservationPeriod <= 0 {
+		return fmt.Errorf("nameReservationPeriod must be a positive integer")
+	}
+	if gov.SuspicionThreshold < 0 {
+		return fmt.Errorf("suspicionThreshold must not be negative")
+	}
+	if gov.RepairCost < 0 {
+		return fmt.Errorf("repairCost must not be negative")
+	}
+	if gov.CarbonCostPerTransfer < 0 {
+		return fmt.Errorf("carbonCostPerTransfer must not be negative")
+	}
+	if gov.FarmingYieldRate < 0 {
+		return fmt.Errorf("farmingYieldRate must not be negative")
+	}
+	if gov.MinDisputeStake < 0 {
+		return fmt.Errorf("minDisputeStake must not be negative")
+	}
+	if gov.FieldChangeQuorum <= 0 {
+		return fmt.Errorf("fieldChangeQuorum must be a positive integer")
+	}
+	if gov.DecayPolicy.DecayRatePerThousandSeconds < 0 || gov.DecayPolicy.DecayRatePerThousandSeconds >= 1 {
+		return fmt.Errorf("decayPolicy.decayRatePerThousandSeconds must be at least 0 and below 1")
+	}
+	if gov.DefaultReputation < 0 {
+		return fmt.Errorf("defaultReputation must not be negative")
+	}
+	if gov.OracleConsensus.RequiredAgreements <= 0 {
+		return fmt.Errorf("oracleConsensus.requiredAgreements must be a positive integer")
+	}
+	if gov.OracleConsensus.PriceTolerancePercent < 0 {
+		return fmt.Errorf("oracleConsensus.priceTolerancePercent must not be negative")
+	}
+	if gov.OracleConsensus.VoteExpirySeconds <= 0 {
+		return fmt.Errorf("oracleConsensus.voteExpirySeconds must be a positive integer")
+	}
+	if gov.InsurancePool.TotalFunds < 0 {
+		return fmt.Errorf("insurancePool.totalFunds must not be negative")
+	}
+	if gov.InsurancePool.PerMarblePremium < 0 {
+		return fmt.Errorf("insurancePool.perMarblePremium must not be negative")
+	}
+	if len(gov.ExternalValidator.ValidatorChannel) != 0 && len(gov.ExternalValidator.ValidatorChaincode) == 0 {
+		return fmt.Errorf("externalValidator.validatorChannel requires a validatorChaincode")
+	}
+	for i, bracket := range gov.TaxRateTable {
+		if bracket.MinPrice < 0 {
+			return fmt.Errorf("taxRateTable[%d].minPrice must not be negative", i)
+		}
+		if bracket.Rate < 0 || bracket.Rate > 1 {
+			return fmt.Errorf("taxRateTable[%d].rate must be between 0 and 1", i)
+		}
+	}
+	for i, period := range gov.BlackoutPeriods {
+		if period.EndTime < period.StartTime {
+			return fmt.Errorf("blackoutPeriods[%d].endTime must not be before its startTime", i)
+		}
+	}
+	return nil
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+func (gov *governance) isBlacklisted(mspID string) bool {
+	return containsString(gov.Blacklist, mspID)
+}
+
+func (gov *governance) addToBlacklist(mspID string) {
+	if !gov.isBlacklisted(mspID) {
+		gov.Blacklist = append(gov.Blacklist, mspID)
+	}
+}
+
+func (gov *governance) removeFromBlacklist(mspID string) {
+	gov.Blacklist = removeString(gov.Blacklist, mspID)
+}
+
+// removeString returns list without any occurrence of s. It reuses list's storage.
+func removeString(list []string, s string) []string {
+	kept := list[:0]
+	for _, item := range list {
+		if item != s {
+			kept = append(kept, item)
+		}
+	}
+	return kept
+}
+
+// checkCallerNotBlacklisted fails if the caller's MSP is on the governance blacklist.
+func checkCallerNotBlacklisted(stub shim.ChaincodeStubInterface, gov *governance) error {
+	callerMSPID, err := getCallerMSPID(stub)
+	if err != nil {
+		return err
+	}
+	if gov.isBlacklisted(callerMSPID) {
+		return fmt.Errorf("organization %s is blacklisted", callerMSPID)
+	}
+	return nil
+}
+
+// checkPrice applies the governance price policy to a new marble price.
+func (p *pricePolicy) checkPrice(price int64) error {
+	if price < int64(p.MinPrice) {
+		return fmt.Errorf("price %d is below the minimum price %d", price, p.MinPrice)
+	}
+	if p.MaxPrice > 0 && price > int64(p.MaxPrice) {
+		return fmt.Errorf("price %d is above the maximum price %d", price, p.MaxPrice)
+	}
+	return nil
+}
+
+// ==================================================================================
+// initialize records the init metadata, the default governance values and an empty
+// market cap. The admin organization is the one that instantiated the chaincode.
+// It fails on a channel where the chaincode is already initialized, so a second Init
+// cannot reset governance; governance changes go through reinitialize.
+// ==================================================================================
+func initialize(stub shim.ChaincodeStubInterface) error {
+	initAsBytes, err := stub.GetPrivateData("collectionMarbles", initInfoKey)
+	if err != nil {
+		return fmt.Errorf("Failed to get init info: %s", err.Error())
+	} else if initAsBytes != nil {
+		return fmt.Errorf("chaincode is already initialized")
+	}
+
+	adminMSPID, err := getCallerMSPID(stub)
+	if err != nil {
+		return err
+	}
+	txTime, err := getTxTime(stub)
+	if err != nil {
+		return err
+	}
+
+	info := &initInfo{
+		ObjectType:    "initInfo",
+		Version:       chaincodeVersion,
+		InitializedAt: txTime.Format(time.RFC3339),
+		InitTxID:      stub.GetTxID(),
+		AdminMSPID:    adminMSPID,
+	}
+	infoAsBytes, err := json.Marshal(info)
+	if err != nil {
+		return err
+	}
+	err = stub.PutPrivateData("collectionMarbles", initInfoKey, infoAsBytes)
+	if err != nil {
+		return err
+	}
+
+	err = putMarketCapBase(stub, &marketCap{ObjectType: "marketCap", History: []marketCapPoint{}})
+	if err != nil {
+		return err
+	}
+	return putGovernance(stub, defaultGovernance(adminMSPID))
+}
+
+// ===============================================
+// getInitInfo - read the chaincode init metadata
+// ===============================================
+func (t *SimpleChaincode) getInitInfo(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	if len(args) != 0 {
+		return shim.Error("Incorrect number of arguments. Expecting 0")
+	}
+
+	initAsBytes, err := stub.GetPrivateData("collectionMarbles", initInfoKey)
+	if err != nil {
+		return shim.Error("Failed to get init info: " + err.Error())
+	} else if initAsBytes == nil {
+		return shim.Error("chaincode has not been initialized")
+	}
+
+	return shim.Success(initAsBytes)
+}
+
+// ==================================================================================
+// reinitialize - update the governance defaults without touching any marble data.
+// The transient input is merged onto the current defaults, so only the fields
+// present in it are changed. Other governance fields are rejected, see
+// governanceDefaults.
+// ==================================================================================
+func (t *SimpleChaincode) reinitialize(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	fmt.Println("- start reinitialize")
+
+	if len(args) != 0 {
+		return shim.Error("Incorrect number of arguments. Governance data must be passed in transient map.")
+	}
+
+	err := requireAdmin(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	gov, err := getGovernance(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	defaults := gov.defaults()
+	err = getTransientInput(stub, "governance", defaults)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	gov.setDefaults(defaults)
+
+	err = gov.validate()
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	err = putGovernance(stub, gov)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	fmt.Println("- end reinitialize")
+	return shim.Success(nil)
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestInitTwiceFails(t *testing.T) {
+	s := newTestStub(t)
+	first := s.mustInvoke("getInitInfo", nil)
+	s.mustInvoke("reinitialize", map[string]interface{}{"governance": map[string]interface{}{"pricePolicy": map[string]interface{}{"minPrice": 5}}})
+
+	s.setCaller("Org2MSP", "admin2")
+	response := s.init(nil)
+	if response.Status == 200 || response.Message != "chaincode is already initialized" {
+		t.Fatalf("expected the second Init to fail, got %d %s", response.Status, response.Message)
+	}
+
+	if second := s.mustInvoke("getInitInfo", nil); string(second) != string(first) {
+		t.Fatalf("expected the init info to be kept, got %s instead of %s", second, first)
+	}
+	gov := s.readTestGovernance()
+	if gov.AdminMSPID != "Org1MSP" || gov.PricePolicy.MinPrice != 5 {
+		t.Fatalf("expected governance to be kept, got admin %s and min price %d", gov.AdminMSPID, gov.PricePolicy.MinPrice)
+	}
+}
+
+func TestInitInfoIsInCollectionMarbles(t *testing.T) {
+	s := newTestStub(t)
+	var info initInfo
+	err := json.Unmarshal(s.PvtState["collectionMarbles"][initInfoKey], &info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Version != chaincodeVersion || info.AdminMSPID != "Org1MSP" || info.InitTxID != "tx1" {
+		t.Fatalf("unexpected init info %+v", info)
+	}
+	var records []queryRecord
+	err = json.Unmarshal(s.mustInvoke("getMarblesByRange", nil, "", ""), &records)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 0 {
+		t.Fatalf("expected the init info to stay out of marble ranges, got %+v", records)
+	}
+}
+
+func TestReinitializeOnlyChangesDefaults(t *testing.T) {
+	s := newTestStub(t)
+	s.mustInvoke("reinitialize", map[string]interface{}{"governance": map[string]interface{}{
+		"repairCost": 7, "insurancePool": map[string]interface{}{"perMarblePremium": 9},
+	}})
+	gov := s.readTestGovernance()
+	if gov.RepairCost != 7 || gov.InsurancePool.PerMarblePremium != 9 || gov.MinDisputeStake != 10 {
+		t.Fatalf("expected only the given defaults to change, got %+v", gov)
+	}
+
+	for field, value := range map[string]interface{}{
+		"adminMSPID":       "Org2MSP",
+		"blacklist":        []string{"Org2MSP"},
+		"ownerReputations": map[string]int{"Org1MSP": 1000},
+		"insurancePool":    map[string]interface{}{"totalFunds": 1000000},
+		"blackoutPeriods":  []interface{}{},
+	} {
+		s.mustFail("unknown field", "reinitialize", map[string]interface{}{"governance": map[string]interface{}{field: value}})
+	}
+	s.mustFail("repairCost must not be negative", "reinitialize", map[string]interface{}{"governance": map[string]interface{}{"repairCost": -1}})
+	s.setCaller("Org2MSP", "user2")
+	s.mustFail("is not the admin organization", "reinitialize", map[string]interface{}{"governance": map[string]interface{}{"repairCost": 1}})
+}
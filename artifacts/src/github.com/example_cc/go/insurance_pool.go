@@ -0,0 +1,347 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// claimQueueIndexName orders the claims waiting for pool funds by the time they were
+// queued. The timestamp is zero-padded so the lexical order of the keys is FIFO.
+const claimQueueIndexName = "claimQueue~queuedAt~claimID"
+
+// States of an insurance pool claim.
+const (
+	claimStatusFiled  = "filed"
+	claimStatusQueued = "queued"
+	claimStatusPaid   = "paid"
+)
+
+// InsurancePool is the collective cover for insured marbles, held in loyalty points.
+type InsurancePool struct {
+	TotalFunds       int `json:"totalFunds"`
+	PerMarblePremium int `json:"perMarblePremium"`
+}
+
+// PoolClaim is a claim against the insurance pool, kept in collectionMarbleClaimQueue
+// under the ID of the transaction that filed it.
+type PoolClaim struct {
+	ObjectType    string `json:"docType"`
+	ClaimID       string `json:"claimID"`
+	MarbleName    string `json:"marbleName"`
+	ClaimantMSPID string `json:"claimantMSPID"`
+	ClaimValue    int    `json:"claimValue"`
+	Status        string `json:"status"`
+}
+
+func getPoolClaim(stub shim.ChaincodeStubInterface, claimID string) (*PoolClaim, error) {
+	claimAsBytes, err := stub.GetPrivateData("collectionMarbleClaimQueue", claimID)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get claim: %s", err.Error())
+	} else if claimAsBytes == nil {
+		return nil, fmt.Errorf("Claim does not exist: %s", claimID)
+	}
+
+	claim := &PoolClaim{}
+	err = json.Unmarshal(claimAsBytes, claim)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to decode JSON of: %s", string(claimAsBytes))
+	}
+	return claim, nil
+}
+
+func putPoolClaim(stub shim.ChaincodeStubInterface, claim *PoolClaim) error {
+	claimAsBytes, err := json.Marshal(claim)
+	if err != nil {
+		return err
+	}
+	return stub.PutPrivateData("collectionMarbleClaimQueue", claim.ClaimID, claimAsBytes)
+}
+
+// payPoolClaim pays a claim out of the pool funds to the claimant's loyalty points.
+// The caller is responsible for writing the governance record back.
+func payPoolClaim(stub shim.ChaincodeStubInterface, gov *governance, claim *PoolClaim) error {
+	err := creditLoyaltyPoints(stub, claim.ClaimantMSPID, claim.ClaimValue)
+	if err != nil {
+		return err
+	}
+	gov.InsurancePool.TotalFunds -= claim.ClaimValue
+	claim.Status = claimStatusPaid
+	return putPoolClaim(stub, claim)
+}
+
+// payQueuedClaims pays queued claims in FIFO order for as long as the pool funds cover
+// the claim at the head of the queue.
+// The caller is responsible for writing the governance record back.
+func payQueuedClaims(stub shim.ChaincodeStubInterface, gov *governance) error {
+	resultsIterator, err := stub.GetPrivateDataByPartialCompositeKey("collectionMarbleClaimQueue", claimQueueIndexName, []string{})
+	if err != nil {
+		return err
+	}
+	defer resultsIterator.Close()
+
+	for resultsIterator.HasNext() {
+		responseRange, err := resultsIterator.Next()
+		if err != nil {
+			return err
+		}
+		_, compositeKeyParts, err := stub.SplitCompositeKey(responseRange.Key)
+		if err != nil {
+			return err
+		}
+		claim, err := getPoolClaim(stub, compositeKeyParts[1])
+		if err != nil {
+			return err
+		}
+		if claim.ClaimValue > gov.InsurancePool.TotalFunds {
+			return nil
+		}
+		err = payPoolClaim(stub, gov, claim)
+		if err != nil {
+			return err
+		}
+		err = stub.DelPrivateData("collectionMarbleClaimQueue", responseRange.Key)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ===========================================================================
+// payInsurancePremium - insure an owned marble against the pool, paid in the
+// caller's loyalty points
+// ===========================================================================
+func (t *SimpleChaincode) payInsurancePremium(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+
+	//   0
+	// "marble1"
+	if len(args) != 1 {
+		return shim.Error("Incorrect number of arguments. Expecting name of the marble to insure")
+	}
+
+	m, err := getMarble(stub, args[0])
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = requireOwner(stub, m)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if m.InsuranceCovered {
+		return shim.Error("Marble is already insured: " + m.Name)
+	}
+
+	gov, err := getGovernance(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	callerMSPID, err := getCallerMSPID(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = debitLoyaltyPoints(stub, callerMSPID, gov.InsurancePool.PerMarblePremium)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	m.InsuranceCovered = true
+	err = putMarble(stub, m)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	gov.InsurancePool.TotalFunds += gov.InsurancePool.PerMarblePremium
+	err = payQueuedClaims(stub, gov)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = putGovernance(stub, gov)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	return shim.Success(nil)
+}
+
+// ===========================================================================
+// filePoolClaim - claim against the pool for an insured marble
+// ===========================================================================
+func (t *SimpleChaincode) filePoolClaim(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+
+	//     0         1
+	// "marble1", "250"
+	if len(args) != 2 {
+		return shim.Error("Incorrect number of arguments. Expecting 2")
+	}
+	claimValue, err := strconv.Atoi(args[1])
+	if err != nil || claimValue <= 0 {
+		return shim.Error("claimValue must be a positive integer")
+	}
+
+	m, err := getMarble(stub, args[0])
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = requireOwner(stub, m)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if !m.InsuranceCovered {
+		return shim.Error("Marble is not insured: " + m.Name)
+	}
+	claimantMSPID, err := getCallerMSPID(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	claim := &PoolClaim{
+		ObjectType:    "poolClaim",
+		ClaimID:       stub.GetTxID(),
+		MarbleName:    m.Name,
+		ClaimantMSPID: claimantMSPID,
+		ClaimValue:    claimValue,
+		Status:        claimStatusFiled,
+	}
+	err = putPoolClaim(stub, claim)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	return shim.Success([]byte(claim.ClaimID))
+}
+
+// ===========================================================================
+// settlePoolClaim - admin approval of a claim. It is paid at once if the pool
+// can cover it, otherwise it joins the FIFO claim queue.
+// ===========================================================================
+func (t *SimpleChaincode) settlePoolClaim(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+
+	//    0
+	// "claimID"
+	if len(args) != 1 {
+		return shim.Error("Incorrect number of arguments. Expecting claim ID")
+	}
+
+	err := requireAdmin(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	claim, err := getPoolClaim(stub, args[0])
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if claim.Status != claimStatusFiled {
+		return shim.Error("Claim is already " + claim.Status + ": " + claim.ClaimID)
+	}
+
+	gov, err := getGovernance(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if claim.ClaimValue <= gov.InsurancePool.TotalFunds {
+		err = payPoolClaim(stub, gov, claim)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		err = putGovernance(stub, gov)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		return shim.Success([]byte(claim.Status))
+	}
+
+	txTime, err := getTxTime(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	queueKey, err := stub.CreateCompositeKey(claimQueueIndexName, []string{fmt.Sprintf("%020d", txTime.UnixNano()), claim.ClaimID})
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = stub.PutPrivateData("collectionMarbleClaimQueue", queueKey, []byte{0x00})
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	claim.Status = claimStatusQueued
+	err = putPoolClaim(stub, claim)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	return shim.Success([]byte(claim.Status))
+}
+
+// ===========================================================================
+// getPoolBalance - the funds held by the insurance pool
+// ===========================================================================
+func (t *SimpleChaincode) getPoolBalance(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	if len(args) != 0 {
+		return shim.Error("Incorrect number of arguments. Expecting 0")
+	}
+
+	gov, err := getGovernance(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	poolAsBytes, err := json.Marshal(gov.InsurancePool)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	return shim.Success(poolAsBytes)
+}
+
+// ===========================================================================
+// addPoolFunds - admin top up of the insurance pool. Queued claims are paid
+// from the new funds.
+// ===========================================================================
+func (t *SimpleChaincode) addPoolFunds(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+
+	//   0
+	// "1000"
+	if len(args) != 1 {
+		return shim.Error("Incorrect number of arguments. Expecting amount")
+	}
+	amount, err := strconv.Atoi(args[0])
+	if err != nil || amount <= 0 {
+		return shim.Error("amount must be a positive integer")
+	}
+
+	err = requireAdmin(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	gov, err := getGovernance(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	gov.InsurancePool.TotalFunds += amount
+	err = payQueuedClaims(stub, gov)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = putGovernance(stub, gov)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	return shim.Success(nil)
+}
+
+// ===========================================================================
+// getInsuredMarbles - marbles covered by the insurance pool
+// ===========================================================================
+func (t *SimpleChaincode) getInsuredMarbles(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	if len(args) != 0 {
+		return shim.Error("Incorrect number of arguments. Expecting 0")
+	}
+
+	records, err := scanMarbles(stub, func(m *marble) bool {
+		return m.InsuranceCovered
+	})
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	return marshalQueryRecords(records)
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// loyaltyIndexName keys the loyalty point balance of each organization in
+// collectionMarbles. A composite key cannot collide with a marble name.
+const loyaltyIndexName = "loyalty~mspid"
+
+type loyaltyAccount struct {
+	ObjectType string `json:"docType"`
+	MSPID      string `json:"mspID"`
+	Points     int    `json:"points"`
+}
+
+func getLoyaltyAccount(stub shim.ChaincodeStubInterface, mspID string) (*loyaltyAccount, error) {
+	accountKey, err := stub.CreateCompositeKey(loyaltyIndexName, []string{mspID})
+	if err != nil {
+		return nil, err
+	}
+	accountAsBytes, err := stub.GetPrivateData("collectionMarbles", accountKey)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get loyalty points: %s", err.Error())
+	}
+
+	account := &loyaltyAccount{ObjectType: "loyaltyAccount", MSPID: mspID}
+	if accountAsBytes == nil {
+		return account, nil
+	}
+	err = json.Unmarshal(accountAsBytes, account)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to decode JSON of: %s", string(accountAsBytes))
+	}
+	return account, nil
+}
+
+func putLoyaltyAccount(stub shim.ChaincodeStubInterface, account *loyaltyAccount) error {
+	accountKey, err := stub.CreateCompositeKey(loyaltyIndexName, []string{account.MSPID})
+	if err != nil {
+		return err
+	}
+	accountAsBytes, err := json.Marshal(account)
+	if err != nil {
+		return err
+	}
+	return stub.PutPrivateData("collectionMarbles", accountKey, accountAsBytes)
+}
+
+// creditLoyaltyPoints adds points to an organization's balance.
+func creditLoyaltyPoints(stub shim.ChaincodeStubInterface, mspID string, points int) error {
+	account, err := getLoyaltyAccount(stub, mspID)
+	if err != nil {
+		return err
+	}
+	account.Points += points
+	return putLoyaltyAccount(stub, account)
+}
+
+// debitLoyaltyPoints takes points from an organization's balance, failing if the balance
+// does not cover them.
+func debitLoyaltyPoints(stub shim.ChaincodeStubInterface, mspID string, points int) error {
+	account, err := getLoyaltyAccount(stub, mspID)
+	if err != nil {
+		return err
+	}
+	if account.Points < points {
+		return fmt.Errorf("insufficient loyalty points for %s: have %d, need %d", mspID, account.Points, points)
+	}
+	account.Points -= points
+	return putLoyaltyAccount(stub, account)
+}
+
+// ===========================================================================
+// getLoyaltyPoints - read the loyalty point balance of an organization
+// ===========================================================================
+func (t *SimpleChaincode) getLoyaltyPoints(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+
+	//   0
+	// "Org1MSP"
+	if len(args) != 1 {
+		return shim.Error("Incorrect number of arguments. Expecting MSP ID of the organization to query")
+	}
+
+	account, err := getLoyaltyAccount(stub, args[0])
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	accountAsBytes, err := json.Marshal(account)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	return shim.Success(accountAsBytes)
+}
+
+// ===========================================================================
+// grantLoyaltyPoints - admin credit of loyalty points to an organization
+// ===========================================================================
+func (t *SimpleChaincode) grantLoyaltyPoints(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+
+	//   0           1
+	// "Org1MSP", "100"
+	if len(args) != 2 {
+		return shim.Error("Incorrect number of arguments. Expecting 2")
+	}
+
+	err := requireAdmin(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	points, err := strconv.Atoi(args[1])
+	if err != nil || points <= 0 {
+		return shim.Error("points must be a positive integer")
+	}
+
+	err = creditLoyaltyPoints(stub, args[0], points)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	return shim.Success(nil)
+}
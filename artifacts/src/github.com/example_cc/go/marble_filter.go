@@ -0,0 +1,242 @@
+package main
+
+import (
+	"encoding/json"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// FilterSpec selects marbles by their public attributes. A nil or empty field does not
+// constrain the result.
+type FilterSpec struct {
+	Color   string `json:"color"`
+	Owner   string `json:"owner"`
+	SizeGte *int   `json:"sizeGte"`
+	SizeLte *int   `json:"sizeLte"`
+}
+
+// matches reports whether m satisfies every constraint in the filter.
+func (f *FilterSpec) matches(m *marble) bool {
+	if m.ObjectType != "marble" {
+		return false
+	}
+	if f.Color != "" && m.Color != f.Color {
+		return false
+	}
+	if f.Owner != "" && m.Owner != f.Owner {
+		return false
+	}
+	if f.SizeGte != nil && m.Size < *f.SizeGte {
+		return false
+	}
+	if f.SizeLte != nil && m.Size > *f.SizeLte {
+		return false
+	}
+	return true
+}
+
+// queryRecord is one element of the JSON array returned by the query functions.
+type queryRecord struct {
+	Key    string          `json:"Key"`
+	Record json.RawMessage `json:"Record"`
+}
+
+// ===== Example: Filtered query without CouchDB ===========================================
+// queryMarblesWithFilter evaluates a FilterSpec in chaincode rather than in the state
+// database, so it works on LevelDB as well as CouchDB.
+// The trade-off is cost: every candidate marble is read and decoded, which is O(n) in the
+// size of collectionMarbles. When a color is given the scan is narrowed to the color~name
+// index, otherwise the whole collection is range scanned.
+// Prefer queryMarbles on CouchDB deployments with large collections.
+// =========================================================================================
+func (t *SimpleChaincode) queryMarblesWithFilter(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+
+	//   0
+	// "{\"color\":\"blue\",\"sizeGte\":30}"
+	if len(args) != 1 {
+		return shim.Error("Incorrect number of arguments. Expecting 1")
+	}
+
+	var filter FilterSpec
+	err := json.Unmarshal([]byte(args[0]), &filter)
+	if err != nil {
+		return shim.Error("Failed to decode JSON of: " + args[0])
+	}
+
+	var records []queryRecord
+	if filter.Color != "" {
+		records, err = filterMarblesByColorIndex(stub, &filter)
+	} else {
+		records, err = filterMarblesByRangeScan(stub, &filter)
+	}
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	return marshalQueryRecords(records)
+}
+
+// marshalQueryRecords returns query records as a successful JSON array response.
+func marshalQueryRecords(records []queryRecord) pb.Response {
+	resultAsBytes, err := json.Marshal(records)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	return shim.Success(resultAsBytes)
+}
+
+// =========================================================================================
+// getMarblesByColor returns the marbles of one color, found through the color~name index
+// and read one by one. Unlike a rich query selector on color, it works on LevelDB as well
+// as CouchDB.
+// =========================================================================================
+func (t *SimpleChaincode) getMarblesByColor(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+
+	//   0
+	// "blue"
+	if len(args) != 1 {
+		return shim.Error("Incorrect number of arguments. Expecting color")
+	}
+	if len(args[0]) == 0 {
+		return shim.Error("color must be a non-empty string")
+	}
+
+	records, err := filterMarblesByColorIndex(stub, &FilterSpec{Color: args[0]})
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	return marshalQueryRecords(records)
+}
+
+// =========================================================================================
+// queryMarblesByOwnerAndColor returns the marbles of one color held by one owner. The
+// governance RichQueryEnabled flag chooses between two paths, neither of which scans the
+// whole collection.
+// Without rich query, the color~name index lists the marbles of the color, which are
+// read one by one and kept when they belong to the owner. This works on LevelDB as well
+// as CouchDB, at the cost of reading every marble of the color.
+// With rich query, a CouchDB selector on docType, owner and color finds the marbles in
+// the state database.
+// =========================================================================================
+func (t *SimpleChaincode) queryMarblesByOwnerAndColor(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+
+	type ownerColorSelector struct {
+		Selector struct {
+			ObjectType string `json:"docType"`
+			Owner      string `json:"owner"`
+			Color      string `json:"color"`
+		} `json:"selector"`
+	}
+
+	//   0       1
+	// "bob", "blue"
+	if len(args) != 2 {
+		return shim.Error("Incorrect number of arguments. Expecting owner and color")
+	}
+	if len(args[0]) == 0 {
+		return shim.Error("owner must be a non-empty string")
+	}
+	if len(args[1]) == 0 {
+		return shim.Error("color must be a non-empty string")
+	}
+
+	gov, err := getGovernance(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	if !gov.RichQueryEnabled {
+		records, err := filterMarblesByColorIndex(stub, &FilterSpec{Color: args[1], Owner: args[0]})
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		return marshalQueryRecords(records)
+	}
+
+	var query ownerColorSelector
+	query.Selector.ObjectType = "marble"
+	query.Selector.Owner = args[0]
+	query.Selector.Color = args[1]
+	queryAsBytes, err := json.Marshal(query)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	queryResults, err := getQueryResultForQueryString(stub, string(queryAsBytes))
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	return shim.Success(queryResults)
+}
+
+// filterMarblesByColorIndex visits only the marbles under the filter's color in the
+// color~name index.
+func filterMarblesByColorIndex(stub shim.ChaincodeStubInterface, filter *FilterSpec) ([]queryRecord, error) {
+	resultsIterator, err := stub.GetPrivateDataByPartialCompositeKey("collectionMarbles", "color~name", []string{filter.Color})
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	records := []queryRecord{}
+	for resultsIterator.HasNext() {
+		responseRange, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+		_, compositeKeyParts, err := stub.SplitCompositeKey(responseRange.Key)
+		if err != nil {
+			return nil, err
+		}
+
+		name := compositeKeyParts[1]
+		marbleAsBytes, err := stub.GetPrivateData("collectionMarbles", name)
+		if err != nil {
+			return nil, err
+		} else if marbleAsBytes == nil {
+			continue
+		}
+
+		var candidate marble
+		err = json.Unmarshal(marbleAsBytes, &candidate)
+		if err != nil {
+			return nil, err
+		}
+		if filter.matches(&candidate) {
+			records = append(records, queryRecord{Key: name, Record: marbleAsBytes})
+		}
+	}
+	return records, nil
+}
+
+// filterMarblesByRangeScan visits every simple key in collectionMarbles.
+func filterMarblesByRangeScan(stub shim.ChaincodeStubInterface, filter *FilterSpec) ([]queryRecord, error) {
+	return scanMarbles(stub, filter.matches)
+}
+
+// scanMarbles range scans every simple key in collectionMarbles and returns the marbles
+// accepted by match. Records that are not marbles are skipped.
+func scanMarbles(stub shim.ChaincodeStubInterface, match func(*marble) bool) ([]queryRecord, error) {
+	resultsIterator, err := stub.GetPrivateDataByRange("collectionMarbles", "", "")
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	records := []queryRecord{}
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var candidate marble
+		if json.Unmarshal(queryResponse.Value, &candidate) != nil || candidate.ObjectType != "marble" {
+			continue
+		}
+		if match(&candidate) {
+			records = append(records, queryRecord{Key: queryResponse.Key, Record: queryResponse.Value})
+		}
+	}
+	return records, nil
+}
@@ -0,0 +1,378 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// listingIndexName orders listed marbles by asking price. The price is zero-padded to the
+// 19 digits of the largest int64 so that the lexical order of the composite keys matches
+// the numeric order of the prices.
+const listingIndexName = "askingPrice~name"
+
+func listingIndexKey(stub shim.ChaincodeStubInterface, m *marble) (string, error) {
+	return stub.CreateCompositeKey(listingIndexName, []string{fmt.Sprintf("%019d", m.AskingPrice), m.Name})
+}
+
+func addListingIndex(stub shim.ChaincodeStubInterface, m *marble) error {
+	key, err := listingIndexKey(stub, m)
+	if err != nil {
+		return err
+	}
+	return stub.PutPrivateData("collectionMarbles", key, []byte{0x00})
+}
+
+func removeListingIndex(stub shim.ChaincodeStubInterface, m *marble) error {
+	key, err := listingIndexKey(stub, m)
+	if err != nil {
+		return err
+	}
+	return stub.DelPrivateData("collectionMarbles", key)
+}
+
+// checkListable fails if the marble may not be offered for sale.
+func checkListable(stub shim.ChaincodeStubInterface, m *marble) error {
+	err := checkNotLocked(m)
+	if err != nil {
+		return err
+	}
+	err = checkNotArchived(m)
+	if err != nil {
+		return err
+	}
+	err = checkNotReserved(stub, m)
+	if err != nil {
+		return err
+	}
+	err = checkAuditPassed(m)
+	if err != nil {
+		return err
+	}
+	return checkCertified(stub, m)
+}
+
+// listMarble offers a marble for sale at askingPrice and writes it back. Re-listing at
+// a new price moves the marble within the price index.
+func listMarble(stub shim.ChaincodeStubInterface, m *marble, askingPrice int64) error {
+	if m.IsForSale {
+		err := removeListingIndex(stub, m)
+		if err != nil {
+			return err
+		}
+	}
+	m.IsForSale = true
+	m.AskingPrice = askingPrice
+
+	err := putMarble(stub, m)
+	if err != nil {
+		return err
+	}
+	return addListingIndex(stub, m)
+}
+
+// ===========================================================================
+// listMarbleForSale - offer a marble for sale at an asking price
+// ===========================================================================
+func (t *SimpleChaincode) listMarbleForSale(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	fmt.Println("- start list marble for sale")
+
+	type marbleListingTransientInput struct {
+		Name        string `json:"name"`
+		AskingPrice int64  `json:"askingPrice"`
+	}
+
+	if len(args) != 0 {
+		return shim.Error("Incorrect number of arguments. Private marble data must be passed in transient map.")
+	}
+
+	var listingInput marbleListingTransientInput
+	err := getTransientInput(stub, "marble_listing", &listingInput)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	if len(listingInput.Name) == 0 {
+		return shim.Error("name field must be a non-empty string")
+	}
+	err = validateMarbleName(listingInput.Name)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if listingInput.AskingPrice < 1 || listingInput.AskingPrice > maxMarblePrice {
+		return shim.Error(fmt.Sprintf("askingPrice field must be between 1 and %d", maxMarblePrice))
+	}
+
+	marbleToList, err := getMarble(stub, listingInput.Name)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = requireOwner(stub, marbleToList)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = checkListable(stub, marbleToList)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = listMarble(stub, marbleToList, listingInput.AskingPrice)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	fmt.Println("- end list marble for sale")
+	return shim.Success(nil)
+}
+
+// ===========================================================================
+// unlistMarbleFromSale - withdraw a marble from sale
+// ===========================================================================
+func (t *SimpleChaincode) unlistMarbleFromSale(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	fmt.Println("- start unlist marble from sale")
+
+	type marbleUnlistTransientInput struct {
+		Name string `json:"name"`
+	}
+
+	if len(args) != 0 {
+		return shim.Error("Incorrect number of arguments. Private marble data must be passed in transient map.")
+	}
+
+	var unlistInput marbleUnlistTransientInput
+	err := getTransientInput(stub, "marble_unlist", &unlistInput)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	if len(unlistInput.Name) == 0 {
+		return shim.Error("name field must be a non-empty string")
+	}
+
+	marbleToUnlist, err := getMarble(stub, unlistInput.Name)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if !marbleToUnlist.IsForSale {
+		return shim.Error("Marble is not listed for sale: " + unlistInput.Name)
+	}
+
+	err = removeListingIndex(stub, marbleToUnlist)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	marbleToUnlist.IsForSale = false
+	marbleToUnlist.AskingPrice = 0
+
+	err = putMarble(stub, marbleToUnlist)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	fmt.Println("- end unlist marble from sale")
+	return shim.Success(nil)
+}
+
+// =========================================================================================
+// queryMarblesForSale queries for the marbles listed for sale.
+// Only available on state databases that support rich query (e.g. CouchDB)
+// =========================================================================================
+func (t *SimpleChaincode) queryMarblesForSale(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	if len(args) != 0 {
+		return shim.Error("Incorrect number of arguments. Expecting 0")
+	}
+
+	queryString := "{\"selector\":{\"docType\":\"marble\",\"isForSale\":true}}"
+
+	queryResults, err := getQueryResultForQueryString(stub, queryString)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	return shim.Success(queryResults)
+}
+
+// =========================================================================================
+// getMarblesForSale returns the marbles listed for sale, cheapest first, found through
+// the askingPrice~name index. Unlike queryMarblesForSale it works on LevelDB as well as
+// CouchDB.
+// =========================================================================================
+func (t *SimpleChaincode) getMarblesForSale(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	if len(args) != 0 {
+		return shim.Error("Incorrect number of arguments. Expecting 0")
+	}
+
+	resultsIterator, err := stub.GetPrivateDataByPartialCompositeKey("collectionMarbles", listingIndexName, []string{})
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	defer resultsIterator.Close()
+
+	records := []queryRecord{}
+	for resultsIterator.HasNext() {
+		responseRange, err := resultsIterator.Next()
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		_, compositeKeyParts, err := stub.SplitCompositeKey(responseRange.Key)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+
+		name := compositeKeyParts[1]
+		marbleAsBytes, err := stub.GetPrivateData("collectionMarbles", name)
+		if err != nil {
+			return shim.Error(err.Error())
+		} else if marbleAsBytes == nil {
+			continue
+		}
+		records = append(records, queryRecord{Key: name, Record: marbleAsBytes})
+	}
+
+	return marshalQueryRecords(records)
+}
+
+// checkSweepable fails if a listed marble cannot be sold to buyer, who has already
+// acquired acquired marbles in the same sweep. It checks the gates of transferMarbleTo
+// without writing anything; the external validator is asked about the marble with its
+// new owner.
+func checkSweepable(stub shim.ChaincodeStubInterface, gov *governance, m *marble, buyer string, acquired int) error {
+	err := checkOwnerChange(stub, gov, m, buyer)
+	if err != nil {
+		return err
+	}
+	err = checkShareholderApproval(stub, m.Name, shareActionTransfer, buyer)
+	if err != nil {
+		return err
+	}
+	// the owner~name index does not show the marbles acquired earlier in the sweep
+	err = checkOwnerMaxMarbles(stub, buyer, acquired+1)
+	if err != nil {
+		return err
+	}
+	sold := *m
+	sold.PreviousOwner = m.Owner
+	sold.Owner = buyer
+	sold.IsForSale = false
+	sold.AskingPrice = 0
+	return checkExternalValidatorForMarble(stub, gov, &sold)
+}
+
+// ===========================================================================================
+// floorSweep buys up to count of the cheapest listed marbles for the caller's organization.
+// Listings are visited in ascending asking price order through the askingPrice~name index,
+// so the sweep stops at the first listing priced above maxPriceEach. Listings that cannot
+// be bought, see checkSweepable, are skipped; the others are transferred through the same
+// gates as transferMarble.
+// ===========================================================================================
+func (t *SimpleChaincode) floorSweep(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	fmt.Println("- start floor sweep")
+
+	type floorSweepTransientInput struct {
+		Count        int   `json:"count"`
+		MaxPriceEach int64 `json:"maxPriceEach"`
+	}
+
+	type floorSweepResult struct {
+		Acquired    []string `json:"acquired"`
+		TotalSpent  int64    `json:"totalSpent"`
+		NotAcquired int      `json:"notAcquired"`
+	}
+
+	if len(args) != 0 {
+		return shim.Error("Incorrect number of arguments. Private marble data must be passed in transient map.")
+	}
+
+	var sweepInput floorSweepTransientInput
+	err := getTransientInput(stub, "floor_sweep", &sweepInput)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	if sweepInput.Count <= 0 {
+		return shim.Error("count field must be a positive integer")
+	}
+	if sweepInput.MaxPriceEach <= 0 {
+		return shim.Error("maxPriceEach field must be a positive integer")
+	}
+
+	gov, err := getGovernance(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = checkCallerNotBlacklisted(stub, gov)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	buyer, err := getCallerMSPID(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	resultsIterator, err := stub.GetPrivateDataByPartialCompositeKey("collectionMarbles", listingIndexName, []string{})
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	defer resultsIterator.Close()
+
+	result := floorSweepResult{Acquired: []string{}}
+	for resultsIterator.HasNext() && len(result.Acquired) < sweepInput.Count {
+		responseRange, err := resultsIterator.Next()
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+
+		_, compositeKeyParts, err := stub.SplitCompositeKey(responseRange.Key)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		price, err := strconv.ParseInt(compositeKeyParts[0], 10, 64)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		if price > sweepInput.MaxPriceEach {
+			// the index is sorted by price, every remaining listing is too expensive
+			break
+		}
+
+		listed, err := getMarble(stub, compositeKeyParts[1])
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		if !listed.IsForSale || listed.AskingPrice != price {
+			// an index entry the listing no longer matches
+			continue
+		}
+		isOwner, err := callerIs(stub, listed.Owner)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		if isOwner {
+			// the caller's own listings are not bought back
+			continue
+		}
+		err = checkSweepable(stub, gov, listed, buyer, len(result.Acquired))
+		if err != nil {
+			fmt.Printf("- floor sweep skips %s: %s\n", listed.Name, err.Error())
+			continue
+		}
+
+		err = transferMarbleTo(stub, gov, listed, buyer)
+		if err != nil {
+			return shim.Error("Failed to transfer marble " + listed.Name + ": " + err.Error())
+		}
+
+		result.Acquired = append(result.Acquired, listed.Name)
+		result.TotalSpent += price
+	}
+	result.NotAcquired = sweepInput.Count - len(result.Acquired)
+
+	resultAsBytes, err := json.Marshal(result)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	fmt.Printf("- end floor sweep: %s\n", string(resultAsBytes))
+	return shim.Success(resultAsBytes)
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func listing(name string, askingPrice int64) map[string]interface{} {
+	return map[string]interface{}{"marble_listing": map[string]interface{}{"name": name, "askingPrice": askingPrice}}
+}
+
+// certify makes Org1MSP a certification body and certifies the marble, so that it can be
+// listed. The caller must be Org1MSP.
+func (s *testStub) certify(name string) {
+	s.mustInvoke("reinitialize", map[string]interface{}{"governance": map[string]interface{}{"authorizedCertBodies": []string{"Org1MSP"}}})
+	s.mustInvoke("requestCertification", map[string]interface{}{"certification_request": map[string]interface{}{"name": name}})
+	s.mustInvoke("issueCertification", map[string]interface{}{"certification": map[string]interface{}{"marbleName": name, "certificateRef": "cert-" + name}})
+}
+
+func TestListMarbleForSaleRequiresOwner(t *testing.T) {
+	s := newTestStub(t)
+	s.createMarble("marble1", "blue", 35, "Org1MSP", 99)
+	s.certify("marble1")
+
+	s.setCaller("Org2MSP", "user2")
+	s.mustFail("caller is not the owner of marble marble1", "listMarbleForSale", listing("marble1", 50))
+	s.setCaller("Org1MSP", "admin")
+	s.mustInvoke("listMarbleForSale", listing("marble1", 50))
+}
+
+func TestFloorSweepBuysForCallerAndSkipsUnsellableListings(t *testing.T) {
+	s := newTestStub(t)
+	s.createMarble("cheap", "blue", 35, "Org1MSP", 99)
+	s.createMarble("intransit", "red", 35, "Org1MSP", 99)
+	s.createMarble("middle", "green", 35, "Org1MSP", 99)
+	s.createMarble("dear", "white", 35, "Org1MSP", 99)
+	for _, name := range []string{"cheap", "intransit", "middle", "dear"} {
+		s.certify(name)
+	}
+	s.mustInvoke("listMarbleForSale", listing("cheap", 10))
+	s.mustInvoke("listMarbleForSale", listing("intransit", 20))
+	s.mustInvoke("listMarbleForSale", listing("middle", 30))
+	s.mustInvoke("listMarbleForSale", listing("dear", 500))
+	s.mustInvoke("recordHandover", map[string]interface{}{"marble_handover": map[string]interface{}{"name": "intransit", "custodian": "courier"}})
+
+	s.setCaller("Org2MSP", "user2")
+	payload := s.mustInvoke("floorSweep", map[string]interface{}{"floor_sweep": map[string]interface{}{"count": 3, "maxPriceEach": 100}})
+
+	var result struct {
+		Acquired    []string `json:"acquired"`
+		TotalSpent  int      `json:"totalSpent"`
+		NotAcquired int      `json:"notAcquired"`
+	}
+	err := json.Unmarshal(payload, &result)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Acquired) != 2 || result.Acquired[0] != "cheap" || result.Acquired[1] != "middle" {
+		t.Fatalf("expected cheap and middle to be acquired, got %v", result.Acquired)
+	}
+	if result.TotalSpent != 40 || result.NotAcquired != 1 {
+		t.Fatalf("expected 40 spent and 1 not acquired, got %+v", result)
+	}
+	for _, name := range result.Acquired {
+		if owner := s.readTestMarble(name).Owner; owner != "Org2MSP" {
+			t.Fatalf("expected %s to belong to Org2MSP, got %s", name, owner)
+		}
+	}
+	if owner := s.readTestMarble("intransit").Owner; owner != "Org1MSP" {
+		t.Fatalf("expected the marble in transit to stay with Org1MSP, got %s", owner)
+	}
+	if receipts := len(s.PvtState["collectionMarbleTaxReceipts"]); receipts != 2 {
+		t.Fatalf("expected a tax receipt per acquired marble, got %d", receipts)
+	}
+}
+
+func TestListingPricesUpToMaxMarblePriceSortNumerically(t *testing.T) {
+	s := newTestStub(t)
+	prices := map[string]int64{"dearest": maxMarblePrice, "dear": 2000000000, "cheap": 9, "middle": 999999999}
+	for _, name := range []string{"dearest", "dear", "cheap", "middle"} {
+		s.createMarble(name, "blue", 35, "Org1MSP", 99)
+		s.certify(name)
+		s.mustInvoke("listMarbleForSale", listing(name, prices[name]))
+	}
+	s.mustFail("askingPrice field must be between 1 and 10000000000", "listMarbleForSale", listing("cheap", maxMarblePrice+1))
+	s.mustFail("askingPrice field must be between 1 and 10000000000", "listMarbleForSale", listing("cheap", 0))
+	s.mustFail("must match", "listMarbleForSale", listing("a/b", 10))
+
+	var records []queryRecord
+	err := json.Unmarshal(s.mustInvoke("getMarblesForSale", nil), &records)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 4 || records[0].Key != "cheap" || records[1].Key != "middle" || records[2].Key != "dear" || records[3].Key != "dearest" {
+		t.Fatalf("expected the listings cheapest first, got %+v", records)
+	}
+
+	s.setCaller("Org2MSP", "user2")
+	payload := s.mustInvoke("floorSweep", map[string]interface{}{"floor_sweep": map[string]interface{}{"count": 4, "maxPriceEach": 2000000000}})
+	var result struct {
+		Acquired   []string `json:"acquired"`
+		TotalSpent int64    `json:"totalSpent"`
+	}
+	err = json.Unmarshal(payload, &result)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Acquired) != 3 || result.TotalSpent != 2999999999+9 {
+		t.Fatalf("expected the three listings up to 2000000000, got %+v", result)
+	}
+}
@@ -0,0 +1,417 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// The market cap lives in collectionMarbleMarketCap, which has the members of
+// collectionMarblePrivateDetails but keeps its data for good (blockToLive 0), so the total
+// outlives the purge of the prices it sums. It holds a base under marketCapBaseKey and
+// one change for each marble a transaction changes the price of, under a key of its own
+// so that concurrent transactions never conflict over the market cap. Reads add the
+// changes onto the base, and changes older than the previous marketCapFoldPeriod are
+// folded into the base by the next transaction that makes one.
+
+// marketCapBaseKey holds the market cap the changes are added onto.
+const marketCapBaseKey = "marketCapBase"
+
+// marketCapDeltaKeyPrefix starts the key of a change, which goes on with its time,
+// zero-padded to sort as a number, its transaction ID and the marble name.
+const marketCapDeltaKeyPrefix = "delta~"
+
+// marketCapDeltaKeyEnd is past every change key, as ':' sorts right after the digits.
+const marketCapDeltaKeyEnd = marketCapDeltaKeyPrefix + ":"
+
+// marketCapFoldPeriod is the length, in seconds, of the periods changes are folded by.
+const marketCapFoldPeriod int64 = 3600
+
+// marketCapHistoryLength is the number of past market cap values kept.
+const marketCapHistoryLength = 20
+
+type marketCapPoint struct {
+	Time      int64 `json:"time"`
+	MarketCap int64 `json:"marketCap"`
+}
+
+type marketCap struct {
+	ObjectType string           `json:"docType"`
+	MarketCap  int64            `json:"marketCap"`
+	History    []marketCapPoint `json:"history"`
+}
+
+// marketCapDelta is a change of the market cap by a single transaction.
+type marketCapDelta struct {
+	ObjectType string `json:"docType"`
+	MarbleName string `json:"marbleName"`
+	TxID       string `json:"txID"`
+	Time       int64  `json:"time"`
+	Delta      int64  `json:"delta"`
+}
+
+// record appends a market cap value to the history. Values of the same transaction
+// make up a single point.
+func (mc *marketCap) record(currentTime int64, value int64, sameTx bool) {
+	mc.MarketCap = value
+	if sameTx && len(mc.History) > 0 {
+		mc.History[len(mc.History)-1].MarketCap = value
+		return
+	}
+	mc.History = append(mc.History, marketCapPoint{Time: currentTime, MarketCap: value})
+	if len(mc.History) > marketCapHistoryLength {
+		mc.History = mc.History[len(mc.History)-marketCapHistoryLength:]
+	}
+}
+
+func getMarketCapBase(stub shim.ChaincodeStubInterface) (*marketCap, error) {
+	capAsBytes, err := stub.GetPrivateData("collectionMarbleMarketCap", marketCapBaseKey)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get market cap: %s", err.Error())
+	}
+
+	mc := &marketCap{ObjectType: "marketCap", History: []marketCapPoint{}}
+	if capAsBytes == nil {
+		return mc, nil
+	}
+	err = json.Unmarshal(capAsBytes, mc)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to decode JSON of: %s", string(capAsBytes))
+	}
+	return mc, nil
+}
+
+func putMarketCapBase(stub shim.ChaincodeStubInterface, mc *marketCap) error {
+	capAsBytes, err := json.Marshal(mc)
+	if err != nil {
+		return err
+	}
+	return stub.PutPrivateData("collectionMarbleMarketCap", marketCapBaseKey, capAsBytes)
+}
+
+// getMarketCapDeltas returns the changes with keys below endKey, oldest first.
+func getMarketCapDeltas(stub shim.ChaincodeStubInterface, endKey string) ([]marketCapDelta, []string, error) {
+	resultsIterator, err := stub.GetPrivateDataByRange("collectionMarbleMarketCap", marketCapDeltaKeyPrefix, endKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resultsIterator.Close()
+
+	deltas := []marketCapDelta{}
+	keys := []string{}
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, nil, err
+		}
+		var delta marketCapDelta
+		err = json.Unmarshal(queryResponse.Value, &delta)
+		if err != nil {
+			return nil, nil, fmt.Errorf("Failed to decode JSON of: %s", string(queryResponse.Value))
+		}
+		deltas = append(deltas, delta)
+		keys = append(keys, queryResponse.Key)
+	}
+	return deltas, keys, nil
+}
+
+// addDeltas adds changes onto a market cap, recording its history.
+func (mc *marketCap) addDeltas(deltas []marketCapDelta) {
+	lastTxID := ""
+	for _, delta := range deltas {
+		mc.record(delta.Time, mc.MarketCap+delta.Delta, delta.TxID == lastTxID)
+		lastTxID = delta.TxID
+	}
+}
+
+// getMarketCapRecord adds every recorded change onto the market cap base. It fails on
+// a peer whose organization cannot read the market cap, rather than report zero.
+func getMarketCapRecord(stub shim.ChaincodeStubInterface) (*marketCap, error) {
+	err := checkPrivateDetailsReadable(stub)
+	if err != nil {
+		return nil, err
+	}
+	mc, err := getMarketCapBase(stub)
+	if err != nil {
+		return nil, err
+	}
+	deltas, _, err := getMarketCapDeltas(stub, marketCapDeltaKeyEnd)
+	if err != nil {
+		return nil, err
+	}
+	mc.addDeltas(deltas)
+	return mc, nil
+}
+
+// foldMarketCapDeltas folds the changes made before the previous fold period into the
+// base and deletes them. Changes are only ever made at the current time, so the range
+// it reads is not written by concurrent transactions, and the base is only read and
+// written when there is something to fold, by about one transaction per period.
+func foldMarketCapDeltas(stub shim.ChaincodeStubInterface, currentTime int64) error {
+	cutoff := (currentTime/marketCapFoldPeriod - 1) * marketCapFoldPeriod
+	deltas, keys, err := getMarketCapDeltas(stub, marketCapDeltaKeyPrefix+fmt.Sprintf("%010d", cutoff))
+	if err != nil || len(deltas) == 0 {
+		return err
+	}
+
+	mc, err := getMarketCapBase(stub)
+	if err != nil {
+		return err
+	}
+	mc.addDeltas(deltas)
+	err = putMarketCapBase(stub, mc)
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		err = stub.DelPrivateData("collectionMarbleMarketCap", key)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// adjustMarketCap records a change, which may be negative, of the market cap through
+// a marble's price. Changes that concern more than one marble are recorded under an
+// empty name, so a transaction makes at most one of those.
+func adjustMarketCap(stub shim.ChaincodeStubInterface, marbleName string, delta int64) error {
+	if delta == 0 {
+		return nil
+	}
+	currentTime, err := getTxUnixTime(stub)
+	if err != nil {
+		return err
+	}
+	deltaKey := fmt.Sprintf("%s%010d~%s~%s", marketCapDeltaKeyPrefix, currentTime, stub.GetTxID(), marbleName)
+	deltaAsBytes, err := json.Marshal(&marketCapDelta{
+		ObjectType: "marketCapDelta",
+		MarbleName: marbleName,
+		TxID:       stub.GetTxID(),
+		Time:       currentTime,
+		Delta:      delta,
+	})
+	if err != nil {
+		return err
+	}
+	err = stub.PutPrivateData("collectionMarbleMarketCap", deltaKey, deltaAsBytes)
+	if err != nil {
+		return err
+	}
+	return foldMarketCapDeltas(stub, currentTime)
+}
+
+// maxMarblePrice is the highest private price a marble may have. Prices are int64, so
+// the sum of many of them in the market cap cannot overflow either.
+const maxMarblePrice int64 = 10000000000
+
+// checkPriceRange fails unless price is between 1 and maxMarblePrice.
+func checkPriceRange(price int64) error {
+	if price < 1 || price > maxMarblePrice {
+		return fmt.Errorf("price field must be between 1 and %d", maxMarblePrice)
+	}
+	return nil
+}
+
+// parsePrice reads a price from JSON, failing with a clear message on fractions and
+// values out of range rather than a generic decoding error.
+func parsePrice(n json.Number) (int64, error) {
+	if len(n) == 0 {
+		return 0, fmt.Errorf("price field must be a positive integer")
+	}
+	price, err := strconv.ParseInt(string(n), 10, 64)
+	if err != nil {
+		// an integer too large for int64 is out of range, not fractional
+		if _, floatErr := n.Float64(); floatErr == nil && strings.ContainsAny(string(n), ".eE") {
+			return 0, fmt.Errorf("price field must be a whole number, got %s", n)
+		}
+		return 0, fmt.Errorf("price field must be between 1 and %d, got %s", maxMarblePrice, n)
+	}
+	return price, checkPriceRange(price)
+}
+
+// setMarblePrice applies the governance price policy and external validator and writes
+// a new private price for the marble. The market cap follows the change and the marble's
+// auto-list policy is applied.
+func setMarblePrice(stub shim.ChaincodeStubInterface, m *marble, newPrice int64) error {
+	gov, err := getGovernance(stub)
+	if err != nil {
+		return err
+	}
+	err = gov.PricePolicy.checkPrice(newPrice)
+	if err != nil {
+		return err
+	}
+	err = checkExternalValidatorForMarble(stub, gov, m)
+	if err != nil {
+		return err
+	}
+
+	details, err := getMarblePrivateDetails(stub, m.Name)
+	if err != nil {
+		return err
+	}
+	delta := newPrice - details.Price
+	details.Price = newPrice
+	err = putMarblePrivateDetails(stub, details)
+	if err != nil {
+		return err
+	}
+	err = adjustMarketCap(stub, m.Name, delta)
+	if err != nil {
+		return err
+	}
+	return autoListOnPriceUpdate(stub, m, newPrice)
+}
+
+// ===========================================================================
+// updateMarblePrice - owner change of a marble's private price
+// ===========================================================================
+func (t *SimpleChaincode) updateMarblePrice(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	fmt.Println("- start update marble price")
+
+	type marblePriceTransientInput struct {
+		Name  string      `json:"name"`
+		Price json.Number `json:"price"`
+	}
+
+	if len(args) != 0 {
+		return shim.Error("Incorrect number of arguments. Private marble data must be passed in transient map.")
+	}
+
+	var priceInput marblePriceTransientInput
+	err := getTransientInput(stub, "marble_price", &priceInput)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if len(priceInput.Name) == 0 {
+		return shim.Error("name field must be a non-empty string")
+	}
+	price, err := parsePrice(priceInput.Price)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	m, err := getMarble(stub, priceInput.Name)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = requireOwner(stub, m)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = setMarblePrice(stub, m, price)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = emitMarbleLifecycleEvent(stub, "PriceUpdated", m.Name)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	fmt.Println("- end update marble price")
+	return shim.Success(nil)
+}
+
+// ===========================================================================
+// getMarketCap - the maintained sum of all marble prices
+// ===========================================================================
+func (t *SimpleChaincode) getMarketCap(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	if len(args) != 0 {
+		return shim.Error("Incorrect number of arguments. Expecting 0")
+	}
+
+	mc, err := getMarketCapRecord(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	return shim.Success([]byte(fmt.Sprintf("{\"marketCap\":%d}", mc.MarketCap)))
+}
+
+// ===========================================================================
+// getMarketCapHistory - the last market cap values with their times
+// ===========================================================================
+func (t *SimpleChaincode) getMarketCapHistory(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	if len(args) != 0 {
+		return shim.Error("Incorrect number of arguments. Expecting 0")
+	}
+
+	mc, err := getMarketCapRecord(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	historyAsBytes, err := json.Marshal(mc.History)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	return shim.Success(historyAsBytes)
+}
+
+// ===========================================================================
+// computeMarketCapFromScratch - admin rebuild of the market cap from every
+// marble's private details. The recorded changes are replaced by the result.
+// ===========================================================================
+func (t *SimpleChaincode) computeMarketCapFromScratch(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	if len(args) != 0 {
+		return shim.Error("Incorrect number of arguments. Expecting 0")
+	}
+
+	err := requireAdmin(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	resultsIterator, err := stub.GetPrivateDataByRange("collectionMarblePrivateDetails", "", "")
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	defer resultsIterator.Close()
+
+	var total int64
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		var details marblePrivateDetails
+		if json.Unmarshal(queryResponse.Value, &details) != nil || details.ObjectType != "marblePrivateDetails" {
+			continue
+		}
+		total += details.Price
+	}
+
+	// fold the recorded changes into the base, keeping their history
+	err = checkPrivateDetailsReadable(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	mc, err := getMarketCapBase(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	deltas, keys, err := getMarketCapDeltas(stub, marketCapDeltaKeyEnd)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	mc.addDeltas(deltas)
+	currentTime, err := getTxUnixTime(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	mc.record(currentTime, total, false)
+	err = putMarketCapBase(stub, mc)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	for _, key := range keys {
+		err = stub.DelPrivateData("collectionMarbleMarketCap", key)
+		if err != nil {
+			return shim.Error("Failed to delete state:" + err.Error())
+		}
+	}
+	return shim.Success([]byte(fmt.Sprintf("{\"marketCap\":%d}", total)))
+}
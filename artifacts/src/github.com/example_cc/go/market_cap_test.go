@@ -0,0 +1,138 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"testing"
+)
+
+func (s *testStub) marketCap() int64 {
+	var result struct {
+		MarketCap int64 `json:"marketCap"`
+	}
+	err := json.Unmarshal(s.mustInvoke("getMarketCap", nil), &result)
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	return result.MarketCap
+}
+
+func TestMarketCapMatchesComputationFromScratch(t *testing.T) {
+	s := newTestStub(t)
+	random := rand.New(rand.NewSource(692))
+	prices := map[string]int64{}
+	for i := 0; i < 10; i++ {
+		name := fmt.Sprintf("marble%d", i)
+		prices[name] = 1 + random.Int63n(1000)
+		s.createMarble(name, "blue", 35, "Org1MSP", prices[name])
+	}
+	for i := 0; i < 100; i++ {
+		name := fmt.Sprintf("marble%d", random.Intn(10))
+		prices[name] = 1 + random.Int63n(1000)
+		s.mustInvoke("updateMarblePrice", map[string]interface{}{"marble_price": map[string]interface{}{"name": name, "price": prices[name]}})
+	}
+	s.mustInvoke("delete", map[string]interface{}{"marble_delete": map[string]interface{}{"name": "marble0"}})
+	delete(prices, "marble0")
+
+	var want int64
+	for _, price := range prices {
+		want += price
+	}
+	if got := s.marketCap(); got != want {
+		t.Fatalf("expected a market cap of %d, got %d", want, got)
+	}
+	if payload := string(s.mustInvoke("computeMarketCapFromScratch", nil)); payload != fmt.Sprintf(`{"marketCap":%d}`, want) {
+		t.Fatalf("expected the computation from scratch to give %d, got %s", want, payload)
+	}
+	if got := s.marketCap(); got != want {
+		t.Fatalf("expected the market cap to stay %d after the rebuild, got %d", want, got)
+	}
+
+	var history []marketCapPoint
+	err := json.Unmarshal(s.mustInvoke("getMarketCapHistory", nil), &history)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != marketCapHistoryLength || history[len(history)-1].MarketCap != want {
+		t.Fatalf("expected %d history points ending at %d, got %+v", marketCapHistoryLength, want, history)
+	}
+	for i := 1; i < len(history); i++ {
+		if history[i].Time <= history[i-1].Time {
+			t.Fatalf("expected one history point per transaction, got %+v", history)
+		}
+	}
+}
+
+func TestMarketCapChangesAreKeyedPerTransaction(t *testing.T) {
+	s := newTestStub(t)
+	s.createMarble("marble1", "blue", 35, "Org1MSP", 10)
+	s.createMarble("marble2", "red", 35, "Org1MSP", 20)
+
+	deltas := 0
+	for key := range s.PvtState["collectionMarbleMarketCap"] {
+		if key != marketCapBaseKey {
+			deltas++
+		}
+	}
+	if deltas != 2 {
+		t.Fatalf("expected a market cap change per transaction, got %d", deltas)
+	}
+	if s.marketCap() != 30 {
+		t.Fatalf("expected a market cap of 30, got %d", s.marketCap())
+	}
+}
+
+func TestMarketCapIsNotReportedToNonMembers(t *testing.T) {
+	s := newTestStub(t)
+	s.createMarble("marble1", "blue", 35, "Org1MSP", 10)
+	s.NonMember["collectionMarbleMarketCap"] = true
+	s.mustFail("cannot read collectionMarblePrivateDetails", "getMarketCap", nil)
+}
+
+func TestOldMarketCapChangesAreFoldedIntoTheBase(t *testing.T) {
+	s := newTestStub(t)
+	s.createMarble("marble1", "blue", 35, "Org1MSP", 10)
+	s.createMarble("marble2", "red", 35, "Org1MSP", 20)
+	s.mustInvoke("updateMarblePrice", map[string]interface{}{"marble_price": map[string]interface{}{"name": "marble1", "price": 15}})
+
+	deltas := func() int {
+		n := 0
+		for key := range s.PvtState["collectionMarbleMarketCap"] {
+			if key != marketCapBaseKey {
+				n++
+			}
+		}
+		return n
+	}
+	// changes of the current and the previous period stay as they are
+	s.Now += marketCapFoldPeriod
+	s.mustInvoke("updateMarblePrice", map[string]interface{}{"marble_price": map[string]interface{}{"name": "marble2", "price": 25}})
+	if deltas() != 4 {
+		t.Fatalf("expected the recent market cap changes to stay, got %d", deltas())
+	}
+
+	s.Now += marketCapFoldPeriod
+	s.mustInvoke("updateMarblePrice", map[string]interface{}{"marble_price": map[string]interface{}{"name": "marble1", "price": 5}})
+	if deltas() != 2 {
+		t.Fatalf("expected the first 3 market cap changes to be folded into the base, got %d changes", deltas())
+	}
+	base, err := getMarketCapBase(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if base.MarketCap != 35 || len(base.History) != 3 {
+		t.Fatalf("expected a base of 35 with the history of the folded changes, got %+v", base)
+	}
+	if s.marketCap() != 30 {
+		t.Fatalf("expected a market cap of 30, got %d", s.marketCap())
+	}
+	var history []marketCapPoint
+	err = json.Unmarshal(s.mustInvoke("getMarketCapHistory", nil), &history)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 5 || history[3].MarketCap != 40 || history[4].MarketCap != 30 {
+		t.Fatalf("expected the history to run on past the base, got %+v", history)
+	}
+}
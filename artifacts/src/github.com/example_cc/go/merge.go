@@ -0,0 +1,167 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// checkConsumable fails unless the caller may consume the marble in a merge or a split:
+// it must be theirs, unlocked (so not in escrow), not archived and not reserved, and
+// its shareholders, if any, must have approved its deletion.
+func checkConsumable(stub shim.ChaincodeStubInterface, m *marble) error {
+	err := requireOwner(stub, m)
+	if err != nil {
+		return err
+	}
+	err = checkNotLocked(m)
+	if err != nil {
+		return err
+	}
+	err = checkNotArchived(m)
+	if err != nil {
+		return err
+	}
+	err = checkNotReserved(stub, m)
+	if err != nil {
+		return err
+	}
+	return requireShareholderApproval(stub, m.Name, shareActionDelete, "")
+}
+
+// ===========================================================================
+// mergeMarbles - owner combination of two marbles of the same color into a
+// new one. The new marble's size, weight and price are the sums of theirs
+// and its condition the worse of the two. Both source marbles are deleted,
+// so the market cap does not change. Returns the name of the new marble.
+// ===========================================================================
+func (t *SimpleChaincode) mergeMarbles(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	fmt.Println("- start merge marbles")
+
+	type marbleMergeTransientInput struct {
+		Marble1 string `json:"marble1"`
+		Marble2 string `json:"marble2"`
+		NewName string `json:"newName"`
+	}
+
+	if len(args) != 0 {
+		return shim.Error("Incorrect number of arguments. Private marble data must be passed in transient map.")
+	}
+
+	var mergeInput marbleMergeTransientInput
+	err := getTransientInput(stub, "marble_merge", &mergeInput)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if len(mergeInput.Marble1) == 0 || len(mergeInput.Marble2) == 0 {
+		return shim.Error("marble1 and marble2 fields must be non-empty strings")
+	}
+	if mergeInput.Marble1 == mergeInput.Marble2 {
+		return shim.Error("cannot merge marble " + mergeInput.Marble1 + " with itself")
+	}
+
+	m1, err := getMarble(stub, mergeInput.Marble1)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	m2, err := getMarble(stub, mergeInput.Marble2)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if m1.Owner != m2.Owner {
+		return shim.Error("Marbles " + m1.Name + " and " + m2.Name + " have different owners")
+	}
+	if m1.Color != m2.Color {
+		return shim.Error("Marbles " + m1.Name + " and " + m2.Name + " have different colors")
+	}
+	for _, m := range []*marble{m1, m2} {
+		err = checkConsumable(stub, m)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+	}
+
+	details1, err := getMarblePrivateDetails(stub, m1.Name)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	details2, err := getMarblePrivateDetails(stub, m2.Name)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	condition := m1.Condition
+	if conditionGrade(m2.Condition) > conditionGrade(m1.Condition) {
+		condition = m2.Condition
+	}
+	series := ""
+	if m1.Series == m2.Series {
+		series = m1.Series
+	}
+	marbleInput := marbleTransientInput{
+		Name:      mergeInput.NewName,
+		Color:     m1.Color,
+		Size:      m1.Size + m2.Size,
+		Owner:     m1.Owner,
+		Price:     details1.Price + details2.Price,
+		Weight:    m1.Weight + m2.Weight,
+		Condition: conditionGrades[conditionGrade(condition)],
+		Series:    series,
+	}
+
+	gov, err := getGovernance(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = checkMarbleInput(stub, gov, &marbleInput)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = checkCallerNotBlacklisted(stub, gov)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	creatorMSPID, err := getCallerMSPID(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = checkAuthorizedCreator(stub, creatorMSPID)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = checkProvenanceRecorded(stub, gov, marbleInput.Name)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	currentTime, err := getTxUnixTime(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	// prepareMarble fails if newName is taken, including by one of the source marbles
+	merged, mergedJSONasBytes, err := prepareMarble(stub, gov, &marbleInput, currentTime)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	for _, m := range []*marble{m1, m2} {
+		err = deleteMarbleState(stub, m)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+	}
+	err = writeNewMarble(stub, merged, mergedJSONasBytes, marbleInput.Price, creatorMSPID)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	err = emitMarbleLifecycleEvent(stub, "MarbleCreated", merged.Name)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	fmt.Println("- end merge marbles")
+	return shim.Success([]byte(merged.Name))
+}
@@ -0,0 +1,412 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes/timestamp"
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	"github.com/hyperledger/fabric/protos/ledger/queryresult"
+	"github.com/hyperledger/fabric/protos/msp"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// testStartTime is the transaction timestamp of the first transaction of a testStub.
+const testStartTime int64 = 1500000000
+
+// testStub is a shim.MockStub completed with what the chaincode needs and the Fabric 1.4
+// mock leaves unimplemented: the creator identity, the transient map, private data
+// deletes, range, composite key and rich queries, private data hashes and events.
+// Transactions are driven through invoke and init rather than MockInvoke, so that the
+// chaincode is handed the testStub and not the embedded MockStub.
+type testStub struct {
+	*shim.MockStub
+	t         *testing.T
+	cc        *SimpleChaincode
+	args      [][]byte
+	creator   []byte
+	transient map[string][]byte
+	txCount   int
+
+	// Now is the timestamp, in seconds, of the next transaction and so the time the
+	// chaincode sees. Each transaction advances it by one.
+	Now int64
+	// NonMember lists the collections the peer's organization is not a member of. Their
+	// values cannot be read, but their hashes can.
+	NonMember map[string]bool
+	// ReadErrors makes private data reads of a collection fail with the given error.
+	ReadErrors map[string]error
+	// Events holds the event set by each transaction, by transaction ID.
+	Events map[string]*pb.ChaincodeEvent
+}
+
+// newTestStub returns a testStub on which Init has run with Org1MSP as the caller, so
+// Org1MSP is the governance admin.
+func newTestStub(t *testing.T) *testStub {
+	s := newUninitializedTestStub(t)
+	if response := s.init(nil); response.Status != shim.OK {
+		t.Fatalf("Init failed: %s", response.Message)
+	}
+	return s
+}
+
+// newUninitializedTestStub returns a testStub with Org1MSP as the caller, on which Init
+// has not run yet.
+func newUninitializedTestStub(t *testing.T) *testStub {
+	cc := new(SimpleChaincode)
+	s := &testStub{
+		MockStub:   shim.NewMockStub("marbles", cc),
+		t:          t,
+		cc:         cc,
+		Now:        testStartTime,
+		NonMember:  map[string]bool{},
+		ReadErrors: map[string]error{},
+		Events:     map[string]*pb.ChaincodeEvent{},
+	}
+	s.setCaller("Org1MSP", "admin")
+	return s
+}
+
+// setCaller makes the identity with the given MSP ID and certificate common name the
+// creator of the following transactions.
+func (s *testStub) setCaller(mspID, commonName string) {
+	creator, err := proto.Marshal(&msp.SerializedIdentity{Mspid: mspID, IdBytes: testCertificatePEM(s.t, commonName)})
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	s.creator = creator
+}
+
+// testCertificatePEM returns a self-signed certificate for commonName.
+func testCertificatePEM(t *testing.T, commonName string) []byte {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Unix(testStartTime, 0),
+		NotAfter:     time.Unix(testStartTime, 0).AddDate(10, 0, 0),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
+
+// startTx begins a transaction with the given arguments and transient map, whose
+// values are marshalled to JSON.
+func (s *testStub) startTx(transient map[string]interface{}, args []string) {
+	s.args = make([][]byte, len(args))
+	for i, arg := range args {
+		s.args[i] = []byte(arg)
+	}
+	s.transient = map[string][]byte{}
+	for key, value := range transient {
+		valueAsBytes, err := json.Marshal(value)
+		if err != nil {
+			s.t.Fatal(err)
+		}
+		s.transient[key] = valueAsBytes
+	}
+	s.txCount++
+	s.MockTransactionStart(fmt.Sprintf("tx%d", s.txCount))
+	s.TxTimestamp = &timestamp.Timestamp{Seconds: s.Now}
+	s.Now++
+}
+
+// init runs Init with args, and with transient as the transient map.
+func (s *testStub) init(transient map[string]interface{}, args ...string) pb.Response {
+	s.startTx(transient, args)
+	defer s.MockTransactionEnd(s.TxID)
+	return s.cc.Init(s)
+}
+
+// invoke runs function with args, and with transient as the transient map.
+func (s *testStub) invoke(function string, transient map[string]interface{}, args ...string) pb.Response {
+	s.startTx(transient, append([]string{function}, args...))
+	defer s.MockTransactionEnd(s.TxID)
+	return s.cc.Invoke(s)
+}
+
+// mustInvoke is invoke for calls that are expected to succeed.
+func (s *testStub) mustInvoke(function string, transient map[string]interface{}, args ...string) []byte {
+	response := s.invoke(function, transient, args...)
+	if response.Status != shim.OK {
+		s.t.Fatalf("%s failed: %s", function, response.Message)
+	}
+	return response.Payload
+}
+
+// mustFail is invoke for calls that are expected to fail with a message containing want.
+func (s *testStub) mustFail(want, function string, transient map[string]interface{}, args ...string) {
+	response := s.invoke(function, transient, args...)
+	if response.Status == shim.OK {
+		s.t.Fatalf("%s succeeded, expected it to fail with %q", function, want)
+	}
+	if !strings.Contains(response.Message, want) {
+		s.t.Fatalf("%s failed with %q, expected %q", function, response.Message, want)
+	}
+}
+
+// createMarble creates a marble through initMarble with the caller as the owner.
+func (s *testStub) createMarble(name, color string, size int, owner string, price int64) {
+	s.mustInvoke("initMarble", map[string]interface{}{"marble": map[string]interface{}{
+		"name": name, "color": color, "size": size, "owner": owner, "price": price, "weight": 10,
+	}})
+}
+
+// readTestMarble reads a marble straight from the mock state.
+func (s *testStub) readTestMarble(name string) *marble {
+	marbleAsBytes := s.PvtState["collectionMarbles"][name]
+	if marbleAsBytes == nil {
+		s.t.Fatalf("marble %s does not exist", name)
+	}
+	m := &marble{}
+	err := json.Unmarshal(marbleAsBytes, m)
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	return m
+}
+
+func (s *testStub) GetArgs() [][]byte {
+	return s.args
+}
+
+func (s *testStub) GetStringArgs() []string {
+	args := make([]string, len(s.args))
+	for i, arg := range s.args {
+		args[i] = string(arg)
+	}
+	return args
+}
+
+func (s *testStub) GetFunctionAndParameters() (string, []string) {
+	args := s.GetStringArgs()
+	if len(args) == 0 {
+		return "", []string{}
+	}
+	return args[0], args[1:]
+}
+
+func (s *testStub) GetCreator() ([]byte, error) {
+	return s.creator, nil
+}
+
+func (s *testStub) GetTransient() (map[string][]byte, error) {
+	return s.transient, nil
+}
+
+func (s *testStub) SetEvent(name string, payload []byte) error {
+	s.Events[s.TxID] = &pb.ChaincodeEvent{EventName: name, Payload: payload}
+	return nil
+}
+
+func (s *testStub) GetPrivateData(collection, key string) ([]byte, error) {
+	if err := s.ReadErrors[collection]; err != nil {
+		return nil, err
+	}
+	if s.NonMember[collection] {
+		return nil, nil
+	}
+	return s.MockStub.GetPrivateData(collection, key)
+}
+
+func (s *testStub) GetPrivateDataHash(collection, key string) ([]byte, error) {
+	value := s.PvtState[collection][key]
+	if value == nil {
+		return nil, nil
+	}
+	hash := sha256.Sum256(value)
+	return hash[:], nil
+}
+
+func (s *testStub) DelPrivateData(collection, key string) error {
+	delete(s.PvtState[collection], key)
+	return nil
+}
+
+// sortedPrivateKeys returns the keys of a collection that match, in key order, or none
+// if the peer cannot read the collection.
+func (s *testStub) sortedPrivateKeys(collection string, match func(key string) bool) []string {
+	keys := []string{}
+	if s.NonMember[collection] {
+		return keys
+	}
+	for key := range s.PvtState[collection] {
+		if match(key) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (s *testStub) GetPrivateDataByRange(collection, startKey, endKey string) (shim.StateQueryIteratorInterface, error) {
+	// like the peer, a range of simple keys never includes composite keys
+	keys := s.sortedPrivateKeys(collection, func(key string) bool {
+		return !strings.HasPrefix(key, "\x00") && key >= startKey && (endKey == "" || key < endKey)
+	})
+	return s.newIterator(collection, keys), nil
+}
+
+func (s *testStub) GetPrivateDataByPartialCompositeKey(collection, objectType string, attributes []string) (shim.StateQueryIteratorInterface, error) {
+	prefix, err := s.CreateCompositeKey(objectType, attributes)
+	if err != nil {
+		return nil, err
+	}
+	keys := s.sortedPrivateKeys(collection, func(key string) bool { return strings.HasPrefix(key, prefix) })
+	return s.newIterator(collection, keys), nil
+}
+
+// GetPrivateDataQueryResult answers the subset of CouchDB selectors the chaincode uses:
+// field equality, $eq, $ne, $gt, $gte, $lt, $lte, $exists, $and and $or.
+func (s *testStub) GetPrivateDataQueryResult(collection, query string) (shim.StateQueryIteratorInterface, error) {
+	var parsed struct {
+		Selector map[string]interface{} `json:"selector"`
+	}
+	err := json.Unmarshal([]byte(query), &parsed)
+	if err != nil {
+		return nil, err
+	}
+	var matchErr error
+	keys := s.sortedPrivateKeys(collection, func(key string) bool {
+		if strings.HasPrefix(key, "\x00") {
+			return false
+		}
+		var doc map[string]interface{}
+		if json.Unmarshal(s.PvtState[collection][key], &doc) != nil {
+			return false
+		}
+		matched, err := matchSelector(doc, parsed.Selector)
+		if err != nil {
+			matchErr = err
+		}
+		return matched
+	})
+	if matchErr != nil {
+		return nil, matchErr
+	}
+	return s.newIterator(collection, keys), nil
+}
+
+func matchSelector(doc map[string]interface{}, selector map[string]interface{}) (bool, error) {
+	for field, condition := range selector {
+		switch field {
+		case "$and", "$or":
+			clauses, ok := condition.([]interface{})
+			if !ok {
+				return false, fmt.Errorf("%s expects an array", field)
+			}
+			matchedAny := false
+			for _, clause := range clauses {
+				clauseSelector, ok := clause.(map[string]interface{})
+				if !ok {
+					return false, fmt.Errorf("%s expects objects", field)
+				}
+				matched, err := matchSelector(doc, clauseSelector)
+				if err != nil {
+					return false, err
+				}
+				if field == "$and" && !matched {
+					return false, nil
+				}
+				matchedAny = matchedAny || matched
+			}
+			if field == "$or" && !matchedAny {
+				return false, nil
+			}
+		default:
+			value, present := doc[field]
+			operators, isOperators := condition.(map[string]interface{})
+			if !isOperators {
+				operators = map[string]interface{}{"$eq": condition}
+			}
+			for operator, operand := range operators {
+				matched, err := matchOperator(operator, value, present, operand)
+				if err != nil || !matched {
+					return false, err
+				}
+			}
+		}
+	}
+	return true, nil
+}
+
+func matchOperator(operator string, value interface{}, present bool, operand interface{}) (bool, error) {
+	if operator == "$exists" {
+		return present == operand, nil
+	}
+	if !present {
+		return false, nil
+	}
+	if operator == "$eq" {
+		return value == operand, nil
+	}
+	if operator == "$ne" {
+		return value != operand, nil
+	}
+	number, ok := value.(float64)
+	bound, boundOk := operand.(float64)
+	if !ok || !boundOk {
+		return false, nil
+	}
+	switch operator {
+	case "$gt":
+		return number > bound, nil
+	case "$gte":
+		return number >= bound, nil
+	case "$lt":
+		return number < bound, nil
+	case "$lte":
+		return number <= bound, nil
+	}
+	return false, errors.New("unsupported selector operator " + operator)
+}
+
+func (s *testStub) newIterator(collection string, keys []string) *testIterator {
+	kvs := make([]*queryresult.KV, len(keys))
+	for i, key := range keys {
+		kvs[i] = &queryresult.KV{Key: key, Value: s.PvtState[collection][key]}
+	}
+	return &testIterator{kvs: kvs}
+}
+
+// testIterator iterates over a fixed list of results.
+type testIterator struct {
+	kvs []*queryresult.KV
+}
+
+func (it *testIterator) HasNext() bool {
+	return len(it.kvs) != 0
+}
+
+func (it *testIterator) Next() (*queryresult.KV, error) {
+	if len(it.kvs) == 0 {
+		return nil, errors.New("no more results")
+	}
+	kv := it.kvs[0]
+	it.kvs = it.kvs[1:]
+	return kv, nil
+}
+
+func (it *testIterator) Close() error {
+	return nil
+}
@@ -0,0 +1,249 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// nameReservationIndexName keys name reservations in collectionMarbles. A composite
+// key cannot collide with a marble name.
+const nameReservationIndexName = "nameReservation~name"
+
+type NameReservation struct {
+	ObjectType     string `json:"docType"`
+	Name           string `json:"name"`
+	ReservedBy     string `json:"reservedBy"`
+	ReservedAtTime int64  `json:"reservedAtTime"`
+	ExpiryTime     int64  `json:"expiryTime"`
+}
+
+// getNameReservation returns the reservation held on a marble name, or nil if there is
+// none or it has expired.
+func getNameReservation(stub shim.ChaincodeStubInterface, name string) (*NameReservation, error) {
+	reservationKey, err := stub.CreateCompositeKey(nameReservationIndexName, []string{name})
+	if err != nil {
+		return nil, err
+	}
+	reservationAsBytes, err := stub.GetPrivateData("collectionMarbles", reservationKey)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get name reservation: %s", err.Error())
+	} else if reservationAsBytes == nil {
+		return nil, nil
+	}
+
+	reservation := &NameReservation{}
+	err = json.Unmarshal(reservationAsBytes, reservation)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to decode JSON of: %s", string(reservationAsBytes))
+	}
+
+	currentTime, err := getTxUnixTime(stub)
+	if err != nil {
+		return nil, err
+	}
+	if currentTime >= reservation.ExpiryTime {
+		return nil, nil
+	}
+	return reservation, nil
+}
+
+// checkNameReservation fails if the name is held by an active reservation of another
+// organization. A reservation held by the caller is consumed.
+func checkNameReservation(stub shim.ChaincodeStubInterface, name string) error {
+	reservation, err := getNameReservation(stub, name)
+	if err != nil || reservation == nil {
+		return err
+	}
+
+	callerMSPID, err := getCallerMSPID(stub)
+	if err != nil {
+		return err
+	}
+	if reservation.ReservedBy != callerMSPID {
+		return fmt.Errorf("marble name %s is reserved by %s", name, reservation.ReservedBy)
+	}
+	return delNameReservation(stub, name)
+}
+
+func delNameReservation(stub shim.ChaincodeStubInterface, name string) error {
+	reservationKey, err := stub.CreateCompositeKey(nameReservationIndexName, []string{name})
+	if err != nil {
+		return err
+	}
+	return stub.DelPrivateData("collectionMarbles", reservationKey)
+}
+
+// ==============================================================================
+// reserveMarbleName - hold a marble name for the caller's organization. The
+// reservation is paid for in loyalty points and lapses after the governance
+// reservation period.
+// ==============================================================================
+func (t *SimpleChaincode) reserveMarbleName(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	fmt.Println("- start reserve marble name")
+
+	type nameReservationTransientInput struct {
+		Name string `json:"name"`
+	}
+
+	if len(args) != 0 {
+		return shim.Error("Incorrect number of arguments. Private marble data must be passed in transient map.")
+	}
+
+	var reservationInput nameReservationTransientInput
+	err := getTransientInput(stub, "marble_name_reservation", &reservationInput)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	if len(reservationInput.Name) == 0 {
+		return shim.Error("name field must be a non-empty string")
+	}
+	err = validateMarbleName(reservationInput.Name)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	marbleAsBytes, err := stub.GetPrivateData("collectionMarbles", reservationInput.Name)
+	if err != nil {
+		return shim.Error("Failed to get marble: " + err.Error())
+	} else if marbleAsBytes != nil {
+		return shim.Error("This marble already exists: " + reservationInput.Name)
+	}
+
+	existing, err := getNameReservation(stub, reservationInput.Name)
+	if err != nil {
+		return shim.Error(err.Error())
+	} else if existing != nil {
+		return shim.Error("marble name " + reservationInput.Name + " is already reserved by " + existing.ReservedBy)
+	}
+
+	gov, err := getGovernance(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	callerMSPID, err := getCallerMSPID(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	currentTime, err := getTxUnixTime(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	err = debitLoyaltyPoints(stub, callerMSPID, gov.NameReservationCost)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	reservation := &NameReservation{
+		ObjectType:     "nameReservation",
+		Name:           reservationInput.Name,
+		ReservedBy:     callerMSPID,
+		ReservedAtTime: currentTime,
+		ExpiryTime:     currentTime + gov.NameReservationPeriod,
+	}
+	reservationKey, err := stub.CreateCompositeKey(nameReservationIndexName, []string{reservation.Name})
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	reservationAsBytes, err := json.Marshal(reservation)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = stub.PutPrivateData("collectionMarbles", reservationKey, reservationAsBytes)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	fmt.Println("- end reserve marble name")
+	return shim.Success(reservationAsBytes)
+}
+
+// ===========================================================================
+// listMyReservations - list the active name reservations of the caller's
+// organization
+// ===========================================================================
+func (t *SimpleChaincode) listMyReservations(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	if len(args) != 0 {
+		return shim.Error("Incorrect number of arguments. Expecting 0")
+	}
+
+	callerMSPID, err := getCallerMSPID(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	currentTime, err := getTxUnixTime(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	resultsIterator, err := stub.GetPrivateDataByPartialCompositeKey("collectionMarbles", nameReservationIndexName, []string{})
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	defer resultsIterator.Close()
+
+	reservations := []NameReservation{}
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+
+		var reservation NameReservation
+		err = json.Unmarshal(queryResponse.Value, &reservation)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		if reservation.ReservedBy == callerMSPID && currentTime < reservation.ExpiryTime {
+			reservations = append(reservations, reservation)
+		}
+	}
+
+	reservationsAsBytes, err := json.Marshal(reservations)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	return shim.Success(reservationsAsBytes)
+}
+
+// ===========================================================================
+// cancelNameReservation - release a reserved name. Callable by the reserving
+// organization or the admin organization.
+// ===========================================================================
+func (t *SimpleChaincode) cancelNameReservation(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+
+	//   0
+	// "marble1"
+	if len(args) != 1 {
+		return shim.Error("Incorrect number of arguments. Expecting the reserved marble name")
+	}
+	name := args[0]
+
+	reservation, err := getNameReservation(stub, name)
+	if err != nil {
+		return shim.Error(err.Error())
+	} else if reservation == nil {
+		return shim.Error("No active reservation for marble name: " + name)
+	}
+
+	callerMSPID, err := getCallerMSPID(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if callerMSPID != reservation.ReservedBy {
+		err = requireAdmin(stub)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+	}
+
+	err = delNameReservation(stub, name)
+	if err != nil {
+		return shim.Error("Failed to delete state:" + err.Error())
+	}
+	return shim.Success(nil)
+}
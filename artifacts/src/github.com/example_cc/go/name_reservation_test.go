@@ -0,0 +1,38 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestReservationsAndBalancesStayOutOfMarbleRanges(t *testing.T) {
+	s := newTestStub(t)
+	s.mustInvoke("grantLoyaltyPoints", nil, "Org1MSP", "100")
+	s.mustInvoke("reserveMarbleName", map[string]interface{}{"marble_name_reservation": map[string]interface{}{"name": "marble2"}})
+	// names that the old simple keys of balances and reservations started with
+	s.createMarble("loyalty_Org1MSP", "blue", 35, "Org1MSP", 99)
+	s.createMarble("nameres_marble2", "red", 35, "Org1MSP", 99)
+
+	var records []queryRecord
+	err := json.Unmarshal(s.mustInvoke("getMarblesByRange", nil, "", ""), &records)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 || records[0].Key != "loyalty_Org1MSP" || records[1].Key != "nameres_marble2" {
+		t.Fatalf("expected only the two marbles in the range, got %+v", records)
+	}
+
+	var reservations []NameReservation
+	err = json.Unmarshal(s.mustInvoke("listMyReservations", nil), &reservations)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reservations) != 1 || reservations[0].Name != "marble2" {
+		t.Fatalf("expected the reservation of marble2, got %+v", reservations)
+	}
+
+	s.setCaller("Org2MSP", "user2")
+	s.mustFail("marble name marble2 is reserved by Org1MSP", "initMarble", map[string]interface{}{"marble": map[string]interface{}{
+		"name": "marble2", "color": "green", "size": 35, "owner": "Org2MSP", "price": 99, "weight": 10,
+	}})
+}
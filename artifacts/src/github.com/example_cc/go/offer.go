@@ -0,0 +1,295 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// offerIndexName keys the offers on each marble by buyer organization.
+const offerIndexName = "offer~name~buyer"
+
+// States of a purchase offer.
+const (
+	offerStatusPending  = "pending"
+	offerStatusAccepted = "accepted"
+	offerStatusRejected = "rejected"
+)
+
+// offerDetails is a buyer organization's private offer to buy a marble at OfferPrice.
+// A pending offer lapses after ExpiresAtTime. It is kept in
+// collectionMarblePrivateDetails next to the price it would replace.
+type offerDetails struct {
+	ObjectType    string `json:"docType"`
+	MarbleName    string `json:"marbleName"`
+	BuyerMSP      string `json:"buyerMSP"`
+	OfferPrice    int    `json:"offerPrice"`
+	Status        string `json:"status"`
+	ExpiresAtTime int64  `json:"expiresAtTime"`
+}
+
+// isActive reports whether the offer can still be accepted at currentTime.
+func (o *offerDetails) isActive(currentTime int64) bool {
+	return o.Status == offerStatusPending && currentTime <= o.ExpiresAtTime
+}
+
+func getOffer(stub shim.ChaincodeStubInterface, marbleName, buyerMSP string) (*offerDetails, error) {
+	offerKey, err := stub.CreateCompositeKey(offerIndexName, []string{marbleName, buyerMSP})
+	if err != nil {
+		return nil, err
+	}
+	offerAsBytes, err := stub.GetPrivateData("collectionMarblePrivateDetails", offerKey)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get offer: %s", err.Error())
+	} else if offerAsBytes == nil {
+		return nil, fmt.Errorf("Offer of %s on %s does not exist", buyerMSP, marbleName)
+	}
+
+	offer := &offerDetails{}
+	err = json.Unmarshal(offerAsBytes, offer)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to decode JSON of: %s", string(offerAsBytes))
+	}
+	return offer, nil
+}
+
+func putOffer(stub shim.ChaincodeStubInterface, offer *offerDetails) error {
+	offerKey, err := stub.CreateCompositeKey(offerIndexName, []string{offer.MarbleName, offer.BuyerMSP})
+	if err != nil {
+		return err
+	}
+	offerAsBytes, err := json.Marshal(offer)
+	if err != nil {
+		return err
+	}
+	return stub.PutPrivateData("collectionMarblePrivateDetails", offerKey, offerAsBytes)
+}
+
+// getActiveOffer returns the offer on a marble that can still be accepted, or nil if
+// there is none.
+func getActiveOffer(stub shim.ChaincodeStubInterface, marbleName string, currentTime int64) (*offerDetails, error) {
+	resultsIterator, err := stub.GetPrivateDataByPartialCompositeKey("collectionMarblePrivateDetails", offerIndexName, []string{marbleName})
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+		offer := &offerDetails{}
+		err = json.Unmarshal(queryResponse.Value, offer)
+		if err != nil {
+			return nil, err
+		}
+		if offer.isActive(currentTime) {
+			return offer, nil
+		}
+	}
+	return nil, nil
+}
+
+// getActionableOffer returns the buyer's offer on a marble for the marble's owner to act
+// on, failing unless it can still be accepted.
+func getActionableOffer(stub shim.ChaincodeStubInterface, marbleName, buyerMSP string) (*offerDetails, *marble, error) {
+	m, err := getMarble(stub, marbleName)
+	if err != nil {
+		return nil, nil, err
+	}
+	err = requireOwner(stub, m)
+	if err != nil {
+		return nil, nil, err
+	}
+	offer, err := getOffer(stub, marbleName, buyerMSP)
+	if err != nil {
+		return nil, nil, err
+	}
+	currentTime, err := getTxUnixTime(stub)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !offer.isActive(currentTime) {
+		return nil, nil, fmt.Errorf("Offer of %s on %s is %s or expired", buyerMSP, marbleName, offer.Status)
+	}
+	return offer, m, nil
+}
+
+// ===========================================================================
+// makeOffer - a buyer organization's private offer on a marble. A marble has
+// at most one active offer at a time.
+// ===========================================================================
+func (t *SimpleChaincode) makeOffer(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	fmt.Println("- start make offer")
+
+	type offerTransientInput struct {
+		MarbleName    string `json:"marbleName"`
+		OfferPrice    int    `json:"offerPrice"`
+		ExpiresAtTime int64  `json:"expiresAtTime"`
+	}
+
+	if len(args) != 0 {
+		return shim.Error("Incorrect number of arguments. Private marble data must be passed in transient map.")
+	}
+
+	var offerInput offerTransientInput
+	err := getTransientInput(stub, "marble_offer", &offerInput)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if len(offerInput.MarbleName) == 0 {
+		return shim.Error("marbleName field must be a non-empty string")
+	}
+	if offerInput.OfferPrice <= 0 {
+		return shim.Error("offerPrice field must be a positive integer")
+	}
+	currentTime, err := getTxUnixTime(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if offerInput.ExpiresAtTime <= currentTime {
+		return shim.Error("expiresAtTime field must be a future time")
+	}
+
+	m, err := getMarble(stub, offerInput.MarbleName)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = checkNotArchived(m)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	buyerMSP, err := getCallerMSPID(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if buyerMSP == m.Owner {
+		return shim.Error("Marble is already owned by " + m.Owner)
+	}
+	active, err := getActiveOffer(stub, m.Name, currentTime)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if active != nil {
+		return shim.Error("Marble " + m.Name + " already has a pending offer")
+	}
+
+	offer := &offerDetails{
+		ObjectType:    "offerDetails",
+		MarbleName:    m.Name,
+		BuyerMSP:      buyerMSP,
+		OfferPrice:    offerInput.OfferPrice,
+		Status:        offerStatusPending,
+		ExpiresAtTime: offerInput.ExpiresAtTime,
+	}
+	err = putOffer(stub, offer)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	fmt.Println("- end make offer")
+	return shim.Success(nil)
+}
+
+// ===========================================================================
+// acceptOffer - owner acceptance of a pending offer. The marble goes to the
+// buyer and the offer price becomes its private price.
+// ===========================================================================
+func (t *SimpleChaincode) acceptOffer(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	fmt.Println("- start accept offer")
+
+	//     0          1
+	// "marble1", "Org2MSP"
+	if len(args) != 2 {
+		return shim.Error("Incorrect number of arguments. Expecting 2")
+	}
+
+	offer, m, err := getActionableOffer(stub, args[0], args[1])
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = changeMarbleOwner(stub, m, offer.BuyerMSP)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = setMarblePrice(stub, m, int64(offer.OfferPrice))
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = putMarble(stub, m)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	offer.Status = offerStatusAccepted
+	err = putOffer(stub, offer)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	fmt.Println("- end accept offer")
+	return shim.Success(nil)
+}
+
+// ===========================================================================
+// rejectOffer - owner refusal of a pending offer
+// ===========================================================================
+func (t *SimpleChaincode) rejectOffer(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+
+	//     0          1
+	// "marble1", "Org2MSP"
+	if len(args) != 2 {
+		return shim.Error("Incorrect number of arguments. Expecting 2")
+	}
+
+	offer, _, err := getActionableOffer(stub, args[0], args[1])
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	offer.Status = offerStatusRejected
+	err = putOffer(stub, offer)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	return shim.Success(nil)
+}
+
+// ===========================================================================
+// readOffer - an offer, readable only by its buyer and the marble's owner
+// ===========================================================================
+func (t *SimpleChaincode) readOffer(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+
+	//     0          1
+	// "marble1", "Org2MSP"
+	if len(args) != 2 {
+		return shim.Error("Incorrect number of arguments. Expecting 2")
+	}
+
+	offer, err := getOffer(stub, args[0], args[1])
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	callerMSPID, err := getCallerMSPID(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if callerMSPID != offer.BuyerMSP {
+		m, err := getMarble(stub, offer.MarbleName)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		err = requireOwner(stub, m)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+	}
+
+	offerAsBytes, err := json.Marshal(offer)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	return shim.Success(offerAsBytes)
+}
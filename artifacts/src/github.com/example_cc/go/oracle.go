@@ -0,0 +1,296 @@
+package main
+
+import (
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// oracleLatestIndexName points at the latest oracle feed of each marble.
+const oracleLatestIndexName = "oracleLatest~name"
+
+// oracleAnomalyRatio is how far, as a fraction of the current price, an oracle price
+// may move before a PRICE_ANOMALY event is emitted.
+const oracleAnomalyRatio = 0.5
+
+// OraclePriceFeed is one price observation by an authorized oracle, kept in
+// collectionMarbleOracle under the ID of the transaction that submitted it.
+// Signature is the base64 ECDSA-SHA256 signature by OracleCert over signedMessage().
+type OraclePriceFeed struct {
+	ObjectType  string `json:"docType"`
+	FeedID      string `json:"feedID"`
+	MarbleName  string `json:"marbleName"`
+	OracleMSPID string `json:"oracleMSPID"`
+	Price       int    `json:"price"`
+	FetchedAt   string `json:"fetchedAt"`
+	Signature   string `json:"signature"`
+	OracleCert  string `json:"oracleCert"`
+}
+
+type priceAnomalyEvent struct {
+	MarbleName   string `json:"marbleName"`
+	CurrentPrice int64  `json:"currentPrice"`
+	OraclePrice  int    `json:"oraclePrice"`
+	FeedID       string `json:"feedID"`
+}
+
+// signedMessage is the message an oracle signs for a feed.
+func (f *OraclePriceFeed) signedMessage() []byte {
+	return []byte(fmt.Sprintf("%s|%d|%s", f.MarbleName, f.Price, f.FetchedAt))
+}
+
+func getOraclePriceFeed(stub shim.ChaincodeStubInterface, feedID string) (*OraclePriceFeed, error) {
+	feedAsBytes, err := stub.GetPrivateData("collectionMarbleOracle", feedID)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get oracle feed: %s", err.Error())
+	} else if feedAsBytes == nil {
+		return nil, fmt.Errorf("Oracle feed does not exist: %s", feedID)
+	}
+
+	feed := &OraclePriceFeed{}
+	err = json.Unmarshal(feedAsBytes, feed)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to decode JSON of: %s", string(feedAsBytes))
+	}
+	return feed, nil
+}
+
+// getLatestOraclePriceFeed returns the most recent oracle feed of a marble.
+func getLatestOraclePriceFeed(stub shim.ChaincodeStubInterface, marbleName string) (*OraclePriceFeed, error) {
+	latestKey, err := stub.CreateCompositeKey(oracleLatestIndexName, []string{marbleName})
+	if err != nil {
+		return nil, err
+	}
+	feedID, err := stub.GetPrivateData("collectionMarbleOracle", latestKey)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get oracle feed: %s", err.Error())
+	} else if feedID == nil {
+		return nil, fmt.Errorf("No oracle price for marble: %s", marbleName)
+	}
+	return getOraclePriceFeed(stub, string(feedID))
+}
+
+// isPriceAnomaly reports whether an oracle price is too far from the current price.
+func isPriceAnomaly(currentPrice int64, oraclePrice int) bool {
+	low := float64(currentPrice) * (1 - oracleAnomalyRatio)
+	high := float64(currentPrice) * (1 + oracleAnomalyRatio)
+	return float64(oraclePrice) < low || float64(oraclePrice) > high
+}
+
+// ===========================================================================
+// submitOraclePrice - record a signed price observation from an authorized
+// oracle. A price far from the marble's current price emits PRICE_ANOMALY.
+// ===========================================================================
+func (t *SimpleChaincode) submitOraclePrice(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	fmt.Println("- start submit oracle price")
+
+	type oraclePriceTransientInput struct {
+		MarbleName string `json:"marbleName"`
+		Price      int    `json:"price"`
+		FetchedAt  string `json:"fetchedAt"`
+		Signature  string `json:"signature"`
+	}
+
+	if len(args) != 0 {
+		return shim.Error("Incorrect number of arguments. Oracle data must be passed in transient map.")
+	}
+
+	var feedInput oraclePriceTransientInput
+	err := getTransientInput(stub, "oracle_price", &feedInput)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if len(feedInput.MarbleName) == 0 {
+		return shim.Error("marbleName field must be a non-empty string")
+	}
+	if feedInput.Price <= 0 {
+		return shim.Error("price field must be a positive integer")
+	}
+	if len(feedInput.FetchedAt) == 0 {
+		return shim.Error("fetchedAt field must be a non-empty string")
+	}
+	if len(feedInput.Signature) == 0 {
+		return shim.Error("signature field must be a non-empty string")
+	}
+
+	gov, err := getGovernance(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	oracleMSPID, err := getCallerMSPID(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if !containsString(gov.AuthorizedOracles, oracleMSPID) {
+		return shim.Error("organization " + oracleMSPID + " is not an authorized oracle")
+	}
+	_, oracleCert, err := getCallerCertificate(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	details, err := getMarblePrivateDetails(stub, feedInput.MarbleName)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	feed := &OraclePriceFeed{
+		ObjectType:  "oraclePriceFeed",
+		FeedID:      stub.GetTxID(),
+		MarbleName:  details.Name,
+		OracleMSPID: oracleMSPID,
+		Price:       feedInput.Price,
+		FetchedAt:   feedInput.FetchedAt,
+		Signature:   feedInput.Signature,
+		OracleCert:  string(oracleCert),
+	}
+	feedAsBytes, err := json.Marshal(feed)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = stub.PutPrivateData("collectionMarbleOracle", feed.FeedID, feedAsBytes)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	latestKey, err := stub.CreateCompositeKey(oracleLatestIndexName, []string{feed.MarbleName})
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = stub.PutPrivateData("collectionMarbleOracle", latestKey, []byte(feed.FeedID))
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	if isPriceAnomaly(details.Price, feed.Price) {
+		err = emitEvent(stub, "PRICE_ANOMALY", priceAnomalyEvent{
+			MarbleName:   feed.MarbleName,
+			CurrentPrice: details.Price,
+			OraclePrice:  feed.Price,
+			FeedID:       feed.FeedID,
+		})
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+	}
+
+	fmt.Println("- end submit oracle price")
+	return shim.Success([]byte(feed.FeedID))
+}
+
+// ===========================================================================
+// getOraclePrice - the latest oracle feed of a marble
+// ===========================================================================
+func (t *SimpleChaincode) getOraclePrice(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+
+	//   0
+	// "marble1"
+	if len(args) != 1 {
+		return shim.Error("Incorrect number of arguments. Expecting name of the marble to query")
+	}
+
+	feed, err := getLatestOraclePriceFeed(stub, args[0])
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	feedAsBytes, err := json.Marshal(feed)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	return shim.Success(feedAsBytes)
+}
+
+// ===========================================================================
+// verifyOraclePriceSignature - check a feed's signature against the
+// certificate of the oracle that submitted it
+// ===========================================================================
+func (t *SimpleChaincode) verifyOraclePriceSignature(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+
+	//    0
+	// "feedID"
+	if len(args) != 1 {
+		return shim.Error("Incorrect number of arguments. Expecting oracle feed ID")
+	}
+
+	feed, err := getOraclePriceFeed(stub, args[0])
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	cert, err := parseCertificatePEM([]byte(feed.OracleCert))
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	signature, err := base64.StdEncoding.DecodeString(feed.Signature)
+	if err != nil {
+		return shim.Error("signature is not valid base64")
+	}
+
+	valid := cert.CheckSignature(x509.ECDSAWithSHA256, feed.signedMessage(), signature) == nil
+	return shim.Success([]byte(fmt.Sprintf("{\"valid\":%t}", valid)))
+}
+
+// ===========================================================================
+// updatePriceFromOracle - owner update of a marble's price to its latest
+// oracle price
+// ===========================================================================
+func (t *SimpleChaincode) updatePriceFromOracle(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+
+	//   0
+	// "marble1"
+	if len(args) != 1 {
+		return shim.Error("Incorrect number of arguments. Expecting name of the marble")
+	}
+
+	m, err := getMarble(stub, args[0])
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = requireOwner(stub, m)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	feed, err := getLatestOraclePriceFeed(stub, m.Name)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = setMarblePrice(stub, m, int64(feed.Price))
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	return shim.Success([]byte(fmt.Sprintf("{\"marbleName\":%q,\"price\":%d,\"feedID\":%q}", m.Name, feed.Price, feed.FeedID)))
+}
+
+// ===========================================================================
+// setOracleAuthority - admin authorization of an oracle organization
+// ===========================================================================
+func (t *SimpleChaincode) setOracleAuthority(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+
+	//     0
+	// "Org2MSP"
+	if len(args) != 1 {
+		return shim.Error("Incorrect number of arguments. Expecting oracle MSP ID")
+	}
+	if len(args[0]) == 0 {
+		return shim.Error("oracle MSP ID must be a non-empty string")
+	}
+
+	err := requireAdmin(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	gov, err := getGovernance(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if !containsString(gov.AuthorizedOracles, args[0]) {
+		gov.AuthorizedOracles = append(gov.AuthorizedOracles, args[0])
+	}
+	err = putGovernance(stub, gov)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	return shim.Success(nil)
+}
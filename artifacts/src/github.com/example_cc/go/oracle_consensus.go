@@ -0,0 +1,265 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// oracleVoteIndexName keys the current oracle vote of each oracle on each marble.
+const oracleVoteIndexName = "oracleVote~name~voter"
+
+// OracleConsensusConfig sets a marble's price once RequiredAgreements of the
+// OracleMSPIDs propose prices within PriceTolerancePercent of each other. Votes older
+// than VoteExpirySeconds no longer count.
+type OracleConsensusConfig struct {
+	RequiredAgreements    int      `json:"requiredAgreements"`
+	PriceTolerancePercent float64  `json:"priceTolerancePercent"`
+	OracleMSPIDs          []string `json:"oracleMSPIDs"`
+	VoteExpirySeconds     int64    `json:"voteExpirySeconds"`
+}
+
+// OracleVote is an oracle's proposed price for a marble. A new vote by the same oracle
+// replaces the previous one.
+type OracleVote struct {
+	ObjectType    string `json:"docType"`
+	VoterMSP      string `json:"voterMSP"`
+	MarbleName    string `json:"marbleName"`
+	ProposedPrice int    `json:"proposedPrice"`
+	VotedAtTime   int64  `json:"votedAtTime"`
+}
+
+// isStale reports whether a vote has expired at currentTime.
+func (c *OracleConsensusConfig) isStale(vote *OracleVote, currentTime int64) bool {
+	return currentTime-vote.VotedAtTime > c.VoteExpirySeconds
+}
+
+// agreedPrice looks for RequiredAgreements proposed prices within the tolerance of the
+// lowest of them and returns their median.
+func (c *OracleConsensusConfig) agreedPrice(votes []OracleVote) (int, bool) {
+	if c.RequiredAgreements <= 0 {
+		return 0, false
+	}
+	prices := make([]int, len(votes))
+	for i, vote := range votes {
+		prices[i] = vote.ProposedPrice
+	}
+	sort.Ints(prices)
+
+	for low := 0; low+c.RequiredAgreements <= len(prices); low++ {
+		limit := float64(prices[low]) * (1 + c.PriceTolerancePercent/100)
+		high := low
+		for high+1 < len(prices) && float64(prices[high+1]) <= limit {
+			high++
+		}
+		if high-low+1 >= c.RequiredAgreements {
+			agreeing := prices[low : high+1]
+			median := agreeing[len(agreeing)/2]
+			if len(agreeing)%2 == 0 {
+				median = (agreeing[len(agreeing)/2-1] + median) / 2
+			}
+			return median, true
+		}
+	}
+	return 0, false
+}
+
+// getMarbleOracleVotes returns the votes on a marble with their keys.
+func getMarbleOracleVotes(stub shim.ChaincodeStubInterface, marbleName string) ([]OracleVote, []string, error) {
+	resultsIterator, err := stub.GetPrivateDataByPartialCompositeKey("collectionMarbleOracleVotes", oracleVoteIndexName, []string{marbleName})
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resultsIterator.Close()
+
+	votes := []OracleVote{}
+	keys := []string{}
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, nil, err
+		}
+		var vote OracleVote
+		err = json.Unmarshal(queryResponse.Value, &vote)
+		if err != nil {
+			return nil, nil, err
+		}
+		votes = append(votes, vote)
+		keys = append(keys, queryResponse.Key)
+	}
+	return votes, keys, nil
+}
+
+// ===========================================================================
+// submitOracleVote - an authorized oracle's proposed price for a marble
+// ===========================================================================
+func (t *SimpleChaincode) submitOracleVote(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	fmt.Println("- start submit oracle vote")
+
+	type oracleVoteTransientInput struct {
+		MarbleName    string `json:"marbleName"`
+		ProposedPrice int    `json:"proposedPrice"`
+	}
+
+	if len(args) != 0 {
+		return shim.Error("Incorrect number of arguments. Oracle data must be passed in transient map.")
+	}
+
+	var voteInput oracleVoteTransientInput
+	err := getTransientInput(stub, "oracle_vote", &voteInput)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if len(voteInput.MarbleName) == 0 {
+		return shim.Error("marbleName field must be a non-empty string")
+	}
+	if voteInput.ProposedPrice <= 0 {
+		return shim.Error("proposedPrice field must be a positive integer")
+	}
+
+	gov, err := getGovernance(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	voterMSP, err := getCallerMSPID(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if !containsString(gov.OracleConsensus.OracleMSPIDs, voterMSP) {
+		return shim.Error("organization " + voterMSP + " is not a consensus oracle")
+	}
+	_, err = getMarble(stub, voteInput.MarbleName)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	currentTime, err := getTxUnixTime(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	vote := &OracleVote{
+		ObjectType:    "oracleVote",
+		VoterMSP:      voterMSP,
+		MarbleName:    voteInput.MarbleName,
+		ProposedPrice: voteInput.ProposedPrice,
+		VotedAtTime:   currentTime,
+	}
+	voteAsBytes, err := json.Marshal(vote)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	voteKey, err := stub.CreateCompositeKey(oracleVoteIndexName, []string{vote.MarbleName, vote.VoterMSP})
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = stub.PutPrivateData("collectionMarbleOracleVotes", voteKey, voteAsBytes)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	fmt.Println("- end submit oracle vote")
+	return shim.Success(nil)
+}
+
+// ===========================================================================
+// tallyOracleVotes - set a marble's price to the median of the agreeing
+// oracle votes. Stale votes are deleted and, once the price is set, so are
+// the votes that set it.
+// ===========================================================================
+func (t *SimpleChaincode) tallyOracleVotes(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+
+	//   0
+	// "marble1"
+	if len(args) != 1 {
+		return shim.Error("Incorrect number of arguments. Expecting name of the marble")
+	}
+
+	gov, err := getGovernance(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	config := &gov.OracleConsensus
+	votes, keys, err := getMarbleOracleVotes(stub, args[0])
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	currentTime, err := getTxUnixTime(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	current := []OracleVote{}
+	currentKeys := []string{}
+	for i := range votes {
+		if config.isStale(&votes[i], currentTime) || !containsString(config.OracleMSPIDs, votes[i].VoterMSP) {
+			err = stub.DelPrivateData("collectionMarbleOracleVotes", keys[i])
+			if err != nil {
+				return shim.Error("Failed to delete state:" + err.Error())
+			}
+			continue
+		}
+		current = append(current, votes[i])
+		currentKeys = append(currentKeys, keys[i])
+	}
+
+	price, agreed := config.agreedPrice(current)
+	if !agreed {
+		return shim.Success([]byte(fmt.Sprintf("{\"marbleName\":%q,\"agreed\":false,\"votes\":%d}", args[0], len(current))))
+	}
+
+	m, err := getMarble(stub, args[0])
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = setMarblePrice(stub, m, int64(price))
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	for _, key := range currentKeys {
+		err = stub.DelPrivateData("collectionMarbleOracleVotes", key)
+		if err != nil {
+			return shim.Error("Failed to delete state:" + err.Error())
+		}
+	}
+	return shim.Success([]byte(fmt.Sprintf("{\"marbleName\":%q,\"agreed\":true,\"price\":%d}", m.Name, price)))
+}
+
+// ===========================================================================
+// getOracleVotes - the unexpired oracle votes on a marble
+// ===========================================================================
+func (t *SimpleChaincode) getOracleVotes(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+
+	//   0
+	// "marble1"
+	if len(args) != 1 {
+		return shim.Error("Incorrect number of arguments. Expecting name of the marble to query")
+	}
+
+	gov, err := getGovernance(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	votes, _, err := getMarbleOracleVotes(stub, args[0])
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	currentTime, err := getTxUnixTime(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	current := []OracleVote{}
+	for i := range votes {
+		if !gov.OracleConsensus.isStale(&votes[i], currentTime) {
+			current = append(current, votes[i])
+		}
+	}
+	votesAsBytes, err := json.Marshal(current)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	return shim.Success(votesAsBytes)
+}
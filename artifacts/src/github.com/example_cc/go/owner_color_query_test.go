@@ -0,0 +1,107 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// queryTrackingStub records how a query reaches the marbles. Rich queries fail unless
+// richQuery is set, as on LevelDB, and full collection scans fail the test.
+type queryTrackingStub struct {
+	*testStub
+	richQuery   bool
+	richQueries []string
+	indexScans  int
+}
+
+func (s *queryTrackingStub) GetPrivateDataQueryResult(collection, query string) (shim.StateQueryIteratorInterface, error) {
+	if !s.richQuery {
+		s.t.Fatalf("unexpected rich query %s", query)
+	}
+	s.richQueries = append(s.richQueries, query)
+	return s.testStub.GetPrivateDataQueryResult(collection, query)
+}
+
+func (s *queryTrackingStub) GetPrivateDataByRange(collection, startKey, endKey string) (shim.StateQueryIteratorInterface, error) {
+	s.t.Fatalf("unexpected scan of %s", collection)
+	return nil, nil
+}
+
+func (s *queryTrackingStub) GetPrivateDataByPartialCompositeKey(collection, objectType string, attributes []string) (shim.StateQueryIteratorInterface, error) {
+	if objectType == "color~name" {
+		s.indexScans++
+	}
+	return s.testStub.GetPrivateDataByPartialCompositeKey(collection, objectType, attributes)
+}
+
+func (s *queryTrackingStub) invoke(function string, args ...string) pb.Response {
+	s.startTx(nil, append([]string{function}, args...))
+	defer s.MockTransactionEnd(s.TxID)
+	return s.cc.Invoke(s)
+}
+
+// newOwnerColorTestStub creates blue and red marbles held by Org1MSP and Org2MSP.
+func newOwnerColorTestStub(t *testing.T, richQuery bool) *queryTrackingStub {
+	s := newTestStub(t)
+	s.createMarble("blue1", "blue", 35, "Org1MSP", 99)
+	s.createMarble("blue2", "blue", 35, "Org2MSP", 99)
+	s.createMarble("red1", "red", 35, "Org1MSP", 99)
+	s.createMarble("blue3", "blue", 35, "Org1MSP", 99)
+	if richQuery {
+		s.mustInvoke("reinitialize", map[string]interface{}{"governance": map[string]interface{}{"richQueryEnabled": true}})
+	}
+	return &queryTrackingStub{testStub: s, richQuery: richQuery}
+}
+
+func (s *queryTrackingStub) ownerColorQuery(owner, color string) []string {
+	response := s.invoke("queryMarblesByOwnerAndColor", owner, color)
+	if response.Status != shim.OK {
+		s.t.Fatalf("queryMarblesByOwnerAndColor failed: %s", response.Message)
+	}
+	var records []queryRecord
+	err := json.Unmarshal(response.Payload, &records)
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	names := []string{}
+	for _, record := range records {
+		names = append(names, record.Key)
+	}
+	return names
+}
+
+func expectNames(t *testing.T, got []string, want ...string) {
+	if len(got) != len(want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("expected %v, got %v", want, got)
+		}
+	}
+}
+
+func TestQueryMarblesByOwnerAndColorWithColorIndex(t *testing.T) {
+	s := newOwnerColorTestStub(t, false)
+	expectNames(t, s.ownerColorQuery("Org1MSP", "blue"), "blue1", "blue3")
+	expectNames(t, s.ownerColorQuery("Org2MSP", "blue"), "blue2")
+	expectNames(t, s.ownerColorQuery("Org2MSP", "red"))
+	if s.indexScans != 3 {
+		t.Fatalf("expected each query to scan the color~name index, got %d scans", s.indexScans)
+	}
+}
+
+func TestQueryMarblesByOwnerAndColorWithRichQuery(t *testing.T) {
+	s := newOwnerColorTestStub(t, true)
+	expectNames(t, s.ownerColorQuery("Org1MSP", "blue"), "blue1", "blue3")
+	expectNames(t, s.ownerColorQuery("Org2MSP", "red"))
+	if s.indexScans != 0 {
+		t.Fatalf("expected no index scans, got %d", s.indexScans)
+	}
+	if len(s.richQueries) != 2 || s.richQueries[0] != `{"selector":{"docType":"marble","owner":"Org1MSP","color":"blue"}}` {
+		t.Fatalf("unexpected rich queries %v", s.richQueries)
+	}
+}
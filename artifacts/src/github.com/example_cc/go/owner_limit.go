@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// ownerMaxMarblesKey holds, in public state, the most marbles a single owner may hold.
+// Until it is set, owners may hold any number of marbles.
+const ownerMaxMarblesKey = "ownerMaxMarbles"
+
+// getOwnerMaxMarbles returns the most marbles an owner may hold, or 0 if there is no
+// limit.
+func getOwnerMaxMarbles(stub shim.ChaincodeStubInterface) (int, error) {
+	limitAsBytes, err := stub.GetState(ownerMaxMarblesKey)
+	if err != nil {
+		return 0, fmt.Errorf("Failed to get owner marble limit: %s", err.Error())
+	} else if limitAsBytes == nil {
+		return 0, nil
+	}
+	limit, err := strconv.Atoi(string(limitAsBytes))
+	if err != nil {
+		return 0, fmt.Errorf("Failed to decode owner marble limit: %s", string(limitAsBytes))
+	}
+	return limit, nil
+}
+
+// checkOwnerMaxMarbles fails if receiving added more marbles would take owner over the
+// limit. The marbles owner holds are counted from the owner~name index, which does not
+// include marbles written earlier in the same transaction.
+func checkOwnerMaxMarbles(stub shim.ChaincodeStubInterface, owner string, added int) error {
+	limit, err := getOwnerMaxMarbles(stub)
+	if err != nil || limit == 0 {
+		return err
+	}
+	names, err := getMarbleNamesByOwner(stub, owner)
+	if err != nil {
+		return err
+	}
+	if len(names)+added > limit {
+		return fmt.Errorf("owner %s already holds %d marbles and may hold at most %d", owner, len(names), limit)
+	}
+	return nil
+}
+
+// ===========================================================================
+// setOwnerMaxMarbles - admin setting of the most marbles a single owner may
+// hold. A limit of 0 removes it.
+// ===========================================================================
+func (t *SimpleChaincode) setOwnerMaxMarbles(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+
+	//  0
+	// "50"
+	if len(args) != 1 {
+		return shim.Error("Incorrect number of arguments. Expecting 1")
+	}
+
+	err := requireAdmin(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	limit, err := strconv.Atoi(args[0])
+	if err != nil || limit < 0 {
+		return shim.Error("limit must be a non-negative integer")
+	}
+
+	if limit == 0 {
+		err = stub.DelState(ownerMaxMarblesKey)
+	} else {
+		err = stub.PutState(ownerMaxMarblesKey, []byte(strconv.Itoa(limit)))
+	}
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	return shim.Success(nil)
+}
@@ -0,0 +1,268 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+	"unicode/utf8"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// maxDisplayNameRunes caps the length of an owner's display name, in characters.
+const maxDisplayNameRunes = 100
+
+// OwnerProfile is what marketplaces show about an owner, stored in
+// collectionOwnerProfiles under the owner ID. ContactHash is the SHA-256 hash of the
+// owner's contact details, never the details themselves.
+type OwnerProfile struct {
+	ObjectType  string `json:"docType"`
+	OwnerID     string `json:"ownerID"`
+	DisplayName string `json:"displayName"`
+	ContactHash string `json:"contactHash,omitempty"`
+	OrgMSP      string `json:"orgMSP"`
+	JoinedAt    string `json:"joinedAt"`
+}
+
+// ownerProfileTransientInput is the transient input of createOwnerProfile and
+// updateOwnerProfile.
+type ownerProfileTransientInput struct {
+	OwnerID     string `json:"ownerID"`
+	DisplayName string `json:"displayName"`
+	// ContactHash is optional
+	ContactHash string `json:"contactHash"`
+}
+
+func (i *ownerProfileTransientInput) validate() error {
+	if len(i.OwnerID) == 0 {
+		return fmt.Errorf("ownerID field must be a non-empty string")
+	}
+	if len(i.DisplayName) == 0 {
+		return fmt.Errorf("displayName field must be a non-empty string")
+	}
+	if !utf8.ValidString(i.DisplayName) || utf8.RuneCountInString(i.DisplayName) > maxDisplayNameRunes {
+		return fmt.Errorf("displayName must be valid UTF-8 of at most %d characters", maxDisplayNameRunes)
+	}
+	if len(i.ContactHash) != 0 && !documentHashPattern.MatchString(i.ContactHash) {
+		return fmt.Errorf("contactHash %q must match %s", i.ContactHash, documentHashPattern.String())
+	}
+	return nil
+}
+
+// requireProfileOwner fails unless the caller is ownerID.
+func requireProfileOwner(stub shim.ChaincodeStubInterface, ownerID string) error {
+	isOwner, err := callerIs(stub, ownerID)
+	if err != nil {
+		return err
+	}
+	if !isOwner {
+		return fmt.Errorf("caller may only manage their own profile, not %s", ownerID)
+	}
+	return nil
+}
+
+// getOwnerProfile returns the profile of ownerID, or nil if there is none.
+func getOwnerProfile(stub shim.ChaincodeStubInterface, ownerID string) (*OwnerProfile, error) {
+	profileAsBytes, err := stub.GetPrivateData("collectionOwnerProfiles", ownerID)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get owner profile: %s", err.Error())
+	} else if profileAsBytes == nil {
+		return nil, nil
+	}
+
+	profile := &OwnerProfile{}
+	err = json.Unmarshal(profileAsBytes, profile)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to decode JSON of: %s", string(profileAsBytes))
+	}
+	return profile, nil
+}
+
+func putOwnerProfile(stub shim.ChaincodeStubInterface, profile *OwnerProfile) error {
+	profileAsBytes, err := json.Marshal(profile)
+	if err != nil {
+		return err
+	}
+	return stub.PutPrivateData("collectionOwnerProfiles", profile.OwnerID, profileAsBytes)
+}
+
+// profiledRecord is a query result with the display name of the marble's owner.
+type profiledRecord struct {
+	queryRecord
+	OwnerDisplayName string `json:"ownerDisplayName,omitempty"`
+}
+
+// enrichWithProfiles adds the display name of each marble's owner to query results.
+// Owners without a profile are left without a display name.
+func enrichWithProfiles(stub shim.ChaincodeStubInterface, records []queryRecord) ([]profiledRecord, error) {
+	displayNames := map[string]string{}
+	enriched := make([]profiledRecord, len(records))
+	for i, record := range records {
+		var m marble
+		err := json.Unmarshal(record.Record, &m)
+		if err != nil {
+			return nil, err
+		}
+		displayName, ok := displayNames[m.Owner]
+		if !ok {
+			profile, err := getOwnerProfile(stub, m.Owner)
+			if err != nil {
+				return nil, err
+			}
+			if profile != nil {
+				displayName = profile.DisplayName
+			}
+			displayNames[m.Owner] = displayName
+		}
+		enriched[i] = profiledRecord{queryRecord: record, OwnerDisplayName: displayName}
+	}
+	return enriched, nil
+}
+
+// ===========================================================================
+// createOwnerProfile - creation of the caller's own profile
+// ===========================================================================
+func (t *SimpleChaincode) createOwnerProfile(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	fmt.Println("- start create owner profile")
+
+	if len(args) != 0 {
+		return shim.Error("Incorrect number of arguments. Owner profile must be passed in transient map.")
+	}
+
+	var profileInput ownerProfileTransientInput
+	err := getTransientInput(stub, "owner_profile", &profileInput)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = profileInput.validate()
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = requireProfileOwner(stub, profileInput.OwnerID)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	existing, err := getOwnerProfile(stub, profileInput.OwnerID)
+	if err != nil {
+		return shim.Error(err.Error())
+	} else if existing != nil {
+		return shim.Error("This owner profile already exists: " + profileInput.OwnerID)
+	}
+
+	callerMSPID, err := getCallerMSPID(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	txTime, err := getTxTime(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = putOwnerProfile(stub, &OwnerProfile{
+		ObjectType:  "ownerProfile",
+		OwnerID:     profileInput.OwnerID,
+		DisplayName: profileInput.DisplayName,
+		ContactHash: profileInput.ContactHash,
+		OrgMSP:      callerMSPID,
+		JoinedAt:    txTime.Format(time.RFC3339),
+	})
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	fmt.Println("- end create owner profile")
+	return shim.Success(nil)
+}
+
+// ===========================================================================
+// updateOwnerProfile - change of the display name and contact hash of the
+// caller's own profile
+// ===========================================================================
+func (t *SimpleChaincode) updateOwnerProfile(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	fmt.Println("- start update owner profile")
+
+	if len(args) != 0 {
+		return shim.Error("Incorrect number of arguments. Owner profile must be passed in transient map.")
+	}
+
+	var profileInput ownerProfileTransientInput
+	err := getTransientInput(stub, "owner_profile_update", &profileInput)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = profileInput.validate()
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = requireProfileOwner(stub, profileInput.OwnerID)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	profile, err := getOwnerProfile(stub, profileInput.OwnerID)
+	if err != nil {
+		return shim.Error(err.Error())
+	} else if profile == nil {
+		return shim.Error("Owner profile does not exist: " + profileInput.OwnerID)
+	}
+
+	profile.DisplayName = profileInput.DisplayName
+	profile.ContactHash = profileInput.ContactHash
+	err = putOwnerProfile(stub, profile)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	fmt.Println("- end update owner profile")
+	return shim.Success(nil)
+}
+
+// ===========================================================================
+// readOwnerProfile - the profile of an owner
+// ===========================================================================
+func (t *SimpleChaincode) readOwnerProfile(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+
+	//   0
+	// "alice"
+	if len(args) != 1 {
+		return shim.Error("Incorrect number of arguments. Expecting owner ID")
+	}
+
+	profileAsBytes, err := stub.GetPrivateData("collectionOwnerProfiles", args[0])
+	if err != nil {
+		return shim.Error("Failed to get owner profile: " + err.Error())
+	} else if profileAsBytes == nil {
+		return shim.Error("Owner profile does not exist: " + args[0])
+	}
+	return shim.Success(profileAsBytes)
+}
+
+// ===========================================================================
+// deleteOwnerProfile - removal of the caller's own profile
+// ===========================================================================
+func (t *SimpleChaincode) deleteOwnerProfile(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+
+	//   0
+	// "alice"
+	if len(args) != 1 {
+		return shim.Error("Incorrect number of arguments. Expecting owner ID")
+	}
+
+	err := requireProfileOwner(stub, args[0])
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	profile, err := getOwnerProfile(stub, args[0])
+	if err != nil {
+		return shim.Error(err.Error())
+	} else if profile == nil {
+		return shim.Error("Owner profile does not exist: " + args[0])
+	}
+
+	err = stub.DelPrivateData("collectionOwnerProfiles", args[0])
+	if err != nil {
+		return shim.Error("Failed to delete state:" + err.Error())
+	}
+	return shim.Success(nil)
+}
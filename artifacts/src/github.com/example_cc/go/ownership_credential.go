@@ -0,0 +1,279 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// credentialIndexName indexes ownership credentials by the marble they prove.
+const credentialIndexName = "marble~credential"
+
+// OwnershipCredential lets HolderMSPID prove to third parties that its member Owner
+// owns a marble until ExpiresAt. It is kept in collectionMarbleCredentials under the ID
+// of the transaction that issued it, and is deleted when the marble changes owner.
+type OwnershipCredential struct {
+	ObjectType  string `json:"docType"`
+	CredID      string `json:"credID"`
+	MarbleName  string `json:"marbleName"`
+	Owner       string `json:"owner"`
+	HolderMSPID string `json:"holderMSPID"`
+	IssuedAt    string `json:"issuedAt"`
+	ExpiresAt   string `json:"expiresAt"`
+	CredHash    string `json:"credHash"`
+}
+
+func computeCredHash(c *OwnershipCredential) string {
+	hash := sha256.Sum256([]byte(c.HolderMSPID + c.MarbleName + c.IssuedAt + c.ExpiresAt))
+	return hex.EncodeToString(hash[:])
+}
+
+// isActive reports whether a credential is intact and unexpired at now.
+func (c *OwnershipCredential) isActive(now time.Time) bool {
+	expiresAt, err := time.Parse(time.RFC3339, c.ExpiresAt)
+	return err == nil && now.Before(expiresAt) && computeCredHash(c) == c.CredHash
+}
+
+func getOwnershipCredential(stub shim.ChaincodeStubInterface, credID string) (*OwnershipCredential, error) {
+	credAsBytes, err := stub.GetPrivateData("collectionMarbleCredentials", credID)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get credential: %s", err.Error())
+	} else if credAsBytes == nil {
+		return nil, fmt.Errorf("Credential does not exist: %s", credID)
+	}
+
+	cred := &OwnershipCredential{}
+	err = json.Unmarshal(credAsBytes, cred)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to decode JSON of: %s", string(credAsBytes))
+	}
+	return cred, nil
+}
+
+// getMarbleCredentials returns every credential issued for a marble.
+func getMarbleCredentials(stub shim.ChaincodeStubInterface, marbleName string) ([]OwnershipCredential, error) {
+	resultsIterator, err := stub.GetPrivateDataByPartialCompositeKey("collectionMarbleCredentials", credentialIndexName, []string{marbleName})
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	creds := []OwnershipCredential{}
+	for resultsIterator.HasNext() {
+		responseRange, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+		_, compositeKeyParts, err := stub.SplitCompositeKey(responseRange.Key)
+		if err != nil {
+			return nil, err
+		}
+		cred, err := getOwnershipCredential(stub, compositeKeyParts[1])
+		if err != nil {
+			return nil, err
+		}
+		creds = append(creds, *cred)
+	}
+	return creds, nil
+}
+
+func deleteOwnershipCredential(stub shim.ChaincodeStubInterface, cred *OwnershipCredential) error {
+	err := stub.DelPrivateData("collectionMarbleCredentials", cred.CredID)
+	if err != nil {
+		return err
+	}
+	indexKey, err := stub.CreateCompositeKey(credentialIndexName, []string{cred.MarbleName, cred.CredID})
+	if err != nil {
+		return err
+	}
+	return stub.DelPrivateData("collectionMarbleCredentials", indexKey)
+}
+
+// invalidateOwnershipCredentials deletes every credential issued for a marble.
+func invalidateOwnershipCredentials(stub shim.ChaincodeStubInterface, marbleName string) error {
+	creds, err := getMarbleCredentials(stub, marbleName)
+	if err != nil {
+		return err
+	}
+	for i := range creds {
+		err = deleteOwnershipCredential(stub, &creds[i])
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ===========================================================================
+// issueOwnershipCredential - owner issue of a credential proving ownership of
+// a marble, held by the caller's organization
+// ===========================================================================
+func (t *SimpleChaincode) issueOwnershipCredential(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	fmt.Println("- start issue ownership credential")
+
+	type credentialTransientInput struct {
+		MarbleName string `json:"marbleName"`
+		ValidFor   int64  `json:"validFor"` // in seconds
+	}
+
+	if len(args) != 0 {
+		return shim.Error("Incorrect number of arguments. Private marble data must be passed in transient map.")
+	}
+
+	var credInput credentialTransientInput
+	err := getTransientInput(stub, "ownership_credential", &credInput)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if len(credInput.MarbleName) == 0 {
+		return shim.Error("marbleName field must be a non-empty string")
+	}
+	if credInput.ValidFor <= 0 {
+		return shim.Error("validFor field must be a positive integer")
+	}
+
+	m, err := getMarble(stub, credInput.MarbleName)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = requireOwner(stub, m)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	holderMSPID, err := getCallerMSPID(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	txTime, err := getTxTime(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	cred := &OwnershipCredential{
+		ObjectType:  "ownershipCredential",
+		CredID:      stub.GetTxID(),
+		MarbleName:  m.Name,
+		Owner:       m.Owner,
+		HolderMSPID: holderMSPID,
+		IssuedAt:    txTime.Format(time.RFC3339),
+		ExpiresAt:   txTime.Add(time.Duration(credInput.ValidFor) * time.Second).Format(time.RFC3339),
+	}
+	cred.CredHash = computeCredHash(cred)
+
+	credAsBytes, err := json.Marshal(cred)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = stub.PutPrivateData("collectionMarbleCredentials", cred.CredID, credAsBytes)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	indexKey, err := stub.CreateCompositeKey(credentialIndexName, []string{cred.MarbleName, cred.CredID})
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = stub.PutPrivateData("collectionMarbleCredentials", indexKey, []byte{0x00})
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	fmt.Println("- end issue ownership credential")
+	return shim.Success(credAsBytes)
+}
+
+// ===========================================================================
+// verifyOwnershipCredential - check that a credential is intact, unexpired,
+// held by an organization and still names the marble's current owner
+// ===========================================================================
+func (t *SimpleChaincode) verifyOwnershipCredential(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+
+	//     0           1
+	// "credID", "holderMSPID"
+	if len(args) != 2 {
+		return shim.Error("Incorrect number of arguments. Expecting 2")
+	}
+
+	cred, err := getOwnershipCredential(stub, args[0])
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	m, err := getMarble(stub, cred.MarbleName)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	txTime, err := getTxTime(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	valid := cred.isActive(txTime) && cred.HolderMSPID == args[1] && m.Owner == cred.Owner
+	return shim.Success([]byte(fmt.Sprintf("{\"valid\":%t}", valid)))
+}
+
+// ===========================================================================
+// listActiveCredentials - the valid credentials of a marble
+// ===========================================================================
+func (t *SimpleChaincode) listActiveCredentials(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+
+	//   0
+	// "marble1"
+	if len(args) != 1 {
+		return shim.Error("Incorrect number of arguments. Expecting name of the marble to query")
+	}
+
+	creds, err := getMarbleCredentials(stub, args[0])
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	txTime, err := getTxTime(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	active := []OwnershipCredential{}
+	for _, cred := range creds {
+		if cred.isActive(txTime) {
+			active = append(active, cred)
+		}
+	}
+	activeAsBytes, err := json.Marshal(active)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	return shim.Success(activeAsBytes)
+}
+
+// ===========================================================================
+// revokeCredential - owner deletion of an ownership credential
+// ===========================================================================
+func (t *SimpleChaincode) revokeCredential(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+
+	//    0
+	// "credID"
+	if len(args) != 1 {
+		return shim.Error("Incorrect number of arguments. Expecting credential ID")
+	}
+
+	cred, err := getOwnershipCredential(stub, args[0])
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	m, err := getMarble(stub, cred.MarbleName)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = requireOwner(stub, m)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = deleteOwnershipCredential(stub, cred)
+	if err != nil {
+		return shim.Error("Failed to delete state:" + err.Error())
+	}
+	return shim.Success(nil)
+}
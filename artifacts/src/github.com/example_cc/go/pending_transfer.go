@@ -0,0 +1,234 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// pendingTransferPrefix prefixes pending transfers in collectionMarbles. Marble names
+// cannot contain "~", so the keys never collide with a marble.
+const pendingTransferPrefix = "pendingTransfer~"
+
+// pendingTransferLock is the LockedBy value of a marble awaiting the acceptance of its
+// new owner. Since a locked marble cannot be requested again, a marble has at most one
+// pending transfer.
+const pendingTransferLock = "pendingTransfer"
+
+// PendingTransfer is a transfer of a marble from FromOwner that waits for ToOwner to
+// accept it.
+type PendingTransfer struct {
+	ObjectType  string `json:"docType"`
+	MarbleName  string `json:"marbleName"`
+	FromOwner   string `json:"fromOwner"`
+	ToOwner     string `json:"toOwner"`
+	RequestedAt string `json:"requestedAt"`
+}
+
+func getPendingTransfer(stub shim.ChaincodeStubInterface, marbleName string) (*PendingTransfer, error) {
+	transferAsBytes, err := stub.GetPrivateData("collectionMarbles", pendingTransferPrefix+marbleName)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get pending transfer: %s", err.Error())
+	} else if transferAsBytes == nil {
+		return nil, fmt.Errorf("Pending transfer does not exist: %s", marbleName)
+	}
+
+	transfer := &PendingTransfer{}
+	err = json.Unmarshal(transferAsBytes, transfer)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to decode JSON of: %s", string(transferAsBytes))
+	}
+	return transfer, nil
+}
+
+// closePendingTransfer removes the pending transfer of a marble and unlocks it. The
+// caller is responsible for writing the marble back.
+func closePendingTransfer(stub shim.ChaincodeStubInterface, m *marble) error {
+	err := stub.DelPrivateData("collectionMarbles", pendingTransferPrefix+m.Name)
+	if err != nil {
+		return err
+	}
+	m.LockedBy = ""
+	return nil
+}
+
+// ===========================================================================
+// requestTransfer - owner offer of a marble to a new owner, who must accept
+// it. The marble stays locked until the transfer is accepted or rejected.
+// ===========================================================================
+func (t *SimpleChaincode) requestTransfer(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	fmt.Println("- start request transfer")
+
+	type transferRequestTransientInput struct {
+		Name    string `json:"name"`
+		ToOwner string `json:"toOwner"`
+	}
+
+	if len(args) != 0 {
+		return shim.Error("Incorrect number of arguments. Private marble data must be passed in transient map.")
+	}
+
+	var requestInput transferRequestTransientInput
+	err := getTransientInput(stub, "transfer_request", &requestInput)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if len(requestInput.Name) == 0 {
+		return shim.Error("name field must be a non-empty string")
+	}
+	if len(requestInput.ToOwner) == 0 {
+		return shim.Error("toOwner field must be a non-empty string")
+	}
+
+	m, err := getMarble(stub, requestInput.Name)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = requireOwner(stub, m)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = checkNotLocked(m)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = checkNotArchived(m)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if m.Owner == requestInput.ToOwner {
+		return shim.Error("Marble is already owned by " + m.Owner)
+	}
+	txTime, err := getTxTime(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	transfer := &PendingTransfer{
+		ObjectType:  "pendingTransfer",
+		MarbleName:  m.Name,
+		FromOwner:   m.Owner,
+		ToOwner:     requestInput.ToOwner,
+		RequestedAt: txTime.Format(time.RFC3339),
+	}
+	transferAsBytes, err := json.Marshal(transfer)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = stub.PutPrivateData("collectionMarbles", pendingTransferPrefix+m.Name, transferAsBytes)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	m.LockedBy = pendingTransferLock
+	err = putMarble(stub, m)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = emitMarbleLifecycleEvent(stub, "TransferRequested", m.Name)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	fmt.Println("- end request transfer")
+	return shim.Success(nil)
+}
+
+// ===========================================================================
+// acceptTransfer - the new owner's acceptance of a pending transfer
+// ===========================================================================
+func (t *SimpleChaincode) acceptTransfer(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	fmt.Println("- start accept transfer")
+
+	//   0
+	// "marble1"
+	if len(args) != 1 {
+		return shim.Error("Incorrect number of arguments. Expecting name of the marble")
+	}
+
+	transfer, err := getPendingTransfer(stub, args[0])
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	isToOwner, err := callerIs(stub, transfer.ToOwner)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if !isToOwner {
+		return shim.Error("caller is not the recipient of the pending transfer of " + transfer.MarbleName)
+	}
+
+	m, err := getMarble(stub, transfer.MarbleName)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = closePendingTransfer(stub, m)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = changeMarbleOwner(stub, m, transfer.ToOwner)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = putMarble(stub, m)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = emitMarbleLifecycleEvent(stub, "TransferAccepted", m.Name)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	fmt.Println("- end accept transfer")
+	return shim.Success(nil)
+}
+
+// ===========================================================================
+// rejectTransfer - withdrawal of a pending transfer by its sender or refusal
+// by its recipient. The marble stays with its owner.
+// ===========================================================================
+func (t *SimpleChaincode) rejectTransfer(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+
+	//   0
+	// "marble1"
+	if len(args) != 1 {
+		return shim.Error("Incorrect number of arguments. Expecting name of the marble")
+	}
+
+	transfer, err := getPendingTransfer(stub, args[0])
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	isFromOwner, err := callerIs(stub, transfer.FromOwner)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	isToOwner, err := callerIs(stub, transfer.ToOwner)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if !isFromOwner && !isToOwner {
+		return shim.Error("caller is not a party to the pending transfer of " + transfer.MarbleName)
+	}
+
+	m, err := getMarble(stub, transfer.MarbleName)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = closePendingTransfer(stub, m)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = putMarble(stub, m)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = emitMarbleLifecycleEvent(stub, "TransferRejected", m.Name)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	return shim.Success(nil)
+}
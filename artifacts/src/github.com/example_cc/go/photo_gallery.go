@@ -0,0 +1,235 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// maxGalleryPhotos caps the photo gallery of a marble.
+const maxGalleryPhotos = 5
+
+// PhotoEntry references an off-chain image by its hex encoded SHA-256 hash.
+type PhotoEntry struct {
+	Hash    string `json:"hash"`
+	Caption string `json:"caption"`
+	AddedAt string `json:"addedAt"`
+}
+
+// checkPhotoIndex fails unless i names an entry of the marble's gallery.
+func checkPhotoIndex(m *marble, i int) error {
+	if i < 0 || i >= len(m.PhotoGallery) {
+		return fmt.Errorf("marble %s has no photo at index %d", m.Name, i)
+	}
+	return nil
+}
+
+// ===========================================================================
+// addMarblePhoto - append an image reference to a marble's gallery
+// ===========================================================================
+func (t *SimpleChaincode) addMarblePhoto(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	fmt.Println("- start add marble photo")
+
+	type marblePhotoTransientInput struct {
+		Name    string `json:"name"`
+		Hash    string `json:"hash"`
+		Caption string `json:"caption"`
+	}
+
+	if len(args) != 0 {
+		return shim.Error("Incorrect number of arguments. Private marble data must be passed in transient map.")
+	}
+
+	var photoInput marblePhotoTransientInput
+	err := getTransientInput(stub, "marble_photo", &photoInput)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if len(photoInput.Name) == 0 {
+		return shim.Error("name field must be a non-empty string")
+	}
+	if len(photoInput.Hash) == 0 {
+		return shim.Error("hash field must be a non-empty string")
+	}
+
+	m, err := getMarble(stub, photoInput.Name)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = requireOwner(stub, m)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if len(m.PhotoGallery) >= maxGalleryPhotos {
+		return shim.Error(fmt.Sprintf("Marble %s already has the maximum of %d photos", m.Name, maxGalleryPhotos))
+	}
+
+	txTime, err := getTxTime(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	m.PhotoGallery = append(m.PhotoGallery, PhotoEntry{
+		Hash:    photoInput.Hash,
+		Caption: photoInput.Caption,
+		AddedAt: txTime.Format(time.RFC3339),
+	})
+	err = putMarble(stub, m)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	fmt.Println("- end add marble photo")
+	return shim.Success(nil)
+}
+
+// ===========================================================================
+// removeMarblePhoto - remove an image reference from a marble's gallery.
+// Removing the hero photo clears HeroPhotoHash.
+// ===========================================================================
+func (t *SimpleChaincode) removeMarblePhoto(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	fmt.Println("- start remove marble photo")
+
+	type marblePhotoRemoveTransientInput struct {
+		Name  string `json:"name"`
+		Index *int   `json:"index"`
+	}
+
+	if len(args) != 0 {
+		return shim.Error("Incorrect number of arguments. Private marble data must be passed in transient map.")
+	}
+
+	var removeInput marblePhotoRemoveTransientInput
+	err := getTransientInput(stub, "marble_photo_remove", &removeInput)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if len(removeInput.Name) == 0 {
+		return shim.Error("name field must be a non-empty string")
+	}
+	if removeInput.Index == nil {
+		return shim.Error("index field must be an integer")
+	}
+
+	m, err := getMarble(stub, removeInput.Name)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = requireOwner(stub, m)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	i := *removeInput.Index
+	err = checkPhotoIndex(m, i)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	if m.PhotoGallery[i].Hash == m.HeroPhotoHash {
+		m.HeroPhotoHash = ""
+	}
+	m.PhotoGallery = append(m.PhotoGallery[:i], m.PhotoGallery[i+1:]...)
+	err = putMarble(stub, m)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	fmt.Println("- end remove marble photo")
+	return shim.Success(nil)
+}
+
+// ===========================================================================
+// getMarbleGallery - list a marble's photos
+// ===========================================================================
+func (t *SimpleChaincode) getMarbleGallery(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+
+	//   0
+	// "marble1"
+	if len(args) != 1 {
+		return shim.Error("Incorrect number of arguments. Expecting name of the marble to query")
+	}
+
+	m, err := getMarble(stub, args[0])
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	gallery := m.PhotoGallery
+	if gallery == nil {
+		gallery = []PhotoEntry{}
+	}
+	galleryAsBytes, err := json.Marshal(gallery)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	return shim.Success(galleryAsBytes)
+}
+
+// ===========================================================================
+// setHeroPhoto - make one gallery photo the marble's hero photo
+// ===========================================================================
+func (t *SimpleChaincode) setHeroPhoto(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+
+	//     0       1
+	// "marble1", "2"
+	if len(args) != 2 {
+		return shim.Error("Incorrect number of arguments. Expecting 2")
+	}
+	i, err := strconv.Atoi(args[1])
+	if err != nil {
+		return shim.Error("index must be an integer")
+	}
+
+	m, err := getMarble(stub, args[0])
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = requireOwner(stub, m)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = checkPhotoIndex(m, i)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	m.HeroPhotoHash = m.PhotoGallery[i].Hash
+	err = putMarble(stub, m)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	return shim.Success(nil)
+}
+
+// ===========================================================================
+// verifyPhoto - check image bytes against a gallery photo's hash
+// ===========================================================================
+func (t *SimpleChaincode) verifyPhoto(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+
+	//     0       1        2
+	// "marble1", "0", "<image bytes>"
+	if len(args) != 3 {
+		return shim.Error("Incorrect number of arguments. Expecting 3")
+	}
+	i, err := strconv.Atoi(args[1])
+	if err != nil {
+		return shim.Error("index must be an integer")
+	}
+
+	m, err := getMarble(stub, args[0])
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = checkPhotoIndex(m, i)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	hash := sha256.Sum256([]byte(args[2]))
+	valid := hex.EncodeToString(hash[:]) == m.PhotoGallery[i].Hash
+	return shim.Success([]byte(fmt.Sprintf("{\"valid\":%t}", valid)))
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+)
+
+func photoOf(name string, i int) map[string]interface{} {
+	return map[string]interface{}{"marble_photo": map[string]interface{}{
+		"name": name, "hash": fmt.Sprintf("%064x", i), "caption": fmt.Sprintf("angle %d", i),
+	}}
+}
+
+func TestAddingASixthPhotoFails(t *testing.T) {
+	s := newTestStub(t)
+	s.createMarble("marble1", "blue", 35, "Org1MSP", 99)
+	for i := 0; i < maxGalleryPhotos; i++ {
+		s.mustInvoke("addMarblePhoto", photoOf("marble1", i))
+	}
+
+	s.mustFail("Marble marble1 already has the maximum of 5 photos", "addMarblePhoto", photoOf("marble1", maxGalleryPhotos))
+	if gallery := s.readTestMarble("marble1").PhotoGallery; len(gallery) != maxGalleryPhotos || gallery[maxGalleryPhotos-1].Caption != "angle 4" {
+		t.Fatalf("expected the gallery to keep its first %d photos, got %+v", maxGalleryPhotos, gallery)
+	}
+}
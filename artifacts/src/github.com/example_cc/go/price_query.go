@@ -0,0 +1,121 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// checkPrivateDetailsReadable fails if this peer's organization is not a member of
+// collectionMarblePrivateDetails, whose members collectionMarbleMarketCap shares. Every
+// peer holds the hashes of a collection, but only members hold the values, so a market
+// cap base with a hash and no value gives a non-member away. Before Init there is
+// nothing to read either way.
+func checkPrivateDetailsReadable(stub shim.ChaincodeStubInterface) error {
+	capHash, err := stub.GetPrivateDataHash("collectionMarbleMarketCap", marketCapBaseKey)
+	if err != nil {
+		return fmt.Errorf("Failed to get private data hash: %s", err.Error())
+	} else if capHash == nil {
+		return nil
+	}
+	capAsBytes, err := stub.GetPrivateData("collectionMarbleMarketCap", marketCapBaseKey)
+	if err != nil || capAsBytes == nil {
+		return fmt.Errorf("not authorized: this organization cannot read collectionMarblePrivateDetails")
+	}
+	return nil
+}
+
+// pricedRecord is a marble from collectionMarbles with its private price.
+type pricedRecord struct {
+	queryRecord
+	Price int64 `json:"price"`
+}
+
+// =========================================================================================
+// queryMarblesByPriceRange returns the marbles whose private price is between minPrice and
+// maxPrice, each joined with its public data from collectionMarbles. Organizations that
+// are not members of collectionMarblePrivateDetails get an error, not an empty result.
+// Only available on state databases that support rich query (e.g. CouchDB)
+// =========================================================================================
+func (t *SimpleChaincode) queryMarblesByPriceRange(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+
+	type priceBounds struct {
+		Gte int64 `json:"$gte"`
+		Lte int64 `json:"$lte"`
+	}
+	type priceRangeSelector struct {
+		Selector struct {
+			ObjectType string      `json:"docType"`
+			Price      priceBounds `json:"price"`
+		} `json:"selector"`
+	}
+
+	//   0      1
+	// "50", "150"
+	if len(args) != 2 {
+		return shim.Error("Incorrect number of arguments. Expecting minPrice and maxPrice")
+	}
+	minPrice, err := strconv.ParseInt(args[0], 10, 64)
+	if err != nil || minPrice <= 0 {
+		return shim.Error("minPrice must be a positive integer")
+	}
+	maxPrice, err := strconv.ParseInt(args[1], 10, 64)
+	if err != nil || maxPrice <= 0 {
+		return shim.Error("maxPrice must be a positive integer")
+	}
+	if minPrice > maxPrice {
+		return shim.Error(fmt.Sprintf("minPrice %d is larger than maxPrice %d", minPrice, maxPrice))
+	}
+
+	err = checkPrivateDetailsReadable(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	var query priceRangeSelector
+	query.Selector.ObjectType = "marblePrivateDetails"
+	query.Selector.Price = priceBounds{Gte: minPrice, Lte: maxPrice}
+	queryAsBytes, err := json.Marshal(query)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	resultsIterator, err := stub.GetPrivateDataQueryResult("collectionMarblePrivateDetails", string(queryAsBytes))
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	defer resultsIterator.Close()
+
+	records := []pricedRecord{}
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		var details marblePrivateDetails
+		err = json.Unmarshal(queryResponse.Value, &details)
+		if err != nil {
+			return shim.Error("Failed to decode JSON of: " + string(queryResponse.Value))
+		}
+
+		marbleAsBytes, err := stub.GetPrivateData("collectionMarbles", details.Name)
+		if err != nil {
+			return shim.Error("Failed to get state for " + details.Name + ": " + err.Error())
+		} else if marbleAsBytes == nil {
+			continue
+		}
+		records = append(records, pricedRecord{
+			queryRecord: queryRecord{Key: details.Name, Record: marbleAsBytes},
+			Price:       details.Price,
+		})
+	}
+
+	resultAsBytes, err := json.Marshal(records)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	return shim.Success(resultAsBytes)
+}
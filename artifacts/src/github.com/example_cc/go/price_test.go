@@ -0,0 +1,72 @@
+package main
+
+import (
+	"encoding/json"
+	"math"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func pricedMarble(name string, price json.Number) map[string]interface{} {
+	return map[string]interface{}{"marble": map[string]interface{}{
+		"name": name, "color": "blue", "size": 35, "owner": "Org1MSP", "price": price, "weight": 10,
+	}}
+}
+
+func (s *testStub) privatePrice(name string) int64 {
+	var details marblePrivateDetails
+	err := json.Unmarshal(s.mustInvoke("readMarblePrivateDetails", nil, name), &details)
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	return details.Price
+}
+
+func TestParsePrice(t *testing.T) {
+	int32Max := strconv.Itoa(math.MaxInt32)
+	int64Max := strconv.FormatInt(math.MaxInt64, 10)
+	for _, c := range []struct {
+		price string
+		want  int64
+		err   string
+	}{
+		{"1", 1, ""},
+		{int32Max, math.MaxInt32, ""},
+		{"2147483648", math.MaxInt32 + 1, ""},
+		{"10000000000", maxMarblePrice, ""},
+		{"10000000001", 0, "price field must be between 1 and 10000000000"},
+		{int64Max, 0, "price field must be between 1 and 10000000000"},
+		{"9223372036854775808", 0, "price field must be between 1 and 10000000000, got 9223372036854775808"},
+		{"0", 0, "price field must be between 1 and 10000000000"},
+		{"-1", 0, "price field must be between 1 and 10000000000"},
+		{"-2147483649", 0, "price field must be between 1 and 10000000000"},
+		{"1.5", 0, "price field must be a whole number, got 1.5"},
+		{"1e3", 0, "price field must be a whole number, got 1e3"},
+	} {
+		price, err := parsePrice(json.Number(c.price))
+		if c.err == "" && (err != nil || price != c.want) {
+			t.Fatalf("%s: expected %d, got %d and %v", c.price, c.want, price, err)
+		}
+		if c.err != "" && (err == nil || !strings.HasPrefix(err.Error(), c.err)) {
+			t.Fatalf("%s: expected error %q, got %v", c.price, c.err, err)
+		}
+	}
+}
+
+func TestPricesAboveInt32Max(t *testing.T) {
+	s := newTestStub(t)
+	s.mustInvoke("initMarble", pricedMarble("marble1", "2147483648"))
+	if price := s.privatePrice("marble1"); price != math.MaxInt32+1 {
+		t.Fatalf("expected a price of 2147483648, got %d", price)
+	}
+	s.mustInvoke("updateMarblePrice", map[string]interface{}{"marble_price": map[string]interface{}{"name": "marble1", "price": json.Number("10000000000")}})
+	if price := s.privatePrice("marble1"); price != maxMarblePrice {
+		t.Fatalf("expected a price of 10000000000, got %d", price)
+	}
+
+	s.mustFail("price field must be between 1 and 10000000000", "initMarble", pricedMarble("marble2", json.Number(strconv.FormatInt(math.MaxInt64, 10))))
+	s.mustFail("price field must be between 1 and 10000000000", "initMarble", pricedMarble("marble2", "-5"))
+	s.mustFail("price field must be a whole number", "initMarble", pricedMarble("marble2", "99.5"))
+	s.mustFail("price field must be between 1 and 10000000000", "updateMarblePrice", map[string]interface{}{"marble_price": map[string]interface{}{"name": "marble1", "price": json.Number("-1")}})
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"testing"
+)
+
+func TestNonMemberCanVerifyPrivateDataHashes(t *testing.T) {
+	s := newTestStub(t)
+	s.createMarble("marble1", "blue", 35, "Org1MSP", 99)
+	marbleHash := sha256.Sum256(s.PvtState["collectionMarbles"]["marble1"])
+	detailsHash := sha256.Sum256(s.PvtState["collectionMarblePrivateDetails"]["marble1"])
+
+	// an auditor organization in neither collection
+	s.NonMember["collectionMarbles"] = true
+	s.NonMember["collectionMarblePrivateDetails"] = true
+	s.setCaller("Org3MSP", "auditor")
+	s.mustFail("Marble does not exist: marble1", "readMarble", nil, "marble1")
+
+	var hashes map[string]string
+	err := json.Unmarshal(s.mustInvoke("getMarblePrivateDataHash", nil, "marble1"), &hashes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hashes["collectionMarbles"] != hex.EncodeToString(marbleHash[:]) {
+		t.Fatalf("unexpected marble hash %s", hashes["collectionMarbles"])
+	}
+	if hashes["collectionMarblePrivateDetails"] != hex.EncodeToString(detailsHash[:]) {
+		t.Fatalf("unexpected private details hash %s", hashes["collectionMarblePrivateDetails"])
+	}
+}
+
+func TestPrivateDataHashOfMissingData(t *testing.T) {
+	s := newTestStub(t)
+	s.mustFail("Marble does not exist: marble1", "getMarblePrivateDataHash", nil, "marble1")
+
+	s.createMarble("marble1", "blue", 35, "Org1MSP", 99)
+	delete(s.PvtState["collectionMarblePrivateDetails"], "marble1")
+	s.mustFail("Marble private details does not exist: marble1", "getMarblePrivateDataHash", nil, "marble1")
+}
@@ -0,0 +1,239 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// provenanceCertIndexName keys, in public state, the certificates marble provenance can
+// be verified against.
+const provenanceCertIndexName = "provenanceCert~id"
+
+// MarbleProvenance records where a marble comes from. It is kept in
+// collectionMarbleProvenance under the marble name.
+type MarbleProvenance struct {
+	ObjectType      string `json:"docType"`
+	MarbleName      string `json:"marbleName"`
+	Origin          string `json:"origin"`
+	RefinedBy       string `json:"refinedBy"`
+	CertifiedAt     string `json:"certifiedAt"`
+	CertificationID string `json:"certificationID"`
+}
+
+// ProvenanceCertificate is a certificate issued by a known certification authority.
+type ProvenanceCertificate struct {
+	ObjectType      string `json:"docType"`
+	CertificationID string `json:"certificationID"`
+	Issuer          string `json:"issuer"`
+}
+
+func (p *MarbleProvenance) validate() error {
+	if len(p.MarbleName) == 0 {
+		return fmt.Errorf("marbleName field must be a non-empty string")
+	}
+	if len(p.Origin) == 0 {
+		return fmt.Errorf("origin field must be a non-empty string")
+	}
+	if len(p.CertificationID) == 0 {
+		return fmt.Errorf("certificationID field must be a non-empty string")
+	}
+	return validateMarbleName(p.MarbleName)
+}
+
+func putProvenance(stub shim.ChaincodeStubInterface, provenance *MarbleProvenance) error {
+	provenance.ObjectType = "marbleProvenance"
+	provenanceAsBytes, err := json.Marshal(provenance)
+	if err != nil {
+		return err
+	}
+	return stub.PutPrivateData("collectionMarbleProvenance", provenance.MarbleName, provenanceAsBytes)
+}
+
+// getProvenance returns the provenance of a marble, or nil if none is recorded.
+func getProvenance(stub shim.ChaincodeStubInterface, marbleName string) (*MarbleProvenance, error) {
+	provenanceAsBytes, err := stub.GetPrivateData("collectionMarbleProvenance", marbleName)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get provenance: %s", err.Error())
+	} else if provenanceAsBytes == nil {
+		return nil, nil
+	}
+
+	provenance := &MarbleProvenance{}
+	err = json.Unmarshal(provenanceAsBytes, provenance)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to decode JSON of: %s", string(provenanceAsBytes))
+	}
+	return provenance, nil
+}
+
+// getProvenanceCertificate returns a known certificate, or nil if the ID is unknown.
+func getProvenanceCertificate(stub shim.ChaincodeStubInterface, certificationID string) (*ProvenanceCertificate, error) {
+	certKey, err := stub.CreateCompositeKey(provenanceCertIndexName, []string{certificationID})
+	if err != nil {
+		return nil, err
+	}
+	certAsBytes, err := stub.GetState(certKey)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get certificate: %s", err.Error())
+	} else if certAsBytes == nil {
+		return nil, nil
+	}
+
+	cert := &ProvenanceCertificate{}
+	err = json.Unmarshal(certAsBytes, cert)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to decode JSON of: %s", string(certAsBytes))
+	}
+	return cert, nil
+}
+
+// checkProvenanceRecorded fails if governance requires provenance and none is recorded
+// for the marble. A provenance recorded in the same transaction cannot be read back, so
+// initMarble only calls it when none was passed along.
+func checkProvenanceRecorded(stub shim.ChaincodeStubInterface, gov *governance, marbleName string) error {
+	if !gov.RequireProvenance {
+		return nil
+	}
+	provenance, err := getProvenance(stub, marbleName)
+	if err != nil {
+		return err
+	}
+	if provenance == nil {
+		return fmt.Errorf("marble %s has no provenance on record", marbleName)
+	}
+	return nil
+}
+
+// ===========================================================================
+// recordProvenance - provenance of a marble, recorded by an authorized
+// organization. The creator of a marble can also pass it to initMarble.
+// ===========================================================================
+func (t *SimpleChaincode) recordProvenance(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	fmt.Println("- start record provenance")
+
+	if len(args) != 0 {
+		return shim.Error("Incorrect number of arguments. Private marble data must be passed in transient map.")
+	}
+
+	var provenanceInput MarbleProvenance
+	err := getTransientInput(stub, "marble_provenance", &provenanceInput)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = provenanceInput.validate()
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	gov, err := getGovernance(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	callerMSPID, err := getCallerMSPID(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if !containsString(gov.ProvenanceRecorders, callerMSPID) {
+		return shim.Error("organization " + callerMSPID + " is not authorized to record provenance")
+	}
+
+	err = putProvenance(stub, &provenanceInput)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	fmt.Println("- end record provenance")
+	return shim.Success(nil)
+}
+
+// ===========================================================================
+// readProvenance - the provenance of a marble
+// ===========================================================================
+func (t *SimpleChaincode) readProvenance(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+
+	//   0
+	// "marble1"
+	if len(args) != 1 {
+		return shim.Error("Incorrect number of arguments. Expecting name of the marble to query")
+	}
+
+	provenanceAsBytes, err := stub.GetPrivateData("collectionMarbleProvenance", args[0])
+	if err != nil {
+		return shim.Error("Failed to get provenance for " + args[0] + ": " + err.Error())
+	} else if provenanceAsBytes == nil {
+		return shim.Error("Provenance does not exist: " + args[0])
+	}
+	return shim.Success(provenanceAsBytes)
+}
+
+// ===========================================================================
+// verifyProvenance - whether the certification of a marble's provenance is a
+// known certificate
+// ===========================================================================
+func (t *SimpleChaincode) verifyProvenance(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+
+	//   0
+	// "marble1"
+	if len(args) != 1 {
+		return shim.Error("Incorrect number of arguments. Expecting name of the marble")
+	}
+
+	provenance, err := getProvenance(stub, args[0])
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if provenance == nil {
+		return shim.Error("Provenance does not exist: " + args[0])
+	}
+	cert, err := getProvenanceCertificate(stub, provenance.CertificationID)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	return shim.Success([]byte(fmt.Sprintf("{\"verified\":%t}", cert != nil)))
+}
+
+// ===========================================================================
+// registerProvenanceCertificate - admin registration of a certificate that
+// marble provenance can be verified against
+// ===========================================================================
+func (t *SimpleChaincode) registerProvenanceCertificate(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+
+	if len(args) != 0 {
+		return shim.Error("Incorrect number of arguments. Certificate must be passed in transient map.")
+	}
+
+	err := requireAdmin(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	var cert ProvenanceCertificate
+	err = getTransientInput(stub, "provenance_certificate", &cert)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if len(cert.CertificationID) == 0 {
+		return shim.Error("certificationID field must be a non-empty string")
+	}
+	if len(cert.Issuer) == 0 {
+		return shim.Error("issuer field must be a non-empty string")
+	}
+	cert.ObjectType = "provenanceCertificate"
+
+	certKey, err := stub.CreateCompositeKey(provenanceCertIndexName, []string{cert.CertificationID})
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	certAsBytes, err := json.Marshal(cert)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = stub.PutState(certKey, certAsBytes)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	return shim.Success(nil)
+}
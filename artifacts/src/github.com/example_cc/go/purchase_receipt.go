@@ -0,0 +1,237 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// PurchaseReceipt is proof that a marble changed hands at a price. Receipts are
+// immutable: the chaincode offers no way to modify or delete them.
+type PurchaseReceipt struct {
+	ObjectType  string `json:"docType"`
+	ReceiptID   string `json:"receiptID"`
+	MarbleName  string `json:"marbleName"`
+	Seller      string `json:"seller"`
+	Buyer       string `json:"buyer"`
+	SalePrice   int    `json:"salePrice"`
+	TxID        string `json:"txID"`
+	Timestamp   string `json:"timestamp"`
+	ReceiptHash string `json:"receiptHash"`
+	// RoyaltyOwed is due to CreatorMSPID under the marble's tenure or tiered royalty
+	CreatorMSPID string `json:"creatorMSPID,omitempty"`
+	RoyaltyOwed  int    `json:"royaltyOwed"`
+}
+
+func computeReceiptHash(r *PurchaseReceipt) string {
+	hash := sha256.Sum256([]byte(r.Seller + r.Buyer + r.MarbleName + strconv.Itoa(r.SalePrice) + r.TxID))
+	return hex.EncodeToString(hash[:])
+}
+
+// ================================================================================
+// generatePurchaseReceipt - issue a receipt for the most recent sale of a marble.
+// The buyer is the current owner and the seller is the owner it was transferred from.
+// ================================================================================
+func (t *SimpleChaincode) generatePurchaseReceipt(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	fmt.Println("- start generate purchase receipt")
+
+	//     0         1
+	// "marble1", "350"
+	if len(args) != 2 {
+		return shim.Error("Incorrect number of arguments. Expecting 2")
+	}
+
+	salePrice, err := strconv.Atoi(args[1])
+	if err != nil || salePrice <= 0 {
+		return shim.Error("salePrice must be a positive integer")
+	}
+
+	sold, err := getMarble(stub, args[0])
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if len(sold.PreviousOwner) == 0 {
+		return shim.Error("Marble has never been transferred: " + sold.Name)
+	}
+
+	txTime, err := getTxTime(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	receipt := &PurchaseReceipt{
+		ObjectType: "purchaseReceipt",
+		ReceiptID:  stub.GetTxID(),
+		MarbleName: sold.Name,
+		Seller:     sold.PreviousOwner,
+		Buyer:      sold.Owner,
+		SalePrice:  salePrice,
+		TxID:       stub.GetTxID(),
+		Timestamp:  txTime.Format(time.RFC3339),
+	}
+	receipt.ReceiptHash = computeReceiptHash(receipt)
+
+	details, err := getMarblePrivateDetails(stub, sold.Name)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if details.TenureRoyalty != nil {
+		// the seller's tenure ended with the sale
+		receipt.CreatorMSPID = details.CreatorMSPID
+		receipt.RoyaltyOwed, _ = details.TenureRoyalty.compute(salePrice, sold.LastTransferTime-sold.PreviousTransferTime)
+	} else if details.TieredRoyalty != nil {
+		receipt.CreatorMSPID = details.CreatorMSPID
+		receipt.RoyaltyOwed, _ = details.TieredRoyalty.compute(salePrice)
+	}
+
+	existing, err := stub.GetPrivateData("collectionMarbleReceipts", receipt.ReceiptID)
+	if err != nil {
+		return shim.Error("Failed to get receipt: " + err.Error())
+	} else if existing != nil {
+		return shim.Error("Receipt already exists: " + receipt.ReceiptID)
+	}
+
+	receiptAsBytes, err := json.Marshal(receipt)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = stub.PutPrivateData("collectionMarbleReceipts", receipt.ReceiptID, receiptAsBytes)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	fmt.Println("- end generate purchase receipt")
+	return shim.Success(receiptAsBytes)
+}
+
+// ================================================================================
+// verifyReceipt - check a receipt hash against the stored receipt
+// ================================================================================
+func (t *SimpleChaincode) verifyReceipt(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+
+	//     0            1
+	// "receiptID", "receiptHash"
+	if len(args) != 2 {
+		return shim.Error("Incorrect number of arguments. Expecting 2")
+	}
+
+	receiptAsBytes, err := stub.GetPrivateData("collectionMarbleReceipts", args[0])
+	if err != nil {
+		return shim.Error("Failed to get receipt: " + err.Error())
+	} else if receiptAsBytes == nil {
+		return shim.Error("Receipt does not exist: " + args[0])
+	}
+
+	var receipt PurchaseReceipt
+	err = json.Unmarshal(receiptAsBytes, &receipt)
+	if err != nil {
+		return shim.Error("Failed to decode JSON of: " + string(receiptAsBytes))
+	}
+
+	valid := receipt.ReceiptHash == args[1] && computeReceiptHash(&receipt) == receipt.ReceiptHash
+	return shim.Success([]byte(fmt.Sprintf("{\"valid\":%t}", valid)))
+}
+
+// queryPurchaseReceipts scans collectionMarbleReceipts for the receipts accepted by match.
+func queryPurchaseReceipts(stub shim.ChaincodeStubInterface, match func(*PurchaseReceipt) bool) ([]PurchaseReceipt, error) {
+	resultsIterator, err := stub.GetPrivateDataByRange("collectionMarbleReceipts", "", "")
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	receipts := []PurchaseReceipt{}
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var receipt PurchaseReceipt
+		err = json.Unmarshal(queryResponse.Value, &receipt)
+		if err != nil {
+			return nil, err
+		}
+		if match(&receipt) {
+			receipts = append(receipts, receipt)
+		}
+	}
+	return receipts, nil
+}
+
+// ===========================================================================
+// getReceiptsByBuyer - list the purchases made by a buyer
+// ===========================================================================
+func (t *SimpleChaincode) getReceiptsByBuyer(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	if len(args) != 1 {
+		return shim.Error("Incorrect number of arguments. Expecting buyer to query")
+	}
+
+	receipts, err := queryPurchaseReceipts(stub, func(r *PurchaseReceipt) bool {
+		return r.Buyer == args[0]
+	})
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	receiptsAsBytes, err := json.Marshal(receipts)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	return shim.Success(receiptsAsBytes)
+}
+
+// ===========================================================================
+// getReceiptsBySeller - list the sales made by a seller
+// ===========================================================================
+func (t *SimpleChaincode) getReceiptsBySeller(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	if len(args) != 1 {
+		return shim.Error("Incorrect number of arguments. Expecting seller to query")
+	}
+
+	receipts, err := queryPurchaseReceipts(stub, func(r *PurchaseReceipt) bool {
+		return r.Seller == args[0]
+	})
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	receiptsAsBytes, err := json.Marshal(receipts)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	return shim.Success(receiptsAsBytes)
+}
+
+// ===========================================================================
+// exportReceiptsAsCSV - every receipt as CSV, with a header row
+// ===========================================================================
+func (t *SimpleChaincode) exportReceiptsAsCSV(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	if len(args) != 0 {
+		return shim.Error("Incorrect number of arguments. Expecting 0")
+	}
+
+	receipts, err := queryPurchaseReceipts(stub, func(r *PurchaseReceipt) bool { return true })
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	var buffer bytes.Buffer
+	writer := csv.NewWriter(&buffer)
+	writer.Write([]string{"receiptID", "marbleName", "seller", "buyer", "salePrice", "txID", "timestamp", "receiptHash"})
+	for _, r := range receipts {
+		writer.Write([]string{r.ReceiptID, r.MarbleName, r.Seller, r.Buyer, strconv.Itoa(r.SalePrice), r.TxID, r.Timestamp, r.ReceiptHash})
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return shim.Error(err.Error())
+	}
+
+	return shim.Success(buffer.Bytes())
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/hyperledger/fabric/protos/ledger/queryresult"
+)
+
+// awkwardRecords are keys and values a hand-built JSON encoding gets wrong.
+var awkwardRecords = map[string]string{
+	`quote"d`:     `{"docType":"marble","owner":"say \"hi\""}`,
+	`back\slash`:  `{"docType":"marble","owner":"C:\\marbles\\"}`,
+	"ünïcødé☃":    `{"docType":"marble","owner":"☃ \u2603 日本"}`,
+	"ctl\x01\x7f": `{"docType":"marble","owner":"tab\there"}`,
+}
+
+// decodeQueryRecords decodes a query result and indexes its records by key.
+func decodeQueryRecords(t *testing.T, resultAsBytes []byte) map[string]interface{} {
+	var records []struct {
+		Key    string
+		Record interface{}
+	}
+	err := json.Unmarshal(resultAsBytes, &records)
+	if err != nil {
+		t.Fatalf("query result is not valid JSON: %s\n%s", err, resultAsBytes)
+	}
+	byKey := map[string]interface{}{}
+	for _, record := range records {
+		byKey[record.Key] = record.Record
+	}
+	return byKey
+}
+
+func expectAwkwardRecords(t *testing.T, byKey map[string]interface{}) {
+	if len(byKey) != len(awkwardRecords) {
+		t.Fatalf("expected %d records, got %d", len(awkwardRecords), len(byKey))
+	}
+	for key, value := range awkwardRecords {
+		var want interface{}
+		err := json.Unmarshal([]byte(value), &want)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(byKey[key], want) {
+			t.Fatalf("record %q: expected %v, got %v", key, want, byKey[key])
+		}
+	}
+}
+
+func TestCollectQueryRecordsEscapesKeysAndValues(t *testing.T) {
+	it := &testIterator{}
+	for key, value := range awkwardRecords {
+		it.kvs = append(it.kvs, &queryresult.KV{Key: key, Value: []byte(value)})
+	}
+	records, err := collectQueryRecords(it)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resultAsBytes, err := json.Marshal(records)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectAwkwardRecords(t, decodeQueryRecords(t, resultAsBytes))
+}
+
+func TestQueriesReturnValidJSONForAwkwardRecords(t *testing.T) {
+	s := newTestStub(t)
+	s.PvtState["collectionMarbles"] = map[string][]byte{}
+	for key, value := range awkwardRecords {
+		s.PvtState["collectionMarbles"][key] = []byte(value)
+	}
+
+	expectAwkwardRecords(t, decodeQueryRecords(t, s.mustInvoke("queryMarbles", nil, `{"selector":{"docType":"marble"}}`)))
+	expectAwkwardRecords(t, decodeQueryRecords(t, s.mustInvoke("getMarblesByRange", nil, "", "")))
+}
+
+func TestQueriesRejectValuesThatAreNotJSON(t *testing.T) {
+	s := newTestStub(t)
+	s.PvtState["collectionMarbles"] = map[string][]byte{"broken": []byte(`{"docType":"marble","owner":"unterminated`)}
+	s.mustFail("json", "getMarblesByRange", nil, "", "")
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// defaultDailyMarbleQuota is the number of marbles an organization may create per day
+// until an admin sets a quota config.
+const defaultDailyMarbleQuota = 100
+
+// quotaConfigKey holds, in public state, the QuotaConfig.
+const quotaConfigKey = "quotaConfig"
+
+// quotaIndexName keys, in public state, the number of marbles an organization created
+// on a day.
+const quotaIndexName = "quota~mspid~date"
+
+// quotaDateLayout is the layout of the date component of the quota keys.
+const quotaDateLayout = "2006-01-02"
+
+// QuotaConfig limits marble creation per organization and UTC day.
+type QuotaConfig struct {
+	DailyLimit int `json:"dailyLimit"`
+}
+
+// getQuotaConfig returns the quota config, or the default quota if it has never been
+// set.
+func getQuotaConfig(stub shim.ChaincodeStubInterface) (*QuotaConfig, error) {
+	configAsBytes, err := stub.GetState(quotaConfigKey)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get quota config: %s", err.Error())
+	} else if configAsBytes == nil {
+		return &QuotaConfig{DailyLimit: defaultDailyMarbleQuota}, nil
+	}
+
+	config := &QuotaConfig{}
+	err = json.Unmarshal(configAsBytes, config)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to decode JSON of: %s", string(configAsBytes))
+	}
+	return config, nil
+}
+
+// getQuotaUsageCount returns the key counting the marbles mspID created on date, and
+// their number.
+func getQuotaUsageCount(stub shim.ChaincodeStubInterface, mspID, date string) (string, int, error) {
+	quotaKey, err := stub.CreateCompositeKey(quotaIndexName, []string{mspID, date})
+	if err != nil {
+		return "", 0, err
+	}
+	countAsBytes, err := stub.GetState(quotaKey)
+	if err != nil {
+		return "", 0, fmt.Errorf("Failed to get quota usage: %s", err.Error())
+	} else if countAsBytes == nil {
+		return quotaKey, 0, nil
+	}
+	count, err := strconv.Atoi(string(countAsBytes))
+	if err != nil {
+		return "", 0, fmt.Errorf("Failed to decode quota usage: %s", string(countAsBytes))
+	}
+	return quotaKey, count, nil
+}
+
+// consumeCreationQuota counts created marbles against the daily quota of mspID, failing
+// if they would exceed it. The day is the UTC date of the transaction timestamp. Reads
+// do not see the writes of their own transaction, so a transaction creating several
+// marbles must consume them in one call.
+func consumeCreationQuota(stub shim.ChaincodeStubInterface, mspID string, created int) error {
+	config, err := getQuotaConfig(stub)
+	if err != nil {
+		return err
+	}
+	txTime, err := getTxTime(stub)
+	if err != nil {
+		return err
+	}
+	date := txTime.Format(quotaDateLayout)
+
+	quotaKey, count, err := getQuotaUsageCount(stub, mspID, date)
+	if err != nil {
+		return err
+	}
+	if count+created > config.DailyLimit {
+		return fmt.Errorf("organization %s has already created %d of the %d marbles allowed on %s", mspID, count, config.DailyLimit, date)
+	}
+	return stub.PutState(quotaKey, []byte(strconv.Itoa(count+created)))
+}
+
+// ===========================================================================
+// setQuotaConfig - admin setting of the daily marble creation quota
+// ===========================================================================
+func (t *SimpleChaincode) setQuotaConfig(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	fmt.Println("- start set quota config")
+
+	if len(args) != 0 {
+		return shim.Error("Incorrect number of arguments. Quota config must be passed in transient map.")
+	}
+
+	err := requireAdmin(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	var config QuotaConfig
+	err = getTransientInput(stub, "quota_config", &config)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if config.DailyLimit <= 0 {
+		return shim.Error("dailyLimit field must be a positive integer")
+	}
+
+	configAsBytes, err := json.Marshal(config)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = stub.PutState(quotaConfigKey, configAsBytes)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	fmt.Println("- end set quota config")
+	return shim.Success(nil)
+}
+
+// ===========================================================================
+// getQuotaUsage - the number of marbles an organization created on a day,
+// with the daily limit
+// ===========================================================================
+func (t *SimpleChaincode) getQuotaUsage(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+
+	type quotaUsage struct {
+		MSPID      string `json:"mspid"`
+		Date       string `json:"date"`
+		Count      int    `json:"count"`
+		DailyLimit int    `json:"dailyLimit"`
+	}
+
+	//     0             1
+	// "Org1MSP", "2024-05-01"
+	if len(args) != 2 {
+		return shim.Error("Incorrect number of arguments. Expecting mspid and date")
+	}
+	if len(args[0]) == 0 {
+		return shim.Error("mspid must be a non-empty string")
+	}
+	_, err := time.Parse(quotaDateLayout, args[1])
+	if err != nil {
+		return shim.Error("date must be formatted as " + quotaDateLayout)
+	}
+
+	config, err := getQuotaConfig(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	_, count, err := getQuotaUsageCount(stub, args[0], args[1])
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	usageAsBytes, err := json.Marshal(quotaUsage{MSPID: args[0], Date: args[1], Count: count, DailyLimit: config.DailyLimit})
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	return shim.Success(usageAsBytes)
+}
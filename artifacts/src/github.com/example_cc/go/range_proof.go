@@ -0,0 +1,173 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// RangeProof attests that a marble's price was above Threshold without disclosing it.
+// Commitment is sha256(price + nonce), so whoever is given the nonce can later check that
+// the attested price is still the marble's price. It is not a zero-knowledge proof in
+// the cryptographic sense: the chaincode reads the price and vouches for the comparison.
+// It is kept in collectionMarbleProofs under the marble name, one proof per marble.
+type RangeProof struct {
+	ObjectType        string `json:"docType"`
+	MarbleName        string `json:"marbleName"`
+	Threshold         int    `json:"threshold"`
+	Commitment        string `json:"commitment"`
+	ThresholdVerified bool   `json:"thresholdVerified"`
+	Nonce             string `json:"nonce"`
+	IssuedAt          string `json:"issuedAt"`
+}
+
+func computePriceCommitment(price int64, nonce string) string {
+	hash := sha256.Sum256([]byte(strconv.FormatInt(price, 10) + nonce))
+	return hex.EncodeToString(hash[:])
+}
+
+func getRangeProofRecord(stub shim.ChaincodeStubInterface, marbleName string) (*RangeProof, error) {
+	proofAsBytes, err := stub.GetPrivateData("collectionMarbleProofs", marbleName)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get range proof: %s", err.Error())
+	} else if proofAsBytes == nil {
+		return nil, fmt.Errorf("No range proof exists for marble: %s", marbleName)
+	}
+
+	proof := &RangeProof{}
+	err = json.Unmarshal(proofAsBytes, proof)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to decode JSON of: %s", string(proofAsBytes))
+	}
+	return proof, nil
+}
+
+// ===========================================================================
+// generateRangeProof - owner attestation that a marble's price is above a
+// threshold. The nonce is derived from the transaction so that every
+// endorser computes the same commitment.
+// ===========================================================================
+func (t *SimpleChaincode) generateRangeProof(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	fmt.Println("- start generate range proof")
+
+	type rangeProofTransientInput struct {
+		MarbleName string `json:"marbleName"`
+		Threshold  int    `json:"threshold"`
+	}
+
+	if len(args) != 0 {
+		return shim.Error("Incorrect number of arguments. Private marble data must be passed in transient map.")
+	}
+
+	var proofInput rangeProofTransientInput
+	err := getTransientInput(stub, "range_proof", &proofInput)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if len(proofInput.MarbleName) == 0 {
+		return shim.Error("marbleName field must be a non-empty string")
+	}
+	if proofInput.Threshold < 0 {
+		return shim.Error("threshold field must be a non-negative integer")
+	}
+
+	m, err := getMarble(stub, proofInput.MarbleName)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = requireOwner(stub, m)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	details, err := getMarblePrivateDetails(stub, m.Name)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	txTime, err := getTxTime(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	nonceHash := sha256.Sum256([]byte(stub.GetTxID() + m.Name))
+	nonce := hex.EncodeToString(nonceHash[:])
+	proof := &RangeProof{
+		ObjectType:        "rangeProof",
+		MarbleName:        m.Name,
+		Threshold:         proofInput.Threshold,
+		Commitment:        computePriceCommitment(details.Price, nonce),
+		ThresholdVerified: details.Price > int64(proofInput.Threshold),
+		Nonce:             nonce,
+		IssuedAt:          txTime.Format(time.RFC3339),
+	}
+	proofAsBytes, err := json.Marshal(proof)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = stub.PutPrivateData("collectionMarbleProofs", proof.MarbleName, proofAsBytes)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	fmt.Println("- end generate range proof")
+	return shim.Success(proofAsBytes)
+}
+
+// ===========================================================================
+// verifyRangeProof - check a marble's range proof for a threshold and nonce
+// against the marble's current price
+// ===========================================================================
+func (t *SimpleChaincode) verifyRangeProof(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+
+	//   0         1       2
+	// "marble1", "50", "nonce"
+	if len(args) != 3 {
+		return shim.Error("Incorrect number of arguments. Expecting 3")
+	}
+	threshold, err := strconv.Atoi(args[1])
+	if err != nil {
+		return shim.Error("2nd argument must be a numeric string")
+	}
+
+	proof, err := getRangeProofRecord(stub, args[0])
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	details, err := getMarblePrivateDetails(stub, args[0])
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	// a proof stops being valid once the price it committed to has changed
+	valid := proof.Threshold == threshold && proof.Nonce == args[2] &&
+		proof.Commitment == computePriceCommitment(details.Price, args[2])
+	return shim.Success([]byte(fmt.Sprintf("{\"marbleName\":%q,\"valid\":%t,\"priceAboveThreshold\":%t}",
+		proof.MarbleName, valid, valid && proof.ThresholdVerified)))
+}
+
+// ===========================================================================
+// getRangeProof - the stored range proof of a marble
+// ===========================================================================
+func (t *SimpleChaincode) getRangeProof(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+
+	//   0
+	// "marble1"
+	if len(args) != 1 {
+		return shim.Error("Incorrect number of arguments. Expecting name of the marble to query")
+	}
+
+	proof, err := getRangeProofRecord(stub, args[0])
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	proofAsBytes, err := json.Marshal(proof)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	return shim.Success(proofAsBytes)
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// rarityLevels lists marble rarities from most to least common.
+var rarityLevels = []string{"common", "uncommon", "rare", "ultra-rare", "legendary"}
+
+// rarityIndexName keys marbles by rarity.
+const rarityIndexName = "rarity~name"
+
+// checkRarity fails unless rarity is one of the rarityLevels.
+func checkRarity(rarity string) error {
+	if !containsString(rarityLevels, rarity) {
+		return fmt.Errorf("rarity %s is not valid, expecting one of %v", rarity, rarityLevels)
+	}
+	return nil
+}
+
+// rarityOf returns the rarity of a marble. Marbles created before rarities were tracked
+// are common.
+func rarityOf(m *marble) string {
+	if len(m.Rarity) == 0 {
+		return rarityLevels[0]
+	}
+	return m.Rarity
+}
+
+func rarityIndexKey(stub shim.ChaincodeStubInterface, m *marble) (string, error) {
+	return stub.CreateCompositeKey(rarityIndexName, []string{rarityOf(m), m.Name})
+}
+
+func addRarityIndex(stub shim.ChaincodeStubInterface, m *marble) error {
+	key, err := rarityIndexKey(stub, m)
+	if err != nil {
+		return err
+	}
+	return stub.PutPrivateData("collectionMarbles", key, []byte{0x00})
+}
+
+func removeRarityIndex(stub shim.ChaincodeStubInterface, m *marble) error {
+	key, err := rarityIndexKey(stub, m)
+	if err != nil {
+		return err
+	}
+	return stub.DelPrivateData("collectionMarbles", key)
+}
+
+// ===========================================================================
+// getMarblesByRarity - the marbles of a rarity, from the rarity~name index
+// ===========================================================================
+func (t *SimpleChaincode) getMarblesByRarity(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+
+	//   0
+	// "rare"
+	if len(args) != 1 {
+		return shim.Error("Incorrect number of arguments. Expecting rarity")
+	}
+	err := checkRarity(args[0])
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	resultsIterator, err := stub.GetPrivateDataByPartialCompositeKey("collectionMarbles", rarityIndexName, []string{args[0]})
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	defer resultsIterator.Close()
+
+	records := []queryRecord{}
+	for resultsIterator.HasNext() {
+		responseRange, err := resultsIterator.Next()
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		_, compositeKeyParts, err := stub.SplitCompositeKey(responseRange.Key)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+
+		name := compositeKeyParts[1]
+		marbleAsBytes, err := stub.GetPrivateData("collectionMarbles", name)
+		if err != nil {
+			return shim.Error(err.Error())
+		} else if marbleAsBytes == nil {
+			continue
+		}
+		records = append(records, queryRecord{Key: name, Record: marbleAsBytes})
+	}
+	return marshalQueryRecords(records)
+}
+
+// ===========================================================================
+// getRarityDistribution - the number of marbles of each rarity. It walks the
+// whole rarity~name index, so it works on LevelDB as well as CouchDB.
+// ===========================================================================
+func (t *SimpleChaincode) getRarityDistribution(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	if len(args) != 0 {
+		return shim.Error("Incorrect number of arguments. Expecting 0")
+	}
+
+	distribution := map[string]int{}
+	for _, rarity := range rarityLevels {
+		distribution[rarity] = 0
+	}
+
+	resultsIterator, err := stub.GetPrivateDataByPartialCompositeKey("collectionMarbles", rarityIndexName, []string{})
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	defer resultsIterator.Close()
+
+	for resultsIterator.HasNext() {
+		responseRange, err := resultsIterator.Next()
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		_, compositeKeyParts, err := stub.SplitCompositeKey(responseRange.Key)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		distribution[compositeKeyParts[0]]++
+	}
+
+	distributionAsBytes, err := json.Marshal(distribution)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	return shim.Success(distributionAsBytes)
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// Bounds of a marble rating.
+const (
+	minRating = 1
+	maxRating = 5
+)
+
+// MarbleRatings holds the ratings of a marble by rater, each rater being the MSP ID and
+// certificate common name of a past owner's identity. It is kept in
+// collectionMarbleRatings under the marble name. A rating cannot be changed.
+type MarbleRatings struct {
+	ObjectType string         `json:"docType"`
+	MarbleName string         `json:"marbleName"`
+	Ratings    map[string]int `json:"ratings"`
+}
+
+// getMarbleRatings returns the ratings of a marble, empty if it was never rated.
+func getMarbleRatings(stub shim.ChaincodeStubInterface, marbleName string) (*MarbleRatings, error) {
+	ratingsAsBytes, err := stub.GetPrivateData("collectionMarbleRatings", marbleName)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get marble ratings: %s", err.Error())
+	} else if ratingsAsBytes == nil {
+		return &MarbleRatings{ObjectType: "marbleRatings", MarbleName: marbleName, Ratings: map[string]int{}}, nil
+	}
+
+	ratings := &MarbleRatings{}
+	err = json.Unmarshal(ratingsAsBytes, ratings)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to decode JSON of: %s", string(ratingsAsBytes))
+	}
+	if ratings.Ratings == nil {
+		ratings.Ratings = map[string]int{}
+	}
+	return ratings, nil
+}
+
+// getCallerRater returns the caller's identity as a rater, "<MSP ID>-<common name>".
+func getCallerRater(stub shim.ChaincodeStubInterface) (string, error) {
+	callerMSPID, err := getCallerMSPID(stub)
+	if err != nil {
+		return "", err
+	}
+	callerName, err := getCallerCommonName(stub)
+	if err != nil {
+		return "", err
+	}
+	return callerMSPID + "-" + callerName, nil
+}
+
+// callerOwnedMarble reports whether the caller owns or ever owned the marble. The owners
+// are read from the event log; the current and previous owner on the marble itself
+// count too, so owners whose events were pruned are not lost.
+func callerOwnedMarble(stub shim.ChaincodeStubInterface, m *marble) (bool, error) {
+	owners := []string{m.Owner, m.PreviousOwner}
+	events, err := getMarbleEvents(stub, m.Name)
+	if err != nil {
+		return false, err
+	}
+	for _, event := range events {
+		if len(event.Payload) == 0 || string(event.Payload) == "null" {
+			continue
+		}
+		var logged marble
+		err = json.Unmarshal(event.Payload, &logged)
+		if err != nil {
+			return false, err
+		}
+		if !containsString(owners, logged.Owner) {
+			owners = append(owners, logged.Owner)
+		}
+	}
+
+	for _, owner := range owners {
+		if len(owner) == 0 {
+			continue
+		}
+		isOwner, err := callerIs(stub, owner)
+		if err != nil {
+			return false, err
+		}
+		if isOwner {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// ===========================================================================
+// rateMarble - a past owner's rating of a marble's quality, from 1 to 5.
+// Each rater rates a marble once.
+// ===========================================================================
+func (t *SimpleChaincode) rateMarble(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	fmt.Println("- start rate marble")
+
+	type marbleRatingTransientInput struct {
+		Name   string `json:"name"`
+		Rating int    `json:"rating"`
+	}
+
+	if len(args) != 0 {
+		return shim.Error("Incorrect number of arguments. Private marble data must be passed in transient map.")
+	}
+
+	var ratingInput marbleRatingTransientInput
+	err := getTransientInput(stub, "marble_rating", &ratingInput)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if len(ratingInput.Name) == 0 {
+		return shim.Error("name field must be a non-empty string")
+	}
+	if ratingInput.Rating < minRating || ratingInput.Rating > maxRating {
+		return shim.Error(fmt.Sprintf("rating field must be between %d and %d", minRating, maxRating))
+	}
+
+	m, err := getMarble(stub, ratingInput.Name)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	isPastOwner, err := callerOwnedMarble(stub, m)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if !isPastOwner {
+		return shim.Error("only past owners can rate marble " + m.Name)
+	}
+
+	rater, err := getCallerRater(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	ratings, err := getMarbleRatings(stub, m.Name)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if _, ok := ratings.Ratings[rater]; ok {
+		return shim.Error(rater + " has already rated marble " + m.Name)
+	}
+	ratings.Ratings[rater] = ratingInput.Rating
+
+	ratingsAsBytes, err := json.Marshal(ratings)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = stub.PutPrivateData("collectionMarbleRatings", m.Name, ratingsAsBytes)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	fmt.Println("- end rate marble")
+	return shim.Success(nil)
+}
+
+// ===========================================================================
+// getAverageRating - the average rating of a marble and how many ratings it
+// is based on
+// ===========================================================================
+func (t *SimpleChaincode) getAverageRating(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+
+	type averageRating struct {
+		MarbleName    string  `json:"marbleName"`
+		AverageRating float64 `json:"averageRating"`
+		RatingCount   int     `json:"ratingCount"`
+	}
+
+	//   0
+	// "marble1"
+	if len(args) != 1 {
+		return shim.Error("Incorrect number of arguments. Expecting name of the marble to query")
+	}
+
+	ratings, err := getMarbleRatings(stub, args[0])
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	result := averageRating{MarbleName: args[0], RatingCount: len(ratings.Ratings)}
+	if result.RatingCount > 0 {
+		sum := 0
+		for _, rating := range ratings.Ratings {
+			sum += rating
+		}
+		result.AverageRating = float64(sum) / float64(result.RatingCount)
+	}
+
+	resultAsBytes, err := json.Marshal(result)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	return shim.Success(resultAsBytes)
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func (s *testStub) readFull(name string) map[string]json.RawMessage {
+	full := map[string]json.RawMessage{}
+	err := json.Unmarshal(s.mustInvoke("readMarbleFull", nil, name), &full)
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	return full
+}
+
+func TestReadMarbleFullMergesThePrice(t *testing.T) {
+	s := newTestStub(t)
+	s.createMarble("marble1", "blue", 35, "Org1MSP", 99)
+
+	full := s.readFull("marble1")
+	if string(full["color"]) != `"blue"` || string(full["price"]) != "99" {
+		t.Fatalf("expected the color and the price, got %v", full)
+	}
+}
+
+func TestReadMarbleFullLeavesThePriceOutOnlyWhenAccessIsDenied(t *testing.T) {
+	s := newTestStub(t)
+	s.createMarble("marble1", "blue", 35, "Org1MSP", 99)
+
+	s.NonMember["collectionMarblePrivateDetails"] = true
+	if full := s.readFull("marble1"); full["price"] != nil || full["color"] == nil {
+		t.Fatalf("expected the public fields only for a non-member, got %v", full)
+	}
+	s.NonMember["collectionMarblePrivateDetails"] = false
+
+	s.ReadErrors["collectionMarblePrivateDetails"] = errors.New("tx creator does not have read access permission on privatedata in chaincodeName:marbles collectionName: collectionMarblePrivateDetails")
+	if full := s.readFull("marble1"); full["price"] != nil || full["color"] == nil {
+		t.Fatalf("expected the public fields only without read access, got %v", full)
+	}
+
+	s.ReadErrors["collectionMarblePrivateDetails"] = errors.New("private data store unavailable")
+	s.mustFail("Failed to get private details for marble1: private data store unavailable", "readMarbleFull", nil, "marble1")
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// redemptionLock is the LockedBy value of a redeemed marble. Nothing releases it, so a
+// redeemed marble can never again change owner, be listed or be deleted.
+const redemptionLock = "redeemed"
+
+// RedemptionRecord records the redemption of a marble for its physical counterpart. It
+// is kept in collectionMarbleRedemptions under the marble name.
+type RedemptionRecord struct {
+	ObjectType      string `json:"docType"`
+	MarbleName      string `json:"marbleName"`
+	Redeemer        string `json:"redeemer"`
+	ShippingAddress string `json:"shippingAddress"`
+	RedemptionTxID  string `json:"redemptionTxID"`
+	RedeemedAt      string `json:"redeemedAt"`
+}
+
+// queryRedemptions scans collectionMarbleRedemptions for the records accepted by match.
+func queryRedemptions(stub shim.ChaincodeStubInterface, match func(*RedemptionRecord) bool) ([]RedemptionRecord, error) {
+	resultsIterator, err := stub.GetPrivateDataByRange("collectionMarbleRedemptions", "", "")
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	records := []RedemptionRecord{}
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+		var record RedemptionRecord
+		err = json.Unmarshal(queryResponse.Value, &record)
+		if err != nil {
+			return nil, err
+		}
+		if match(&record) {
+			records = append(records, record)
+		}
+	}
+	return records, nil
+}
+
+// ===========================================================================
+// redeemMarble - owner redemption of a marble for physical delivery. The
+// marble is withdrawn from sale and permanently locked.
+// ===========================================================================
+func (t *SimpleChaincode) redeemMarble(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	fmt.Println("- start redeem marble")
+
+	type redemptionTransientInput struct {
+		MarbleName      string `json:"marbleName"`
+		ShippingAddress string `json:"shippingAddress"`
+	}
+
+	if len(args) != 0 {
+		return shim.Error("Incorrect number of arguments. Private marble data must be passed in transient map.")
+	}
+
+	var redemptionInput redemptionTransientInput
+	err := getTransientInput(stub, "marble_redemption", &redemptionInput)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if len(redemptionInput.MarbleName) == 0 {
+		return shim.Error("marbleName field must be a non-empty string")
+	}
+	if len(redemptionInput.ShippingAddress) == 0 {
+		return shim.Error("shippingAddress field must be a non-empty string")
+	}
+
+	m, err := getMarble(stub, redemptionInput.MarbleName)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = requireOwner(stub, m)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	// a marble held by an escrow-like mechanism must be released first
+	err = checkNotLocked(m)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	txTime, err := getTxTime(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	if m.IsForSale {
+		err = removeListingIndex(stub, m)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		m.IsForSale = false
+		m.AskingPrice = 0
+	}
+	m.Redeemed = true
+	m.LockedBy = redemptionLock
+	err = putMarble(stub, m)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	record := &RedemptionRecord{
+		ObjectType:      "redemptionRecord",
+		MarbleName:      m.Name,
+		Redeemer:        m.Owner,
+		ShippingAddress: redemptionInput.ShippingAddress,
+		RedemptionTxID:  stub.GetTxID(),
+		RedeemedAt:      txTime.Format(time.RFC3339),
+	}
+	recordAsBytes, err := json.Marshal(record)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = stub.PutPrivateData("collectionMarbleRedemptions", record.MarbleName, recordAsBytes)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	fmt.Println("- end redeem marble")
+	return shim.Success(recordAsBytes)
+}
+
+// ===========================================================================
+// getRedemptionRecord - the redemption record of a marble
+// ===========================================================================
+func (t *SimpleChaincode) getRedemptionRecord(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+
+	//   0
+	// "marble1"
+	if len(args) != 1 {
+		return shim.Error("Incorrect number of arguments. Expecting name of the marble to query")
+	}
+
+	recordAsBytes, err := stub.GetPrivateData("collectionMarbleRedemptions", args[0])
+	if err != nil {
+		return shim.Error("Failed to get redemption record: " + err.Error())
+	} else if recordAsBytes == nil {
+		return shim.Error("Marble has not been redeemed: " + args[0])
+	}
+	return shim.Success(recordAsBytes)
+}
+
+// ===========================================================================
+// getTotalRedemptions - the number of redeemed marbles
+// ===========================================================================
+func (t *SimpleChaincode) getTotalRedemptions(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	if len(args) != 0 {
+		return shim.Error("Incorrect number of arguments. Expecting 0")
+	}
+
+	records, err := queryRedemptions(stub, func(r *RedemptionRecord) bool { return true })
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	return shim.Success([]byte(fmt.Sprintf("{\"totalRedemptions\":%d}", len(records))))
+}
+
+// ===========================================================================
+// getRedemptionsByRedeemer - list the redemptions made by a redeemer
+// ===========================================================================
+func (t *SimpleChaincode) getRedemptionsByRedeemer(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	if len(args) != 1 {
+		return shim.Error("Incorrect number of arguments. Expecting redeemer to query")
+	}
+
+	records, err := queryRedemptions(stub, func(r *RedemptionRecord) bool {
+		return r.Redeemer == args[0]
+	})
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	recordsAsBytes, err := json.Marshal(records)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	return shim.Success(recordsAsBytes)
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// registryStatsKey holds, in public state, the RegistryStats cached by the last full
+// scan. Every marble write deletes it, see logMarbleWrite.
+const registryStatsKey = "registryStats"
+
+// RegistryStats summarizes every marble in collectionMarbles. It is cached in public
+// state, so it holds no prices.
+type RegistryStats struct {
+	ObjectType   string `json:"docType"`
+	MarbleCount  int    `json:"marbleCount"`
+	UniqueOwners int    `json:"uniqueOwners"`
+	TotalSize    int64  `json:"totalSize"`
+	// ComputedAtTime is the Unix time of the transaction that scanned the marbles
+	ComputedAtTime int64 `json:"computedAtTime"`
+}
+
+// computeRegistryStats scans every marble in collectionMarbles.
+func computeRegistryStats(stub shim.ChaincodeStubInterface) (*RegistryStats, error) {
+	records, err := scanMarbles(stub, func(*marble) bool { return true })
+	if err != nil {
+		return nil, err
+	}
+	currentTime, err := getTxUnixTime(stub)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &RegistryStats{ObjectType: "registryStats", MarbleCount: len(records), ComputedAtTime: currentTime}
+	owners := map[string]bool{}
+	for _, record := range records {
+		var m marble
+		err = json.Unmarshal(record.Record, &m)
+		if err != nil {
+			return nil, err
+		}
+		owners[m.Owner] = true
+		stats.TotalSize += int64(m.Size)
+	}
+	stats.UniqueOwners = len(owners)
+	return stats, nil
+}
+
+// getCachedRegistryStats returns the cached registry stats, or nil if there are none.
+func getCachedRegistryStats(stub shim.ChaincodeStubInterface) (*RegistryStats, error) {
+	statsAsBytes, err := stub.GetState(registryStatsKey)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get registry stats: %s", err.Error())
+	} else if statsAsBytes == nil {
+		return nil, nil
+	}
+
+	stats := &RegistryStats{}
+	err = json.Unmarshal(statsAsBytes, stats)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to decode JSON of: %s", string(statsAsBytes))
+	}
+	return stats, nil
+}
+
+// invalidateRegistryStats drops the cached registry stats. It does not read the cache,
+// so concurrent marble writes do not conflict over it.
+func invalidateRegistryStats(stub shim.ChaincodeStubInterface) error {
+	return stub.DelState(registryStatsKey)
+}
+
+// ===========================================================================
+// getRegistryStats - the number of marbles, of distinct owners and the total
+// size of all marbles, from the cache when it is fresh. Otherwise the marbles
+// are scanned, and the cache is refilled when the call is submitted as a
+// transaction. Callers whose organization can read the private details also
+// get the total value, from the market cap.
+// ===========================================================================
+func (t *SimpleChaincode) getRegistryStats(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+
+	type registryStatsResult struct {
+		*RegistryStats
+		Cached     bool   `json:"cached"`
+		TotalValue *int64 `json:"totalValue,omitempty"`
+	}
+
+	if len(args) != 0 {
+		return shim.Error("Incorrect number of arguments. Expecting 0")
+	}
+
+	stats, err := getCachedRegistryStats(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	result := registryStatsResult{RegistryStats: stats, Cached: stats != nil}
+	if stats == nil {
+		stats, err = computeRegistryStats(stub)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		statsAsBytes, err := json.Marshal(stats)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		err = stub.PutState(registryStatsKey, statsAsBytes)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		result.RegistryStats = stats
+	}
+
+	// the caller's organization may not be a member of collectionMarblePrivateDetails
+	if mc, err := getMarketCapRecord(stub); err == nil {
+		result.TotalValue = &mc.MarketCap
+	}
+
+	resultAsBytes, err := json.Marshal(result)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	return shim.Success(resultAsBytes)
+}
+
+// ===========================================================================
+// invalidateRegistryCache - admin removal of the cached registry stats, so
+// the next getRegistryStats scans the marbles again
+// ===========================================================================
+func (t *SimpleChaincode) invalidateRegistryCache(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	if len(args) != 0 {
+		return shim.Error("Incorrect number of arguments. Expecting 0")
+	}
+
+	err := requireAdmin(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = invalidateRegistryStats(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	return shim.Success(nil)
+}
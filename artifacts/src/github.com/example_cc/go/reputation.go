@@ -0,0 +1,131 @@
+package main
+
+import (
+	"encoding/json"
+	"math"
+	"sort"
+	"strconv"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// Reputation changes for the outcome of a dispute or an audit.
+const (
+	disputeReputationDelta = 10
+	auditReputationDelta   = 5
+)
+
+// Weights of the seller's and the buyer's reputation in the reputation a marble
+// carries after a transfer.
+const (
+	sellerReputationWeight = 0.3
+	buyerReputationWeight  = 0.7
+)
+
+// OwnerReputation is the reputation score of an owner.
+type OwnerReputation struct {
+	MSPID string `json:"mspID"`
+	Score int    `json:"score"`
+}
+
+// ownerReputation returns an owner's score, or the default score if the owner has no
+// history.
+func (gov *governance) ownerReputation(owner string) int {
+	if score, ok := gov.OwnerReputations[owner]; ok {
+		return score
+	}
+	return gov.DefaultReputation
+}
+
+// adjustReputation changes an owner's score by delta. Scores never fall below zero.
+func (gov *governance) adjustReputation(owner string, delta int) {
+	if gov.OwnerReputations == nil {
+		gov.OwnerReputations = map[string]int{}
+	}
+	score := gov.ownerReputation(owner) + delta
+	if score < 0 {
+		score = 0
+	}
+	gov.OwnerReputations[owner] = score
+}
+
+// inheritedReputation is the reputation a marble carries once newOwner acquires it,
+// rounded to the nearest score.
+func (gov *governance) inheritedReputation(m *marble, newOwner string) int {
+	return int(math.Round(float64(m.SellerReputation)*sellerReputationWeight + float64(gov.ownerReputation(newOwner))*buyerReputationWeight))
+}
+
+// updateOwnerReputation changes an owner's score by delta. Callers that change several
+// scores in one transaction must use adjustReputation on a single governance record
+// instead, as a second read of the governance would not see the first write.
+func updateOwnerReputation(stub shim.ChaincodeStubInterface, owner string, delta int) error {
+	gov, err := getGovernance(stub)
+	if err != nil {
+		return err
+	}
+	gov.adjustReputation(owner, delta)
+	return putGovernance(stub, gov)
+}
+
+// ===========================================================================
+// getOwnerReputation - the reputation score of an owner
+// ===========================================================================
+func (t *SimpleChaincode) getOwnerReputation(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+
+	//     0
+	// "Org1MSP"
+	if len(args) != 1 {
+		return shim.Error("Incorrect number of arguments. Expecting owner to query")
+	}
+
+	gov, err := getGovernance(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	reputationAsBytes, err := json.Marshal(OwnerReputation{MSPID: args[0], Score: gov.ownerReputation(args[0])})
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	return shim.Success(reputationAsBytes)
+}
+
+// ===========================================================================
+// getTopReputationOwners - the owners with the highest reputation scores
+// ===========================================================================
+func (t *SimpleChaincode) getTopReputationOwners(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+
+	//   0
+	// "10"
+	if len(args) != 1 {
+		return shim.Error("Incorrect number of arguments. Expecting limit")
+	}
+	limit, err := strconv.Atoi(args[0])
+	if err != nil || limit <= 0 {
+		return shim.Error("limit must be a positive integer")
+	}
+
+	gov, err := getGovernance(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	reputations := []OwnerReputation{}
+	for owner, score := range gov.OwnerReputations {
+		reputations = append(reputations, OwnerReputation{MSPID: owner, Score: score})
+	}
+	sort.Slice(reputations, func(i, j int) bool {
+		if reputations[i].Score != reputations[j].Score {
+			return reputations[i].Score > reputations[j].Score
+		}
+		return reputations[i].MSPID < reputations[j].MSPID
+	})
+	if len(reputations) > limit {
+		reputations = reputations[:limit]
+	}
+
+	reputationsAsBytes, err := json.Marshal(reputations)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	return shim.Success(reputationsAsBytes)
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func (s *testStub) ownerReputation(mspID string) int {
+	var reputation OwnerReputation
+	err := json.Unmarshal(s.mustInvoke("getOwnerReputation", nil, mspID), &reputation)
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	return reputation.Score
+}
+
+func TestNewOwnerInheritsSeventyPercentOfDefaultScore(t *testing.T) {
+	s := newTestStub(t)
+	s.createMarble("marble1", "blue", 35, "Org1MSP", 99)
+	if score := s.ownerReputation("Org2MSP"); score != 100 {
+		t.Fatalf("expected Org2MSP to have the default score of 100, got %d", score)
+	}
+
+	s.mustInvoke("transferMarble", transferTo("marble1", "Org2MSP"))
+	if reputation := s.readTestMarble("marble1").SellerReputation; reputation != 70 {
+		t.Fatalf("expected the marble to carry 70%% of the default score, got %d", reputation)
+	}
+	s.setCaller("Org2MSP", "user2")
+	s.mustInvoke("transferMarble", transferTo("marble1", "Org3MSP"))
+	if reputation := s.readTestMarble("marble1").SellerReputation; reputation != 91 {
+		t.Fatalf("expected 30%% of 70 and 70%% of 100, got %d", reputation)
+	}
+}
+
+func TestDisputeResolutionUpdatesReputation(t *testing.T) {
+	s, disputeID := newDisputedMarble(t)
+	s.setCaller("Org1MSP", "admin")
+	s.mustInvoke("resolveDisputeWithSlashing", nil, disputeID, "false")
+	if score := s.ownerReputation("Org1MSP"); score != 110 {
+		t.Fatalf("expected the winning respondent to gain reputation, got %d", score)
+	}
+	if score := s.ownerReputation("Org2MSP"); score != 90 {
+		t.Fatalf("expected the losing complainant to lose reputation, got %d", score)
+	}
+
+	var top []OwnerReputation
+	err := json.Unmarshal(s.mustInvoke("getTopReputationOwners", nil, "1"), &top)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(top) != 1 || top[0].MSPID != "Org1MSP" || top[0].Score != 110 {
+		t.Fatalf("expected Org1MSP to top the reputations, got %+v", top)
+	}
+
+	s.mustInvoke("transferMarble", transferTo("marble1", "Org2MSP"))
+	if reputation := s.readTestMarble("marble1").SellerReputation; reputation != 63 {
+		t.Fatalf("expected the marble to carry 70%% of the buyer's 90, got %d", reputation)
+	}
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// isReserved reports whether the marble is reserved for a buyer at currentTime.
+func isReserved(m *marble, currentTime int64) bool {
+	return len(m.ReservedFor) != 0 && currentTime <= m.ReservedUntilTime
+}
+
+// clearReservation drops the reservation of a marble. The caller is responsible for
+// writing the marble back.
+func clearReservation(m *marble) {
+	m.ReservedFor = ""
+	m.ReservedUntilTime = 0
+}
+
+// checkNotReserved fails while the marble is reserved for a buyer.
+func checkNotReserved(stub shim.ChaincodeStubInterface, m *marble) error {
+	currentTime, err := getTxUnixTime(stub)
+	if err != nil {
+		return err
+	}
+	if isReserved(m, currentTime) {
+		return fmt.Errorf("marble %s is reserved until %d", m.Name, m.ReservedUntilTime)
+	}
+	return nil
+}
+
+// ===========================================================================
+// reserveMarble - owner reservation of a marble for a buyer. Until it lapses
+// the marble may only be transferred to the buyer and cannot be listed.
+// ===========================================================================
+func (t *SimpleChaincode) reserveMarble(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	fmt.Println("- start reserve marble")
+
+	type reservationTransientInput struct {
+		Name            string `json:"name"`
+		Buyer           string `json:"buyer"`
+		DurationSeconds int64  `json:"durationSeconds"`
+	}
+
+	if len(args) != 0 {
+		return shim.Error("Incorrect number of arguments. Private marble data must be passed in transient map.")
+	}
+
+	var reservationInput reservationTransientInput
+	err := getTransientInput(stub, "marble_reservation", &reservationInput)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if len(reservationInput.Name) == 0 {
+		return shim.Error("name field must be a non-empty string")
+	}
+	if len(reservationInput.Buyer) == 0 {
+		return shim.Error("buyer field must be a non-empty string")
+	}
+	if reservationInput.DurationSeconds <= 0 {
+		return shim.Error("durationSeconds field must be a positive integer")
+	}
+
+	m, err := getMarble(stub, reservationInput.Name)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = requireOwner(stub, m)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = checkNotLocked(m)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = checkNotArchived(m)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = checkNotReserved(stub, m)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if m.Owner == reservationInput.Buyer {
+		return shim.Error("Marble is already owned by " + m.Owner)
+	}
+	currentTime, err := getTxUnixTime(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	// a reserved marble is no longer offered to other buyers
+	if m.IsForSale {
+		err = removeListingIndex(stub, m)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		m.IsForSale = false
+		m.AskingPrice = 0
+	}
+	m.ReservedFor = reservationInput.Buyer
+	m.ReservedUntilTime = currentTime + reservationInput.DurationSeconds
+	err = putMarble(stub, m)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	fmt.Println("- end reserve marble")
+	return shim.Success(nil)
+}
+
+// ===========================================================================
+// cancelReservation - release of a reservation by the owner or the buyer
+// ===========================================================================
+func (t *SimpleChaincode) cancelReservation(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+
+	//   0
+	// "marble1"
+	if len(args) != 1 {
+		return shim.Error("Incorrect number of arguments. Expecting name of the marble")
+	}
+
+	m, err := getMarble(stub, args[0])
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if len(m.ReservedFor) == 0 {
+		return shim.Error("marble " + m.Name + " is not reserved")
+	}
+	isBuyer, err := callerIs(stub, m.ReservedFor)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if !isBuyer {
+		err = requireOwner(stub, m)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+	}
+
+	clearReservation(m)
+	err = putMarble(stub, m)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	return shim.Success(nil)
+}
@@ -0,0 +1,288 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// royaltySettlementIndexName keys the settlement of each receipt's royalty in
+// collectionMarbleReceipts.
+const royaltySettlementIndexName = "royaltySettlement~receiptID"
+
+// RoyaltyTier applies RatePercent to sale prices up to and including MaxPrice. A
+// MaxPrice of zero means no upper bound and is only allowed on the last tier.
+type RoyaltyTier struct {
+	MaxPrice    int     `json:"maxPrice"`
+	RatePercent float64 `json:"ratePercent"`
+}
+
+// TieredRoyalty is a list of royalty tiers ordered by MaxPrice. Each tier starts where
+// the previous one ends, so the tiers cover prices contiguously.
+type TieredRoyalty struct {
+	Tiers []RoyaltyTier `json:"tiers"`
+}
+
+type royaltySettlement struct {
+	ObjectType string `json:"docType"`
+	ReceiptID  string `json:"receiptID"`
+	MarbleName string `json:"marbleName"`
+	TxRef      string `json:"txRef"`
+	SettledAt  string `json:"settledAt"`
+}
+
+// validate rejects tiers that are out of order, leave a gap or have a negative rate.
+func (r *TieredRoyalty) validate() error {
+	if len(r.Tiers) == 0 {
+		return fmt.Errorf("tiers must not be empty")
+	}
+	for i, tier := range r.Tiers {
+		if tier.RatePercent < 0 || tier.RatePercent > 100 {
+			return fmt.Errorf("tiers[%d].ratePercent must be between 0 and 100", i)
+		}
+		if tier.MaxPrice < 0 {
+			return fmt.Errorf("tiers[%d].maxPrice must not be negative", i)
+		}
+		if tier.MaxPrice == 0 && i != len(r.Tiers)-1 {
+			return fmt.Errorf("only the last tier may be unbounded")
+		}
+		if i > 0 && tier.MaxPrice != 0 && tier.MaxPrice <= r.Tiers[i-1].MaxPrice {
+			return fmt.Errorf("tiers[%d].maxPrice must be above the previous tier", i)
+		}
+	}
+	return nil
+}
+
+// compute returns the royalty due on a sale and the index of the tier applied. A price
+// above every bounded tier owes no royalty and returns tier -1.
+func (r *TieredRoyalty) compute(salePrice int) (int, int) {
+	for i, tier := range r.Tiers {
+		if tier.MaxPrice == 0 || salePrice <= tier.MaxPrice {
+			return int(float64(salePrice) * tier.RatePercent / 100), i
+		}
+	}
+	return 0, -1
+}
+
+func getRoyaltySettlement(stub shim.ChaincodeStubInterface, receiptID string) (*royaltySettlement, error) {
+	settlementKey, err := stub.CreateCompositeKey(royaltySettlementIndexName, []string{receiptID})
+	if err != nil {
+		return nil, err
+	}
+	settlementAsBytes, err := stub.GetPrivateData("collectionMarbleReceipts", settlementKey)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get royalty settlement: %s", err.Error())
+	} else if settlementAsBytes == nil {
+		return nil, nil
+	}
+
+	settlement := &royaltySettlement{}
+	err = json.Unmarshal(settlementAsBytes, settlement)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to decode JSON of: %s", string(settlementAsBytes))
+	}
+	return settlement, nil
+}
+
+// unsettledRoyaltyReceipts returns the receipts accepted by match that owe a royalty
+// which has not been settled.
+func unsettledRoyaltyReceipts(stub shim.ChaincodeStubInterface, match func(*PurchaseReceipt) bool) ([]PurchaseReceipt, error) {
+	receipts, err := queryPurchaseReceipts(stub, func(r *PurchaseReceipt) bool {
+		return r.RoyaltyOwed > 0 && match(r)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	unsettled := []PurchaseReceipt{}
+	for _, receipt := range receipts {
+		settlement, err := getRoyaltySettlement(stub, receipt.ReceiptID)
+		if err != nil {
+			return nil, err
+		}
+		if settlement == nil {
+			unsettled = append(unsettled, receipt)
+		}
+	}
+	return unsettled, nil
+}
+
+// ===========================================================================
+// setTieredRoyalty - creator setting of a marble's royalty tiers
+// ===========================================================================
+func (t *SimpleChaincode) setTieredRoyalty(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	fmt.Println("- start set tiered royalty")
+
+	type tieredRoyaltyTransientInput struct {
+		Name  string        `json:"name"`
+		Tiers []RoyaltyTier `json:"tiers"`
+	}
+
+	if len(args) != 0 {
+		return shim.Error("Incorrect number of arguments. Private marble data must be passed in transient map.")
+	}
+
+	var royaltyInput tieredRoyaltyTransientInput
+	err := getTransientInput(stub, "tiered_royalty", &royaltyInput)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if len(royaltyInput.Name) == 0 {
+		return shim.Error("name field must be a non-empty string")
+	}
+	royalty := &TieredRoyalty{Tiers: royaltyInput.Tiers}
+	err = royalty.validate()
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	details, err := getMarblePrivateDetails(stub, royaltyInput.Name)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	callerMSPID, err := getCallerMSPID(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if callerMSPID != details.CreatorMSPID {
+		return shim.Error("only the creator of marble " + details.Name + " can set its royalty")
+	}
+
+	details.TieredRoyalty = royalty
+	err = putMarblePrivateDetails(stub, details)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	fmt.Println("- end set tiered royalty")
+	return shim.Success(nil)
+}
+
+// ===========================================================================
+// computeRoyalty - the royalty a sale of a marble at a price would owe
+// ===========================================================================
+func (t *SimpleChaincode) computeRoyalty(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+
+	//     0         1
+	// "marble1", "350"
+	if len(args) != 2 {
+		return shim.Error("Incorrect number of arguments. Expecting 2")
+	}
+	salePrice, err := strconv.Atoi(args[1])
+	if err != nil || salePrice <= 0 {
+		return shim.Error("salePrice must be a positive integer")
+	}
+
+	details, err := getMarblePrivateDetails(stub, args[0])
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if details.TieredRoyalty == nil {
+		return shim.Error("Marble has no tiered royalty: " + details.Name)
+	}
+
+	royalty, tier := details.TieredRoyalty.compute(salePrice)
+	return shim.Success([]byte(fmt.Sprintf("{\"marbleName\":%q,\"salePrice\":%d,\"tier\":%d,\"royalty\":%d}", details.Name, salePrice, tier, royalty)))
+}
+
+// ===========================================================================
+// getRoyaltiesOwedToCreator - unsettled royalties owed to a creator
+// ===========================================================================
+func (t *SimpleChaincode) getRoyaltiesOwedToCreator(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+
+	//     0
+	// "Org1MSP"
+	if len(args) != 1 {
+		return shim.Error("Incorrect number of arguments. Expecting creator MSP ID to query")
+	}
+
+	receipts, err := unsettledRoyaltyReceipts(stub, func(r *PurchaseReceipt) bool {
+		return r.CreatorMSPID == args[0]
+	})
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	total := 0
+	for _, receipt := range receipts {
+		total += receipt.RoyaltyOwed
+	}
+	owed := struct {
+		CreatorMSPID string            `json:"creatorMSPID"`
+		TotalOwed    int               `json:"totalOwed"`
+		Receipts     []PurchaseReceipt `json:"receipts"`
+	}{args[0], total, receipts}
+	owedAsBytes, err := json.Marshal(owed)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	return shim.Success(owedAsBytes)
+}
+
+// ===========================================================================
+// markRoyaltySettled - creator record of the off-chain payment of a marble's
+// outstanding royalties
+// ===========================================================================
+func (t *SimpleChaincode) markRoyaltySettled(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+
+	//     0          1
+	// "marble1", "txRef"
+	if len(args) != 2 {
+		return shim.Error("Incorrect number of arguments. Expecting 2")
+	}
+	if len(args[1]) == 0 {
+		return shim.Error("txRef must be a non-empty string")
+	}
+
+	details, err := getMarblePrivateDetails(stub, args[0])
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	callerMSPID, err := getCallerMSPID(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if callerMSPID != details.CreatorMSPID {
+		return shim.Error("only the creator of marble " + details.Name + " can settle its royalties")
+	}
+
+	receipts, err := unsettledRoyaltyReceipts(stub, func(r *PurchaseReceipt) bool {
+		return r.MarbleName == details.Name
+	})
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if len(receipts) == 0 {
+		return shim.Error("Marble has no unsettled royalties: " + details.Name)
+	}
+
+	txTime, err := getTxTime(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	for _, receipt := range receipts {
+		settlementAsBytes, err := json.Marshal(&royaltySettlement{
+			ObjectType: "royaltySettlement",
+			ReceiptID:  receipt.ReceiptID,
+			MarbleName: receipt.MarbleName,
+			TxRef:      args[1],
+			SettledAt:  txTime.Format(time.RFC3339),
+		})
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		settlementKey, err := stub.CreateCompositeKey(royaltySettlementIndexName, []string{receipt.ReceiptID})
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		err = stub.PutPrivateData("collectionMarbleReceipts", settlementKey, settlementAsBytes)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+	}
+	return shim.Success([]byte(fmt.Sprintf("{\"settled\":%d}", len(receipts))))
+}
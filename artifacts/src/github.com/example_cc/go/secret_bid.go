@@ -0,0 +1,439 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// secretAuctionIndexName keys the open sealed bid auction of a marble.
+const secretAuctionIndexName = "secretAuction~name"
+
+// secretBidIndexName keys sealed and revealed bids by marble and bidder.
+const secretBidIndexName = "secretBid~name~bidder"
+
+// SecretAuction is a sealed bid auction on a marble. Bids are accepted up to
+// BidDeadline and can only be revealed after it.
+type SecretAuction struct {
+	ObjectType  string `json:"docType"`
+	MarbleName  string `json:"marbleName"`
+	Seller      string `json:"seller"`
+	BidDeadline int64  `json:"bidDeadline"`
+}
+
+// SecretBid is a sealed bid. BidHash is sha256(bidderMSPID + amount + salt), computed
+// by the bidder so that the amount never leaves the client before the reveal.
+type SecretBid struct {
+	ObjectType  string `json:"docType"`
+	MarbleName  string `json:"marbleName"`
+	BidderMSPID string `json:"bidderMSPID"`
+	BidHash     string `json:"bidHash"`
+}
+
+// RevealedBid is a sealed bid whose amount has been proven against its hash. The salt
+// is not stored.
+type RevealedBid struct {
+	ObjectType  string `json:"docType"`
+	MarbleName  string `json:"marbleName"`
+	BidderMSPID string `json:"bidderMSPID"`
+	Amount      int    `json:"amount"`
+}
+
+func computeBidHash(bidderMSPID string, amount int, salt string) string {
+	hash := sha256.Sum256([]byte(bidderMSPID + strconv.Itoa(amount) + salt))
+	return hex.EncodeToString(hash[:])
+}
+
+func getSecretAuction(stub shim.ChaincodeStubInterface, marbleName string) (*SecretAuction, error) {
+	auctionKey, err := stub.CreateCompositeKey(secretAuctionIndexName, []string{marbleName})
+	if err != nil {
+		return nil, err
+	}
+	auctionAsBytes, err := stub.GetPrivateData("collectionMarbleSecretBids", auctionKey)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get secret auction: %s", err.Error())
+	} else if auctionAsBytes == nil {
+		return nil, fmt.Errorf("No secret auction is open for marble: %s", marbleName)
+	}
+
+	auction := &SecretAuction{}
+	err = json.Unmarshal(auctionAsBytes, auction)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to decode JSON of: %s", string(auctionAsBytes))
+	}
+	return auction, nil
+}
+
+// getRevealedBidList returns the revealed bids on a marble.
+func getRevealedBidList(stub shim.ChaincodeStubInterface, marbleName string) ([]RevealedBid, error) {
+	resultsIterator, err := stub.GetPrivateDataByPartialCompositeKey("collectionMarbleRevealedBids", secretBidIndexName, []string{marbleName})
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	bids := []RevealedBid{}
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+		var bid RevealedBid
+		err = json.Unmarshal(queryResponse.Value, &bid)
+		if err != nil {
+			return nil, err
+		}
+		bids = append(bids, bid)
+	}
+	return bids, nil
+}
+
+// deleteByPartialKey deletes every key of a collection under a partial composite key.
+func deleteByPartialKey(stub shim.ChaincodeStubInterface, collection, objectType string, attributes []string) error {
+	resultsIterator, err := stub.GetPrivateDataByPartialCompositeKey(collection, objectType, attributes)
+	if err != nil {
+		return err
+	}
+	defer resultsIterator.Close()
+
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return err
+		}
+		err = stub.DelPrivateData(collection, queryResponse.Key)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ===========================================================================
+// openSecretAuction - start a sealed bid auction on an owned marble
+// ===========================================================================
+func (t *SimpleChaincode) openSecretAuction(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	fmt.Println("- start open secret auction")
+
+	type secretAuctionTransientInput struct {
+		MarbleName    string `json:"marbleName"`
+		BiddingPeriod int64  `json:"biddingPeriod"` // in seconds, see getTxUnixTime
+	}
+
+	if len(args) != 0 {
+		return shim.Error("Incorrect number of arguments. Private marble data must be passed in transient map.")
+	}
+
+	var auctionInput secretAuctionTransientInput
+	err := getTransientInput(stub, "secret_auction", &auctionInput)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if len(auctionInput.MarbleName) == 0 {
+		return shim.Error("marbleName field must be a non-empty string")
+	}
+	if auctionInput.BiddingPeriod <= 0 {
+		return shim.Error("biddingPeriod field must be a positive integer")
+	}
+
+	m, err := getMarble(stub, auctionInput.MarbleName)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = requireOwner(stub, m)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = checkNotLocked(m)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if _, err := getSecretAuction(stub, m.Name); err == nil {
+		return shim.Error("A secret auction is already open for marble: " + m.Name)
+	}
+
+	currentTime, err := getTxUnixTime(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	auction := &SecretAuction{
+		ObjectType:  "secretAuction",
+		MarbleName:  m.Name,
+		Seller:      m.Owner,
+		BidDeadline: currentTime + auctionInput.BiddingPeriod,
+	}
+	auctionAsBytes, err := json.Marshal(auction)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	auctionKey, err := stub.CreateCompositeKey(secretAuctionIndexName, []string{m.Name})
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = stub.PutPrivateData("collectionMarbleSecretBids", auctionKey, auctionAsBytes)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	fmt.Println("- end open secret auction")
+	return shim.Success(auctionAsBytes)
+}
+
+// ===========================================================================
+// submitSecretBid - place or replace the caller's sealed bid on a marble
+// ===========================================================================
+func (t *SimpleChaincode) submitSecretBid(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	fmt.Println("- start submit secret bid")
+
+	type secretBidTransientInput struct {
+		MarbleName string `json:"marbleName"`
+		BidHash    string `json:"bidHash"`
+	}
+
+	if len(args) != 0 {
+		return shim.Error("Incorrect number of arguments. Private marble data must be passed in transient map.")
+	}
+
+	var bidInput secretBidTransientInput
+	err := getTransientInput(stub, "secret_bid", &bidInput)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if len(bidInput.MarbleName) == 0 {
+		return shim.Error("marbleName field must be a non-empty string")
+	}
+	if len(bidInput.BidHash) == 0 {
+		return shim.Error("bidHash field must be a non-empty string")
+	}
+
+	auction, err := getSecretAuction(stub, bidInput.MarbleName)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	currentTime, err := getTxUnixTime(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if currentTime > auction.BidDeadline {
+		return shim.Error("Bidding has closed for marble: " + auction.MarbleName)
+	}
+
+	bidderMSPID, err := getCallerMSPID(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	bidAsBytes, err := json.Marshal(&SecretBid{
+		ObjectType:  "secretBid",
+		MarbleName:  auction.MarbleName,
+		BidderMSPID: bidderMSPID,
+		BidHash:     bidInput.BidHash,
+	})
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	bidKey, err := stub.CreateCompositeKey(secretBidIndexName, []string{auction.MarbleName, bidderMSPID})
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = stub.PutPrivateData("collectionMarbleSecretBids", bidKey, bidAsBytes)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	fmt.Println("- end submit secret bid")
+	return shim.Success(nil)
+}
+
+// ===========================================================================
+// revealSecretBid - prove the amount of the caller's sealed bid
+// ===========================================================================
+func (t *SimpleChaincode) revealSecretBid(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	fmt.Println("- start reveal secret bid")
+
+	type revealBidTransientInput struct {
+		MarbleName string `json:"marbleName"`
+		Amount     int    `json:"amount"`
+		Salt       string `json:"salt"`
+	}
+
+	if len(args) != 0 {
+		return shim.Error("Incorrect number of arguments. Private marble data must be passed in transient map.")
+	}
+
+	var revealInput revealBidTransientInput
+	err := getTransientInput(stub, "secret_bid_reveal", &revealInput)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if len(revealInput.MarbleName) == 0 {
+		return shim.Error("marbleName field must be a non-empty string")
+	}
+	if revealInput.Amount <= 0 {
+		return shim.Error("amount field must be a positive integer")
+	}
+
+	auction, err := getSecretAuction(stub, revealInput.MarbleName)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	currentTime, err := getTxUnixTime(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if currentTime <= auction.BidDeadline {
+		return shim.Error("Bids cannot be revealed before the bid deadline of marble: " + auction.MarbleName)
+	}
+
+	bidderMSPID, err := getCallerMSPID(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	bidKey, err := stub.CreateCompositeKey(secretBidIndexName, []string{auction.MarbleName, bidderMSPID})
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	bidAsBytes, err := stub.GetPrivateData("collectionMarbleSecretBids", bidKey)
+	if err != nil {
+		return shim.Error("Failed to get secret bid: " + err.Error())
+	} else if bidAsBytes == nil {
+		return shim.Error("No secret bid from " + bidderMSPID + " on marble: " + auction.MarbleName)
+	}
+	var bid SecretBid
+	err = json.Unmarshal(bidAsBytes, &bid)
+	if err != nil {
+		return shim.Error("Failed to decode JSON of: " + string(bidAsBytes))
+	}
+	if computeBidHash(bidderMSPID, revealInput.Amount, revealInput.Salt) != bid.BidHash {
+		return shim.Error("Revealed amount and salt do not match the secret bid")
+	}
+
+	revealedAsBytes, err := json.Marshal(&RevealedBid{
+		ObjectType:  "revealedBid",
+		MarbleName:  auction.MarbleName,
+		BidderMSPID: bidderMSPID,
+		Amount:      revealInput.Amount,
+	})
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = stub.PutPrivateData("collectionMarbleRevealedBids", bidKey, revealedAsBytes)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	fmt.Println("- end reveal secret bid")
+	return shim.Success(nil)
+}
+
+// ===========================================================================
+// closeSecretAuction - transfer the marble to the highest revealed bidder.
+// Bids that were not revealed before the close are disqualified.
+// ===========================================================================
+func (t *SimpleChaincode) closeSecretAuction(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	fmt.Println("- start close secret auction")
+
+	//   0
+	// "marble1"
+	if len(args) != 1 {
+		return shim.Error("Incorrect number of arguments. Expecting name of the auctioned marble")
+	}
+
+	auction, err := getSecretAuction(stub, args[0])
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	m, err := getMarble(stub, auction.MarbleName)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = requireOwner(stub, m)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	currentTime, err := getTxUnixTime(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if currentTime <= auction.BidDeadline {
+		return shim.Error("Secret auction cannot be closed before the bid deadline of marble: " + auction.MarbleName)
+	}
+
+	bids, err := getRevealedBidList(stub, auction.MarbleName)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	var winner *RevealedBid
+	for i := range bids {
+		if winner == nil || bids[i].Amount > winner.Amount {
+			winner = &bids[i]
+		}
+	}
+	if winner != nil {
+		err = changeMarbleOwner(stub, m, winner.BidderMSPID)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		err = putMarble(stub, m)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+	}
+
+	// ==== Remove the auction with its sealed and revealed bids ====
+	auctionKey, err := stub.CreateCompositeKey(secretAuctionIndexName, []string{auction.MarbleName})
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = stub.DelPrivateData("collectionMarbleSecretBids", auctionKey)
+	if err != nil {
+		return shim.Error("Failed to delete state:" + err.Error())
+	}
+	err = deleteByPartialKey(stub, "collectionMarbleSecretBids", secretBidIndexName, []string{auction.MarbleName})
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = deleteByPartialKey(stub, "collectionMarbleRevealedBids", secretBidIndexName, []string{auction.MarbleName})
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	result := struct {
+		MarbleName string       `json:"marbleName"`
+		Winner     *RevealedBid `json:"winner"`
+		Bids       int          `json:"revealedBids"`
+	}{auction.MarbleName, winner, len(bids)}
+	resultAsBytes, err := json.Marshal(result)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	fmt.Println("- end close secret auction")
+	return shim.Success(resultAsBytes)
+}
+
+// ===========================================================================
+// getRevealedBids - list the revealed bid amounts on a marble
+// ===========================================================================
+func (t *SimpleChaincode) getRevealedBids(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+
+	//   0
+	// "marble1"
+	if len(args) != 1 {
+		return shim.Error("Incorrect number of arguments. Expecting name of the auctioned marble")
+	}
+
+	bids, err := getRevealedBidList(stub, args[0])
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	bidsAsBytes, err := json.Marshal(bids)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	return shim.Success(bidsAsBytes)
+}
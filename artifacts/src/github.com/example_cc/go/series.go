@@ -0,0 +1,217 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// seriesIndexName keys the marbles of each series. Marbles outside any series are not
+// indexed.
+const seriesIndexName = "series~name"
+
+// seriesRegistryKey holds, in public state, the JSON array of every series a marble was
+// ever created in.
+const seriesRegistryKey = "seriesRegistry"
+
+func addSeriesIndex(stub shim.ChaincodeStubInterface, m *marble) error {
+	if len(m.Series) == 0 {
+		return nil
+	}
+	key, err := stub.CreateCompositeKey(seriesIndexName, []string{m.Series, m.Name})
+	if err != nil {
+		return err
+	}
+	return stub.PutPrivateData("collectionMarbles", key, []byte{0x00})
+}
+
+func removeSeriesIndex(stub shim.ChaincodeStubInterface, m *marble) error {
+	if len(m.Series) == 0 {
+		return nil
+	}
+	key, err := stub.CreateCompositeKey(seriesIndexName, []string{m.Series, m.Name})
+	if err != nil {
+		return err
+	}
+	return stub.DelPrivateData("collectionMarbles", key)
+}
+
+func getSeriesRegistry(stub shim.ChaincodeStubInterface) ([]string, error) {
+	registryAsBytes, err := stub.GetState(seriesRegistryKey)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get series registry: %s", err.Error())
+	} else if registryAsBytes == nil {
+		return []string{}, nil
+	}
+
+	var registry []string
+	err = json.Unmarshal(registryAsBytes, &registry)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to decode JSON of: %s", string(registryAsBytes))
+	}
+	return registry, nil
+}
+
+// registerSeries adds the series of new marbles to the series registry, skipping empty
+// ones. Reads do not see the writes of their own transaction, so a transaction creating
+// several marbles must register all their series in one call.
+func registerSeries(stub shim.ChaincodeStubInterface, series []string) error {
+	registry, err := getSeriesRegistry(stub)
+	if err != nil {
+		return err
+	}
+	changed := false
+	for _, s := range series {
+		if len(s) != 0 && !containsString(registry, s) {
+			registry = append(registry, s)
+			changed = true
+		}
+	}
+	if !changed {
+		return nil
+	}
+	registryAsBytes, err := json.Marshal(registry)
+	if err != nil {
+		return err
+	}
+	return stub.PutState(seriesRegistryKey, registryAsBytes)
+}
+
+// getSeriesMarbles reads every marble listed under series in the series~name index.
+func getSeriesMarbles(stub shim.ChaincodeStubInterface, series string) ([]queryRecord, error) {
+	resultsIterator, err := stub.GetPrivateDataByPartialCompositeKey("collectionMarbles", seriesIndexName, []string{series})
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	records := []queryRecord{}
+	for resultsIterator.HasNext() {
+		responseRange, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+		_, compositeKeyParts, err := stub.SplitCompositeKey(responseRange.Key)
+		if err != nil {
+			return nil, err
+		}
+
+		name := compositeKeyParts[1]
+		marbleAsBytes, err := stub.GetPrivateData("collectionMarbles", name)
+		if err != nil {
+			return nil, err
+		} else if marbleAsBytes == nil {
+			continue
+		}
+		records = append(records, queryRecord{Key: name, Record: marbleAsBytes})
+	}
+	return records, nil
+}
+
+// ===========================================================================
+// getMarblesBySeries - the marbles of a series, from the series~name index
+// ===========================================================================
+func (t *SimpleChaincode) getMarblesBySeries(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+
+	//       0
+	// "Galaxy 2024"
+	if len(args) != 1 {
+		return shim.Error("Incorrect number of arguments. Expecting series")
+	}
+	if len(args[0]) == 0 {
+		return shim.Error("series must be a non-empty string")
+	}
+
+	records, err := getSeriesMarbles(stub, args[0])
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	return marshalQueryRecords(records)
+}
+
+// ===========================================================================
+// getSeriesStats - the size, total price and owners of a series. The total
+// is zero for callers whose organization cannot read the marble prices.
+// ===========================================================================
+func (t *SimpleChaincode) getSeriesStats(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+
+	type seriesStats struct {
+		Series     string   `json:"series"`
+		Count      int      `json:"count"`
+		TotalValue int64    `json:"totalValue"`
+		Owners     []string `json:"owners"`
+	}
+
+	//       0
+	// "Galaxy 2024"
+	if len(args) != 1 {
+		return shim.Error("Incorrect number of arguments. Expecting series")
+	}
+	if len(args[0]) == 0 {
+		return shim.Error("series must be a non-empty string")
+	}
+
+	records, err := getSeriesMarbles(stub, args[0])
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	stats := seriesStats{Series: args[0], Count: len(records), Owners: []string{}}
+	pricesReadable := true
+	for _, record := range records {
+		var m marble
+		err = json.Unmarshal(record.Record, &m)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		if !containsString(stats.Owners, m.Owner) {
+			stats.Owners = append(stats.Owners, m.Owner)
+		}
+
+		if !pricesReadable {
+			continue
+		}
+		detailsAsBytes, err := stub.GetPrivateData("collectionMarblePrivateDetails", m.Name)
+		if err != nil || detailsAsBytes == nil {
+			// the caller's organization is not a member of the collection
+			pricesReadable = false
+			stats.TotalValue = 0
+			continue
+		}
+		var details marblePrivateDetails
+		err = json.Unmarshal(detailsAsBytes, &details)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		stats.TotalValue += details.Price
+	}
+	sort.Strings(stats.Owners)
+
+	statsAsBytes, err := json.Marshal(stats)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	return shim.Success(statsAsBytes)
+}
+
+// ===========================================================================
+// listSeries - every series a marble was ever created in
+// ===========================================================================
+func (t *SimpleChaincode) listSeries(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	if len(args) != 0 {
+		return shim.Error("Incorrect number of arguments. Expecting 0")
+	}
+
+	registry, err := getSeriesRegistry(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	registryAsBytes, err := json.Marshal(registry)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	return shim.Success(registryAsBytes)
+}
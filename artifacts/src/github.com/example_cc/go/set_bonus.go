@@ -0,0 +1,272 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// setBonusObjectType keys set bonus definitions in public state.
+const setBonusObjectType = "setBonus"
+
+// SetBonus rewards an owner holding at least one marble of every color in ColorSet.
+type SetBonus struct {
+	ObjectType      string   `json:"docType"`
+	SetName         string   `json:"setName"`
+	ColorSet        []string `json:"colorSet"`
+	BonusMultiplier float64  `json:"bonusMultiplier"`
+}
+
+// completedBy reports whether the colors held by an owner cover the whole set.
+func (b *SetBonus) completedBy(heldColors map[string]bool) bool {
+	for _, color := range b.ColorSet {
+		if !heldColors[color] {
+			return false
+		}
+	}
+	return true
+}
+
+func getSetBonuses(stub shim.ChaincodeStubInterface) ([]SetBonus, error) {
+	resultsIterator, err := stub.GetStateByPartialCompositeKey(setBonusObjectType, []string{})
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	bonuses := []SetBonus{}
+	for resultsIterator.HasNext() {
+		responseRange, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+		var bonus SetBonus
+		err = json.Unmarshal(responseRange.Value, &bonus)
+		if err != nil {
+			return nil, err
+		}
+		bonuses = append(bonuses, bonus)
+	}
+	return bonuses, nil
+}
+
+// getOwnedMarbles reads every marble listed under owner in the owner~name index.
+func getOwnedMarbles(stub shim.ChaincodeStubInterface, owner string) ([]*marble, error) {
+	names, err := getMarbleNamesByOwner(stub, owner)
+	if err != nil {
+		return nil, err
+	}
+
+	owned := make([]*marble, 0, len(names))
+	for _, name := range names {
+		m, err := getMarble(stub, name)
+		if err != nil {
+			return nil, err
+		}
+		owned = append(owned, m)
+	}
+	return owned, nil
+}
+
+// getCompletedSets returns the set bonuses completed by an owner along with the owner's
+// marbles.
+func getCompletedSets(stub shim.ChaincodeStubInterface, owner string) ([]SetBonus, []*marble, error) {
+	owned, err := getOwnedMarbles(stub, owner)
+	if err != nil {
+		return nil, nil, err
+	}
+	heldColors := map[string]bool{}
+	for _, m := range owned {
+		heldColors[m.Color] = true
+	}
+
+	bonuses, err := getSetBonuses(stub)
+	if err != nil {
+		return nil, nil, err
+	}
+	completed := []SetBonus{}
+	for _, bonus := range bonuses {
+		if bonus.completedBy(heldColors) {
+			completed = append(completed, bonus)
+		}
+	}
+	return completed, owned, nil
+}
+
+// ===========================================================================
+// createSetBonus - admin definition of a color set bonus
+// ===========================================================================
+func (t *SimpleChaincode) createSetBonus(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	fmt.Println("- start create set bonus")
+
+	if len(args) != 0 {
+		return shim.Error("Incorrect number of arguments. Set bonus data must be passed in transient map.")
+	}
+
+	err := requireAdmin(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	var bonus SetBonus
+	err = getTransientInput(stub, "set_bonus", &bonus)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	if len(bonus.SetName) == 0 {
+		return shim.Error("setName field must be a non-empty string")
+	}
+	if len(bonus.ColorSet) == 0 {
+		return shim.Error("colorSet field must be a non-empty array")
+	}
+	for _, color := range bonus.ColorSet {
+		if len(color) == 0 {
+			return shim.Error("colorSet entries must be non-empty strings")
+		}
+	}
+	if bonus.BonusMultiplier < 1 {
+		return shim.Error("bonusMultiplier field must be at least 1")
+	}
+	bonus.ObjectType = setBonusObjectType
+
+	bonusKey, err := stub.CreateCompositeKey(setBonusObjectType, []string{bonus.SetName})
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	bonusAsBytes, err := json.Marshal(bonus)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = stub.PutState(bonusKey, bonusAsBytes)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	fmt.Println("- end create set bonus")
+	return shim.Success(nil)
+}
+
+// ===========================================================================
+// checkSetBonus - the highest set bonus an owner currently qualifies for
+// ===========================================================================
+func (t *SimpleChaincode) checkSetBonus(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+
+	type setBonusStatus struct {
+		BonusActive     bool    `json:"bonusActive"`
+		BonusMultiplier float64 `json:"bonusMultiplier"`
+		SetName         string  `json:"setName"`
+	}
+
+	//   0
+	// "bob"
+	if len(args) != 1 {
+		return shim.Error("Incorrect number of arguments. Expecting owner to query")
+	}
+
+	completed, _, err := getCompletedSets(stub, args[0])
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	status := setBonusStatus{BonusMultiplier: 1}
+	for _, bonus := range completed {
+		if bonus.BonusMultiplier > status.BonusMultiplier || !status.BonusActive {
+			status = setBonusStatus{BonusActive: true, BonusMultiplier: bonus.BonusMultiplier, SetName: bonus.SetName}
+		}
+	}
+
+	statusAsBytes, err := json.Marshal(status)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	return shim.Success(statusAsBytes)
+}
+
+// ===========================================================================================
+// applySetBonusToAppraisal values each of an owner's marbles at its price times the highest
+// multiplier of the completed sets containing its color. Nothing is written to state.
+// ===========================================================================================
+func (t *SimpleChaincode) applySetBonusToAppraisal(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+
+	type marbleAppraisal struct {
+		Name            string  `json:"name"`
+		BaseValue       int64   `json:"baseValue"`
+		BonusMultiplier float64 `json:"bonusMultiplier"`
+		AppraisedValue  float64 `json:"appraisedValue"`
+	}
+
+	type portfolioAppraisal struct {
+		Owner      string            `json:"owner"`
+		Marbles    []marbleAppraisal `json:"marbles"`
+		TotalValue float64           `json:"totalValue"`
+	}
+
+	//   0
+	// "bob"
+	if len(args) != 1 {
+		return shim.Error("Incorrect number of arguments. Expecting owner to query")
+	}
+
+	completed, owned, err := getCompletedSets(stub, args[0])
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	appraisal := portfolioAppraisal{Owner: args[0], Marbles: []marbleAppraisal{}}
+	for _, m := range owned {
+		details, err := getMarblePrivateDetails(stub, m.Name)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+
+		multiplier := 1.0
+		for _, bonus := range completed {
+			for _, color := range bonus.ColorSet {
+				if color == m.Color && bonus.BonusMultiplier > multiplier {
+					multiplier = bonus.BonusMultiplier
+				}
+			}
+		}
+
+		value := float64(details.Price) * multiplier
+		appraisal.Marbles = append(appraisal.Marbles, marbleAppraisal{
+			Name:            m.Name,
+			BaseValue:       details.Price,
+			BonusMultiplier: multiplier,
+			AppraisedValue:  value,
+		})
+		appraisal.TotalValue += value
+	}
+
+	appraisalAsBytes, err := json.Marshal(appraisal)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	return shim.Success(appraisalAsBytes)
+}
+
+// ===========================================================================
+// listCompletedSetsByOwner - the set bonuses an owner has completed
+// ===========================================================================
+func (t *SimpleChaincode) listCompletedSetsByOwner(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+
+	//   0
+	// "bob"
+	if len(args) != 1 {
+		return shim.Error("Incorrect number of arguments. Expecting owner to query")
+	}
+
+	completed, _, err := getCompletedSets(stub, args[0])
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	completedAsBytes, err := json.Marshal(completed)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	return shim.Success(completedAsBytes)
+}
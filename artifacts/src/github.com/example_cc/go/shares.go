@@ -0,0 +1,463 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// totalShares is what the shares of a jointly owned marble add up to, so a share is one
+// percent of the marble.
+const totalShares = 100
+
+// Decisions on a jointly owned marble that need the approval of its shareholders.
+// Transfers and deletions need a majority of the shares, reallocations all of them.
+const (
+	shareActionTransfer = "transfer"
+	shareActionDelete   = "delete"
+	shareActionAllocate = "allocate"
+)
+
+// shareActionIndexName keys the pending action of each kind on each marble.
+const shareActionIndexName = "shareAction~name~action"
+
+// MarbleShares splits the ownership of a marble between shareholders. It is kept in
+// collectionMarbleShares under the marble name; marbles without it are not jointly
+// owned and need no approvals.
+type MarbleShares struct {
+	ObjectType string         `json:"docType"`
+	MarbleName string         `json:"marbleName"`
+	Shares     map[string]int `json:"shares"`
+}
+
+// PendingAction collects shareholder approvals of a decision on a jointly owned marble.
+// NewOwner is the recipient of a transfer and Shares the proposed allocation of a
+// reallocation. Approving different parameters starts the collection over.
+type PendingAction struct {
+	ObjectType string         `json:"docType"`
+	MarbleName string         `json:"marbleName"`
+	Action     string         `json:"action"`
+	NewOwner   string         `json:"newOwner,omitempty"`
+	Shares     map[string]int `json:"shares,omitempty"`
+	Approvals  []string       `json:"approvals"`
+}
+
+// validateShares fails unless every shareholder holds a positive number of shares and
+// the shares add up to totalShares.
+func validateShares(shares map[string]int) error {
+	sum := 0
+	for shareholder, amount := range shares {
+		if len(shareholder) == 0 {
+			return fmt.Errorf("shareholders must be non-empty strings")
+		}
+		if amount <= 0 {
+			return fmt.Errorf("shares of %s must be a positive integer", shareholder)
+		}
+		sum += amount
+	}
+	if sum != totalShares {
+		return fmt.Errorf("shares must add up to %d, got %d", totalShares, sum)
+	}
+	return nil
+}
+
+func sharesEqual(a, b map[string]int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for shareholder, amount := range a {
+		if b[shareholder] != amount {
+			return false
+		}
+	}
+	return true
+}
+
+// approvedShares returns the shares held by the shareholders who approved the action.
+func (s *MarbleShares) approvedShares(action *PendingAction) int {
+	approved := 0
+	for _, shareholder := range action.Approvals {
+		approved += s.Shares[shareholder]
+	}
+	return approved
+}
+
+// callerShareholder returns the shareholder the caller is, failing if it holds no shares.
+func (s *MarbleShares) callerShareholder(stub shim.ChaincodeStubInterface) (string, error) {
+	for shareholder := range s.Shares {
+		isShareholder, err := callerIs(stub, shareholder)
+		if err != nil {
+			return "", err
+		}
+		if isShareholder {
+			return shareholder, nil
+		}
+	}
+	return "", fmt.Errorf("caller holds no shares of marble %s", s.MarbleName)
+}
+
+// getMarbleShares returns the shares of a marble, or nil if it is not jointly owned.
+func getMarbleShares(stub shim.ChaincodeStubInterface, marbleName string) (*MarbleShares, error) {
+	sharesAsBytes, err := stub.GetPrivateData("collectionMarbleShares", marbleName)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get marble shares: %s", err.Error())
+	} else if sharesAsBytes == nil {
+		return nil, nil
+	}
+
+	shares := &MarbleShares{}
+	err = json.Unmarshal(sharesAsBytes, shares)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to decode JSON of: %s", string(sharesAsBytes))
+	}
+	return shares, nil
+}
+
+func putMarbleShares(stub shim.ChaincodeStubInterface, shares *MarbleShares) error {
+	sharesAsBytes, err := json.Marshal(shares)
+	if err != nil {
+		return err
+	}
+	return stub.PutPrivateData("collectionMarbleShares", shares.MarbleName, sharesAsBytes)
+}
+
+// getPendingAction returns the pending action of a kind on a marble, or nil if there is
+// none.
+func getPendingAction(stub shim.ChaincodeStubInterface, marbleName, action string) (*PendingAction, error) {
+	actionKey, err := stub.CreateCompositeKey(shareActionIndexName, []string{marbleName, action})
+	if err != nil {
+		return nil, err
+	}
+	actionAsBytes, err := stub.GetPrivateData("collectionMarbleShares", actionKey)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get pending action: %s", err.Error())
+	} else if actionAsBytes == nil {
+		return nil, nil
+	}
+
+	pending := &PendingAction{}
+	err = json.Unmarshal(actionAsBytes, pending)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to decode JSON of: %s", string(actionAsBytes))
+	}
+	return pending, nil
+}
+
+func putPendingAction(stub shim.ChaincodeStubInterface, pending *PendingAction) error {
+	actionKey, err := stub.CreateCompositeKey(shareActionIndexName, []string{pending.MarbleName, pending.Action})
+	if err != nil {
+		return err
+	}
+	actionAsBytes, err := json.Marshal(pending)
+	if err != nil {
+		return err
+	}
+	return stub.PutPrivateData("collectionMarbleShares", actionKey, actionAsBytes)
+}
+
+func delPendingAction(stub shim.ChaincodeStubInterface, pending *PendingAction) error {
+	actionKey, err := stub.CreateCompositeKey(shareActionIndexName, []string{pending.MarbleName, pending.Action})
+	if err != nil {
+		return err
+	}
+	return stub.DelPrivateData("collectionMarbleShares", actionKey)
+}
+
+// findApproval fails unless the shareholders of a jointly owned marble approved the
+// action with these parameters, holding more than minShares between them. It returns
+// the approved action, or nil for marbles that are not jointly owned and need no
+// approval.
+func findApproval(stub shim.ChaincodeStubInterface, marbleName, action, newOwner string, shares map[string]int, minShares int) (*PendingAction, error) {
+	marbleShares, err := getMarbleShares(stub, marbleName)
+	if err != nil {
+		return nil, err
+	}
+	if marbleShares == nil {
+		return nil, nil
+	}
+	pending, err := getPendingAction(stub, marbleName, action)
+	if err != nil {
+		return nil, err
+	}
+	if pending == nil || pending.NewOwner != newOwner || !sharesEqual(pending.Shares, shares) {
+		return nil, fmt.Errorf("%s of marble %s has not been approved by its shareholders", action, marbleName)
+	}
+	if approved := marbleShares.approvedShares(pending); approved <= minShares {
+		return nil, fmt.Errorf("%s of marble %s is approved by %d of %d shares, more than %d are needed", action, marbleName, approved, totalShares, minShares)
+	}
+	return pending, nil
+}
+
+// consumeApproval is findApproval that uses the approval up.
+func consumeApproval(stub shim.ChaincodeStubInterface, marbleName, action, newOwner string, shares map[string]int, minShares int) error {
+	pending, err := findApproval(stub, marbleName, action, newOwner, shares, minShares)
+	if err != nil || pending == nil {
+		return err
+	}
+	return delPendingAction(stub, pending)
+}
+
+// requireShareholderApproval fails unless shareholders holding a majority of the shares
+// of a jointly owned marble approved the transfer to newOwner, or the deletion when
+// newOwner is empty. The approval is used up.
+func requireShareholderApproval(stub shim.ChaincodeStubInterface, marbleName, action, newOwner string) error {
+	return consumeApproval(stub, marbleName, action, newOwner, nil, totalShares/2)
+}
+
+// checkShareholderApproval is requireShareholderApproval without using the approval up.
+func checkShareholderApproval(stub shim.ChaincodeStubInterface, marbleName, action, newOwner string) error {
+	_, err := findApproval(stub, marbleName, action, newOwner, nil, totalShares/2)
+	return err
+}
+
+// removeMarbleShares drops the shares of a deleted marble and its pending actions.
+func removeMarbleShares(stub shim.ChaincodeStubInterface, marbleName string) error {
+	err := stub.DelPrivateData("collectionMarbleShares", marbleName)
+	if err != nil {
+		return err
+	}
+	return deleteByPartialKey(stub, "collectionMarbleShares", shareActionIndexName, []string{marbleName})
+}
+
+// ===========================================================================
+// allocateShares - split the ownership of a marble between shareholders. The
+// owner sets up the first allocation; a reallocation needs the approval of
+// every shareholder through approvePendingAction.
+// ===========================================================================
+func (t *SimpleChaincode) allocateShares(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	fmt.Println("- start allocate shares")
+
+	type shareAllocationTransientInput struct {
+		MarbleName string         `json:"marbleName"`
+		Shares     map[string]int `json:"shares"`
+	}
+
+	if len(args) != 0 {
+		return shim.Error("Incorrect number of arguments. Private marble data must be passed in transient map.")
+	}
+
+	var allocationInput shareAllocationTransientInput
+	err := getTransientInput(stub, "share_allocation", &allocationInput)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if len(allocationInput.MarbleName) == 0 {
+		return shim.Error("marbleName field must be a non-empty string")
+	}
+	err = validateShares(allocationInput.Shares)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	m, err := getMarble(stub, allocationInput.MarbleName)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	marbleShares, err := getMarbleShares(stub, m.Name)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if marbleShares == nil {
+		err = requireOwner(stub, m)
+	} else {
+		err = consumeApproval(stub, m.Name, shareActionAllocate, "", allocationInput.Shares, totalShares-1)
+	}
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	err = putMarbleShares(stub, &MarbleShares{
+		ObjectType: "marbleShares",
+		MarbleName: m.Name,
+		Shares:     allocationInput.Shares,
+	})
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	fmt.Println("- end allocate shares")
+	return shim.Success(nil)
+}
+
+// ===========================================================================
+// transferShares - a shareholder's transfer of some of its shares
+// ===========================================================================
+func (t *SimpleChaincode) transferShares(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	fmt.Println("- start transfer shares")
+
+	type shareTransferTransientInput struct {
+		MarbleName string `json:"marbleName"`
+		From       string `json:"from"`
+		To         string `json:"to"`
+		Amount     int    `json:"amount"`
+	}
+
+	if len(args) != 0 {
+		return shim.Error("Incorrect number of arguments. Private marble data must be passed in transient map.")
+	}
+
+	var transferInput shareTransferTransientInput
+	err := getTransientInput(stub, "share_transfer", &transferInput)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if len(transferInput.MarbleName) == 0 {
+		return shim.Error("marbleName field must be a non-empty string")
+	}
+	if len(transferInput.From) == 0 {
+		return shim.Error("from field must be a non-empty string")
+	}
+	if len(transferInput.To) == 0 {
+		return shim.Error("to field must be a non-empty string")
+	}
+	if transferInput.From == transferInput.To {
+		return shim.Error("from and to must differ")
+	}
+	if transferInput.Amount <= 0 {
+		return shim.Error("amount field must be a positive integer")
+	}
+
+	marbleShares, err := getMarbleShares(stub, transferInput.MarbleName)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if marbleShares == nil {
+		return shim.Error("Marble is not jointly owned: " + transferInput.MarbleName)
+	}
+	isFrom, err := callerIs(stub, transferInput.From)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if !isFrom {
+		return shim.Error("caller is not " + transferInput.From)
+	}
+	held := marbleShares.Shares[transferInput.From]
+	if transferInput.Amount > held {
+		return shim.Error(fmt.Sprintf("%s holds %d shares of %s, cannot transfer %d", transferInput.From, held, marbleShares.MarbleName, transferInput.Amount))
+	}
+
+	marbleShares.Shares[transferInput.From] -= transferInput.Amount
+	if marbleShares.Shares[transferInput.From] == 0 {
+		delete(marbleShares.Shares, transferInput.From)
+	}
+	marbleShares.Shares[transferInput.To] += transferInput.Amount
+	err = putMarbleShares(stub, marbleShares)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	fmt.Println("- end transfer shares")
+	return shim.Success(nil)
+}
+
+// ===========================================================================
+// getShareholders - the shares of a jointly owned marble
+// ===========================================================================
+func (t *SimpleChaincode) getShareholders(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+
+	//   0
+	// "marble1"
+	if len(args) != 1 {
+		return shim.Error("Incorrect number of arguments. Expecting name of the marble to query")
+	}
+
+	marbleShares, err := getMarbleShares(stub, args[0])
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if marbleShares == nil {
+		return shim.Error("Marble is not jointly owned: " + args[0])
+	}
+	sharesAsBytes, err := json.Marshal(marbleShares)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	return shim.Success(sharesAsBytes)
+}
+
+// ===========================================================================
+// approvePendingAction - a shareholder's approval of a transfer, deletion or
+// reallocation of a jointly owned marble. The decision can be carried out
+// once enough shares approve it.
+// ===========================================================================
+func (t *SimpleChaincode) approvePendingAction(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	fmt.Println("- start approve pending action")
+
+	type pendingActionTransientInput struct {
+		MarbleName string         `json:"marbleName"`
+		Action     string         `json:"action"`
+		NewOwner   string         `json:"newOwner"`
+		Shares     map[string]int `json:"shares"`
+	}
+
+	if len(args) != 0 {
+		return shim.Error("Incorrect number of arguments. Private marble data must be passed in transient map.")
+	}
+
+	var actionInput pendingActionTransientInput
+	err := getTransientInput(stub, "pending_action", &actionInput)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if len(actionInput.MarbleName) == 0 {
+		return shim.Error("marbleName field must be a non-empty string")
+	}
+	switch actionInput.Action {
+	case shareActionTransfer:
+		if len(actionInput.NewOwner) == 0 {
+			return shim.Error("newOwner field must be a non-empty string")
+		}
+		actionInput.Shares = nil
+	case shareActionDelete:
+		actionInput.NewOwner = ""
+		actionInput.Shares = nil
+	case shareActionAllocate:
+		err = validateShares(actionInput.Shares)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		actionInput.NewOwner = ""
+	default:
+		return shim.Error("action must be " + shareActionTransfer + ", " + shareActionDelete + " or " + shareActionAllocate)
+	}
+
+	marbleShares, err := getMarbleShares(stub, actionInput.MarbleName)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if marbleShares == nil {
+		return shim.Error("Marble is not jointly owned: " + actionInput.MarbleName)
+	}
+	shareholder, err := marbleShares.callerShareholder(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	pending, err := getPendingAction(stub, actionInput.MarbleName, actionInput.Action)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if pending == nil || pending.NewOwner != actionInput.NewOwner || !sharesEqual(pending.Shares, actionInput.Shares) {
+		pending = &PendingAction{
+			ObjectType: "pendingAction",
+			MarbleName: actionInput.MarbleName,
+			Action:     actionInput.Action,
+			NewOwner:   actionInput.NewOwner,
+			Shares:     actionInput.Shares,
+			Approvals:  []string{},
+		}
+	}
+	if containsString(pending.Approvals, shareholder) {
+		return shim.Error(shareholder + " has already approved the " + pending.Action + " of marble " + pending.MarbleName)
+	}
+	pending.Approvals = append(pending.Approvals, shareholder)
+	err = putPendingAction(stub, pending)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	fmt.Println("- end approve pending action")
+	return shim.Success([]byte(fmt.Sprintf("{\"approvedShares\":%d}", marbleShares.approvedShares(pending))))
+}
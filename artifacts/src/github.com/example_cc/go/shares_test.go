@@ -0,0 +1,54 @@
+package main
+
+import "testing"
+
+func approveTransfer(name, newOwner string) map[string]interface{} {
+	return map[string]interface{}{"pending_action": map[string]interface{}{"marbleName": name, "action": "transfer", "newOwner": newOwner}}
+}
+
+// newJointlyOwnedMarble creates marble1, owned by Org1MSP and split between alice (60)
+// and bob (40).
+func newJointlyOwnedMarble(t *testing.T) *testStub {
+	s := newTestStub(t)
+	s.createMarble("marble1", "blue", 35, "Org1MSP", 99)
+	s.mustInvoke("allocateShares", map[string]interface{}{"share_allocation": map[string]interface{}{
+		"marbleName": "marble1", "shares": map[string]int{"alice": 60, "bob": 40},
+	}})
+	return s
+}
+
+func TestEveryOwnerChangeNeedsShareholderApproval(t *testing.T) {
+	s := newJointlyOwnedMarble(t)
+	bulk := map[string]interface{}{"marble_bulk_owner": map[string]interface{}{"fromOwner": "Org1MSP", "toOwner": "Org2MSP"}}
+
+	s.mustFail("transfer of marble marble1 has not been approved", "transferMarble", transferTo("marble1", "Org2MSP"))
+	s.mustFail("transfer of marble marble1 has not been approved", "bulkTransferMarbles", bulk)
+
+	// bob's 40 shares are no majority
+	s.setCaller("Org1MSP", "bob")
+	s.mustInvoke("approvePendingAction", approveTransfer("marble1", "Org2MSP"))
+	s.setCaller("Org1MSP", "admin")
+	s.mustFail("approved by 40 of 100 shares", "bulkTransferMarbles", bulk)
+
+	s.setCaller("Org1MSP", "alice")
+	s.mustInvoke("approvePendingAction", approveTransfer("marble1", "Org2MSP"))
+	s.setCaller("Org1MSP", "admin")
+	s.mustInvoke("bulkTransferMarbles", bulk)
+	if owner := s.readTestMarble("marble1").Owner; owner != "Org2MSP" {
+		t.Fatalf("expected marble1 to belong to Org2MSP, got %s", owner)
+	}
+
+	// the approval was used up by the transfer
+	s.setCaller("Org2MSP", "user2")
+	s.mustFail("transfer of marble marble1 has not been approved", "transferMarble", transferTo("marble1", "Org3MSP"))
+}
+
+func TestApprovalIsForOneNewOwner(t *testing.T) {
+	s := newJointlyOwnedMarble(t)
+	s.setCaller("Org1MSP", "alice")
+	s.mustInvoke("approvePendingAction", approveTransfer("marble1", "Org2MSP"))
+
+	s.setCaller("Org1MSP", "admin")
+	s.mustFail("transfer of marble marble1 has not been approved", "transferMarble", transferTo("marble1", "Org3MSP"))
+	s.mustInvoke("transferMarble", transferTo("marble1", "Org2MSP"))
+}
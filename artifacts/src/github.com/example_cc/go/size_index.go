@@ -0,0 +1,145 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// sizeIndexName orders marbles by size. The size is zero-padded so that the lexical
+// order of the composite keys matches the numeric order of the sizes.
+const sizeIndexName = "size~name"
+
+func sizeIndexKey(stub shim.ChaincodeStubInterface, m *marble) (string, error) {
+	return stub.CreateCompositeKey(sizeIndexName, []string{fmt.Sprintf("%010d", m.Size), m.Name})
+}
+
+func addSizeIndex(stub shim.ChaincodeStubInterface, m *marble) error {
+	key, err := sizeIndexKey(stub, m)
+	if err != nil {
+		return err
+	}
+	return stub.PutPrivateData("collectionMarbles", key, []byte{0x00})
+}
+
+func removeSizeIndex(stub shim.ChaincodeStubInterface, m *marble) error {
+	key, err := sizeIndexKey(stub, m)
+	if err != nil {
+		return err
+	}
+	return stub.DelPrivateData("collectionMarbles", key)
+}
+
+// ===========================================================================================
+// getMarblesBySizeRange returns the marbles whose size lies between startSize and endSize,
+// both inclusive, with their public records.
+// The shim rejects composite keys as range query bounds, so the size~name index is walked
+// in order instead, stopping at the first marble larger than endSize.
+// ===========================================================================================
+func (t *SimpleChaincode) getMarblesBySizeRange(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+
+	//   0     1
+	// "10", "50"
+	if len(args) != 2 {
+		return shim.Error("Incorrect number of arguments. Expecting 2")
+	}
+	startSize, err := strconv.Atoi(args[0])
+	if err != nil || startSize < 0 {
+		return shim.Error("startSize must be a non-negative integer")
+	}
+	endSize, err := strconv.Atoi(args[1])
+	if err != nil || endSize < startSize {
+		return shim.Error("endSize must be an integer no smaller than startSize")
+	}
+
+	resultsIterator, err := stub.GetPrivateDataByPartialCompositeKey("collectionMarbles", sizeIndexName, []string{})
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	defer resultsIterator.Close()
+
+	records := []queryRecord{}
+	for resultsIterator.HasNext() {
+		responseRange, err := resultsIterator.Next()
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		_, compositeKeyParts, err := stub.SplitCompositeKey(responseRange.Key)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		size, err := strconv.Atoi(compositeKeyParts[0])
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		if size < startSize {
+			continue
+		}
+		if size > endSize {
+			// the index is sorted by size, every remaining marble is too large
+			break
+		}
+
+		name := compositeKeyParts[1]
+		marbleAsBytes, err := stub.GetPrivateData("collectionMarbles", name)
+		if err != nil {
+			return shim.Error(err.Error())
+		} else if marbleAsBytes == nil {
+			continue
+		}
+		records = append(records, queryRecord{Key: name, Record: marbleAsBytes})
+	}
+
+	return marshalQueryRecords(records)
+}
+
+// =========================================================================================
+// queryMarblesBySizeRange is getMarblesBySizeRange as a rich query, in pages of pageSize
+// marbles, optionally narrowed to one owner. An empty owner matches every owner.
+// Only available on state databases that support rich query (e.g. CouchDB)
+// =========================================================================================
+func (t *SimpleChaincode) queryMarblesBySizeRange(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+
+	type sizeBounds struct {
+		Gte int `json:"$gte"`
+		Lte int `json:"$lte"`
+	}
+	type sizeRangeSelector struct {
+		Selector struct {
+			ObjectType string     `json:"docType"`
+			Size       sizeBounds `json:"size"`
+			Owner      string     `json:"owner,omitempty"`
+		} `json:"selector"`
+	}
+
+	//   0     1      2      3       4
+	// "20", "50", "bob", "25", "bookmark"
+	if len(args) != 5 {
+		return shim.Error("Incorrect number of arguments. Expecting minSize, maxSize, owner, pageSize and bookmark")
+	}
+	minSize, err := strconv.Atoi(args[0])
+	if err != nil || minSize <= 0 {
+		return shim.Error("minSize must be a positive integer")
+	}
+	maxSize, err := strconv.Atoi(args[1])
+	if err != nil || maxSize <= 0 {
+		return shim.Error("maxSize must be a positive integer")
+	}
+	if minSize > maxSize {
+		return shim.Error(fmt.Sprintf("minSize %d is larger than maxSize %d, swap them to query sizes %d to %d", minSize, maxSize, maxSize, minSize))
+	}
+
+	var query sizeRangeSelector
+	query.Selector.ObjectType = "marble"
+	query.Selector.Size = sizeBounds{Gte: minSize, Lte: maxSize}
+	query.Selector.Owner = args[2]
+	queryAsBytes, err := json.Marshal(query)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	return paginatedQueryResponse(stub, string(queryAsBytes), args[3], args[4])
+}
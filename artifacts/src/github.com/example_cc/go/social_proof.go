@@ -0,0 +1,192 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// shareWeight is how many views a single share is worth in the social score.
+const shareWeight = 5
+
+// addSocialActivity adds views and shares to a marble and refreshes its social score.
+func addSocialActivity(stub shim.ChaincodeStubInterface, name string, views, shares int64) error {
+	m, err := getMarble(stub, name)
+	if err != nil {
+		return err
+	}
+	m.ViewCount += views
+	m.ShareCount += shares
+	m.SocialScore = m.ViewCount + m.ShareCount*shareWeight
+	return putMarble(stub, m)
+}
+
+// socialActivityName reads the marble name from a social activity transient input.
+func socialActivityName(stub shim.ChaincodeStubInterface, transientKey string) (string, error) {
+	type socialActivityTransientInput struct {
+		Name string `json:"name"`
+	}
+
+	var activityInput socialActivityTransientInput
+	err := getTransientInput(stub, transientKey, &activityInput)
+	if err != nil {
+		return "", err
+	}
+	if len(activityInput.Name) == 0 {
+		return "", fmt.Errorf("name field must be a non-empty string")
+	}
+	return activityInput.Name, nil
+}
+
+// ===========================================================================
+// recordMarbleView - count one view of a marble
+// ===========================================================================
+func (t *SimpleChaincode) recordMarbleView(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	if len(args) != 0 {
+		return shim.Error("Incorrect number of arguments. Private marble data must be passed in transient map.")
+	}
+
+	name, err := socialActivityName(stub, "marble_view")
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = addSocialActivity(stub, name, 1, 0)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	return shim.Success(nil)
+}
+
+// ===========================================================================
+// recordMarbleShare - count one share of a marble
+// ===========================================================================
+func (t *SimpleChaincode) recordMarbleShare(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	if len(args) != 0 {
+		return shim.Error("Incorrect number of arguments. Private marble data must be passed in transient map.")
+	}
+
+	name, err := socialActivityName(stub, "marble_share")
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = addSocialActivity(stub, name, 0, 1)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	return shim.Success(nil)
+}
+
+// ===========================================================================================
+// batchRecordViews records views of many marbles in one transaction. Every view makes the
+// marble a write target, so clients should collect views and submit them in batches.
+// ===========================================================================================
+func (t *SimpleChaincode) batchRecordViews(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+
+	type marbleViewsTransientInput struct {
+		Name  string `json:"name"`
+		Views int64  `json:"views"`
+	}
+
+	if len(args) != 0 {
+		return shim.Error("Incorrect number of arguments. Private marble data must be passed in transient map.")
+	}
+
+	var viewInputs []marbleViewsTransientInput
+	err := getTransientInput(stub, "marble_views", &viewInputs)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	// merge entries for the same marble so each marble is written once
+	viewsByName := map[string]int64{}
+	names := []string{}
+	for i, input := range viewInputs {
+		if len(input.Name) == 0 {
+			return shim.Error(fmt.Sprintf("entry %d: name field must be a non-empty string", i))
+		}
+		if input.Views <= 0 {
+			return shim.Error(fmt.Sprintf("entry %d: views field must be a positive integer", i))
+		}
+		if _, ok := viewsByName[input.Name]; !ok {
+			names = append(names, input.Name)
+		}
+		viewsByName[input.Name] += input.Views
+	}
+
+	for _, name := range names {
+		err = addSocialActivity(stub, name, viewsByName[name], 0)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+	}
+	return shim.Success(nil)
+}
+
+// ===========================================================================
+// resetSocialStats - admin reset of a marble's views, shares and score
+// ===========================================================================
+func (t *SimpleChaincode) resetSocialStats(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+
+	//   0
+	// "marble1"
+	if len(args) != 1 {
+		return shim.Error("Incorrect number of arguments. Expecting name of the marble to reset")
+	}
+
+	err := requireAdmin(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	m, err := getMarble(stub, args[0])
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	m.ViewCount = 0
+	m.ShareCount = 0
+	m.SocialScore = 0
+	err = putMarble(stub, m)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	return shim.Success(nil)
+}
+
+// ===== Example: Sorted rich query ========================================================
+// queryTrendingMarbles returns the marbles with the highest social score.
+// The sort is served by the indexSocialScore CouchDB index shipped under META-INF.
+// Only available on state databases that support rich query (e.g. CouchDB)
+// =========================================================================================
+func (t *SimpleChaincode) queryTrendingMarbles(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+
+	//   0
+	// "10"
+	if len(args) != 1 {
+		return shim.Error("Incorrect number of arguments. Expecting 1")
+	}
+
+	limit, err := strconv.Atoi(args[0])
+	if err != nil || limit <= 0 {
+		return shim.Error("limit must be a positive integer")
+	}
+
+	queryString := fmt.Sprintf("{\"selector\":{\"docType\":\"marble\",\"socialScore\":{\"$gt\":0}},\"sort\":[{\"docType\":\"desc\"},{\"socialScore\":\"desc\"}],\"limit\":%d}", limit)
+
+	resultsIterator, err := stub.GetPrivateDataQueryResult("collectionMarbles", queryString)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	defer resultsIterator.Close()
+
+	records := []queryRecord{}
+	for resultsIterator.HasNext() && len(records) < limit {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		records = append(records, queryRecord{Key: queryResponse.Key, Record: queryResponse.Value})
+	}
+	return marshalQueryRecords(records)
+}
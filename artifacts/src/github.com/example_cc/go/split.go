@@ -0,0 +1,159 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// marbleSplitEvent is the payload of the MarbleSplit event.
+type marbleSplitEvent struct {
+	SourceName string   `json:"sourceName"`
+	NewNames   []string `json:"newNames"`
+}
+
+// ===========================================================================
+// splitMarble - owner division of a marble into two new ones whose sizes add
+// up to its size. The pieces keep its color, owner, condition, series and
+// rarity, share its weight in proportion to their sizes and get their own
+// prices. The source marble is deleted.
+// ===========================================================================
+func (t *SimpleChaincode) splitMarble(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	fmt.Println("- start split marble")
+
+	type marbleSplitTransientInput struct {
+		SourceName string      `json:"sourceName"`
+		Name1      string      `json:"name1"`
+		Size1      int         `json:"size1"`
+		Price1     json.Number `json:"price1"`
+		Name2      string      `json:"name2"`
+		Size2      int         `json:"size2"`
+		Price2     json.Number `json:"price2"`
+	}
+
+	if len(args) != 0 {
+		return shim.Error("Incorrect number of arguments. Private marble data must be passed in transient map.")
+	}
+
+	var splitInput marbleSplitTransientInput
+	err := getTransientInput(stub, "marble_split", &splitInput)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if len(splitInput.SourceName) == 0 {
+		return shim.Error("sourceName field must be a non-empty string")
+	}
+	if splitInput.Name1 == splitInput.Name2 {
+		return shim.Error("name1 and name2 must differ")
+	}
+	price1, err := parsePrice(splitInput.Price1)
+	if err != nil {
+		return shim.Error("price1: " + err.Error())
+	}
+	price2, err := parsePrice(splitInput.Price2)
+	if err != nil {
+		return shim.Error("price2: " + err.Error())
+	}
+
+	source, err := getMarble(stub, splitInput.SourceName)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = checkConsumable(stub, source)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if splitInput.Size1 <= 0 || splitInput.Size2 <= 0 || splitInput.Size1+splitInput.Size2 != source.Size {
+		return shim.Error(fmt.Sprintf("size1 and size2 must be positive and add up to %d, the size of marble %s", source.Size, source.Name))
+	}
+	sourceDetails, err := getMarblePrivateDetails(stub, source.Name)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	// the two pieces replace the source, so the owner gains one marble
+	err = checkOwnerMaxMarbles(stub, source.Owner, 1)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	gov, err := getGovernance(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = checkCallerNotBlacklisted(stub, gov)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	creatorMSPID, err := getCallerMSPID(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = checkAuthorizedCreator(stub, creatorMSPID)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	currentTime, err := getTxUnixTime(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	pieceInputs := []marbleTransientInput{
+		{Name: splitInput.Name1, Size: splitInput.Size1, Price: price1},
+		{Name: splitInput.Name2, Size: splitInput.Size2, Price: price2},
+	}
+	pieces := make([]*marble, len(pieceInputs))
+	piecesJSONasBytes := make([][]byte, len(pieceInputs))
+	for i := range pieceInputs {
+		pieceInput := &pieceInputs[i]
+		pieceInput.Color = source.Color
+		pieceInput.Owner = source.Owner
+		pieceInput.Weight = source.Weight * float64(pieceInput.Size) / float64(source.Size)
+		pieceInput.Condition = conditionGrades[conditionGrade(source.Condition)]
+		pieceInput.Series = source.Series
+		pieceInput.Rarity = rarityOf(source)
+
+		err = checkMarbleInput(stub, gov, pieceInput)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		err = checkProvenanceRecorded(stub, gov, pieceInput.Name)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		// prepareMarble fails if the name is taken, including by the source marble
+		pieces[i], piecesJSONasBytes[i], err = prepareMarble(stub, gov, pieceInput, currentTime)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+	}
+
+	err = deleteMarbleState(stub, source)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	for i, piece := range pieces {
+		err = writeNewMarble(stub, piece, piecesJSONasBytes[i], pieceInputs[i].Price, creatorMSPID)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+	}
+	// reads do not see the writes of their own transaction, so the market cap is
+	// adjusted once by the net change
+	err = adjustMarketCap(stub, source.Name, price1+price2-sourceDetails.Price)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	err = emitEvent(stub, "MarbleSplit", marbleSplitEvent{
+		SourceName: source.Name,
+		NewNames:   []string{pieces[0].Name, pieces[1].Name},
+	})
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	fmt.Println("- end split marble")
+	return shim.Success(nil)
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	"github.com/hyperledger/fabric/protos/msp"
+)
+
+// =========================================================================================
+// getCallerMSPID returns the MSP ID of the identity that submitted the transaction.
+// =========================================================================================
+func getCallerMSPID(stub shim.ChaincodeStubInterface) (string, error) {
+	creator, err := stub.GetCreator()
+	if err != nil {
+		return "", fmt.Errorf("Failed to get creator: %s", err.Error())
+	}
+
+	serializedID := &msp.SerializedIdentity{}
+	err = proto.Unmarshal(creator, serializedID)
+	if err != nil {
+		return "", fmt.Errorf("Failed to deserialize creator identity: %s", err.Error())
+	}
+	if len(serializedID.Mspid) == 0 {
+		return "", fmt.Errorf("creator identity does not carry an MSP ID")
+	}
+	return serializedID.Mspid, nil
+}
+
+// =========================================================================================
+// getCallerCertificate returns the certificate that signed the transaction.
+// =========================================================================================
+func getCallerCertificate(stub shim.ChaincodeStubInterface) (*x509.Certificate, []byte, error) {
+	creator, err := stub.GetCreator()
+	if err != nil {
+		return nil, nil, fmt.Errorf("Failed to get creator: %s", err.Error())
+	}
+
+	serializedID := &msp.SerializedIdentity{}
+	err = proto.Unmarshal(creator, serializedID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Failed to deserialize creator identity: %s", err.Error())
+	}
+
+	cert, err := parseCertificatePEM(serializedID.IdBytes)
+	if err != nil {
+		return nil, nil, err
+	}
+	return cert, serializedID.IdBytes, nil
+}
+
+// parseCertificatePEM parses a PEM encoded X.509 certificate.
+func parseCertificatePEM(certPEM []byte) (*x509.Certificate, error) {
+	block, _ := pem.Decode(certPEM)
+	if block == nil {
+		return nil, fmt.Errorf("identity does not contain a PEM encoded certificate")
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to parse certificate: %s", err.Error())
+	}
+	return cert, nil
+}
+
+// =========================================================================================
+// getCallerCommonName returns the common name of the certificate that signed the
+// transaction.
+// =========================================================================================
+func getCallerCommonName(stub shim.ChaincodeStubInterface) (string, error) {
+	cert, _, err := getCallerCertificate(stub)
+	if err != nil {
+		return "", err
+	}
+	return cert.Subject.CommonName, nil
+}
+
+// =========================================================================================
+// callerIs reports whether the caller is the given identity. Identities are recorded
+// either by the common name of a certificate or by an organization's MSP ID.
+// =========================================================================================
+func callerIs(stub shim.ChaincodeStubInterface, identity string) (bool, error) {
+	callerMSPID, err := getCallerMSPID(stub)
+	if err != nil {
+		return false, err
+	}
+	if callerMSPID == identity {
+		return true, nil
+	}
+	callerName, err := getCallerCommonName(stub)
+	if err != nil {
+		return false, err
+	}
+	return callerName == identity, nil
+}
+
+// requireOwner fails unless the caller is the owner of the marble.
+func requireOwner(stub shim.ChaincodeStubInterface, m *marble) error {
+	isOwner, err := callerIs(stub, m.Owner)
+	if err != nil {
+		return err
+	}
+	if !isOwner {
+		return fmt.Errorf("caller is not the owner of marble %s", m.Name)
+	}
+	return nil
+}
+
+// =========================================================================================
+// getTxTime returns the transaction timestamp chosen by the client, in UTC.
+// It is the same on every endorser, so it is safe to write to state.
+// =========================================================================================
+func getTxTime(stub shim.ChaincodeStubInterface) (time.Time, error) {
+	txTimestamp, err := stub.GetTxTimestamp()
+	if err != nil {
+		return time.Time{}, fmt.Errorf("Failed to get transaction timestamp: %s", err.Error())
+	}
+	txTime, err := ptypes.Timestamp(txTimestamp)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return txTime.UTC(), nil
+}
+
+// =========================================================================================
+// getTxUnixTime returns the transaction timestamp in Unix seconds. Every deadline,
+// period and rate in this chaincode is measured with it. Fabric does not expose the
+// ledger height to chaincode, and the timestamp is chosen by the submitting client, so
+// these are wall clock times the client vouches for, not block heights.
+// =========================================================================================
+func getTxUnixTime(stub shim.ChaincodeStubInterface) (int64, error) {
+	txTimestamp, err := stub.GetTxTimestamp()
+	if err != nil {
+		return 0, fmt.Errorf("Failed to get transaction timestamp: %s", err.Error())
+	}
+	return txTimestamp.Seconds, nil
+}
+
+// =========================================================================================
+// isReadAccessDenied reports whether a private data read failed because the caller's
+// organization may not read the collection, as happens with memberOnlyRead. Without
+// memberOnlyRead, a non-member's read does not fail but returns no value.
+// =========================================================================================
+func isReadAccessDenied(err error) bool {
+	return err != nil && strings.Contains(err.Error(), "does not have read access permission")
+}
+
+// =========================================================================================
+// emitEvent marshals payload to JSON and sets it as the transaction's chaincode event.
+// Fabric delivers a single event per transaction, so a later call replaces an earlier one.
+// =========================================================================================
+func emitEvent(stub shim.ChaincodeStubInterface, name string, payload interface{}) error {
+	payloadAsBytes, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	return stub.SetEvent(name, payloadAsBytes)
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// maxTags caps the tags of a marble.
+const maxTags = 20
+
+// tagEvent is the payload of the TagAdded and TagRemoved events.
+type tagEvent struct {
+	MarbleName string `json:"marbleName"`
+	Tag        string `json:"tag"`
+}
+
+// tagTransientInput names a tag of a marble.
+type tagTransientInput struct {
+	Name string `json:"name"`
+	Tag  string `json:"tag"`
+}
+
+func (i *tagTransientInput) validate() error {
+	if len(i.Name) == 0 {
+		return fmt.Errorf("name field must be a non-empty string")
+	}
+	return validateTag(i.Tag)
+}
+
+// ===========================================================================
+// addTag - owner labelling of a marble, e.g. "vintage" or "gift"
+// ===========================================================================
+func (t *SimpleChaincode) addTag(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	fmt.Println("- start add tag")
+
+	if len(args) != 0 {
+		return shim.Error("Incorrect number of arguments. Private marble data must be passed in transient map.")
+	}
+
+	var tagInput tagTransientInput
+	err := getTransientInput(stub, "marble_tag", &tagInput)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = tagInput.validate()
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	m, err := getMarble(stub, tagInput.Name)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = requireOwner(stub, m)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if containsString(m.Tags, tagInput.Tag) {
+		return shim.Error("Marble " + m.Name + " is already tagged " + tagInput.Tag)
+	}
+	if len(m.Tags) >= maxTags {
+		return shim.Error(fmt.Sprintf("Marble %s already has the maximum of %d tags", m.Name, maxTags))
+	}
+
+	m.Tags = append(m.Tags, tagInput.Tag)
+	err = putMarble(stub, m)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = emitEvent(stub, "TagAdded", tagEvent{MarbleName: m.Name, Tag: tagInput.Tag})
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	fmt.Println("- end add tag")
+	return shim.Success(nil)
+}
+
+// ===========================================================================
+// removeTag - owner removal of a tag from a marble
+// ===========================================================================
+func (t *SimpleChaincode) removeTag(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	fmt.Println("- start remove tag")
+
+	if len(args) != 0 {
+		return shim.Error("Incorrect number of arguments. Private marble data must be passed in transient map.")
+	}
+
+	var tagInput tagTransientInput
+	err := getTransientInput(stub, "marble_tag_remove", &tagInput)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = tagInput.validate()
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	m, err := getMarble(stub, tagInput.Name)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = requireOwner(stub, m)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	kept := []string{}
+	for _, tag := range m.Tags {
+		if tag != tagInput.Tag {
+			kept = append(kept, tag)
+		}
+	}
+	if len(kept) == len(m.Tags) {
+		return shim.Error("Marble " + m.Name + " is not tagged " + tagInput.Tag)
+	}
+	m.Tags = kept
+	err = putMarble(stub, m)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = emitEvent(stub, "TagRemoved", tagEvent{MarbleName: m.Name, Tag: tagInput.Tag})
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	fmt.Println("- end remove tag")
+	return shim.Success(nil)
+}
+
+// =========================================================================================
+// queryMarblesByTag queries for the marbles carrying a tag.
+// Only available on state databases that support rich query (e.g. CouchDB)
+// =========================================================================================
+func (t *SimpleChaincode) queryMarblesByTag(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+
+	//     0
+	// "vintage"
+	if len(args) != 1 {
+		return shim.Error("Incorrect number of arguments. Expecting tag")
+	}
+	// the tag pattern also keeps quotes out of the query string
+	err := validateTag(args[0])
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	queryString := fmt.Sprintf("{\"selector\":{\"docType\":\"marble\",\"tags\":{\"$elemMatch\":{\"$eq\":\"%s\"}}}}", args[0])
+
+	queryResults, err := getQueryResultForQueryString(stub, queryString)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	return shim.Success(queryResults)
+}
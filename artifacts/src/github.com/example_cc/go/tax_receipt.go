@@ -0,0 +1,193 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// TaxBracket taxes transfers of marbles priced at MinPrice or more at Rate, a fraction
+// of the price.
+type TaxBracket struct {
+	MinPrice int     `json:"minPrice"`
+	Rate     float64 `json:"rate"`
+}
+
+// TaxReceipt records the tax due on a marble transfer. One is written for every
+// transfer, with a zero TaxAmount below the lowest bracket. Its ID joins the transaction
+// ID and the marble name, so one transaction may transfer several marbles. Tax is owed by SellerMSPID,
+// the organization that made the transfer. Tax receipts are immutable: the chaincode
+// offers no way to modify or delete them.
+type TaxReceipt struct {
+	ObjectType   string  `json:"docType"`
+	ReceiptID    string  `json:"receiptID"`
+	MarbleName   string  `json:"marbleName"`
+	TaxAmount    int     `json:"taxAmount"`
+	TaxRate      float64 `json:"taxRate"`
+	Buyer        string  `json:"buyer"`
+	Seller       string  `json:"seller"`
+	SellerMSPID  string  `json:"sellerMSPID"`
+	TaxAuthority string  `json:"taxAuthority"`
+	TxID         string  `json:"txID"`
+	Timestamp    string  `json:"timestamp"`
+}
+
+// taxRate returns the rate of the highest bracket a price falls in, or zero.
+func (gov *governance) taxRate(price int64) float64 {
+	rate, floor := 0.0, -1
+	for _, bracket := range gov.TaxRateTable {
+		if price >= int64(bracket.MinPrice) && bracket.MinPrice > floor {
+			rate, floor = bracket.Rate, bracket.MinPrice
+		}
+	}
+	return rate
+}
+
+// writeTaxReceipt records the tax due on a transfer of m, which has just changed owner,
+// taxed on its private price.
+func writeTaxReceipt(stub shim.ChaincodeStubInterface, gov *governance, m *marble) error {
+	details, err := getMarblePrivateDetails(stub, m.Name)
+	if err != nil {
+		return err
+	}
+	sellerMSPID, err := getCallerMSPID(stub)
+	if err != nil {
+		return err
+	}
+	txTime, err := getTxTime(stub)
+	if err != nil {
+		return err
+	}
+
+	rate := gov.taxRate(details.Price)
+	receipt := &TaxReceipt{
+		ObjectType:   "taxReceipt",
+		ReceiptID:    stub.GetTxID() + "~" + m.Name,
+		MarbleName:   m.Name,
+		TaxAmount:    int(float64(details.Price) * rate),
+		TaxRate:      rate,
+		Buyer:        m.Owner,
+		Seller:       m.PreviousOwner,
+		SellerMSPID:  sellerMSPID,
+		TaxAuthority: gov.TaxAuthority,
+		TxID:         stub.GetTxID(),
+		Timestamp:    txTime.Format(time.RFC3339),
+	}
+	receiptAsBytes, err := json.Marshal(receipt)
+	if err != nil {
+		return err
+	}
+	return stub.PutPrivateData("collectionMarbleTaxReceipts", receipt.ReceiptID, receiptAsBytes)
+}
+
+// queryTaxReceipts scans collectionMarbleTaxReceipts for the receipts accepted by match.
+func queryTaxReceipts(stub shim.ChaincodeStubInterface, match func(*TaxReceipt) bool) ([]TaxReceipt, error) {
+	resultsIterator, err := stub.GetPrivateDataByRange("collectionMarbleTaxReceipts", "", "")
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	receipts := []TaxReceipt{}
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var receipt TaxReceipt
+		err = json.Unmarshal(queryResponse.Value, &receipt)
+		if err != nil {
+			return nil, err
+		}
+		if match(&receipt) {
+			receipts = append(receipts, receipt)
+		}
+	}
+	return receipts, nil
+}
+
+// ===========================================================================
+// getTaxReceiptsByBuyer - list the tax receipts of transfers to a buyer
+// ===========================================================================
+func (t *SimpleChaincode) getTaxReceiptsByBuyer(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	if len(args) != 1 {
+		return shim.Error("Incorrect number of arguments. Expecting buyer to query")
+	}
+
+	receipts, err := queryTaxReceipts(stub, func(r *TaxReceipt) bool {
+		return r.Buyer == args[0]
+	})
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	receiptsAsBytes, err := json.Marshal(receipts)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	return shim.Success(receiptsAsBytes)
+}
+
+// ===========================================================================
+// getTaxReceiptsBySeller - list the tax receipts of transfers from a seller
+// ===========================================================================
+func (t *SimpleChaincode) getTaxReceiptsBySeller(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	if len(args) != 1 {
+		return shim.Error("Incorrect number of arguments. Expecting seller to query")
+	}
+
+	receipts, err := queryTaxReceipts(stub, func(r *TaxReceipt) bool {
+		return r.Seller == args[0]
+	})
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	receiptsAsBytes, err := json.Marshal(receipts)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	return shim.Success(receiptsAsBytes)
+}
+
+// ===========================================================================
+// generateTaxSummary - the tax owed by an organization over a range of
+// calendar years, inclusive
+// ===========================================================================
+func (t *SimpleChaincode) generateTaxSummary(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+
+	//     0          1       2
+	// "Org1MSP", "2019", "2020"
+	if len(args) != 3 {
+		return shim.Error("Incorrect number of arguments. Expecting 3")
+	}
+	yearStart, err := strconv.Atoi(args[1])
+	if err != nil {
+		return shim.Error("yearStart must be a year")
+	}
+	yearEnd, err := strconv.Atoi(args[2])
+	if err != nil || yearEnd < yearStart {
+		return shim.Error("yearEnd must be a year no earlier than yearStart")
+	}
+
+	receipts, err := queryTaxReceipts(stub, func(r *TaxReceipt) bool {
+		if r.SellerMSPID != args[0] {
+			return false
+		}
+		issuedAt, err := time.Parse(time.RFC3339, r.Timestamp)
+		return err == nil && issuedAt.Year() >= yearStart && issuedAt.Year() <= yearEnd
+	})
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	totalTax := 0
+	for _, r := range receipts {
+		totalTax += r.TaxAmount
+	}
+	return shim.Success([]byte(fmt.Sprintf("{\"orgMSPID\":%q,\"yearStart\":%d,\"yearEnd\":%d,\"transfers\":%d,\"totalTax\":%d}",
+		args[0], yearStart, yearEnd, len(receipts), totalTax)))
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// TenureRoyaltyPolicy lowers a marble's royalty the longer its seller held it: the rate
+// starts at BaseRatePercent and falls by ReductionPerThousandSeconds for every thousand
+// seconds of tenure, down to MinRatePercent. It is kept in the marble's private details
+// and takes precedence over a tiered royalty.
+type TenureRoyaltyPolicy struct {
+	BaseRatePercent             float64 `json:"baseRatePercent"`
+	ReductionPerThousandSeconds float64 `json:"reductionPerThousandSeconds"`
+	MinRatePercent              float64 `json:"minRatePercent"`
+}
+
+// validate rejects rates outside 0..100 and a floor above the base rate.
+func (p *TenureRoyaltyPolicy) validate() error {
+	if p.BaseRatePercent < 0 || p.BaseRatePercent > 100 {
+		return fmt.Errorf("baseRatePercent must be between 0 and 100")
+	}
+	if p.MinRatePercent < 0 || p.MinRatePercent > p.BaseRatePercent {
+		return fmt.Errorf("minRatePercent must be between 0 and baseRatePercent")
+	}
+	if p.ReductionPerThousandSeconds < 0 {
+		return fmt.Errorf("reductionPerThousandSeconds must not be negative")
+	}
+	return nil
+}
+
+// rate returns the royalty rate, in percent, after tenureSeconds of ownership.
+func (p *TenureRoyaltyPolicy) rate(tenureSeconds int64) float64 {
+	reduced := p.BaseRatePercent - float64(tenureSeconds)/1000*p.ReductionPerThousandSeconds
+	return math.Max(p.MinRatePercent, reduced)
+}
+
+// compute returns the royalty due on a sale after tenureSeconds of ownership, and the
+// rate applied.
+func (p *TenureRoyaltyPolicy) compute(salePrice int, tenureSeconds int64) (int, float64) {
+	rate := p.rate(tenureSeconds)
+	return int(float64(salePrice) * rate / 100), rate
+}
+
+// ===========================================================================
+// setTenureRoyalty - creator setting of a marble's tenure based royalty
+// ===========================================================================
+func (t *SimpleChaincode) setTenureRoyalty(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	fmt.Println("- start set tenure royalty")
+
+	type tenureRoyaltyTransientInput struct {
+		Name string `json:"name"`
+		TenureRoyaltyPolicy
+	}
+
+	if len(args) != 0 {
+		return shim.Error("Incorrect number of arguments. Private marble data must be passed in transient map.")
+	}
+
+	var royaltyInput tenureRoyaltyTransientInput
+	err := getTransientInput(stub, "tenure_royalty", &royaltyInput)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if len(royaltyInput.Name) == 0 {
+		return shim.Error("name field must be a non-empty string")
+	}
+	policy := royaltyInput.TenureRoyaltyPolicy
+	err = policy.validate()
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	details, err := getMarblePrivateDetails(stub, royaltyInput.Name)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	callerMSPID, err := getCallerMSPID(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if callerMSPID != details.CreatorMSPID {
+		return shim.Error("only the creator of marble " + details.Name + " can set its royalty")
+	}
+
+	details.TenureRoyalty = &policy
+	err = putMarblePrivateDetails(stub, details)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	fmt.Println("- end set tenure royalty")
+	return shim.Success(nil)
+}
+
+// ===========================================================================
+// computeTenureRoyalty - the royalty the current owner would owe on selling a
+// marble at a price now
+// ===========================================================================
+func (t *SimpleChaincode) computeTenureRoyalty(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+
+	//     0         1
+	// "marble1", "350"
+	if len(args) != 2 {
+		return shim.Error("Incorrect number of arguments. Expecting 2")
+	}
+	salePrice, err := strconv.Atoi(args[1])
+	if err != nil || salePrice <= 0 {
+		return shim.Error("salePrice must be a positive integer")
+	}
+
+	m, err := getMarble(stub, args[0])
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	details, err := getMarblePrivateDetails(stub, m.Name)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if details.TenureRoyalty == nil {
+		return shim.Error("Marble has no tenure royalty: " + details.Name)
+	}
+	currentTime, err := getTxUnixTime(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	tenureSeconds := currentTime - m.LastTransferTime
+	royalty, rate := details.TenureRoyalty.compute(salePrice, tenureSeconds)
+	return shim.Success([]byte(fmt.Sprintf("{\"marbleName\":%q,\"salePrice\":%d,\"tenureSeconds\":%d,\"ratePercent\":%g,\"royalty\":%d}",
+		m.Name, salePrice, tenureSeconds, rate, royalty)))
+}
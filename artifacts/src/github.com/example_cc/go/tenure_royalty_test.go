@@ -0,0 +1,81 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func tenureRoyalty(name string, base, reduction, min float64) map[string]interface{} {
+	return map[string]interface{}{"tenure_royalty": map[string]interface{}{
+		"name": name, "baseRatePercent": base, "reductionPerThousandSeconds": reduction, "minRatePercent": min,
+	}}
+}
+
+func (s *testStub) tenureRoyaltyAfter(name string, tenureSeconds int64) (float64, int) {
+	s.Now = s.readTestMarble(name).LastTransferTime + tenureSeconds
+	var result struct {
+		TenureSeconds int64   `json:"tenureSeconds"`
+		RatePercent   float64 `json:"ratePercent"`
+		Royalty       int     `json:"royalty"`
+	}
+	err := json.Unmarshal(s.mustInvoke("computeTenureRoyalty", nil, name, "1000"), &result)
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	if result.TenureSeconds != tenureSeconds {
+		s.t.Fatalf("expected a tenure of %d seconds, got %d", tenureSeconds, result.TenureSeconds)
+	}
+	return result.RatePercent, result.Royalty
+}
+
+func TestTenureRoyaltyFallsToMinimumRate(t *testing.T) {
+	s := newTestStub(t)
+	s.createMarble("marble1", "blue", 35, "Org1MSP", 99)
+	s.mustInvoke("setTenureRoyalty", tenureRoyalty("marble1", 10, 1, 2))
+
+	for _, c := range []struct {
+		tenureSeconds int64
+		rate          float64
+		royalty       int
+	}{
+		{0, 10, 100},
+		{2500, 7.5, 75},
+		{8000, 2, 20},
+		{8001, 2, 20},
+		{1 << 40, 2, 20},
+	} {
+		rate, royalty := s.tenureRoyaltyAfter("marble1", c.tenureSeconds)
+		if rate != c.rate || royalty != c.royalty {
+			t.Fatalf("after %d seconds expected a rate of %g and royalty of %d, got %g and %d", c.tenureSeconds, c.rate, c.royalty, rate, royalty)
+		}
+	}
+}
+
+func TestPurchaseReceiptChargesTenureRoyalty(t *testing.T) {
+	s := newTestStub(t)
+	s.createMarble("marble1", "blue", 35, "Org1MSP", 99)
+	s.mustInvoke("setTenureRoyalty", tenureRoyalty("marble1", 10, 1, 2))
+
+	// the seller held the marble far longer than it takes to reach the floor
+	s.Now = s.readTestMarble("marble1").LastTransferTime + 1000000
+	s.mustInvoke("transferMarble", transferTo("marble1", "Org2MSP"))
+	var receipt PurchaseReceipt
+	err := json.Unmarshal(s.mustInvoke("generatePurchaseReceipt", nil, "marble1", "1000"), &receipt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if receipt.RoyaltyOwed != 20 || receipt.CreatorMSPID != "Org1MSP" {
+		t.Fatalf("expected 20 owed to Org1MSP at the minimum rate, got %+v", receipt)
+	}
+}
+
+func TestSetTenureRoyaltyValidation(t *testing.T) {
+	s := newTestStub(t)
+	s.createMarble("marble1", "blue", 35, "Org1MSP", 99)
+
+	s.mustFail("minRatePercent must be between 0 and baseRatePercent", "setTenureRoyalty", tenureRoyalty("marble1", 5, 1, 6))
+	s.mustFail("reductionPerThousandSeconds must not be negative", "setTenureRoyalty", tenureRoyalty("marble1", 5, -1, 1))
+	s.mustFail("Marble has no tenure royalty: marble1", "computeTenureRoyalty", nil, "marble1", "1000")
+	s.setCaller("Org2MSP", "user2")
+	s.mustFail("only the creator of marble marble1 can set its royalty", "setTenureRoyalty", tenureRoyalty("marble1", 10, 1, 2))
+}
@@ -0,0 +1,309 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// mutationConfigIndexName keys the mutation config of each marble.
+const mutationConfigIndexName = "mutationConfig~name"
+
+// mutationLogIndexName keys the mutations of each marble by transaction.
+const mutationLogIndexName = "mutationLog~name~txID"
+
+// mutationColors are the colors a mutating marble may take.
+var mutationColors = []string{"blue", "red", "green", "yellow", "purple", "white", "black"}
+
+// maxSizeMutation is the most a mutation changes a marble's size by, either way.
+const maxSizeMutation = 3
+
+// MutationConfig makes a marble's MutableFields change at random. Each triggered
+// mutation happens with probability MutationRate. A Frozen marble never mutates again.
+type MutationConfig struct {
+	ObjectType    string   `json:"docType"`
+	MarbleName    string   `json:"marbleName"`
+	MutationRate  float64  `json:"mutationRate"`
+	MutableFields []string `json:"mutableFields"`
+	Frozen        bool     `json:"frozen"`
+}
+
+// MutationLog records one mutation of a marble.
+type MutationLog struct {
+	ObjectType   string `json:"docType"`
+	MarbleName   string `json:"marbleName"`
+	MutatedField string `json:"mutatedField"`
+	OldValue     string `json:"oldValue"`
+	NewValue     string `json:"newValue"`
+	TxID         string `json:"txID"`
+}
+
+func getMutationConfig(stub shim.ChaincodeStubInterface, marbleName string) (*MutationConfig, error) {
+	configKey, err := stub.CreateCompositeKey(mutationConfigIndexName, []string{marbleName})
+	if err != nil {
+		return nil, err
+	}
+	configAsBytes, err := stub.GetPrivateData("collectionMarbleMutations", configKey)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get mutation config: %s", err.Error())
+	} else if configAsBytes == nil {
+		return nil, fmt.Errorf("Marble has no mutation config: %s", marbleName)
+	}
+
+	config := &MutationConfig{}
+	err = json.Unmarshal(configAsBytes, config)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to decode JSON of: %s", string(configAsBytes))
+	}
+	return config, nil
+}
+
+func putMutationConfig(stub shim.ChaincodeStubInterface, config *MutationConfig) error {
+	configKey, err := stub.CreateCompositeKey(mutationConfigIndexName, []string{config.MarbleName})
+	if err != nil {
+		return err
+	}
+	configAsBytes, err := json.Marshal(config)
+	if err != nil {
+		return err
+	}
+	return stub.PutPrivateData("collectionMarbleMutations", configKey, configAsBytes)
+}
+
+// mutationSeed is the deterministic pseudo-random seed of a mutation: every endorser
+// derives the same bytes from the transaction ID.
+func mutationSeed(txID, marbleName string) [sha256.Size]byte {
+	return sha256.Sum256([]byte(txID + marbleName))
+}
+
+// mutatedValue picks the new value of a mutating field from the seed.
+func mutatedValue(m *marble, field string, seed [sha256.Size]byte) (string, string) {
+	switch field {
+	case "color":
+		newColor := mutationColors[int(seed[5])%len(mutationColors)]
+		if newColor == m.Color {
+			newColor = mutationColors[(int(seed[5])+1)%len(mutationColors)]
+		}
+		return m.Color, newColor
+	default: // size
+		step := int(seed[5])%maxSizeMutation + 1
+		if seed[6]%2 == 0 && m.Size > step {
+			step = -step
+		}
+		return strconv.Itoa(m.Size), strconv.Itoa(m.Size + step)
+	}
+}
+
+// ===========================================================================
+// setMutationConfig - admin choice of how a marble mutates
+// ===========================================================================
+func (t *SimpleChaincode) setMutationConfig(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	fmt.Println("- start set mutation config")
+
+	type mutationConfigTransientInput struct {
+		MarbleName    string   `json:"marbleName"`
+		MutationRate  float64  `json:"mutationRate"`
+		MutableFields []string `json:"mutableFields"`
+	}
+
+	if len(args) != 0 {
+		return shim.Error("Incorrect number of arguments. Private marble data must be passed in transient map.")
+	}
+
+	var configInput mutationConfigTransientInput
+	err := getTransientInput(stub, "mutation_config", &configInput)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if len(configInput.MarbleName) == 0 {
+		return shim.Error("marbleName field must be a non-empty string")
+	}
+	if configInput.MutationRate < 0 || configInput.MutationRate > 1 {
+		return shim.Error("mutationRate field must be between 0 and 1")
+	}
+	if len(configInput.MutableFields) == 0 {
+		return shim.Error("mutableFields field must be a non-empty array")
+	}
+	for _, field := range configInput.MutableFields {
+		if field != "color" && field != "size" {
+			return shim.Error("field " + field + " cannot mutate, expecting color or size")
+		}
+	}
+
+	err = requireAdmin(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	_, err = getMarble(stub, configInput.MarbleName)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	frozen := false
+	if existing, err := getMutationConfig(stub, configInput.MarbleName); err == nil {
+		frozen = existing.Frozen
+	}
+	err = putMutationConfig(stub, &MutationConfig{
+		ObjectType:    "mutationConfig",
+		MarbleName:    configInput.MarbleName,
+		MutationRate:  configInput.MutationRate,
+		MutableFields: configInput.MutableFields,
+		Frozen:        frozen,
+	})
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	fmt.Println("- end set mutation config")
+	return shim.Success(nil)
+}
+
+// ===========================================================================
+// triggerMutation - roll for a random trait change of a marble. The roll is
+// seeded by the transaction ID, so it is the same on every endorser.
+// ===========================================================================
+func (t *SimpleChaincode) triggerMutation(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	fmt.Println("- start trigger mutation")
+
+	type mutationTransientInput struct {
+		MarbleName string `json:"marbleName"`
+	}
+
+	if len(args) != 0 {
+		return shim.Error("Incorrect number of arguments. Private marble data must be passed in transient map.")
+	}
+
+	var mutationInput mutationTransientInput
+	err := getTransientInput(stub, "marble_mutation", &mutationInput)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if len(mutationInput.MarbleName) == 0 {
+		return shim.Error("marbleName field must be a non-empty string")
+	}
+
+	config, err := getMutationConfig(stub, mutationInput.MarbleName)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if config.Frozen {
+		return shim.Error("Mutations are frozen for marble: " + config.MarbleName)
+	}
+	m, err := getMarble(stub, config.MarbleName)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	seed := mutationSeed(stub.GetTxID(), m.Name)
+	roll := float64(binary.BigEndian.Uint32(seed[0:4])) / math.MaxUint32
+	if roll >= config.MutationRate {
+		fmt.Println("- end trigger mutation (no mutation)")
+		return shim.Success([]byte("{\"mutated\":false}"))
+	}
+
+	field := config.MutableFields[int(seed[4])%len(config.MutableFields)]
+	oldValue, newValue := mutatedValue(m, field, seed)
+	err = applyFieldChange(stub, m, field, newValue)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	mutation := &MutationLog{
+		ObjectType:   "mutationLog",
+		MarbleName:   m.Name,
+		MutatedField: field,
+		OldValue:     oldValue,
+		NewValue:     newValue,
+		TxID:         stub.GetTxID(),
+	}
+	mutationAsBytes, err := json.Marshal(mutation)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	logKey, err := stub.CreateCompositeKey(mutationLogIndexName, []string{mutation.MarbleName, mutation.TxID})
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = stub.PutPrivateData("collectionMarbleMutations", logKey, mutationAsBytes)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	fmt.Println("- end trigger mutation")
+	return shim.Success(mutationAsBytes)
+}
+
+// ===========================================================================
+// getMutationLog - the mutations of a marble
+// ===========================================================================
+func (t *SimpleChaincode) getMutationLog(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+
+	//   0
+	// "marble1"
+	if len(args) != 1 {
+		return shim.Error("Incorrect number of arguments. Expecting name of the marble to query")
+	}
+
+	resultsIterator, err := stub.GetPrivateDataByPartialCompositeKey("collectionMarbleMutations", mutationLogIndexName, []string{args[0]})
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	defer resultsIterator.Close()
+
+	mutations := []MutationLog{}
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		var mutation MutationLog
+		err = json.Unmarshal(queryResponse.Value, &mutation)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		mutations = append(mutations, mutation)
+	}
+
+	mutationsAsBytes, err := json.Marshal(mutations)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	return shim.Success(mutationsAsBytes)
+}
+
+// ===========================================================================
+// freezeMutations - owner opt-out of any further mutation of a marble
+// ===========================================================================
+func (t *SimpleChaincode) freezeMutations(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+
+	//   0
+	// "marble1"
+	if len(args) != 1 {
+		return shim.Error("Incorrect number of arguments. Expecting name of the marble")
+	}
+
+	m, err := getMarble(stub, args[0])
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = requireOwner(stub, m)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	config, err := getMutationConfig(stub, m.Name)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	config.Frozen = true
+	err = putMutationConfig(stub, config)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	return shim.Success(nil)
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// marbleNamePattern is the shape of a valid marble name. Names are used as private data
+// keys and as composite key attributes, so characters such as "/" or the composite key
+// delimiter "\x00" are kept out of them.
+var marbleNamePattern = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9\-_]{0,63}$`)
+
+// validateMarbleName fails unless name starts with a letter or digit and continues with
+// at most 63 letters, digits, dashes or underscores.
+func validateMarbleName(name string) error {
+	if !marbleNamePattern.MatchString(name) {
+		return fmt.Errorf("marble name %q must match %s", name, marbleNamePattern.String())
+	}
+	return nil
+}
+
+// documentHashPattern is the shape of a hex encoded SHA-256 hash of a marble document.
+var documentHashPattern = regexp.MustCompile(`^[a-f0-9]{64}$`)
+
+// validateDocumentHash fails unless hash is 64 lowercase hex digits.
+func validateDocumentHash(hash string) error {
+	if !documentHashPattern.MatchString(hash) {
+		return fmt.Errorf("document hash %q must match %s", hash, documentHashPattern.String())
+	}
+	return nil
+}
+
+// tagPattern is the shape of a marble tag: up to 32 lowercase letters, digits or dashes.
+var tagPattern = regexp.MustCompile(`^[a-z0-9-]{1,32}$`)
+
+// validateTag fails unless tag is 1 to 32 lowercase letters, digits or dashes.
+func validateTag(tag string) error {
+	if !tagPattern.MatchString(tag) {
+		return fmt.Errorf("tag %q must match %s", tag, tagPattern.String())
+	}
+	return nil
+}
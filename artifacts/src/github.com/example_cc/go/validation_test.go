@@ -0,0 +1,59 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// pathologicalMarbleNames are names that would corrupt keys or composite key indexes.
+// The empty name is rejected before validation by each function, with its own error.
+var pathologicalMarbleNames = []string{
+	"a/b",
+	"/marble1",
+	"marble\x00",
+	"\x00marble~name",
+	"marble\\1",
+	"marble 1",
+	" marble1",
+	"marble1\n",
+	"-marble1",
+	"_marble1",
+	"marble.1",
+	"marble~1",
+	"marblé",
+	"marble☃",
+	"\xff\xfe",
+	strings.Repeat("a", 65),
+}
+
+func TestValidateMarbleName(t *testing.T) {
+	for _, name := range []string{"m", "marble1", "Marble-1_a", "0", strings.Repeat("a", 64)} {
+		if err := validateMarbleName(name); err != nil {
+			t.Fatalf("expected %q to be valid, got %s", name, err)
+		}
+	}
+	for _, name := range append([]string{""}, pathologicalMarbleNames...) {
+		if err := validateMarbleName(name); err == nil {
+			t.Fatalf("expected %q to be rejected", name)
+		}
+	}
+}
+
+func TestMarbleFunctionsRejectPathologicalNames(t *testing.T) {
+	s := newTestStub(t)
+	s.createMarble("marble1", "blue", 35, "Org1MSP", 99)
+	for _, name := range pathologicalMarbleNames {
+		s.mustFail("must match", "initMarble", map[string]interface{}{"marble": map[string]interface{}{
+			"name": name, "color": "blue", "size": 35, "owner": "Org1MSP", "price": 99, "weight": 10,
+		}})
+		s.mustFail("must match", "transferMarble", transferTo(name, "Org2MSP"))
+		s.mustFail("must match", "delete", map[string]interface{}{"marble_delete": map[string]interface{}{"name": name}})
+		s.mustFail("must match", "readMarble", nil, name)
+		s.mustFail("must match", "readMarblePrivateDetails", nil, name)
+	}
+	for key := range s.PvtState["collectionMarbles"] {
+		if key != "marble1" && key != initInfoKey && !strings.HasPrefix(key, "\x00") {
+			t.Fatalf("expected only marble1 to be stored, found %q", key)
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// defaultMaxUsages applies to marbles created without a maxUsages value.
+const defaultMaxUsages = 100
+
+// isWornOut reports whether a marble has used up its last condition grade, damaged.
+func isWornOut(m *marble) bool {
+	return m.UsageCount >= m.MaxUsages && conditionGrade(m.Condition) == len(conditionGrades)-1
+}
+
+// ===========================================================================================
+// useMarble records one use of a marble. Every MaxUsages uses the marble drops one
+// condition grade and its usage count starts again; once it reaches the worst grade the
+// count keeps climbing until the marble is worn out and can no longer be used.
+// ===========================================================================================
+func (t *SimpleChaincode) useMarble(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	fmt.Println("- start use marble")
+
+	type marbleUseTransientInput struct {
+		Name string `json:"name"`
+	}
+
+	if len(args) != 0 {
+		return shim.Error("Incorrect number of arguments. Private marble data must be passed in transient map.")
+	}
+
+	var useInput marbleUseTransientInput
+	err := getTransientInput(stub, "marble_use", &useInput)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if len(useInput.Name) == 0 {
+		return shim.Error("name field must be a non-empty string")
+	}
+
+	used, err := getMarble(stub, useInput.Name)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if used.MaxUsages <= 0 {
+		used.MaxUsages = defaultMaxUsages
+	}
+	if isWornOut(used) {
+		return shim.Error("Marble is worn out: " + used.Name)
+	}
+
+	used.UsageCount++
+	grade := conditionGrade(used.Condition)
+	if used.UsageCount >= used.MaxUsages && grade < len(conditionGrades)-1 {
+		err = setMarbleCondition(stub, used, conditionGrades[grade+1])
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		used.UsageCount = 0
+	} else {
+		used.Condition = conditionGrades[grade]
+	}
+
+	err = putMarble(stub, used)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	fmt.Println("- end use marble")
+	return shim.Success(nil)
+}
+
+// ===========================================================================================
+// repairMarble resets a marble's usage count and raises its condition one grade. Only the
+// owner can repair a marble, and the repair is paid for in loyalty points by the owner's
+// organization.
+// ===========================================================================================
+func (t *SimpleChaincode) repairMarble(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	fmt.Println("- start repair marble")
+
+	type marbleRepairTransientInput struct {
+		Name string `json:"name"`
+	}
+
+	if len(args) != 0 {
+		return shim.Error("Incorrect number of arguments. Private marble data must be passed in transient map.")
+	}
+
+	var repairInput marbleRepairTransientInput
+	err := getTransientInput(stub, "marble_repair", &repairInput)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if len(repairInput.Name) == 0 {
+		return shim.Error("name field must be a non-empty string")
+	}
+
+	repaired, err := getMarble(stub, repairInput.Name)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = requireOwner(stub, repaired)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	gov, err := getGovernance(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	callerMSPID, err := getCallerMSPID(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = debitLoyaltyPoints(stub, callerMSPID, gov.RepairCost)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	grade := conditionGrade(repaired.Condition)
+	if grade > 0 {
+		grade--
+	}
+	err = setMarbleCondition(stub, repaired, conditionGrades[grade])
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	repaired.UsageCount = 0
+
+	err = putMarble(stub, repaired)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	fmt.Println("- end repair marble")
+	return shim.Success(nil)
+}
+
+// ===========================================================================
+// getWornOutMarbles - list the marbles that can no longer be used. The test
+// compares two fields of the marble, which a CouchDB selector cannot express,
+// so the collection is scanned.
+// ===========================================================================
+func (t *SimpleChaincode) getWornOutMarbles(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	if len(args) != 0 {
+		return shim.Error("Incorrect number of arguments. Expecting 0")
+	}
+
+	records, err := scanMarbles(stub, func(m *marble) bool {
+		return m.MaxUsages > 0 && isWornOut(m)
+	})
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	return marshalQueryRecords(records)
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// maxMarbleWeight is the heaviest a marble may be, in grams.
+const maxMarbleWeight = 5000
+
+// checkWeight fails unless weight is above zero and at most maxMarbleWeight. NaN fails
+// both comparisons and is rejected with the rest.
+func checkWeight(weight float64) error {
+	if !(weight > 0 && weight <= maxMarbleWeight) {
+		return fmt.Errorf("weight must be above 0 and at most %d grams", maxMarbleWeight)
+	}
+	return nil
+}
+
+// ===========================================================================
+// updateMarbleWeight - owner correction of a marble's weight
+// ===========================================================================
+func (t *SimpleChaincode) updateMarbleWeight(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	fmt.Println("- start update marble weight")
+
+	type marbleWeightTransientInput struct {
+		Name   string  `json:"name"`
+		Weight float64 `json:"weight"`
+	}
+
+	if len(args) != 0 {
+		return shim.Error("Incorrect number of arguments. Private marble data must be passed in transient map.")
+	}
+
+	var weightInput marbleWeightTransientInput
+	err := getTransientInput(stub, "marble_weight", &weightInput)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if len(weightInput.Name) == 0 {
+		return shim.Error("name field must be a non-empty string")
+	}
+	err = checkWeight(weightInput.Weight)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	m, err := getMarble(stub, weightInput.Name)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = requireOwner(stub, m)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	m.Weight = weightInput.Weight
+	err = putMarble(stub, m)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	fmt.Println("- end update marble weight")
+	return shim.Success(nil)
+}
+
+// =========================================================================================
+// getMarblesByWeightRange queries for the marbles weighing between minWeight and
+// maxWeight grams, both inclusive. Weights are not integers, so a composite key index
+// would have to round them; a rich query compares them exactly instead.
+// Only available on state databases that support rich query (e.g. CouchDB)
+// =========================================================================================
+func (t *SimpleChaincode) getMarblesByWeightRange(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+
+	//    0       1
+	// "10.5", "250"
+	if len(args) != 2 {
+		return shim.Error("Incorrect number of arguments. Expecting 2")
+	}
+	minWeight, err := strconv.ParseFloat(args[0], 64)
+	if err != nil || !(minWeight >= 0) {
+		return shim.Error("minWeight must be a non-negative number")
+	}
+	maxWeight, err := strconv.ParseFloat(args[1], 64)
+	if err != nil || !(maxWeight >= minWeight) || maxWeight > maxMarbleWeight {
+		return shim.Error(fmt.Sprintf("maxWeight must be a number between minWeight and %d", maxMarbleWeight))
+	}
+
+	queryString := fmt.Sprintf("{\"selector\":{\"docType\":\"marble\",\"weight\":{\"$gte\":%s,\"$lte\":%s}}}",
+		strconv.FormatFloat(minWeight, 'f', -1, 64), strconv.FormatFloat(maxWeight, 'f', -1, 64))
+
+	queryResults, err := getQueryResultForQueryString(stub, queryString)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	return shim.Success(queryResults)
+}
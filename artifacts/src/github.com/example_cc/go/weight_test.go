@@ -0,0 +1,70 @@
+package main
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+)
+
+func weighedMarble(name string, weight float64) map[string]interface{} {
+	return map[string]interface{}{"marble": map[string]interface{}{
+		"name": name, "color": "blue", "size": 35, "owner": "Org1MSP", "price": 99, "weight": weight,
+	}}
+}
+
+func weightTo(name string, weight float64) map[string]interface{} {
+	return map[string]interface{}{"marble_weight": map[string]interface{}{"name": name, "weight": weight}}
+}
+
+func TestCheckWeightEdgeCases(t *testing.T) {
+	for _, weight := range []float64{0, -1, math.Copysign(0, -1), 5000.001, 5001, math.NaN(), math.Inf(1), math.Inf(-1)} {
+		if checkWeight(weight) == nil {
+			t.Fatalf("expected a weight of %v to be rejected", weight)
+		}
+	}
+	for _, weight := range []float64{0.001, 1, 4999.5, 5000} {
+		if err := checkWeight(weight); err != nil {
+			t.Fatalf("expected a weight of %v to be accepted, got %s", weight, err)
+		}
+	}
+}
+
+func TestInitAndUpdateRejectInvalidWeights(t *testing.T) {
+	s := newTestStub(t)
+	for _, weight := range []float64{0, -1, 5001} {
+		s.mustFail("weight must be above 0 and at most 5000 grams", "initMarble", weighedMarble("marble1", weight))
+	}
+	s.mustInvoke("initMarble", weighedMarble("marble1", 5000))
+
+	for _, weight := range []float64{0, -1, 5001} {
+		s.mustFail("weight must be above 0 and at most 5000 grams", "updateMarbleWeight", weightTo("marble1", weight))
+	}
+	s.mustInvoke("updateMarbleWeight", weightTo("marble1", 0.5))
+	if weight := s.readTestMarble("marble1").Weight; weight != 0.5 {
+		t.Fatalf("expected a weight of 0.5, got %v", weight)
+	}
+	s.setCaller("Org2MSP", "user2")
+	s.mustFail("caller is not the owner of marble marble1", "updateMarbleWeight", weightTo("marble1", 10))
+}
+
+func TestGetMarblesByWeightRangeIsInclusive(t *testing.T) {
+	s := newTestStub(t)
+	s.mustInvoke("initMarble", weighedMarble("light", 10.25))
+	s.mustInvoke("initMarble", weighedMarble("middle", 100))
+	s.mustInvoke("initMarble", weighedMarble("heavy", 250.75))
+
+	var records []queryRecord
+	err := json.Unmarshal(s.mustInvoke("getMarblesByWeightRange", nil, "10.25", "100"), &records)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 || records[0].Key != "light" || records[1].Key != "middle" {
+		t.Fatalf("expected light and middle, got %+v", records)
+	}
+
+	s.mustFail("minWeight must be a non-negative number", "getMarblesByWeightRange", nil, "NaN", "100")
+	s.mustFail("minWeight must be a non-negative number", "getMarblesByWeightRange", nil, "-1", "100")
+	s.mustFail("maxWeight must be a number between minWeight and 5000", "getMarblesByWeightRange", nil, "10", "NaN")
+	s.mustFail("maxWeight must be a number between minWeight and 5000", "getMarblesByWeightRange", nil, "10", "5001")
+	s.mustFail("maxWeight must be a number between minWeight and 5000", "getMarblesByWeightRange", nil, "100", "10")
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// whitepaperIndexName indexes marbles by the CID of their specification document.
+const whitepaperIndexName = "whitepaper~name"
+
+func addWhitepaperIndex(stub shim.ChaincodeStubInterface, m *marble) error {
+	whitepaperIndexKey, err := stub.CreateCompositeKey(whitepaperIndexName, []string{m.WhitepaperCID, m.Name})
+	if err != nil {
+		return err
+	}
+	return stub.PutPrivateData("collectionMarbles", whitepaperIndexKey, []byte{0x00})
+}
+
+func removeWhitepaperIndex(stub shim.ChaincodeStubInterface, m *marble) error {
+	whitepaperIndexKey, err := stub.CreateCompositeKey(whitepaperIndexName, []string{m.WhitepaperCID, m.Name})
+	if err != nil {
+		return err
+	}
+	return stub.DelPrivateData("collectionMarbles", whitepaperIndexKey)
+}
+
+// ===========================================================================
+// setWhitepaper - link a marble to its specification document. Marble series
+// are not modelled, so only the admin organization may set the link.
+// ===========================================================================
+func (t *SimpleChaincode) setWhitepaper(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	fmt.Println("- start set whitepaper")
+
+	type whitepaperTransientInput struct {
+		Name string `json:"name"`
+		CID  string `json:"cid"`
+		Hash string `json:"hash"`
+	}
+
+	if len(args) != 0 {
+		return shim.Error("Incorrect number of arguments. Private marble data must be passed in transient map.")
+	}
+
+	var whitepaperInput whitepaperTransientInput
+	err := getTransientInput(stub, "marble_whitepaper", &whitepaperInput)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if len(whitepaperInput.Name) == 0 {
+		return shim.Error("name field must be a non-empty string")
+	}
+	if len(whitepaperInput.CID) == 0 {
+		return shim.Error("cid field must be a non-empty string")
+	}
+	if len(whitepaperInput.Hash) == 0 {
+		return shim.Error("hash field must be a non-empty string")
+	}
+
+	err = requireAdmin(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	m, err := getMarble(stub, whitepaperInput.Name)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if len(m.WhitepaperCID) != 0 {
+		return shim.Error("Marble already has a whitepaper, use updateWhitepaper: " + m.Name)
+	}
+
+	m.WhitepaperCID = whitepaperInput.CID
+	m.WhitepaperHash = whitepaperInput.Hash
+	err = putMarble(stub, m)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = addWhitepaperIndex(stub, m)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	fmt.Println("- end set whitepaper")
+	return shim.Success(nil)
+}
+
+// ===========================================================================
+// updateWhitepaper - replace a marble's specification document, keeping the
+// old reference in its history
+// ===========================================================================
+func (t *SimpleChaincode) updateWhitepaper(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+
+	//     0          1          2
+	// "marble1", "newCID", "newHash"
+	if len(args) != 3 {
+		return shim.Error("Incorrect number of arguments. Expecting 3")
+	}
+	if len(args[1]) == 0 || len(args[2]) == 0 {
+		return shim.Error("newCID and newHash must be non-empty strings")
+	}
+
+	err := requireAdmin(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	m, err := getMarble(stub, args[0])
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if len(m.WhitepaperCID) == 0 {
+		return shim.Error("Marble has no whitepaper, use setWhitepaper: " + m.Name)
+	}
+
+	err = removeWhitepaperIndex(stub, m)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	m.WhitepaperHistory = append(m.WhitepaperHistory, m.WhitepaperCID+":"+m.WhitepaperHash)
+	m.WhitepaperCID = args[1]
+	m.WhitepaperHash = args[2]
+	err = putMarble(stub, m)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = addWhitepaperIndex(stub, m)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	return shim.Success(nil)
+}
+
+// ===========================================================================
+// verifyWhitepaper - check document contents against a marble's whitepaper hash
+// ===========================================================================
+func (t *SimpleChaincode) verifyWhitepaper(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+
+	//     0            1
+	// "marble1", "<content bytes>"
+	if len(args) != 2 {
+		return shim.Error("Incorrect number of arguments. Expecting 2")
+	}
+
+	m, err := getMarble(stub, args[0])
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if len(m.WhitepaperCID) == 0 {
+		return shim.Error("Marble has no whitepaper: " + m.Name)
+	}
+
+	hash := sha256.Sum256([]byte(args[1]))
+	valid := hex.EncodeToString(hash[:]) == m.WhitepaperHash
+	return shim.Success([]byte(fmt.Sprintf("{\"valid\":%t}", valid)))
+}
+
+// ===========================================================================
+// getMarblesByWhitepaper - marbles specified by a document, read from the
+// whitepaper~name index
+// ===========================================================================
+func (t *SimpleChaincode) getMarblesByWhitepaper(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+
+	//   0
+	// "QmCID"
+	if len(args) != 1 {
+		return shim.Error("Incorrect number of arguments. Expecting whitepaper CID to query")
+	}
+
+	resultsIterator, err := stub.GetPrivateDataByPartialCompositeKey("collectionMarbles", whitepaperIndexName, []string{args[0]})
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	defer resultsIterator.Close()
+
+	records := []queryRecord{}
+	for resultsIterator.HasNext() {
+		responseRange, err := resultsIterator.Next()
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		_, compositeKeyParts, err := stub.SplitCompositeKey(responseRange.Key)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		marbleAsBytes, err := stub.GetPrivateData("collectionMarbles", compositeKeyParts[1])
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		if marbleAsBytes != nil {
+			records = append(records, queryRecord{Key: compositeKeyParts[1], Record: marbleAsBytes})
+		}
+	}
+	return marshalQueryRecords(records)
+}
+
+// ===========================================================================
+// getMarblesWithoutWhitepaper - marbles with no specification document
+// ===========================================================================
+func (t *SimpleChaincode) getMarblesWithoutWhitepaper(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	if len(args) != 0 {
+		return shim.Error("Incorrect number of arguments. Expecting 0")
+	}
+
+	records, err := scanMarbles(stub, func(m *marble) bool {
+		return len(m.WhitepaperCID) == 0
+	})
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	return marshalQueryRecords(records)
+}
@@ -0,0 +1,312 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// farmLock is the LockedBy value of a marble deposited in the farm.
+const farmLock = "farm"
+
+// farmYieldKeyPrefix prefixes the harvested yield totals of each farmer in
+// collectionMarbleFarming.
+const farmYieldKeyPrefix = "farmYield_"
+
+// secondsPerYear converts the per second yield rate into an annual rate, see
+// getTxUnixTime.
+const secondsPerYear = 365 * 24 * 60 * 60
+
+// FarmingRecord tracks a marble deposited in the farm. It is keyed by marble name.
+type FarmingRecord struct {
+	ObjectType   string  `json:"docType"`
+	MarbleName   string  `json:"marbleName"`
+	FarmerMSPID  string  `json:"farmerMSPID"`
+	DepositTime  int64   `json:"depositTime"`
+	YieldToken   string  `json:"yieldToken"`
+	YieldAccrued float64 `json:"yieldAccrued"`
+}
+
+// farmYieldTotal is the yield a farmer has harvested across all deposits.
+type farmYieldTotal struct {
+	ObjectType     string  `json:"docType"`
+	FarmerMSPID    string  `json:"farmerMSPID"`
+	TotalHarvested float64 `json:"totalHarvested"`
+}
+
+// checkNotLocked fails if the marble is held by a lock such as the farm.
+func checkNotLocked(m *marble) error {
+	if len(m.LockedBy) != 0 {
+		return fmt.Errorf("marble %s is locked by %s", m.Name, m.LockedBy)
+	}
+	return nil
+}
+
+func getFarmingRecord(stub shim.ChaincodeStubInterface, marbleName string) (*FarmingRecord, error) {
+	recordAsBytes, err := stub.GetPrivateData("collectionMarbleFarming", marbleName)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get farming record: %s", err.Error())
+	} else if recordAsBytes == nil {
+		return nil, fmt.Errorf("Marble is not in the farm: %s", marbleName)
+	}
+
+	record := &FarmingRecord{}
+	err = json.Unmarshal(recordAsBytes, record)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to decode JSON of: %s", string(recordAsBytes))
+	}
+	return record, nil
+}
+
+func putFarmingRecord(stub shim.ChaincodeStubInterface, record *FarmingRecord) error {
+	recordAsBytes, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	return stub.PutPrivateData("collectionMarbleFarming", record.MarbleName, recordAsBytes)
+}
+
+func getFarmYieldTotal(stub shim.ChaincodeStubInterface, farmerMSPID string) (*farmYieldTotal, error) {
+	totalAsBytes, err := stub.GetPrivateData("collectionMarbleFarming", farmYieldKeyPrefix+farmerMSPID)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get farm yield: %s", err.Error())
+	}
+
+	total := &farmYieldTotal{ObjectType: "farmYieldTotal", FarmerMSPID: farmerMSPID}
+	if totalAsBytes == nil {
+		return total, nil
+	}
+	err = json.Unmarshal(totalAsBytes, total)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to decode JSON of: %s", string(totalAsBytes))
+	}
+	return total, nil
+}
+
+// harvest accrues the yield earned since the last deposit or harvest, adds it to the
+// farmer's total and restarts accrual from the current time. It returns the yield
+// harvested by this call.
+func harvest(stub shim.ChaincodeStubInterface, record *FarmingRecord) (float64, error) {
+	gov, err := getGovernance(stub)
+	if err != nil {
+		return 0, err
+	}
+	currentTime, err := getTxUnixTime(stub)
+	if err != nil {
+		return 0, err
+	}
+
+	harvested := float64(currentTime-record.DepositTime) * gov.FarmingYieldRate
+	record.YieldAccrued += harvested
+	record.DepositTime = currentTime
+
+	total, err := getFarmYieldTotal(stub, record.FarmerMSPID)
+	if err != nil {
+		return 0, err
+	}
+	total.TotalHarvested += harvested
+	totalAsBytes, err := json.Marshal(total)
+	if err != nil {
+		return 0, err
+	}
+	err = stub.PutPrivateData("collectionMarbleFarming", farmYieldKeyPrefix+total.FarmerMSPID, totalAsBytes)
+	if err != nil {
+		return 0, err
+	}
+	return harvested, nil
+}
+
+// requireFarmer fails unless the caller deposited the farmed marble.
+func requireFarmer(stub shim.ChaincodeStubInterface, record *FarmingRecord) error {
+	callerMSPID, err := getCallerMSPID(stub)
+	if err != nil {
+		return err
+	}
+	if callerMSPID != record.FarmerMSPID {
+		return fmt.Errorf("only the farmer %s can manage farmed marble %s", record.FarmerMSPID, record.MarbleName)
+	}
+	return nil
+}
+
+// ===========================================================================
+// depositToFarm - lock an owned marble in the farm and start accruing yield
+// ===========================================================================
+func (t *SimpleChaincode) depositToFarm(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	fmt.Println("- start deposit to farm")
+
+	type farmDepositTransientInput struct {
+		MarbleName string `json:"marbleName"`
+		YieldToken string `json:"yieldToken"`
+	}
+
+	if len(args) != 0 {
+		return shim.Error("Incorrect number of arguments. Private marble data must be passed in transient map.")
+	}
+
+	var depositInput farmDepositTransientInput
+	err := getTransientInput(stub, "farm_deposit", &depositInput)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if len(depositInput.MarbleName) == 0 {
+		return shim.Error("marbleName field must be a non-empty string")
+	}
+	if len(depositInput.YieldToken) == 0 {
+		return shim.Error("yieldToken field must be a non-empty string")
+	}
+
+	m, err := getMarble(stub, depositInput.MarbleName)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = requireOwner(stub, m)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = checkNotLocked(m)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if m.IsForSale {
+		return shim.Error("Marble is listed for sale: " + m.Name)
+	}
+
+	farmerMSPID, err := getCallerMSPID(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	currentTime, err := getTxUnixTime(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	m.LockedBy = farmLock
+	err = putMarble(stub, m)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = putFarmingRecord(stub, &FarmingRecord{
+		ObjectType:  "farmingRecord",
+		MarbleName:  m.Name,
+		FarmerMSPID: farmerMSPID,
+		DepositTime: currentTime,
+		YieldToken:  depositInput.YieldToken,
+	})
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	fmt.Println("- end deposit to farm")
+	return shim.Success(nil)
+}
+
+// ===========================================================================
+// harvestYield - collect the yield accrued by a farmed marble
+// ===========================================================================
+func (t *SimpleChaincode) harvestYield(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+
+	//   0
+	// "marble1"
+	if len(args) != 1 {
+		return shim.Error("Incorrect number of arguments. Expecting name of the farmed marble")
+	}
+
+	record, err := getFarmingRecord(stub, args[0])
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = requireFarmer(stub, record)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	harvested, err := harvest(stub, record)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = putFarmingRecord(stub, record)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	return shim.Success([]byte(fmt.Sprintf("{\"marbleName\":%q,\"harvested\":%g}", record.MarbleName, harvested)))
+}
+
+// ===========================================================================
+// withdrawFromFarm - harvest the final yield and unlock the marble
+// ===========================================================================
+func (t *SimpleChaincode) withdrawFromFarm(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+
+	//   0
+	// "marble1"
+	if len(args) != 1 {
+		return shim.Error("Incorrect number of arguments. Expecting name of the farmed marble")
+	}
+
+	record, err := getFarmingRecord(stub, args[0])
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = requireFarmer(stub, record)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	harvested, err := harvest(stub, record)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = stub.DelPrivateData("collectionMarbleFarming", record.MarbleName)
+	if err != nil {
+		return shim.Error("Failed to delete state:" + err.Error())
+	}
+
+	m, err := getMarble(stub, record.MarbleName)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	m.LockedBy = ""
+	err = putMarble(stub, m)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	return shim.Success([]byte(fmt.Sprintf("{\"marbleName\":%q,\"harvested\":%g,\"yieldAccrued\":%g}", record.MarbleName, harvested, record.YieldAccrued)))
+}
+
+// ===========================================================================
+// getTotalFarmYield - total yield harvested by a farmer organization
+// ===========================================================================
+func (t *SimpleChaincode) getTotalFarmYield(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+
+	//   0
+	// "Org1MSP"
+	if len(args) != 1 {
+		return shim.Error("Incorrect number of arguments. Expecting farmer MSP ID to query")
+	}
+
+	total, err := getFarmYieldTotal(stub, args[0])
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	totalAsBytes, err := json.Marshal(total)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	return shim.Success(totalAsBytes)
+}
+
+// ===========================================================================
+// getFarmingAPR - the governance yield rate, per second and annualized
+// ===========================================================================
+func (t *SimpleChaincode) getFarmingAPR(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	if len(args) != 0 {
+		return shim.Error("Incorrect number of arguments. Expecting 0")
+	}
+
+	gov, err := getGovernance(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	return shim.Success([]byte(fmt.Sprintf("{\"yieldRatePerSecond\":%g,\"apr\":%g}", gov.FarmingYieldRate, gov.FarmingYieldRate*secondsPerYear)))
+}